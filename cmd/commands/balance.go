@@ -15,7 +15,6 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -32,7 +31,6 @@ import (
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/account"
 	"github.com/sboehler/knut/lib/model/commodity"
-	"github.com/sboehler/knut/lib/model/registry"
 	"github.com/sboehler/knut/lib/reports/balance"
 
 	"github.com/spf13/cobra"
@@ -48,8 +46,8 @@ func CreateBalanceCommand() *cobra.Command {
 		Use:   "balance",
 		Short: "create a balance sheet",
 		Long:  `Compute a balance for a date or set of dates.`,
-		Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-		Run:   r.run,
+		Args:  flags.JournalArg,
+		Run:   flags.WithJournal(r.run),
 	}
 	r.setupFlags(c)
 	return c
@@ -57,24 +55,52 @@ func CreateBalanceCommand() *cobra.Command {
 
 type balanceRunner struct {
 	flags.Multiperiod
+	output flags.Output
 
 	// internal
 	cpuprofile string
+	explain    bool
+	watch      bool
+	cache      bool
 
 	// journal structure
-	close     bool
-	valuation flags.CommodityFlag
+	close         bool
+	strict        bool
+	valuation     flags.CommodityFlag
+	impliedPrices bool
+	effective     bool
+	virtual       bool
+	groupPostings bool
 
 	// mapping
 	mapping flags.MappingFlag
 	remap   flags.RegexFlag
 
 	// filters
-	accounts    flags.RegexFlag
-	commodities flags.RegexFlag
+	accounts           flags.RegexFlag
+	accountGlobs       flags.GlobFlag
+	commodities        flags.RegexFlag
+	excludeAccounts    flags.RegexFlag
+	excludeCommodities flags.RegexFlag
+	tags               flags.RegexFlag
+	where              flags.ExprFlag
+	groupTags          flags.RegexFlag
 
 	// report structure
 	diff               bool
+	flows              bool
+	subtotals          bool
+	onlyTotals         bool
+	annualize          bool
+	commodityTotal     bool
+	notes              bool
+	flat               bool
+	pivot              bool
+	transpose          bool
+	reverse            bool
+	collapseZero       bool
+	positiveOnly       bool
+	negativeOnly       bool
 	showCommodities    flags.RegexFlag
 	sortAlphabetically bool
 
@@ -95,49 +121,147 @@ func (r *balanceRunner) run(cmd *cobra.Command, args []string) {
 		defer pprof.StopCPUProfile()
 	}
 
+	if r.watch {
+		if err := watch(cmd, args[0], func() error { return r.execute(cmd, args) }); err != nil {
+			flags.PrintError(cmd, err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintf(cmd.ErrOrStderr(), "%+v\n", err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
 
 func (r *balanceRunner) setupFlags(c *cobra.Command) {
 	r.Multiperiod.Setup(c)
+	r.output.Setup(c)
 	c.Flags().StringVar(&r.cpuprofile, "cpuprofile", "", "file to write profile")
-	c.Flags().BoolVarP(&r.diff, "diff", "d", false, "diff")
+	c.Flags().BoolVar(&r.explain, "explain", false, "print what each processing stage did to stderr")
+	c.Flags().BoolVar(&r.watch, "watch", false, "re-run whenever a file of the journal changes")
+	c.Flags().BoolVar(&r.cache, "cache", false, "reuse the output of a previous run if neither the journal nor the options changed")
+	c.Flags().BoolVarP(&r.diff, "diff", "d", false, "show the changes within each period instead of cumulative balances")
+	c.Flags().BoolVar(&r.flows, "flows", false, "show the flows within each period for income and expenses, and cumulative balances otherwise")
 	c.Flags().BoolVarP(&r.csv, "csv", "", false, "csv")
+	c.Flags().BoolVar(&r.subtotals, "subtotals", false, "show a subtotal for each account type")
+	c.Flags().BoolVar(&r.commodityTotal, "commodity-total", false, "show the total of the assets and liabilities for each commodity")
+	c.Flags().BoolVar(&r.onlyTotals, "only-totals", false, "show only the total of each account type and the grand totals")
+	c.Flags().BoolVar(&r.annualize, "annualize", false, "scale income and expenses to a year, based on the length of each period")
+	c.Flags().BoolVar(&r.notes, "notes", false, "show the notes of the open directives")
+	c.Flags().BoolVar(&r.flat, "flat", false, "show full account names without hierarchy")
+	c.Flags().BoolVar(&r.pivot, "group-by-commodity", false, "show a column for each commodity, for the last period")
+	c.Flags().BoolVar(&r.transpose, "transpose", false, "show a row for each period and a column for each account")
+	c.Flags().BoolVar(&r.reverse, "reverse", false, "show the newest period first")
+	c.Flags().BoolVar(&r.collapseZero, "collapse-zero", false, "hide accounts which are zero in all periods")
+	c.Flags().BoolVar(&r.positiveOnly, "positive-only", false, "show only rows whose amount in the last period is positive")
+	c.Flags().BoolVar(&r.negativeOnly, "negative-only", false, "show only rows whose amount in the last period is negative")
+	c.MarkFlagsMutuallyExclusive("positive-only", "negative-only")
 	c.Flags().BoolVar(&r.close, "close", true, "close")
+	c.Flags().BoolVar(&r.strict, "strict", false, "fail if the period lies outside of the journal")
 	c.Flags().BoolVarP(&r.sortAlphabetically, "sort", "a", false, "Sort accounts alphabetically")
 	c.Flags().VarP(&r.showCommodities, "show-commodities", "s", "<regex>")
 	c.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	c.Flags().BoolVar(&r.impliedPrices, "implied-prices", false, "derive prices from transactions exchanging two commodities")
+	c.Flags().BoolVar(&r.effective, "effective", false, "use the effective dates of transactions instead of their booking dates")
+	c.Flags().BoolVar(&r.virtual, "virtual", false, "include virtual postings")
+	c.Flags().BoolVar(&r.groupPostings, "group-postings", false, "merge the bookings of each transaction which move the same commodity between the same accounts")
 	c.Flags().VarP(&r.mapping, "map", "m", "<level>,<regex>")
 	c.Flags().VarP(&r.remap, "remap", "r", "<regex>")
 	c.Flags().Var(&r.accounts, "account", "filter accounts with a regex")
 	c.Flags().Var(&r.commodities, "commodity", "filter commodities with a regex")
+	r.accounts.AddModifiers(c, "account")
+	c.Flags().Var(&r.accountGlobs, "account-glob", "filter accounts with a glob pattern, e.g. Assets:Bank:* or Expenses:**")
+	r.commodities.AddModifiers(c, "commodity")
+	c.Flags().Var(&r.excludeAccounts, "exclude-account", "exclude accounts matching a regex")
+	c.Flags().Var(&r.excludeCommodities, "exclude-commodity", "exclude commodities matching a regex")
+	c.Flags().Var(&r.tags, "tag", "filter postings by tag with a regex")
+	c.Flags().Var(&r.groupTags, "group-tag", "show a row for each tag matching a regex")
+	c.Flags().Var(&r.where, "where", "filter postings with an expression, e.g. \"account =~ 'Expenses' and amount > 100\"")
 	c.Flags().Int32Var(&r.digits, "digits", 0, "round to number of digits")
 	c.Flags().BoolVarP(&r.thousands, "thousands", "k", false, "show numbers in units of 1000")
 	c.Flags().BoolVar(&r.color, "color", true, "print output in color")
 }
 
 func (r balanceRunner) execute(cmd *cobra.Command, args []string) error {
-	reg := registry.New()
+	return r.output.Write(cmd, func(w io.Writer) error {
+		if r.cache {
+			return cached(cmd, args, args[0], w, func(w io.Writer) error {
+				return r.render(cmd, args, w)
+			})
+		}
+		return r.render(cmd, args, w)
+	})
+}
+
+// checkAnnualize checks that the income and expenses of each period are
+// the flows within that period, which annualizing requires.
+func (r balanceRunner) checkAnnualize() error {
+	if !r.annualize || r.flows {
+		return nil
+	}
+	if r.diff && r.close {
+		// The differences of closed accounts are those between the
+		// flows of consecutive periods.
+		return fmt.Errorf("--annualize with --diff requires --flows or --close=false")
+	}
+	if !r.diff && !r.close {
+		// Without closing, income and expenses accumulate over periods.
+		return fmt.Errorf("--annualize requires --close, --diff or --flows")
+	}
+	return nil
+}
+
+func (r balanceRunner) render(cmd *cobra.Command, args []string, w io.Writer) error {
+	if err := r.checkAnnualize(); err != nil {
+		return err
+	}
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 	valuation, err := r.valuation.Value(reg)
 	if err != nil {
 		return err
 	}
-	j, err := journal.FromPath(cmd.Context(), reg, args[0])
+	j, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
 	if err != nil {
 		return err
 	}
+	flags.ResolvePrices(cmd, j)
+	if r.effective {
+		j.UseEffectiveDates()
+	}
+	if err := r.Multiperiod.Check(j.Period()); err != nil {
+		if r.strict {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+	}
 	partition := r.Multiperiod.Partition(j.Period())
 	report := balance.NewReport(reg, partition)
-	procs := []*journal.Processor{
-		check.Check(),
-		journal.ComputePrices(valuation),
-		journal.Valuate(reg, valuation),
-		journal.Filter(partition),
-		journal.CloseAccounts(j, reg, r.close, partition),
-		journal.Query{
+	var notes map[*model.Account]string
+	if r.notes {
+		notes = make(map[*model.Account]string)
+	}
+	var explainer *journal.Explainer
+	if r.explain {
+		explainer = new(journal.Explainer)
+	}
+	pipeline := journal.Pipeline{
+		Context: journal.PipelineContext{Registry: reg, Valuation: valuation, Partition: partition},
+	}
+	pipeline.
+		Add("check", check.Check()).
+		Add("group", journal.GroupPostings(r.groupPostings)).
+		Add("notes", collectNotes(notes)).
+		Add("implied", journal.ImplyPrices(r.impliedPrices && valuation != nil)).
+		Add("prices", journal.ComputePrices(valuation)).
+		Add("valuate", journal.Valuate(reg, valuation)).
+		Add("filter", journal.Filter(partition)).
+		// Closing entries would show up as flows in the following period.
+		Add("close", journal.CloseAccounts(j, reg, r.close && !r.flows, partition)).
+		Add("query", journal.Query{
 			Select: amounts.KeyMapper{
 				Date: partition.Align(),
 				Account: mapper.Sequence(
@@ -146,23 +270,52 @@ func (r balanceRunner) execute(cmd *cobra.Command, args []string) error {
 				),
 				Commodity: mapper.Identity[*model.Commodity],
 				Valuation: commodity.IdentityIf(valuation != nil),
+				Tag:       mapper.IdentityIf[model.Tag](len(r.groupTags.Regex()) > 0),
 			}.Build(),
 			Where: predicate.And(
-				amounts.AccountMatches(r.accounts.Regex()),
+				amounts.AccountMatches(append(r.accounts.Regex(), r.accountGlobs.Regex()...)),
 				amounts.CommodityMatches(r.commodities.Regex()),
+				amounts.AccountExcludes(r.excludeAccounts.Regex()),
+				amounts.CommodityExcludes(r.excludeCommodities.Regex()),
 			),
 			Valuation: valuation,
-		}.Into(report),
+			Tags:      r.tags.Regex(),
+			Filter:    r.where.Value(),
+			GroupTags: r.groupTags.Regex(),
+			Virtual:   r.virtual,
+		}.Into(report))
+	procs, err := pipeline.Build(explainer)
+	if err != nil {
+		return err
 	}
 	err = j.Build().Process(procs...)
 	if err != nil {
 		return err
 	}
+	if explainer != nil {
+		if err := explainer.Report(cmd.ErrOrStderr()); err != nil {
+			return err
+		}
+	}
 	reportRenderer := balance.Renderer{
 		Valuation:          valuation,
 		CommodityDetails:   r.showCommodities.Regex(),
 		SortAlphabetically: r.sortAlphabetically,
 		Diff:               r.diff,
+		Flows:              r.flows,
+		Subtotals:          r.subtotals,
+		OnlyTotals:         r.onlyTotals,
+		Annualize:          r.annualize,
+		CommodityTotals:    r.commodityTotal,
+		Notes:              notes,
+		Flat:               r.flat,
+		Pivot:              r.pivot,
+		Transpose:          r.transpose,
+		Reverse:            r.reverse,
+		CollapseZero:       r.collapseZero,
+		PositiveOnly:       r.positiveOnly,
+		NegativeOnly:       r.negativeOnly,
+		Tags:               len(r.groupTags.Regex()) > 0,
 	}
 	var tableRenderer Renderer
 	if r.csv {
@@ -174,11 +327,23 @@ func (r balanceRunner) execute(cmd *cobra.Command, args []string) error {
 			Round:     r.digits,
 		}
 	}
-	out := bufio.NewWriter(cmd.OutOrStdout())
-	defer out.Flush()
-	return tableRenderer.Render(reportRenderer.Render(report), out)
+	return tableRenderer.Render(reportRenderer.Render(report), w)
 }
 
 type Renderer interface {
 	Render(*table.Table, io.Writer) error
 }
+
+func collectNotes(notes map[*model.Account]string) *journal.Processor {
+	if notes == nil {
+		return nil
+	}
+	return &journal.Processor{
+		Open: func(o *model.Open) error {
+			if o.Note != "" {
+				notes[o.Account] = o.Note
+			}
+			return nil
+		},
+	}
+}
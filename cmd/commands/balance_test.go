@@ -0,0 +1,66 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckAnnualize(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		runner  balanceRunner
+		wantErr string
+	}{
+		{
+			desc:   "close",
+			runner: balanceRunner{annualize: true, close: true},
+		},
+		{
+			desc:   "flows",
+			runner: balanceRunner{annualize: true, close: true, diff: true, flows: true},
+		},
+		{
+			desc:   "diff without close",
+			runner: balanceRunner{annualize: true, diff: true},
+		},
+		{
+			desc:    "diff with close",
+			runner:  balanceRunner{annualize: true, close: true, diff: true},
+			wantErr: "--annualize with --diff requires --flows or --close=false",
+		},
+		{
+			desc:    "without close",
+			runner:  balanceRunner{annualize: true},
+			wantErr: "--annualize requires --close, --diff or --flows",
+		},
+		{
+			desc:   "not annualized",
+			runner: balanceRunner{close: true, diff: true},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var got string
+			if err := test.runner.checkAnnualize(); err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(test.wantErr, got); diff != "" {
+				t.Errorf("checkAnnualize() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
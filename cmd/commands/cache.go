@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// cached writes the output of f to w. With caching, the output is stored
+// on disk and replayed as long as neither the journal at path, nor any file
+// which it includes, nor the arguments and flags of the command change.
+//
+// An entry is stored under a hash of the command line and starts with a
+// hash of the content of all journal files. A change to a file therefore
+// invalidates the entry, which the next run overwrites. The warnings which
+// f prints to the error output of the command are stored with the output
+// and printed again when the entry is replayed.
+func cached(cmd *cobra.Command, args []string, path string, w io.Writer, f func(io.Writer) error) error {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return err
+	}
+	dir = filepath.Join(dir, "knut")
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%x", commandHash(cmd, args, abs)))
+	sum, err := contentHash(cmd, path)
+	if err != nil {
+		// The journal can not be read, let f report the error.
+		return f(w)
+	}
+	if entry, err := os.ReadFile(name); err == nil && bytes.HasPrefix(entry, sum) {
+		if warnings, output, ok := splitEntry(entry[len(sum):]); ok {
+			if _, err := cmd.ErrOrStderr().Write(warnings); err != nil {
+				return err
+			}
+			_, err := w.Write(output)
+			return err
+		}
+	}
+	var buf, warnings bytes.Buffer
+	stderr := cmd.ErrOrStderr()
+	cmd.SetErr(io.MultiWriter(stderr, &warnings))
+	err = f(&buf)
+	cmd.SetErr(stderr)
+	if err != nil {
+		return err
+	}
+	entry := binary.AppendUvarint(sum, uint64(warnings.Len()))
+	entry = append(entry, warnings.Bytes()...)
+	if err := writeEntry(dir, name, append(entry, buf.Bytes()...)); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: failed to write cache: %v\n", err)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// splitEntry splits an entry, after the hash of the content, into the
+// warnings and the output.
+func splitEntry(entry []byte) ([]byte, []byte, bool) {
+	n, k := binary.Uvarint(entry)
+	if k <= 0 || uint64(len(entry)-k) < n {
+		return nil, nil, false
+	}
+	entry = entry[k:]
+	return entry[:n], entry[n:], true
+}
+
+// cacheFormat is the version of the format of the entries. Entries of
+// other versions are not read.
+const cacheFormat = 2
+
+// commandHash hashes everything which determines the output of a command
+// apart from the journal: the version, the command, its arguments and
+// flags, and today's date, which is the default end of reports.
+func commandHash(cmd *cobra.Command, args []string, path string) []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%q\n%q\n%q\n", cacheFormat, cmd.Root().Version, cmd.CommandPath(), path)
+	for _, arg := range args {
+		fmt.Fprintf(h, "%q\n", arg)
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		fmt.Fprintf(h, "%q=%q\n", f.Name, f.Value.String())
+	})
+	fmt.Fprintf(h, "%s\n", date.Today().Format("2006-01-02"))
+	return h.Sum(nil)
+}
+
+// contentHash hashes the paths and contents of the journal at path and of
+// all files which it includes, recursively. The files are parsed
+// concurrently, so the paths are sorted to hash them in a fixed order.
+func contentHash(cmd *cobra.Command, path string) ([]byte, error) {
+	paths, err := syntax.Files(cmd.Context(), path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	h := sha256.New()
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(h, "%q %d\n", p, len(content))
+		h.Write(content)
+	}
+	return h.Sum(nil), nil
+}
+
+// writeEntry writes an entry atomically, such that concurrent runs never
+// read a partial entry.
+func writeEntry(dir, name string, entry []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(entry); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
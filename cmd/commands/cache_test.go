@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/knut/cmd/cmdtest"
+)
+
+func TestBalanceCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	journal, prices := filepath.Join(dir, "journal.knut"), filepath.Join(dir, "prices.knut")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(journal, strings.Join([]string{
+		`include "prices.knut"`,
+		`2020-01-01 open Assets:Bank`,
+		`2020-01-01 open Equity:Equity`,
+		`2020-01-01 "Deposit"`,
+		`Equity:Equity Assets:Bank 100 USD`,
+	}, "\n"))
+	write(prices, "2020-01-01 price USD 0.9 CHF\n")
+	balance := func(args ...string) string {
+		t.Helper()
+		args = append([]string{"--cache", "--color=false", "--to", "2020-01-31"}, args...)
+		return string(cmdtest.Run(t, CreateBalanceCommand(), append(args, journal)...))
+	}
+
+	uncached := balance("-v", "CHF")
+	if !strings.Contains(uncached, "90") {
+		t.Fatalf("balance() = %s, want a value of 90", uncached)
+	}
+	if got := balance("-v", "CHF"); got != uncached {
+		t.Errorf("balance() from cache = %s, want %s", got, uncached)
+	}
+	if got := balance(); strings.Contains(got, "90") {
+		t.Errorf("balance() without valuation = %s, want no value of 90", got)
+	}
+
+	// A change to an included file invalidates the entry.
+	write(prices, "2020-01-01 price USD 0.8 CHF\n")
+	if got := balance("-v", "CHF"); !strings.Contains(got, "80") {
+		t.Errorf("balance() after changing prices = %s, want a value of 80", got)
+	}
+}
+
+func TestBalanceCacheWarnings(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal.knut")
+	for path, content := range map[string]string{
+		journal: strings.Join([]string{
+			`include "prices.knut"`,
+			`include "prices.knut"`,
+			`2020-01-01 open Assets:Bank`,
+		}, "\n"),
+		filepath.Join(dir, "prices.knut"): "2020-01-01 price USD 0.9 CHF\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	balance := func() (string, string) {
+		t.Helper()
+		cmd := CreateBalanceCommand()
+		var stderr bytes.Buffer
+		cmd.SetErr(&stderr)
+		out := cmdtest.Run(t, cmd, "--cache", "--color=false", "--to", "2020-01-31", journal)
+		return string(out), stderr.String()
+	}
+
+	out, warnings := balance()
+	if !strings.Contains(warnings, "has already been included") {
+		t.Fatalf("balance() printed warnings %q, want a warning about the duplicate include", warnings)
+	}
+	if gotOut, gotWarnings := balance(); gotOut != out || gotWarnings != warnings {
+		t.Errorf("balance() from cache = %q with warnings %q, want %q with warnings %q", gotOut, gotWarnings, out, warnings)
+	}
+}
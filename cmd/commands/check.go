@@ -19,10 +19,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/sboehler/knut/cmd/flags"
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/journal/check"
 	"github.com/sboehler/knut/lib/model"
-	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
 
 	"github.com/spf13/cobra"
 )
@@ -37,22 +38,31 @@ func CreateCheckCommand() *cobra.Command {
 		Use:   "check",
 		Short: "check the journal",
 		Long:  `Check the journal.`,
-		Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-		Run:   r.run,
+		Args:  flags.JournalArg,
+		Run:   flags.WithJournal(r.run),
 	}
 	r.setupFlags(c)
 	return c
 }
 
 type checkRunner struct {
-	write   bool
-	noCheck bool
+	write         bool
+	noCheck       bool
+	ignorePending bool
+	assert        flags.DateFlag
+	skipZero      bool
+	allErrors     bool
+	valuation     flags.CommodityFlag
+	maxResidual   float64
+	maxAge        int
+	assertValue   bool
+	tolerance     float64
 }
 
 func (r *checkRunner) run(cmd *cobra.Command, args []string) {
 
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err.Error())
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
@@ -60,27 +70,92 @@ func (r *checkRunner) run(cmd *cobra.Command, args []string) {
 func (r *checkRunner) setupFlags(c *cobra.Command) {
 	c.Flags().BoolVar(&r.write, "write", false, "create a complete set of assertions")
 	c.Flags().BoolVar(&r.noCheck, "no-check", false, "do not check assertions")
+	c.Flags().BoolVar(&r.ignorePending, "ignore-pending", false, "ignore pending postings in assertions")
+	c.Flags().Var(&r.assert, "assert", "create assertions for all balances as of the given date")
+	c.Flags().BoolVar(&r.skipZero, "skip-zero", false, "omit zero balances from created assertions")
+	c.Flags().BoolVar(&r.allErrors, "all-errors", false, "report all parse errors instead of stopping at the first")
+	c.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	c.Flags().Float64Var(&r.maxResidual, "max-residual", 0, "check trades for value residuals larger than the given fraction of the exchanged value, e.g. 0.05 (requires --val)")
+	c.Flags().BoolVar(&r.assertValue, "assert-value", false, "check assertions in the --val commodity against the value of the account")
+	c.Flags().Float64Var(&r.tolerance, "tolerance", 0.01, "maximum difference between an asserted and the actual value")
+	c.Flags().IntVar(&r.maxAge, "max-age", 0, "warn about accounts whose last assertion is more than the given number of days older than their last posting")
 }
 
 func (r *checkRunner) execute(cmd *cobra.Command, args []string) error {
-	reg := registry.New()
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 
-	j, err := journal.FromPath(cmd.Context(), reg, args[0])
+	var j *journal.Builder
+	if r.allErrors {
+		j, err = journal.FromPathAllErrors(reg, args[0], flags.Resolver(cmd))
+	} else {
+		j, err = journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	}
 	if err != nil {
 		return err
 	}
+	flags.ResolvePrices(cmd, j)
+	valuation, err := r.valuation.Value(reg)
+	if err != nil {
+		return err
+	}
+	if r.assertValue && valuation == nil {
+		return fmt.Errorf("--assert-value requires --val")
+	}
+	if r.maxResidual > 0 && valuation == nil {
+		return fmt.Errorf("--max-residual requires --val")
+	}
 	checker := check.Checker{
-		Write:   r.write,
-		NoCheck: r.noCheck,
+		Write:         r.write,
+		NoCheck:       r.noCheck,
+		IgnorePending: r.ignorePending,
+		AssertOn:      r.assert.Value(),
+		SkipZero:      r.skipZero,
+	}
+	var valueCheck *journal.Processor
+	if r.assertValue && !r.noCheck {
+		checker.Valued = valuation
+		valueCheck = (&check.ValueAssertions{
+			Valuation: valuation,
+			Tolerance: decimal.NewFromFloat(r.tolerance),
+		}).Process()
+	}
+	residuals := &check.Residuals{
+		Equity:    reg.Accounts().EquityAccount(),
+		Valuation: valuation,
+		Tolerance: decimal.NewFromFloat(r.maxResidual),
+	}
+	var residualCheck *journal.Processor
+	if r.maxResidual > 0 {
+		residualCheck = residuals.Process()
+	}
+	stale := &check.StaleAssertions{MaxAge: r.maxAge}
+	var staleCheck *journal.Processor
+	if r.maxAge > 0 {
+		staleCheck = stale.Process()
 	}
-
 	err = j.Build().Process(
+		journal.ComputePrices(valuation),
 		checker.Check(),
+		staleCheck,
+		journal.Valuate(reg, valuation),
+		valueCheck,
+		residualCheck,
 	)
 	if err != nil {
 		return err
 	}
-	if r.write {
+	if staleCheck != nil {
+		for _, s := range stale.Stale() {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", s)
+		}
+	}
+	if err := residuals.Err(); err != nil {
+		return err
+	}
+	if r.write || !r.assert.Value().IsZero() {
 		out := bufio.NewWriter(os.Stdout)
 		defer out.Flush()
 		return r.writeFile(checker.Assertions())
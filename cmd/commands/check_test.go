@@ -0,0 +1,38 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckMaxAgeWithValuation(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	c := CreateCheckCommand()
+	c.SetArgs([]string{"--max-age", "30", "--val", "CHF", "testdata/check/stale.knut"})
+	c.SetOut(&stdout)
+	c.SetErr(&stderr)
+
+	if err := c.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The valuation books a gain into Assets:Bank on 2020-06-01, which is
+	// not a posting of the journal.
+	if got := stderr.String(); got != "" {
+		t.Errorf("check returned unexpected warnings:\n%s", got)
+	}
+}
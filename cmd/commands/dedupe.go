@@ -0,0 +1,175 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/dedupe"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/sboehler/knut/lib/syntax"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateDedupeCommand creates the command.
+func CreateDedupeCommand() *cobra.Command {
+
+	var r dedupeRunner
+
+	// Cmd is the dedupe command.
+	c := &cobra.Command{
+		Use:   "dedupe",
+		Short: "detect duplicate transactions",
+		Long: `Detect likely duplicate transactions, i.e. transactions which book the same amounts
+within a few days and have similar descriptions.`,
+		Args: flags.JournalArg,
+		Run:  flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type dedupeRunner struct {
+	window    int
+	threshold float64
+	write     bool
+}
+
+func (r *dedupeRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *dedupeRunner) setupFlags(c *cobra.Command) {
+	c.Flags().IntVar(&r.window, "window", 3, "maximum number of days between duplicates")
+	c.Flags().Float64Var(&r.threshold, "threshold", 0.8, "minimum similarity of the descriptions, between 0 and 1")
+	c.Flags().BoolVar(&r.write, "write", false, "remove the duplicates from the files of the journal, in place")
+}
+
+func (r *dedupeRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	j := b.Build()
+	detector := dedupe.Detector{
+		Window:    r.window,
+		Threshold: r.threshold,
+	}
+	if err := j.Process(journal.Sort(), detector.Detect()); err != nil {
+		return err
+	}
+	if r.write {
+		return r.writeFiles(cmd, j, &detector)
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	for _, d := range detector.Duplicates() {
+		_, err := fmt.Fprintf(out, "%s: %q duplicates %s: %q (similarity %.2f)\n",
+			position(d.Duplicate), d.Duplicate.Description,
+			position(d.Original), d.Original.Description,
+			d.Similarity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFiles removes the duplicates from the files of the journal, in
+// place. Duplicates which do not stem from a transaction directive of
+// their own, such as those created by accruals or templates, are kept.
+func (r *dedupeRunner) writeFiles(cmd *cobra.Command, j *journal.Journal, detector *dedupe.Detector) error {
+	type source struct {
+		path  string
+		start int
+	}
+	count := make(map[source]int)
+	for _, d := range j.Days {
+		for _, t := range d.Transactions {
+			if t.Src != nil {
+				count[source{t.Src.Path, t.Src.Start}]++
+			}
+		}
+	}
+	var (
+		kept       []*model.Transaction
+		duplicates = make(map[source][]*model.Transaction)
+	)
+	for _, d := range detector.Duplicates() {
+		if d.Duplicate.Src == nil {
+			kept = append(kept, d.Duplicate)
+			continue
+		}
+		src := source{d.Duplicate.Src.Path, d.Duplicate.Src.Start}
+		duplicates[src] = append(duplicates[src], d.Duplicate)
+	}
+	paths := make(map[string][]*syntax.Transaction)
+	for src, ts := range duplicates {
+		if len(ts) < count[src] {
+			kept = append(kept, ts...)
+			continue
+		}
+		paths[src.path] = append(paths[src.path], ts[0].Src)
+	}
+	for _, path := range dict.SortedKeys(paths, compare.Ordered[string]) {
+		f, err := syntax.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		text, rest := dedupe.Remove(f, paths[path])
+		for _, t := range rest {
+			kept = append(kept, duplicates[source{t.Path, t.Start}]...)
+		}
+		if len(rest) == len(paths[path]) {
+			continue
+		}
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s: removed %d duplicates\n", path, len(paths[path])-len(rest)); err != nil {
+			return err
+		}
+		if err := syntax.WriteFile(path, strings.NewReader(text)); err != nil {
+			return err
+		}
+	}
+	compare.Sort(kept, transaction.Compare)
+	for _, t := range kept {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: kept the duplicate %q at %s, which is not a transaction directive of its own\n", t.Description, position(t))
+	}
+	return nil
+}
+
+func position(t *model.Transaction) string {
+	if t.Src == nil {
+		return "<generated>"
+	}
+	rng := t.Src.Range
+	rng.End = rng.Start
+	return fmt.Sprintf("%s:%s", rng.Path, rng.Location())
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/cmd/cmdtest"
+)
+
+func TestDedupeWrite(t *testing.T) {
+	dir := t.TempDir()
+	main, other := filepath.Join(dir, "main.knut"), filepath.Join(dir, "other.knut")
+	mainText := strings.Join([]string{
+		`include "other.knut"`,
+		``,
+		`* Accounts`,
+		`2020-01-01 open Assets:Bank`,
+		`2020-01-01 open Expenses:Food`,
+		``,
+		`template food amount "Migros"`,
+		`Assets:Bank Expenses:Food $amount CHF`,
+		``,
+		`2020-01-02 "Coop"`,
+		`Assets:Bank Expenses:Food 10 CHF`,
+		``,
+		`2020-01-10 "Migros"`,
+		`Assets:Bank Expenses:Food 20 CHF`,
+		``,
+		`2020-01-11 apply food 20`,
+		``,
+		`2020-01-11 "Migros"`,
+		`Assets:Bank Expenses:Food 20 CHF`,
+		``,
+	}, "\n")
+	for path, content := range map[string]string{
+		main: mainText,
+		other: strings.Join([]string{
+			`* Imported`,
+			`2020-01-03 "Coop"`,
+			`Assets:Bank Expenses:Food 10 CHF`,
+			``,
+			`2020-01-20 "Rent"`,
+			`Assets:Bank Expenses:Food 1000 CHF`,
+			``,
+		}, "\n"),
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := CreateDedupeCommand()
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+
+	out := cmdtest.Run(t, cmd, "--write", main)
+
+	if want := main + ": removed 1 duplicates\n" + other + ": removed 1 duplicates\n"; string(out) != want {
+		t.Errorf("dedupe --write printed %q, want %q", out, want)
+	}
+	if want := "warning: kept the duplicate \"Migros\" at " + main + ":16:1"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("dedupe --write printed warnings %q, want a warning about the applied template", stderr.String())
+	}
+	for path, want := range map[string]string{
+		main: strings.Replace(mainText, "\n2020-01-11 \"Migros\"\nAssets:Bank Expenses:Food 20 CHF\n", "", 1),
+		other: strings.Join([]string{
+			`* Imported`,
+			`2020-01-20 "Rent"`,
+			`Assets:Bank Expenses:Food 1000 CHF`,
+			``,
+		}, "\n"),
+	} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("%s: dedupe --write returned unexpected diff (-want/+got):\n%s\n", filepath.Base(path), diff)
+		}
+	}
+}
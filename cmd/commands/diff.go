@@ -0,0 +1,141 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/diff"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateDiffCommand creates the command.
+func CreateDiffCommand() *cobra.Command {
+
+	var r diffRunner
+
+	// Cmd is the diff command.
+	c := &cobra.Command{
+		Use:   "diff",
+		Short: "compare two journals",
+		Long:  `Compare two journals and report the directives which were added, removed or changed, grouped by date.`,
+		Args:  cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+		Run:   r.run,
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type diffRunner struct {
+	csv bool
+}
+
+func (r *diffRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *diffRunner) setupFlags(c *cobra.Command) {
+	c.Flags().BoolVar(&r.csv, "csv", false, "print the changes as CSV")
+}
+
+func (r *diffRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	from, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	to, err := journal.FromPathWith(cmd.Context(), reg, args[1], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	changes, err := diff.Compare(from.Build(), to.Build())
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	if r.csv {
+		return r.writeCSV(out, changes)
+	}
+	return r.writeText(out, changes)
+}
+
+func (r *diffRunner) writeText(w io.Writer, changes []diff.Change) error {
+	var current time.Time
+	for _, c := range changes {
+		if c.Date != current {
+			if !current.IsZero() {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "=== %s\n", c.Date.Format("2006-01-02")); err != nil {
+				return err
+			}
+			current = c.Date
+		}
+		if _, err := fmt.Fprintf(w, "%s %s\n", c.Kind, c.Identity); err != nil {
+			return err
+		}
+		if err := writePrefixed(w, "- ", c.Old); err != nil {
+			return err
+		}
+		if err := writePrefixed(w, "+ ", c.New); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePrefixed(w io.Writer, prefix, text string) error {
+	if len(text) == 0 {
+		return nil
+	}
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if _, err := fmt.Fprintf(w, "%s%s\n", prefix, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *diffRunner) writeCSV(w io.Writer, changes []diff.Change) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "change", "directive", "old", "new"}); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if err := cw.Write([]string{c.Date.Format("2006-01-02"), c.Kind.String(), c.Identity, c.Old, c.New}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
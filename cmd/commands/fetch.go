@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/sboehler/knut/cmd/flags"
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/price"
@@ -32,7 +33,6 @@ import (
 	"go.uber.org/multierr"
 
 	"github.com/cheggaaa/pb/v3"
-	"github.com/natefinch/atomic"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 )
@@ -55,7 +55,7 @@ type fetchRunner struct{}
 
 func (r *fetchRunner) run(cmd *cobra.Command, args []string) {
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintln(cmd.ErrOrStderr(), err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
@@ -63,7 +63,10 @@ func (r *fetchRunner) run(cmd *cobra.Command, args []string) {
 const fetchConcurrency = 5
 
 func (r *fetchRunner) execute(cmd *cobra.Command, args []string) error {
-	reg := registry.New()
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 	configs, err := r.readConfig(args[0])
 	if err != nil {
 		return err
@@ -168,7 +171,7 @@ func (r *fetchRunner) writeFile(prices map[time.Time]*model.Price, filepath stri
 	if err != nil {
 		return err
 	}
-	return atomic.WriteFile(filepath, &buf)
+	return syntax.WriteFile(filepath, &buf)
 }
 
 type fetchConfig struct {
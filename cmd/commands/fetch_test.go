@@ -0,0 +1,65 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/shopspring/decimal"
+)
+
+func TestFetchWriteFileCompressed(t *testing.T) {
+	reg := registry.New()
+	path := filepath.Join(t.TempDir(), "prices.knut.gz")
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte("2020-01-01 price USD 0.9 CHF\n"))
+	w.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := date.Date(2020, 1, 2)
+	prices := map[time.Time]*model.Price{
+		d: {Date: d, Commodity: reg.Commodities().MustGet("USD"), Target: reg.Commodities().MustGet("CHF"), Price: decimal.RequireFromString("0.8")},
+	}
+
+	if err := new(fetchRunner).writeFile(prices, path); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gzip.NewReader(bytes.NewReader(raw)); err != nil {
+		t.Fatalf("writeFile() did not keep the file compressed: %v", err)
+	}
+	text, err := syntax.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2020-01-02 price USD 0.8 CHF\n\n"; string(text) != want {
+		t.Errorf("writeFile() wrote %q, want %q", text, want)
+	}
+}
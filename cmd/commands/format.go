@@ -16,14 +16,13 @@ package commands
 
 import (
 	"bytes"
-	"fmt"
 	"os"
 
-	"github.com/natefinch/atomic"
 	"github.com/sourcegraph/conc/iter"
 	"github.com/spf13/cobra"
 	"go.uber.org/multierr"
 
+	"github.com/sboehler/knut/cmd/flags"
 	"github.com/sboehler/knut/lib/syntax"
 )
 
@@ -43,7 +42,7 @@ type formatRunner struct{}
 
 func (r formatRunner) run(cmd *cobra.Command, args []string) {
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintln(cmd.ErrOrStderr(), err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
@@ -61,5 +60,5 @@ func (formatRunner) formatFile(target *string) error {
 	if err := syntax.FormatFile(&dest, file); err != nil {
 		return err
 	}
-	return atomic.WriteFile(*target, &dest)
+	return syntax.WriteFile(*target, &dest)
 }
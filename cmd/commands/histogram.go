@@ -0,0 +1,127 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/check"
+	"github.com/sboehler/knut/lib/reports/histogram"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+// CreateExpensesHistogramCommand creates the command.
+func CreateExpensesHistogramCommand() *cobra.Command {
+	var r expensesHistogramRunner
+	c := &cobra.Command{
+		Use:   "expenses-histogram",
+		Short: "bucket expenses by amount",
+		Long:  `Count and sum the expenses of transactions in buckets by amount, to show the distribution of spending.`,
+		Args:  flags.JournalArg,
+		Run:   flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type expensesHistogramRunner struct {
+	period    flags.PeriodFlag
+	valuation flags.CommodityFlag
+	accounts  flags.RegexFlag
+	edges     []float64
+
+	// formatting
+	thousands, color bool
+	digits           int32
+	output           flags.Output
+}
+
+func (r *expensesHistogramRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *expensesHistogramRunner) setupFlags(c *cobra.Command) {
+	r.period.Setup(c, date.Period{End: date.Today()})
+	c.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	c.Flags().Var(&r.accounts, "account", "filter expense accounts with a regex")
+	r.accounts.AddModifiers(c, "account")
+	c.Flags().Float64SliceVar(&r.edges, "edges", []float64{10, 50, 100}, "the bounds between the buckets")
+	c.Flags().Int32Var(&r.digits, "digits", 0, "round to number of digits")
+	c.Flags().BoolVarP(&r.thousands, "thousands", "k", false, "show numbers in units of 1000")
+	c.Flags().BoolVar(&r.color, "color", true, "print output in color")
+	r.output.Setup(c)
+}
+
+func (r *expensesHistogramRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	valuation, err := r.valuation.Value(reg)
+	if err != nil {
+		return err
+	}
+	rep := &histogram.Report{Valuation: valuation, Accounts: r.accounts.Regex()}
+	for _, e := range r.edges {
+		if e <= 0 {
+			return fmt.Errorf("invalid edge %v, edges must be positive", e)
+		}
+		rep.Edges = append(rep.Edges, decimal.NewFromFloat(e))
+	}
+	slices.SortFunc(rep.Edges, func(e1, e2 decimal.Decimal) int { return e1.Cmp(e2) })
+	rep.Edges = slices.CompactFunc(rep.Edges, decimal.Decimal.Equal)
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	flags.ResolvePrices(cmd, b)
+	partition := date.NewPartition(r.period.Value().Clip(b.Period()), date.Once, 0)
+	pipeline := journal.Pipeline{
+		Context: journal.PipelineContext{Registry: reg, Valuation: valuation, Partition: partition},
+	}
+	pipeline.
+		Add("sort", journal.Sort()).
+		Add("prices", journal.ComputePrices(valuation)).
+		Add("check", check.Check()).
+		Add("valuate", journal.Valuate(reg, valuation)).
+		Add("filter", journal.Filter(partition)).
+		Add("histogram", rep.Process())
+	procs, err := pipeline.Build(nil)
+	if err != nil {
+		return err
+	}
+	if err := b.Build().Process(procs...); err != nil {
+		return err
+	}
+	tableRenderer := table.TextRenderer{
+		Color:     r.color,
+		Thousands: r.thousands,
+		Round:     r.digits,
+	}
+	return r.output.Write(cmd, func(w io.Writer) error {
+		return tableRenderer.Render(histogram.Renderer{}.Render(rep), w)
+	})
+}
@@ -25,6 +25,8 @@ func CreateImportCommand() *cobra.Command {
 		Use:   "import",
 		Short: "Import financial account statements",
 	}
+	importer.SetupDedupe(&cmd)
+	importer.SetupSplit(&cmd)
 	for _, constructor := range importer.GetImporters() {
 		cmd.AddCommand(constructor())
 	}
@@ -19,15 +19,19 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 
-	"github.com/natefinch/atomic"
 	"github.com/sourcegraph/conc/pool"
 	"github.com/spf13/cobra"
 
+	"github.com/sboehler/knut/cmd/flags"
 	"github.com/sboehler/knut/lib/common/cpr"
 	"github.com/sboehler/knut/lib/syntax"
-	"github.com/sboehler/knut/lib/syntax/bayes"
+	"github.com/sboehler/knut/lib/syntax/infer"
+
+	// enable models here
+	_ "github.com/sboehler/knut/lib/syntax/bayes"
 )
 
 // CreateInferCmd creates the command.
@@ -49,18 +53,27 @@ type inferRunner struct {
 	account      string
 	trainingFile string
 	inplace      bool
+	normalize    bool
+
+	minConfidence  float64
+	showConfidence bool
+	model          string
 }
 
 func (r *inferRunner) setupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&r.account, "account", "a", "Expenses:TBD", "account name")
 	cmd.Flags().BoolVarP(&r.inplace, "inplace", "i", false, "infer the accounts inplace")
+	cmd.Flags().BoolVar(&r.normalize, "normalize", false, "ignore case, digits and punctuation in descriptions")
+	cmd.Flags().StringVar(&r.model, "model", "bayes", fmt.Sprintf("the model to infer accounts, one of %v", infer.Names()))
+	cmd.Flags().Float64Var(&r.minConfidence, "min-confidence", 0, "leave the account unchanged if the confidence is below, between 0 and 1")
+	cmd.Flags().BoolVar(&r.showConfidence, "show-confidence", false, "print the confidence of every prediction to stderr")
 	cmd.Flags().StringVarP(&r.trainingFile, "training-file", "t", "", "the journal file with existing data")
 	cmd.MarkFlagRequired("training-file")
 }
 
 func (r *inferRunner) run(cmd *cobra.Command, args []string) {
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintln(cmd.ErrOrStderr(), err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
@@ -74,16 +87,21 @@ func (r *inferRunner) execute(cmd *cobra.Command, args []string) (errors error)
 	if err != nil {
 		return err
 	}
-	file, err := r.parseAndInfer(cmd.Context(), model, targetFile)
+	file, predictions, err := r.parseAndInfer(cmd.Context(), model, targetFile)
 	if err != nil {
 		return err
 	}
+	if r.showConfidence {
+		if err := r.printPredictions(cmd.ErrOrStderr(), predictions); err != nil {
+			return err
+		}
+	}
 	if r.inplace {
 		var buf bytes.Buffer
 		if err := syntax.FormatFile(&buf, file); err != nil {
 			return err
 		}
-		return atomic.WriteFile(targetFile, &buf)
+		return syntax.WriteFile(targetFile, &buf)
 	} else {
 		out := bufio.NewWriter(cmd.OutOrStdout())
 		defer out.Flush()
@@ -91,8 +109,15 @@ func (r *inferRunner) execute(cmd *cobra.Command, args []string) (errors error)
 	}
 }
 
-func (inferRunner) train(ctx context.Context, file string, account string) (*bayes.Model, error) {
-	model := bayes.NewModel(account)
+func (r inferRunner) train(ctx context.Context, file string, account string) (infer.Model, error) {
+	model, err := infer.New(r.model, infer.Options{
+		Account:       account,
+		Normalize:     r.normalize,
+		MinConfidence: r.minConfidence,
+	})
+	if err != nil {
+		return nil, err
+	}
 	p := pool.New().WithErrors().WithFirstError().WithContext(ctx)
 	ch, worker := syntax.ParseFileRecursively(file)
 	p.Go(worker)
@@ -109,15 +134,40 @@ func (inferRunner) train(ctx context.Context, file string, account string) (*bay
 	return model, p.Wait()
 }
 
-func (r *inferRunner) parseAndInfer(ctx context.Context, model *bayes.Model, targetFile string) (syntax.File, error) {
+func (r *inferRunner) parseAndInfer(ctx context.Context, model infer.Model, targetFile string) (syntax.File, []infer.Prediction, error) {
 	f, err := syntax.ParseFile(targetFile)
 	if err != nil {
-		return syntax.File{}, err
+		return syntax.File{}, nil, err
 	}
+	var predictions []infer.Prediction
 	for i := range f.Directives {
 		if t, ok := f.Directives[i].Directive.(syntax.Transaction); ok {
-			model.Infer(&t)
+			predictions = append(predictions, model.Infer(&t)...)
+		}
+	}
+	return f, predictions, nil
+}
+
+func (r *inferRunner) printPredictions(w io.Writer, predictions []infer.Prediction) error {
+	for _, p := range predictions {
+		rng := p.Booking.Range
+		rng.End = rng.Start
+		status := "accepted"
+		if !p.Accepted {
+			status = "rejected"
+		}
+		if _, err := fmt.Fprintf(w, "%s:%s: %s %s (confidence %.2f)\n", rng.Path, rng.Location(), status, p.Account, p.Confidence); err != nil {
+			return err
+		}
+		if len(p.Candidates) == 0 {
+			continue
+		}
+		// show up to three alternatives
+		for _, c := range p.Candidates[1:min(len(p.Candidates), 4)] {
+			if _, err := fmt.Fprintf(w, "    %s (confidence %.2f)\n", c.Account, c.Confidence); err != nil {
+				return err
+			}
 		}
 	}
-	return f, nil
+	return nil
 }
@@ -0,0 +1,124 @@
+package portfolio
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/predicate"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/check"
+	"github.com/sboehler/knut/lib/journal/performance"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/reports/allocation"
+)
+
+// CreateAllocationCommand creates the command.
+func CreateAllocationCommand() *cobra.Command {
+	var r allocationRunner
+	c := &cobra.Command{
+		Use:   "allocation",
+		Short: "compute the asset allocation",
+		Long: `Compute the value of the portfolio in each class of commodities on the given date,
+and its share of the total value. The classes are read from the universe file,
+which maps each class to its commodities:
+
+    Equity: [AAPL, VT]
+    Bond: [BND]
+    Cash: [CHF, USD]
+
+Commodities which are not classified are shown as Other.`,
+
+		Args: flags.JournalArg,
+
+		Run: flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type allocationRunner struct {
+	date                  flags.DateFlag
+	valuation             flags.CommodityFlag
+	accounts, commodities flags.RegexFlag
+	universe              string
+
+	// formatting
+	color  bool
+	digits int32
+	csv    bool
+}
+
+func (r *allocationRunner) setupFlags(cmd *cobra.Command) {
+	cmd.Flags().Var(&r.date, "date", "the report date (default: today)")
+	cmd.Flags().StringVarP(&r.universe, "universe", "", "", "universe file")
+	cmd.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	cmd.Flags().Var(&r.accounts, "account", "filter accounts with a regex")
+	cmd.Flags().Var(&r.commodities, "commodity", "filter commodities with a regex")
+	cmd.Flags().BoolVar(&r.csv, "csv", false, "render csv")
+	cmd.Flags().Int32Var(&r.digits, "digits", 0, "round to number of digits")
+	cmd.Flags().BoolVar(&r.color, "color", true, "print output in color")
+	cmd.MarkFlagRequired("val")
+}
+
+func (r *allocationRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *allocationRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	var universe performance.Universe
+	if len(r.universe) > 0 {
+		if universe, err = performance.LoadUniverseFromFile(reg.Commodities(), r.universe); err != nil {
+			return err
+		}
+	}
+	valuation, err := r.valuation.Value(reg)
+	if err != nil {
+		return err
+	}
+	j, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	flags.ResolvePrices(cmd, j)
+	calculator := &performance.Calculator{
+		Context:         reg,
+		Valuation:       valuation,
+		AccountFilter:   predicate.ByName[*model.Account](r.accounts.Regex()),
+		CommodityFilter: predicate.ByName[*model.Commodity](r.commodities.Regex()),
+	}
+	rep := allocation.NewReport(universe, r.date.ValueOr(date.Today()))
+	err = j.Build().Process(
+		journal.ComputePrices(valuation),
+		check.Check(),
+		journal.Valuate(reg, valuation),
+		calculator.ComputeValues(),
+		rep.Process(),
+	)
+	if err != nil {
+		return err
+	}
+	var tableRenderer Renderer
+	if r.csv {
+		tableRenderer = &table.CSVRenderer{}
+	} else {
+		tableRenderer = &table.TextRenderer{
+			Color: r.color,
+			Round: r.digits,
+		}
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	return tableRenderer.Render(allocation.Renderer{}.Render(rep), out)
+}
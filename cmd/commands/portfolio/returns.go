@@ -14,7 +14,6 @@
 package portfolio
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"runtime/pprof"
@@ -27,7 +26,6 @@ import (
 	"github.com/sboehler/knut/lib/journal/check"
 	"github.com/sboehler/knut/lib/journal/performance"
 	"github.com/sboehler/knut/lib/model"
-	"github.com/sboehler/knut/lib/model/registry"
 )
 
 // CreateReturnsCommand creates the command.
@@ -40,9 +38,9 @@ func CreateReturnsCommand() *cobra.Command {
 		Short: "compute portfolio returns",
 		Long:  `Compute portfolio returns.`,
 
-		Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Args: flags.JournalArg,
 
-		Run: r.run,
+		Run: flags.WithJournal(r.run),
 	}
 	r.setupFlags(c)
 	return c
@@ -73,22 +71,26 @@ func (r *returnsRunner) run(cmd *cobra.Command, args []string) {
 		defer pprof.StopCPUProfile()
 	}
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintln(cmd.ErrOrStderr(), err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
 
 func (r *returnsRunner) execute(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
-	reg := registry.New()
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 	valuation, err := r.valuation.Value(reg)
 	if err != nil {
 		return err
 	}
-	j, err := journal.FromPath(ctx, reg, args[0])
+	j, err := journal.FromPathWith(ctx, reg, args[0], flags.Resolver(cmd))
 	if err != nil {
 		return err
 	}
+	flags.ResolvePrices(cmd, j)
 	partition := r.Multiperiod.Partition(j.Period())
 	calculator := &performance.Calculator{
 		Context:         reg,
@@ -15,7 +15,6 @@ package portfolio
 
 import (
 	"bufio"
-	"fmt"
 	"io"
 	"os"
 
@@ -28,7 +27,6 @@ import (
 	"github.com/sboehler/knut/lib/journal/check"
 	"github.com/sboehler/knut/lib/journal/performance"
 	"github.com/sboehler/knut/lib/model"
-	"github.com/sboehler/knut/lib/model/registry"
 	"github.com/sboehler/knut/lib/reports/weights"
 )
 
@@ -42,9 +40,9 @@ func CreateWeightsCommand() *cobra.Command {
 		Short: "compute portfolio weights",
 		Long:  `Compute portfolio weights.`,
 
-		Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Args: flags.JournalArg,
 
-		Run: r.run,
+		Run: flags.WithJournal(r.run),
 	}
 	r.setupFlags(c)
 	return c
@@ -87,14 +85,17 @@ func (r *weightsRunner) setupFlags(cmd *cobra.Command) {
 
 func (r *weightsRunner) run(cmd *cobra.Command, args []string) {
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintln(cmd.ErrOrStderr(), err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
 
 func (r *weightsRunner) execute(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
-	reg := registry.New()
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 	var universe performance.Universe
 	if len(r.universe) > 0 {
 		var err error
@@ -107,10 +108,11 @@ func (r *weightsRunner) execute(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	j, err := journal.FromPath(ctx, reg, args[0])
+	j, err := journal.FromPathWith(ctx, reg, args[0], flags.Resolver(cmd))
 	if err != nil {
 		return err
 	}
+	flags.ResolvePrices(cmd, j)
 	partition := r.Multiperiod.Partition(j.Period())
 	calculator := &performance.Calculator{
 		Context:         reg,
@@ -0,0 +1,93 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/reports/pricegaps"
+
+	"github.com/spf13/cobra"
+)
+
+// CreatePriceGapsCommand creates the command.
+func CreatePriceGapsCommand() *cobra.Command {
+
+	var r priceGapsRunner
+
+	// Cmd is the price-gaps command.
+	c := &cobra.Command{
+		Use:   "price-gaps",
+		Short: "show the largest gaps between prices",
+		Long: `For each commodity pair with price directives, show the largest gaps between
+the dates of consecutive prices, and between the last price and the end of the
+journal or the given date, sorted by length. Valuations carry the last price
+forward over these gaps.`,
+		Args: flags.JournalArg,
+		Run:  flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type priceGapsRunner struct {
+	commodities flags.RegexFlag
+	date        flags.DateFlag
+	perPair     int
+	color       bool
+}
+
+func (r *priceGapsRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *priceGapsRunner) setupFlags(c *cobra.Command) {
+	c.Flags().Var(&r.commodities, "commodity", "show only pairs with a commodity matching a regex")
+	c.Flags().Var(&r.date, "date", "the end of the last gap of each pair (default: the end of the journal)")
+	c.Flags().IntVar(&r.perPair, "per-pair", 3, "the number of gaps to show for each pair (0 = all)")
+	c.Flags().BoolVar(&r.color, "color", true, "print output in color")
+}
+
+func (r *priceGapsRunner) execute(cmd *cobra.Command, args []string) error {
+	if r.perPair < 0 {
+		return fmt.Errorf("--per-pair must not be negative, got %d", r.perPair)
+	}
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	rep := &pricegaps.Report{Commodities: r.commodities.Regex(), End: r.date.Value()}
+	if err := b.Build().Process(rep.Process()); err != nil {
+		return err
+	}
+	tableRenderer := table.TextRenderer{
+		Color: r.color,
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	return tableRenderer.Render(pricegaps.Renderer{PerPair: r.perPair}.Render(rep), out)
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"io"
+	"os"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/reports/prices"
+
+	"github.com/spf13/cobra"
+)
+
+// CreatePricesCommand creates the command.
+func CreatePricesCommand() *cobra.Command {
+
+	var r pricesRunner
+
+	// Cmd is the prices command.
+	c := &cobra.Command{
+		Use:   "prices",
+		Short: "show the prices over time",
+		Long:  `Show the price of each commodity in the valuation commodity at the end of each period.`,
+		Args:  flags.JournalArg,
+		Run:   flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type pricesRunner struct {
+	flags.Multiperiod
+	output flags.Output
+
+	valuation     flags.CommodityFlag
+	impliedPrices bool
+
+	// formatting
+	color       bool
+	roundPrices int32
+	csv         bool
+}
+
+func (r *pricesRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *pricesRunner) setupFlags(c *cobra.Command) {
+	r.Multiperiod.Setup(c)
+	r.output.Setup(c)
+	c.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	c.Flags().BoolVar(&r.impliedPrices, "implied-prices", false, "derive prices from transactions exchanging two commodities")
+	c.Flags().Int32Var(&r.roundPrices, "round-prices", 0, "round prices to the number of significant digits for display")
+	c.Flags().BoolVarP(&r.csv, "csv", "", false, "csv")
+	c.Flags().BoolVar(&r.color, "color", true, "print output in color")
+	c.MarkFlagRequired("val")
+}
+
+func (r *pricesRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	valuation, err := r.valuation.Value(reg)
+	if err != nil {
+		return err
+	}
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	flags.ResolvePrices(cmd, b)
+	partition := r.Multiperiod.Partition(b.Period())
+	// Make sure that there is a day at the end of each period.
+	b.Days(partition.EndDates())
+	rep := prices.NewReport(valuation, partition)
+	if err := b.Build().Process(journal.ImplyPrices(r.impliedPrices), journal.ComputePrices(valuation), rep.Process()); err != nil {
+		return err
+	}
+	var tableRenderer Renderer
+	if r.csv {
+		tableRenderer = &table.CSVRenderer{}
+	} else {
+		tableRenderer = &table.TextRenderer{
+			Color: r.color,
+		}
+	}
+	reportRenderer := prices.Renderer{
+		Significant: r.roundPrices,
+	}
+	return r.output.Write(cmd, func(w io.Writer) error {
+		return tableRenderer.Render(reportRenderer.Render(rep), w)
+	})
+}
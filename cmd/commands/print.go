@@ -16,12 +16,11 @@ package commands
 
 import (
 	"bufio"
-	"fmt"
 	"os"
 
+	"github.com/sboehler/knut/cmd/flags"
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/journal/check"
-	"github.com/sboehler/knut/lib/model/registry"
 
 	"github.com/spf13/cobra"
 )
@@ -36,37 +35,47 @@ func CreatePrintCommand() *cobra.Command {
 		Short: "print the journal",
 		Long:  `Print the given journal.`,
 
-		Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Args: flags.JournalArg,
 
-		Run: r.run,
+		Run: flags.WithJournal(r.run),
 	}
 	r.setupFlags(cmd)
 	return cmd
 }
 
 type printRunner struct {
+	annotate      bool
+	groupPostings bool
 }
 
 func (r *printRunner) setupFlags(c *cobra.Command) {
+	c.Flags().BoolVar(&r.annotate, "annotate", false, "annotate open and close directives with the first and last activity of the account")
+	c.Flags().BoolVar(&r.groupPostings, "group-postings", false, "merge the bookings of each transaction which move the same commodity between the same accounts")
 }
 
 func (r *printRunner) run(cmd *cobra.Command, args []string) {
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintln(cmd.ErrOrStderr(), err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
 
 func (r *printRunner) execute(cmd *cobra.Command, args []string) (errors error) {
-	reg := registry.New()
-	j, err := journal.FromPath(cmd.Context(), reg, args[0])
+	reg, err := flags.NewRegistry(cmd)
 	if err != nil {
 		return err
 	}
-	if err := j.Build().Process(check.Check()); err != nil {
+	j, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	if err := j.Build().Process(check.Check(), journal.GroupPostings(r.groupPostings)); err != nil {
 		return err
 	}
 	w := bufio.NewWriter(cmd.OutOrStdout())
 	defer w.Flush()
+	if r.annotate {
+		return journal.PrintAnnotated(w, j.Build())
+	}
 	return journal.Print(w, j.Build())
 }
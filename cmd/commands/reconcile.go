@@ -0,0 +1,149 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/cmd/importer"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/reports/reconcile"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateReconcileCommand creates the command.
+func CreateReconcileCommand() *cobra.Command {
+
+	var r reconcileRunner
+
+	// Cmd is the reconcile command.
+	c := &cobra.Command{
+		Use:   "reconcile",
+		Short: "reconcile an account",
+		Long: `Show the postings of an account since its last passing balance assertion, with the
+running balance, to reconcile the account against a statement.
+
+With --statement and --format, read a CSV account statement with the parser of the given
+importer, match its postings with the postings of the account in the journal by date and
+amount, and show the postings missing on either side.`,
+		Args: flags.JournalArg,
+		Run:  flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type reconcileRunner struct {
+	account          flags.AccountFlag
+	cleared, pending bool
+	statement        string
+	format           string
+	window           int
+
+	// formatting
+	thousands, color bool
+	digits           int32
+}
+
+func (r *reconcileRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *reconcileRunner) setupFlags(c *cobra.Command) {
+	c.Flags().VarP(&r.account, "account", "a", "the account to reconcile")
+	c.Flags().BoolVar(&r.cleared, "cleared", false, "show cleared postings only")
+	c.Flags().BoolVar(&r.pending, "pending", false, "show pending postings only")
+	c.Flags().StringVar(&r.statement, "statement", "", "match the postings against the given CSV account statement")
+	c.Flags().StringVar(&r.format, "format", "", "the importer of the statement, for example ch.postfinance")
+	c.Flags().IntVar(&r.window, "window", 0, "the number of days by which the dates of matching postings may differ")
+	c.Flags().Int32Var(&r.digits, "digits", 0, "round to number of digits")
+	c.Flags().BoolVarP(&r.thousands, "thousands", "k", false, "show numbers in units of 1000")
+	c.Flags().BoolVar(&r.color, "color", true, "print output in color")
+	c.MarkFlagRequired("account")
+}
+
+func (r *reconcileRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	account, err := r.account.Value(reg.Accounts())
+	if err != nil {
+		return err
+	}
+	if r.statement != "" {
+		return r.match(cmd, reg, b, account)
+	}
+	rep := reconcile.NewReport(account)
+	rep.States = states(r.cleared, r.pending)
+	if err := b.Build().Process(journal.Sort(), rep.Process()); err != nil {
+		return err
+	}
+	tableRenderer := table.TextRenderer{
+		Color:     r.color,
+		Thousands: r.thousands,
+		Round:     r.digits,
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	return tableRenderer.Render(reconcile.Renderer{}.Render(rep), out)
+}
+
+func (r *reconcileRunner) match(cmd *cobra.Command, reg *model.Registry, b *journal.Builder, account *model.Account) error {
+	if r.format == "" {
+		return fmt.Errorf("--statement requires --format")
+	}
+	parse, err := importer.GetStatement(r.format)
+	if err != nil {
+		return err
+	}
+	f, err := flags.OpenFile(r.statement)
+	if err != nil {
+		return err
+	}
+	st, err := parse(reg, account, f)
+	if err != nil {
+		return err
+	}
+	statement, entries := &reconcile.Items{Account: account}, &reconcile.Items{Account: account}
+	if err := st.Build().Process(statement.Process()); err != nil {
+		return err
+	}
+	if err := b.Build().Process(entries.Process()); err != nil {
+		return err
+	}
+	m := reconcile.Match(statement.Items, entries.Items, r.window)
+	tableRenderer := table.TextRenderer{
+		Color:     r.color,
+		Thousands: r.thousands,
+		Round:     r.digits,
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	return tableRenderer.Render(reconcile.MatchRenderer{}.Render(m), out)
+}
@@ -15,8 +15,7 @@
 package commands
 
 import (
-	"bufio"
-	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime/pprof"
@@ -31,7 +30,7 @@ import (
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/account"
 	"github.com/sboehler/knut/lib/model/commodity"
-	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/posting"
 	"github.com/sboehler/knut/lib/reports/register"
 
 	"github.com/spf13/cobra"
@@ -47,8 +46,8 @@ func CreateRegisterCmd() *cobra.Command {
 		Use:    "register",
 		Short:  "create a register sheet",
 		Long:   `Compute a register report.`,
-		Args:   cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-		Run:    r.run,
+		Args:   flags.JournalArg,
+		Run:    flags.WithJournal(r.run),
 		Hidden: true,
 	}
 	r.setupFlags(c)
@@ -57,9 +56,12 @@ func CreateRegisterCmd() *cobra.Command {
 
 type registerRunner struct {
 	flags.Multiperiod
+	output flags.Output
 
 	// internal
 	cpuprofile string
+	explain    bool
+	watch      bool
 
 	// transformations
 	showCommodities               bool
@@ -68,12 +70,24 @@ type registerRunner struct {
 	mapping                       flags.MappingFlag
 	remap                         flags.RegexFlag
 	valuation                     flags.CommodityFlag
+	impliedPrices                 bool
+	effective                     bool
+	virtual                       bool
+	groupPostings                 bool
 	accounts, others, commodities flags.RegexFlag
+	accountGlobs, otherGlobs      flags.GlobFlag
+	excludeAccounts               flags.RegexFlag
+	excludeCommodities            flags.RegexFlag
+	tags                          flags.RegexFlag
+	where                         flags.ExprFlag
+	cleared, pending              bool
 
 	// formatting
 	thousands, color   bool
 	sortAlphabetically bool
 	digits             int32
+	reverse            bool
+	offset, limit      int
 }
 
 func (r *registerRunner) run(cmd *cobra.Command, args []string) {
@@ -86,42 +100,77 @@ func (r *registerRunner) run(cmd *cobra.Command, args []string) {
 		defer pprof.StopCPUProfile()
 	}
 
+	if r.watch {
+		if err := watch(cmd, args[0], func() error { return r.execute(cmd, args) }); err != nil {
+			flags.PrintError(cmd, err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintf(cmd.ErrOrStderr(), "%+v\n", err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
 
 func (r *registerRunner) setupFlags(c *cobra.Command) {
 	r.Multiperiod.Setup(c)
+	r.output.Setup(c)
 	c.Flags().StringVar(&r.cpuprofile, "cpuprofile", "", "file to write profile")
+	c.Flags().BoolVar(&r.explain, "explain", false, "print what each processing stage did to stderr")
+	c.Flags().BoolVar(&r.watch, "watch", false, "re-run whenever a file of the journal changes")
 	c.Flags().BoolVarP(&r.sortAlphabetically, "sort", "s", false, "Sort accounts alphabetically")
 	c.Flags().BoolVarP(&r.showCommodities, "show-commodities", "c", false, "Show commodities")
 	c.Flags().BoolVarP(&r.showDescriptions, "show-descriptions", "d", false, "Show descriptions")
 	c.Flags().BoolVarP(&r.showSource, "show-source", "a", false, "Show the source accounts")
 	c.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	c.Flags().BoolVar(&r.impliedPrices, "implied-prices", false, "derive prices from transactions exchanging two commodities")
+	c.Flags().BoolVar(&r.effective, "effective", false, "use the effective dates of transactions instead of their booking dates")
+	c.Flags().BoolVar(&r.virtual, "virtual", false, "include virtual postings")
+	c.Flags().BoolVar(&r.groupPostings, "group-postings", false, "merge the bookings of each transaction which move the same commodity between the same accounts")
 	c.Flags().VarP(&r.mapping, "map", "m", "<level>,<regex>")
 	c.Flags().VarP(&r.remap, "remap", "r", "<regex>")
 	c.Flags().Var(&r.accounts, "source", "filter source accounts with a regex")
 	c.Flags().Var(&r.others, "dest", "filter dest accounts with a regex")
 	c.Flags().Var(&r.commodities, "commodity", "filter commodities with a regex")
+	r.accounts.AddModifiers(c, "source")
+	r.others.AddModifiers(c, "dest")
+	c.Flags().Var(&r.accountGlobs, "source-glob", "filter source accounts with a glob pattern, e.g. Assets:Bank:* or Expenses:**")
+	c.Flags().Var(&r.otherGlobs, "dest-glob", "filter dest accounts with a glob pattern, e.g. Assets:Bank:* or Expenses:**")
+	r.commodities.AddModifiers(c, "commodity")
+	c.Flags().Var(&r.excludeAccounts, "exclude-account", "exclude accounts matching a regex")
+	c.Flags().Var(&r.excludeCommodities, "exclude-commodity", "exclude commodities matching a regex")
+	c.Flags().Var(&r.tags, "tag", "filter postings by tag with a regex")
+	c.Flags().Var(&r.where, "where", "filter postings with an expression, e.g. \"account =~ 'Expenses' and amount > 100\"")
+	c.Flags().BoolVar(&r.cleared, "cleared", false, "show cleared postings only")
+	c.Flags().BoolVar(&r.pending, "pending", false, "show pending postings only")
 	c.Flags().Int32Var(&r.digits, "digits", 0, "round to number of digits")
 	c.Flags().BoolVarP(&r.thousands, "thousands", "k", false, "show numbers in units of 1000")
 	c.Flags().BoolVar(&r.color, "color", true, "print output in color")
+	c.Flags().BoolVar(&r.reverse, "reverse", false, "show the most recent dates first")
+	c.Flags().IntVar(&r.offset, "offset", 0, "skip the given number of dates")
+	c.Flags().IntVar(&r.limit, "limit", 0, "show at most the given number of dates (0 = all)")
 }
 
 func (r registerRunner) execute(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
-	reg := registry.New()
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 	valuation, err := r.valuation.Value(reg)
 	if err != nil {
 		return err
 	}
 	r.showCommodities = r.showCommodities || valuation == nil
-	b, err := journal.FromPath(ctx, reg, args[0])
+	b, err := journal.FromPathWith(ctx, reg, args[0], flags.Resolver(cmd))
 	if err != nil {
 		return err
 	}
+	flags.ResolvePrices(cmd, b)
+	if r.effective {
+		b.UseEffectiveDates()
+	}
 	var am mapper.Mapper[*model.Account]
 	if r.showSource {
 		am = account.Remap(reg.Accounts(), r.remap.Regex())
@@ -129,13 +178,22 @@ func (r registerRunner) execute(cmd *cobra.Command, args []string) error {
 	partition := r.Multiperiod.Partition(b.Period())
 	rep := register.NewReport(reg)
 	j := b.Build()
-	err = j.Process(
-		journal.Sort(),
-		journal.ComputePrices(valuation),
-		check.Check(),
-		journal.Valuate(reg, valuation),
-		journal.Filter(partition),
-		journal.Query{
+	var explainer *journal.Explainer
+	if r.explain {
+		explainer = new(journal.Explainer)
+	}
+	pipeline := journal.Pipeline{
+		Context: journal.PipelineContext{Registry: reg, Valuation: valuation, Partition: partition},
+	}
+	pipeline.
+		Add("sort", journal.Sort()).
+		Add("implied", journal.ImplyPrices(r.impliedPrices && valuation != nil)).
+		Add("prices", journal.ComputePrices(valuation)).
+		Add("check", check.Check()).
+		Add("group", journal.GroupPostings(r.groupPostings)).
+		Add("valuate", journal.Valuate(reg, valuation)).
+		Add("filter", journal.Filter(partition)).
+		Add("query", journal.Query{
 			Select: amounts.KeyMapper{
 				Date:    partition.Align(),
 				Account: am,
@@ -148,28 +206,57 @@ func (r registerRunner) execute(cmd *cobra.Command, args []string) error {
 				Description: mapper.IdentityIf[string](r.showDescriptions),
 			}.Build(),
 			Where: predicate.And(
-				amounts.AccountMatches(r.accounts.Regex()),
-				amounts.OtherAccountMatches(r.others.Regex()),
+				amounts.AccountMatches(append(r.accounts.Regex(), r.accountGlobs.Regex()...)),
+				amounts.OtherAccountMatches(append(r.others.Regex(), r.otherGlobs.Regex()...)),
 				amounts.CommodityMatches(r.commodities.Regex()),
+				amounts.AccountExcludes(r.excludeAccounts.Regex()),
+				amounts.CommodityExcludes(r.excludeCommodities.Regex()),
 			),
 			Valuation: valuation,
-		}.Into(rep),
-	)
+			Tags:      r.tags.Regex(),
+			Filter:    r.where.Value(),
+			States:    states(r.cleared, r.pending),
+			Virtual:   r.virtual,
+		}.Into(rep))
+	procs, err := pipeline.Build(explainer)
 	if err != nil {
 		return err
 	}
+	if err := j.Process(procs...); err != nil {
+		return err
+	}
+	if explainer != nil {
+		if err := explainer.Report(cmd.ErrOrStderr()); err != nil {
+			return err
+		}
+	}
 	reportRenderer := register.Renderer{
+		Valuation:          valuation,
 		ShowCommodities:    r.showCommodities,
 		ShowDescriptions:   r.showDescriptions,
 		ShowSource:         r.showSource,
 		SortAlphabetically: r.sortAlphabetically,
+		Reverse:            r.reverse,
+		Offset:             r.offset,
+		Limit:              r.limit,
 	}
 	tableRenderer := table.TextRenderer{
 		Color:     r.color,
 		Thousands: r.thousands,
 		Round:     r.digits,
 	}
-	out := bufio.NewWriter(cmd.OutOrStdout())
-	defer out.Flush()
-	return tableRenderer.Render(reportRenderer.Render(rep), out)
+	return r.output.Write(cmd, func(w io.Writer) error {
+		return tableRenderer.Render(reportRenderer.Render(rep), w)
+	})
+}
+
+func states(cleared, pending bool) []posting.State {
+	var res []posting.State
+	if cleared {
+		res = append(res, posting.Cleared)
+	}
+	if pending {
+		res = append(res, posting.Pending)
+	}
+	return res
 }
@@ -0,0 +1,92 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/model/account"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/sboehler/knut/lib/syntax/rename"
+)
+
+// CreateRenameAccountCommand creates the command.
+func CreateRenameAccountCommand() *cobra.Command {
+	var r renameAccountRunner
+	c := &cobra.Command{
+		Use:   "rename-account <old> <new> <journal>",
+		Short: "rename an account and its sub-accounts",
+		Long: `Rename an account and all of its sub-accounts in every directive of the journal and its included files.
+The files are rewritten in-place, and any text other than the renamed accounts is preserved.`,
+		Args: cobra.ExactArgs(3),
+		Run:  r.run,
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type renameAccountRunner struct {
+	dryRun bool
+}
+
+func (r *renameAccountRunner) setupFlags(c *cobra.Command) {
+	c.Flags().BoolVar(&r.dryRun, "dry-run", false, "report the changes without writing the files")
+}
+
+func (r *renameAccountRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *renameAccountRunner) execute(cmd *cobra.Command, args []string) error {
+	rule := rename.Rule{Old: args[0], New: args[1]}
+	reg := account.NewRegistry()
+	for _, name := range []string{rule.Old, rule.New} {
+		if _, err := reg.Get(name); err != nil {
+			return err
+		}
+	}
+	paths, err := syntax.Files(cmd.Context(), args[2])
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		f, err := syntax.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		text, n := rename.File(f, rule)
+		if n == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s: renamed %d accounts\n", path, n); err != nil {
+			return err
+		}
+		if r.dryRun {
+			continue
+		}
+		if err := syntax.WriteFile(path, strings.NewReader(text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/cmd/cmdtest"
+)
+
+func TestRenameAccountIncludedTwice(t *testing.T) {
+	dir := t.TempDir()
+	main, accounts := filepath.Join(dir, "main.knut"), filepath.Join(dir, "accounts.knut")
+	for path, content := range map[string]string{
+		main:     "include \"accounts.knut\"\ninclude \"./accounts.knut\"\n",
+		accounts: "2020-01-01 open Assets:Bank\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := cmdtest.Run(t, CreateRenameAccountCommand(), "Assets:Bank", "Assets:Bank:Checking", main)
+
+	if want := accounts + ": renamed 1 accounts\n"; string(out) != want {
+		t.Errorf("rename-account printed %q, want %q", out, want)
+	}
+	got, err := os.ReadFile(accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("2020-01-01 open Assets:Bank:Checking\n", string(got)); diff != "" {
+		t.Errorf("rename-account returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
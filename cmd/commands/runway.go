@@ -0,0 +1,113 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/check"
+	"github.com/sboehler/knut/lib/reports/runway"
+	"github.com/spf13/cobra"
+)
+
+// CreateRunwayCommand creates the command.
+func CreateRunwayCommand() *cobra.Command {
+	var r runwayRunner
+	c := &cobra.Command{
+		Use:   "runway",
+		Short: "compute the burn rate and the runway",
+		Long: `Compute the burn rate, the average monthly net outflow from the cash accounts over the
+last months, and the runway, the number of months until the cash is used up at this rate.`,
+		Args: flags.JournalArg,
+		Run:  flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type runwayRunner struct {
+	valuation flags.CommodityFlag
+	date      flags.DateFlag
+	months    int
+	accounts  flags.RegexFlag
+	digits    int32
+}
+
+func (r *runwayRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *runwayRunner) setupFlags(c *cobra.Command) {
+	c.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	c.Flags().Var(&r.date, "date", "the report date (default: today)")
+	c.Flags().IntVar(&r.months, "months", 6, "the number of months over which the burn rate is averaged")
+	c.Flags().Var(&r.accounts, "account", "the cash accounts, as a regex (default: all assets and liabilities)")
+	r.accounts.AddModifiers(c, "account")
+	c.Flags().Int32Var(&r.digits, "digits", 0, "round to number of digits")
+	c.MarkFlagRequired("val")
+}
+
+func (r *runwayRunner) execute(cmd *cobra.Command, args []string) error {
+	if r.months <= 0 {
+		return fmt.Errorf("--months must be positive, got %d", r.months)
+	}
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	valuation, err := r.valuation.Value(reg)
+	if err != nil {
+		return err
+	}
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	flags.ResolvePrices(cmd, b)
+	rep := &runway.Report{
+		Valuation: valuation,
+		Cash:      r.accounts.Regex(),
+		Date:      r.date.ValueOr(date.Today()),
+		Months:    r.months,
+	}
+	partition := date.NewPartition(date.Period{End: rep.Date}.Clip(b.Period()), date.Once, 0)
+	pipeline := journal.Pipeline{
+		Context: journal.PipelineContext{Registry: reg, Valuation: valuation, Partition: partition},
+	}
+	pipeline.
+		Add("sort", journal.Sort()).
+		Add("prices", journal.ComputePrices(valuation)).
+		Add("check", check.Check()).
+		Add("valuate", journal.Valuate(reg, valuation)).
+		Add("runway", rep.Process())
+	procs, err := pipeline.Build(nil)
+	if err != nil {
+		return err
+	}
+	if err := b.Build().Process(procs...); err != nil {
+		return err
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	return rep.Render(out, r.digits)
+}
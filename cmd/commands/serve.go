@@ -0,0 +1,87 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/server"
+	"github.com/sboehler/knut/lib/syntax"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateServeCommand creates the command.
+func CreateServeCommand() *cobra.Command {
+
+	var r serveRunner
+
+	// Cmd is the serve command.
+	c := &cobra.Command{
+		Use:   "serve",
+		Short: "serve reports over HTTP",
+		Long: `Serve balance, register and prices reports of the journal as JSON over HTTP.
+The endpoints /balance, /register and /prices accept the query parameters
+from, to, interval, last, val, account, commodity and where.`,
+		Args: flags.JournalArg,
+		Run:  flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type serveRunner struct {
+	addr string
+}
+
+func (r *serveRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *serveRunner) setupFlags(c *cobra.Command) {
+	c.Flags().StringVar(&r.addr, "addr", "localhost:8080", "the address to listen on")
+}
+
+func (r *serveRunner) execute(cmd *cobra.Command, args []string) error {
+	s := &server.Server{
+		Path: args[0],
+		NewRegistry: func() (*model.Registry, error) {
+			return flags.NewRegistry(cmd)
+		},
+		NewResolver: func() *syntax.Resolver {
+			return flags.Resolver(cmd)
+		},
+		Prepare: func(j *journal.Builder) {
+			flags.ResolvePrices(cmd, j)
+		},
+	}
+	srv := &http.Server{
+		Addr:              r.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      5 * time.Minute,
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "listening on %s\n", r.addr)
+	return srv.ListenAndServe()
+}
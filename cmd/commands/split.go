@@ -0,0 +1,93 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/sboehler/knut/lib/syntax/split"
+)
+
+// CreateSplitCmd creates the command.
+func CreateSplitCmd() *cobra.Command {
+	var r splitRunner
+	cmd := &cobra.Command{
+		Use:   "split",
+		Short: "Split bookings according to rules",
+		Long: `Split the bookings of matching transactions into multiple bookings, according to the
+rules in the supplied YAML file. Every split is either a fixed amount or a percentage, the remainder
+is booked to the remainder account of the rule or to the catch-all account. This is typically run
+on imported transactions after their accounts have been inferred.`,
+		Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Run:  r.run,
+	}
+	r.setupFlags(cmd)
+	return cmd
+}
+
+type splitRunner struct {
+	rulesFile string
+	catchAll  string
+	inplace   bool
+}
+
+func (r *splitRunner) setupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&r.rulesFile, "rules", "r", "", "the YAML file with the split rules")
+	cmd.Flags().StringVar(&r.catchAll, "catch-all", "", "the account for remainders of rules without a remainder account")
+	cmd.Flags().BoolVarP(&r.inplace, "inplace", "i", false, "split the bookings inplace")
+	cmd.MarkFlagRequired("rules")
+}
+
+func (r *splitRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *splitRunner) execute(cmd *cobra.Command, args []string) error {
+	targetFile := args[0]
+	rules, err := split.LoadRulesFromFile(r.rulesFile)
+	if err != nil {
+		return err
+	}
+	splitter := split.Splitter{
+		Rules:    rules,
+		CatchAll: r.catchAll,
+	}
+	file, err := syntax.ParseFile(targetFile)
+	if err != nil {
+		return err
+	}
+	if err := splitter.ApplyFile(&file); err != nil {
+		return err
+	}
+	if r.inplace {
+		var buf bytes.Buffer
+		if err := syntax.FormatFile(&buf, file); err != nil {
+			return err
+		}
+		return syntax.WriteFile(targetFile, &buf)
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	return syntax.FormatFile(out, file)
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/reports/stats"
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// CreateStatsCommand creates the command.
+func CreateStatsCommand() *cobra.Command {
+	var r statsRunner
+	c := &cobra.Command{
+		Use:   "stats",
+		Short: "summarize the journal",
+		Long:  `Summarize the journal: the number of files, transactions, postings, accounts and commodities, the date span and the most used accounts and commodities.`,
+		Args:  flags.JournalArg,
+		Run:   flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type statsRunner struct {
+	top  int
+	json bool
+}
+
+func (r *statsRunner) setupFlags(c *cobra.Command) {
+	c.Flags().IntVar(&r.top, "top", 5, "number of most used accounts and commodities to show")
+	c.Flags().BoolVar(&r.json, "json", false, "print the statistics as JSON")
+}
+
+func (r *statsRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *statsRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	j, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	files, err := syntax.Files(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+	s := stats.Compute(j.Build(), r.top)
+	s.Files = len(files)
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	if r.json {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	return s.Render(out)
+}
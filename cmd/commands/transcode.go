@@ -16,15 +16,12 @@ package commands
 
 import (
 	"bufio"
-	"fmt"
 	"os"
 
 	"github.com/sboehler/knut/cmd/flags"
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/journal/beancount"
 	"github.com/sboehler/knut/lib/journal/check"
-	"github.com/sboehler/knut/lib/model"
-	"github.com/sboehler/knut/lib/model/registry"
 
 	"github.com/spf13/cobra"
 )
@@ -40,9 +37,9 @@ func CreateTranscodeCommand() *cobra.Command {
 		Long: `Transcode the given journal to beancount, to leverage their amazing tooling. This command requires a valuation commodity, so` +
 			` that all currency conversions can be done by knut.`,
 
-		Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Args: flags.JournalArg,
 
-		Run: r.run,
+		Run: flags.WithJournal(r.run),
 	}
 	r.setupFlags(cmd)
 	return cmd
@@ -58,24 +55,25 @@ func (r *transcodeRunner) setupFlags(c *cobra.Command) {
 
 func (r *transcodeRunner) run(cmd *cobra.Command, args []string) {
 	if err := r.execute(cmd, args); err != nil {
-		fmt.Fprintln(cmd.ErrOrStderr(), err)
+		flags.PrintError(cmd, err)
 		os.Exit(1)
 	}
 }
 
 func (r *transcodeRunner) execute(cmd *cobra.Command, args []string) (errors error) {
-	var (
-		reg       = registry.New()
-		valuation *model.Commodity
-		err       error
-	)
-	if valuation, err = r.valuation.Value(reg); err != nil {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	valuation, err := r.valuation.Value(reg)
+	if err != nil {
 		return err
 	}
-	b, err := journal.FromPath(cmd.Context(), reg, args[0])
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
 	if err != nil {
 		return err
 	}
+	flags.ResolvePrices(cmd, b)
 	j := b.Build()
 	err = j.Process(
 		journal.Sort(),
@@ -89,5 +87,5 @@ func (r *transcodeRunner) execute(cmd *cobra.Command, args []string) (errors err
 	w := bufio.NewWriter(cmd.OutOrStdout())
 	defer w.Flush()
 
-	return beancount.Transcode(w, j, valuation)
+	return beancount.Transcode(w, j, valuation, reg.Accounts().RoundingAccount())
 }
@@ -0,0 +1,108 @@
+// Copyright 2021 Silvio Böhler
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/reports/pricecheck"
+
+	"github.com/spf13/cobra"
+)
+
+// CreateValidatePricesCommand creates the command.
+func CreateValidatePricesCommand() *cobra.Command {
+
+	var r validatePricesRunner
+
+	// Cmd is the validate-prices command.
+	c := &cobra.Command{
+		Use:   "validate-prices",
+		Short: "check prices for staleness",
+		Long: `For each commodity held on the given date, show the age of its latest price
+and flag prices which are missing or older than the maximum age.`,
+		Args: flags.JournalArg,
+		Run:  flags.WithJournal(r.run),
+	}
+	r.setupFlags(c)
+	return c
+}
+
+type validatePricesRunner struct {
+	valuation flags.CommodityFlag
+	date      flags.DateFlag
+	maxAge    int
+	color     bool
+}
+
+func (r *validatePricesRunner) run(cmd *cobra.Command, args []string) {
+	if err := r.execute(cmd, args); err != nil {
+		flags.PrintError(cmd, err)
+		os.Exit(1)
+	}
+}
+
+func (r *validatePricesRunner) setupFlags(c *cobra.Command) {
+	c.Flags().VarP(&r.valuation, "val", "v", "valuate in the given commodity")
+	c.Flags().Var(&r.date, "date", "the report date (default: today)")
+	c.Flags().IntVar(&r.maxAge, "max-age", 7, "maximum age of a price in days")
+	c.Flags().BoolVar(&r.color, "color", true, "print output in color")
+	c.MarkFlagRequired("val")
+}
+
+func (r *validatePricesRunner) execute(cmd *cobra.Command, args []string) error {
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
+	valuation, err := r.valuation.Value(reg)
+	if err != nil {
+		return err
+	}
+	b, err := journal.FromPathWith(cmd.Context(), reg, args[0], flags.Resolver(cmd))
+	if err != nil {
+		return err
+	}
+	flags.ResolvePrices(cmd, b)
+	rep := pricecheck.NewReport(valuation, r.date.ValueOr(date.Today()), r.maxAge)
+	if err := b.Build().Process(journal.ComputePrices(valuation), rep.Process()); err != nil {
+		return err
+	}
+	tableRenderer := table.TextRenderer{
+		Color: r.color,
+	}
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	defer out.Flush()
+	if err := tableRenderer.Render(pricecheck.Renderer{}.Render(rep), out); err != nil {
+		return err
+	}
+	var invalid int
+	for _, e := range rep.Entries() {
+		if e.Status != pricecheck.OK {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		out.Flush()
+		return fmt.Errorf("%d commodities with stale or missing prices", invalid)
+	}
+	return nil
+}
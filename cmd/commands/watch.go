@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+)
+
+// watchInterval is the interval in which watched files are checked for
+// changes.
+const watchInterval = 500 * time.Millisecond
+
+// watch runs f, and runs it again whenever the journal at path or one of
+// the files it includes changes. The terminal is cleared before each run.
+// Errors returned by f are printed, but do not end the loop.
+func watch(cmd *cobra.Command, path string, f func() error) error {
+	ctx := cmd.Context()
+	files := map[string]time.Time{path: {}}
+	for {
+		if _, err := io.WriteString(cmd.OutOrStdout(), "\033[H\033[2J"); err != nil {
+			return err
+		}
+		if err := f(); err != nil {
+			flags.PrintError(cmd, err)
+		}
+		// Keep watching the previous files if the journal can not be
+		// parsed, such that fixing the error triggers a new run.
+		paths, err := syntax.Files(ctx, path)
+		if err != nil {
+			paths = append(paths, maps.Keys(files)...)
+		}
+		files = make(map[string]time.Time)
+		for _, p := range paths {
+			files[p] = modTime(p)
+		}
+		for !changed(files) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(watchInterval):
+			}
+		}
+	}
+}
+
+func changed(files map[string]time.Time) bool {
+	for p, t := range files {
+		if !modTime(p).Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// modTime returns the modification time of the file, or the zero time
+// if the file does not exist.
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
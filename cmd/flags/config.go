@@ -0,0 +1,179 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFile is the name of the config file which is looked up in the
+// working directory and in the home directory.
+const ConfigFile = ".knut.yaml"
+
+// mutuallyExclusive is the annotation of cobra.MarkFlagsMutuallyExclusive.
+const mutuallyExclusive = "cobra_annotation_mutually_exclusive"
+
+// Config holds default values of flags. Top-level keys are flag names,
+// which apply to every command having such a flag. A key which is the
+// path of a command, such as `balance` or `portfolio allocation`, holds
+// flags for this command only, which take precedence:
+//
+//	val: CHF
+//	digits: 2
+//	balance:
+//	  months: true
+//	  account: [Assets, Liabilities]
+type Config map[string]any
+
+// LoadConfig loads the config from the given YAML file.
+func LoadConfig(path string) (Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// FindConfig returns the path of the config file: the file given with
+// --config or the environment variable KNUT_CONFIG, or ConfigFile in the
+// working directory or the home directory. It returns an empty path if
+// there is no config file.
+func FindConfig(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		return path, nil
+	}
+	if path := os.Getenv(envName("config")); path != "" {
+		return path, nil
+	}
+	var dirs []string
+	if wd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, wd)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, ConfigFile)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// ApplyConfig sets the flags of the command which are not given on the
+// command line. A flag is taken from the environment variable KNUT_<FLAG>,
+// such as KNUT_VAL for --val, or else from the config file. Flags in a
+// mutually exclusive group with a flag given on the command line are left
+// alone, so that for example --weeks overrides `months: true`.
+func ApplyConfig(cmd *cobra.Command) error {
+	path, err := FindConfig(cmd)
+	if err != nil {
+		return err
+	}
+	var cfg Config
+	if path != "" {
+		if cfg, err = LoadConfig(path); err != nil {
+			return err
+		}
+	}
+	return cfg.Apply(cmd)
+}
+
+// Apply sets the flags of the command which are not given on the command
+// line, see ApplyConfig.
+func (cfg Config) Apply(cmd *cobra.Command) error {
+	values := cfg.values(cmd)
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || excluded(cmd, f) {
+			return
+		}
+		vs, ok := values[f.Name]
+		if env, found := os.LookupEnv(envName(f.Name)); found {
+			vs, ok = []string{env}, true
+		}
+		if !ok {
+			return
+		}
+		for _, v := range vs {
+			if err := f.Value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid default %q for flag --%s: %w", v, f.Name, err))
+				return
+			}
+		}
+		// Required flags are validated by whether they were changed.
+		if _, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok {
+			f.Changed = true
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// values returns the values of the flags for the given command.
+func (cfg Config) values(cmd *cobra.Command) map[string][]string {
+	res := make(map[string][]string)
+	path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	for k, v := range cfg {
+		if k == path {
+			continue
+		}
+		if vs, ok := toStrings(v); ok {
+			res[k] = vs
+		}
+	}
+	if m, ok := cfg[path].(map[any]any); ok {
+		for k, v := range m {
+			if vs, ok := toStrings(v); ok {
+				res[fmt.Sprint(k)] = vs
+			}
+		}
+	}
+	return res
+}
+
+func toStrings(v any) ([]string, bool) {
+	switch t := v.(type) {
+	case map[any]any:
+		return nil, false
+	case []any:
+		var res []string
+		for _, e := range t {
+			res = append(res, fmt.Sprint(e))
+		}
+		return res, true
+	}
+	return []string{fmt.Sprint(v)}, true
+}
+
+// excluded returns whether a flag in a mutually exclusive group with f
+// was given on the command line.
+func excluded(cmd *cobra.Command, f *pflag.Flag) bool {
+	for _, group := range f.Annotations[mutuallyExclusive] {
+		for _, name := range strings.Fields(group) {
+			if other := cmd.Flags().Lookup(name); other != nil && other.Changed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func envName(flag string) string {
+	return "KNUT_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
@@ -0,0 +1,151 @@
+package flags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+const testConfig = `
+val: CHF
+digits: 2
+balance:
+  digits: 4
+  months: true
+  account: [Assets, Liabilities]
+`
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFile)
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		desc string
+		cmd  string
+		args []string
+		env  map[string]string
+		want map[string]string
+	}{
+		{
+			desc: "command section overrides globals",
+			cmd:  "balance",
+			want: map[string]string{"val": "CHF", "digits": "4", "months": "true", "weeks": "false", "account": "[Assets,Liabilities]"},
+		},
+		{
+			desc: "globals only",
+			cmd:  "register",
+			want: map[string]string{"val": "CHF", "digits": "2", "months": "false", "weeks": "false", "account": "[]"},
+		},
+		{
+			desc: "command line overrides config",
+			cmd:  "balance",
+			args: []string{"--val", "USD", "--account", "Income"},
+			want: map[string]string{"val": "USD", "digits": "4", "months": "true", "weeks": "false", "account": "[Income]"},
+		},
+		{
+			desc: "command line overrides mutually exclusive flags",
+			cmd:  "balance",
+			args: []string{"--weeks"},
+			want: map[string]string{"val": "CHF", "digits": "4", "months": "false", "weeks": "true", "account": "[Assets,Liabilities]"},
+		},
+		{
+			desc: "environment overrides config",
+			cmd:  "balance",
+			env:  map[string]string{"KNUT_VAL": "EUR", "KNUT_DIGITS": "0"},
+			want: map[string]string{"val": "EUR", "digits": "0", "months": "true", "weeks": "false", "account": "[Assets,Liabilities]"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+			got := make(map[string]string)
+			root := &cobra.Command{
+				Use: "knut",
+				PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+					return ApplyConfig(cmd)
+				},
+			}
+			root.PersistentFlags().String("config", "", "")
+			for _, name := range []string{"balance", "register"} {
+				cmd := &cobra.Command{
+					Use: name,
+					Run: func(cmd *cobra.Command, args []string) {
+						for k := range test.want {
+							got[k] = cmd.Flag(k).Value.String()
+						}
+					},
+				}
+				cmd.Flags().String("val", "", "")
+				cmd.Flags().Int("digits", 0, "")
+				cmd.Flags().Bool("months", false, "")
+				cmd.Flags().Bool("weeks", false, "")
+				cmd.Flags().StringSlice("account", nil, "")
+				cmd.MarkFlagsMutuallyExclusive("months", "weeks")
+				root.AddCommand(cmd)
+			}
+			root.SetArgs(append([]string{test.cmd, "--config", path}, test.args...))
+
+			if err := root.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ApplyConfig() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestApplyConfigRequiredFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFile)
+	if err := os.WriteFile(path, []byte("val: CHF\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := &cobra.Command{
+		Use: "knut",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return ApplyConfig(cmd)
+		},
+	}
+	root.PersistentFlags().String("config", "", "")
+	cmd := &cobra.Command{Use: "portfolio", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.Flags().String("val", "", "")
+	cmd.MarkFlagRequired("val")
+	root.AddCommand(cmd)
+	root.SetArgs([]string{"portfolio", "--config", path})
+
+	if err := root.Execute(); err != nil {
+		t.Errorf("Execute() = %v, want the config to satisfy the required flag", err)
+	}
+}
+
+func TestFindConfigEnvironment(t *testing.T) {
+	t.Setenv("KNUT_CONFIG", "env.yaml")
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{want: "env.yaml"},
+		{args: []string{"--config", "flag.yaml"}, want: "flag.yaml"},
+	} {
+		cmd := &cobra.Command{Use: "knut"}
+		cmd.Flags().String("config", "", "")
+		if err := cmd.ParseFlags(test.args); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := FindConfig(cmd)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("FindConfig() = %q, want %q", got, test.want)
+		}
+	}
+}
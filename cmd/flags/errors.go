@@ -0,0 +1,46 @@
+package flags
+
+import (
+	"fmt"
+
+	"github.com/sboehler/knut/lib/diagnostic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ErrorFormatFlag manages the flag for the format of errors, either
+// text or json.
+type ErrorFormatFlag string
+
+var _ pflag.Value = (*ErrorFormatFlag)(nil)
+
+func (ef ErrorFormatFlag) String() string {
+	if ef == "" {
+		return "text"
+	}
+	return string(ef)
+}
+
+// Set implements pflag.Value.
+func (ef *ErrorFormatFlag) Set(v string) error {
+	if v != "text" && v != "json" {
+		return fmt.Errorf("invalid error format %q, want text or json", v)
+	}
+	*ef = ErrorFormatFlag(v)
+	return nil
+}
+
+// Type implements pflag.Value.
+func (ef ErrorFormatFlag) Type() string {
+	return "text|json"
+}
+
+// PrintError prints an error to the error output of the command, in the
+// format given by the persistent --error-format flag.
+func PrintError(cmd *cobra.Command, err error) {
+	format := "text"
+	if f := cmd.Flags().Lookup("error-format"); f != nil {
+		format = f.Value.String()
+	}
+	diagnostic.Write(cmd.ErrOrStderr(), format, err)
+}
@@ -26,7 +26,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/sboehler/knut/lib/amounts/expr"
 	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/predicate"
 	"github.com/sboehler/knut/lib/common/regex"
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/account"
@@ -41,9 +43,10 @@ func (tf DateFlag) String() string {
 	return tf.Value().String()
 }
 
-// Set implements pflag.Value.
+// Set implements pflag.Value. Besides absolute dates, it accepts relative
+// dates such as `today`, `-30d` or `eom-1m`, see date.Parse.
 func (tf *DateFlag) Set(v string) error {
-	t, err := time.Parse("2006-01-02", v)
+	t, err := date.Parse(v, date.Today())
 	if err != nil {
 		return err
 	}
@@ -53,7 +56,7 @@ func (tf *DateFlag) Set(v string) error {
 
 // Type implements pflag.Value.
 func (tf DateFlag) Type() string {
-	return "YYYY-MM-DD"
+	return "<date>"
 }
 
 // Value returns the flag value.
@@ -71,26 +74,25 @@ func (tf DateFlag) ValueOr(t time.Time) time.Time {
 
 // RegexFlag manages a flag to get a regex.
 type RegexFlag struct {
-	rxs regex.Regexes
+	patterns []string
+
+	// ignoreCase makes the regexes case-insensitive, anchor makes them
+	// match whole segments from the start of a name.
+	ignoreCase, anchor bool
 }
 
 var _ pflag.Value = (*RegexFlag)(nil)
 
 func (rf RegexFlag) String() string {
-	var ss []string
-	for _, r := range rf.rxs {
-		ss = append(ss, r.String())
-	}
-	return strings.Join(ss, ",")
+	return strings.Join(rf.patterns, ",")
 }
 
 // Set implements pflag.Set.
 func (rf *RegexFlag) Set(v string) error {
-	t, err := regexp.Compile(v)
-	if err != nil {
+	if _, err := regexp.Compile(v); err != nil {
 		return err
 	}
-	rf.rxs.Add(t)
+	rf.patterns = append(rf.patterns, v)
 	return nil
 }
 
@@ -99,8 +101,93 @@ func (rf RegexFlag) Type() string {
 	return "<regex>"
 }
 
+// AddModifiers adds the flags --<name>-ci, which makes the regexes
+// case-insensitive, and --<name>-anchored, which makes them match whole
+// segments from the start of a name. With the latter, Assets:Bank matches
+// Assets:Bank and Assets:Bank:Savings, but neither OtherAssets:Bank nor
+// Assets:Banking.
+func (rf *RegexFlag) AddModifiers(cmd *cobra.Command, name string) {
+	cmd.Flags().BoolVar(&rf.ignoreCase, name+"-ci", false, fmt.Sprintf("match --%s case-insensitively", name))
+	cmd.Flags().BoolVar(&rf.anchor, name+"-anchored", false, fmt.Sprintf("match --%s against whole segments from the start", name))
+}
+
+// Regex returns the regexes, with the modifiers applied.
 func (rf *RegexFlag) Regex() regex.Regexes {
-	return rf.rxs
+	var rxs regex.Regexes
+	for _, p := range rf.patterns {
+		if rf.anchor {
+			p = "^(?:" + p + ")(?::|$)"
+		}
+		if rf.ignoreCase {
+			p = "(?i)" + p
+		}
+		rxs.Add(regexp.MustCompile(p))
+	}
+	return rxs
+}
+
+// GlobFlag manages a flag to get glob patterns over account segments,
+// see regex.FromGlob.
+type GlobFlag struct {
+	globs []string
+	rxs   regex.Regexes
+}
+
+var _ pflag.Value = (*GlobFlag)(nil)
+
+func (gf GlobFlag) String() string {
+	return strings.Join(gf.globs, ",")
+}
+
+// Set implements pflag.Set.
+func (gf *GlobFlag) Set(v string) error {
+	rx, err := regex.FromGlob(v)
+	if err != nil {
+		return err
+	}
+	gf.globs = append(gf.globs, v)
+	gf.rxs.Add(rx)
+	return nil
+}
+
+// Type implements pflag.Type.
+func (gf GlobFlag) Type() string {
+	return "<glob>"
+}
+
+// Regex returns the regexes which the patterns translate to.
+func (gf *GlobFlag) Regex() regex.Regexes {
+	return gf.rxs
+}
+
+// ExprFlag manages a flag to get a query expression.
+type ExprFlag struct {
+	s    string
+	pred predicate.Predicate[expr.Posting]
+}
+
+func (ef ExprFlag) String() string {
+	return ef.s
+}
+
+// Set implements pflag.Set.
+func (ef *ExprFlag) Set(v string) error {
+	pred, err := expr.Parse(v)
+	if err != nil {
+		return err
+	}
+	ef.s, ef.pred = v, pred
+	return nil
+}
+
+// Type implements pflag.Type.
+func (ef ExprFlag) Type() string {
+	return "<expr>"
+}
+
+// Value returns the predicate, or nil if the flag is not set.
+func (ef ExprFlag) Value() predicate.Predicate[expr.Posting] {
+	return ef.pred
 }
 
 // IntervalFlags manages multiple flags to determine a time period.
@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRegexFlagModifiers(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		args    []string
+		matches map[string]bool
+	}{
+		{
+			desc: "plain",
+			args: []string{"--account", "Assets:Bank"},
+			matches: map[string]bool{
+				"Assets:Bank":      true,
+				"Assets:Banking":   true,
+				"OtherAssets:Bank": true,
+				"assets:bank":      false,
+			},
+		},
+		{
+			desc: "case-insensitive",
+			args: []string{"--account-ci", "--account", "assets:bank"},
+			matches: map[string]bool{
+				"Assets:Bank": true,
+				"ASSETS:BANK": true,
+			},
+		},
+		{
+			desc: "anchored",
+			args: []string{"--account", "Assets:Bank", "--account-anchored"},
+			matches: map[string]bool{
+				"Assets:Bank":         true,
+				"Assets:Bank:Savings": true,
+				"Assets:Banking":      false,
+				"OtherAssets:Bank":    false,
+				"assets:bank":         false,
+			},
+		},
+		{
+			desc: "anchored alternatives",
+			args: []string{"--account", "Assets|Income", "--account-anchored", "--account-ci"},
+			matches: map[string]bool{
+				"assets:Bank":    true,
+				"Income:Salary":  true,
+				"Expenses:Asset": false,
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var rf RegexFlag
+			cmd := new(cobra.Command)
+			cmd.Flags().Var(&rf, "account", "")
+			rf.AddModifiers(cmd, "account")
+			if err := cmd.Flags().Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+
+			rxs := rf.Regex()
+
+			for name, want := range test.matches {
+				if got := rxs.MatchString(name); got != want {
+					t.Errorf("MatchString(%q) = %t, want %t", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package flags
+
+import (
+	"fmt"
+
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/spf13/cobra"
+)
+
+// Resolver returns a resolver for include directives, configured by the
+// persistent --dedupe-includes, --parallelism and --sequential flags. Files which are
+// included more than once are reported as warnings on the error output of
+// the command.
+func Resolver(cmd *cobra.Command) *syntax.Resolver {
+	dedupe, _ := cmd.Flags().GetBool("dedupe-includes")
+	parallelism, _ := cmd.Flags().GetInt("parallelism")
+	sequential, _ := cmd.Flags().GetBool("sequential")
+	w := cmd.ErrOrStderr()
+	return &syntax.Resolver{
+		Dedupe:      dedupe,
+		Parallelism: parallelism,
+		Sequential:  sequential,
+		Warn: func(d syntax.Duplicate) {
+			fmt.Fprintf(w, "warning: %s\n", d)
+		},
+	}
+}
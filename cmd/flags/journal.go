@@ -0,0 +1,65 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// JournalFile is the name of the journal which is discovered in the
+// working directory or one of its parents.
+const JournalFile = "main.knut"
+
+// JournalArg validates the arguments of commands which take the journal
+// as their only argument. The journal can be omitted, see WithJournal.
+func JournalArg(cmd *cobra.Command, args []string) error {
+	return cobra.MatchAll(cobra.RangeArgs(0, 1), cobra.OnlyValidArgs)(cmd, args)
+}
+
+// WithJournal wraps the run function of a command which takes the journal
+// as its only argument. If the journal is omitted, it passes the journal
+// given with --journal or, failing that, the first JournalFile found
+// walking up from the working directory.
+func WithJournal(run func(*cobra.Command, []string)) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			path, err := DefaultJournal(cmd)
+			if err != nil {
+				PrintError(cmd, err)
+				os.Exit(1)
+			}
+			args = []string{path}
+		}
+		run(cmd, args)
+	}
+}
+
+// DefaultJournal returns the journal given with --journal or, failing
+// that, the first JournalFile in the working directory or its parents.
+func DefaultJournal(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("journal"); path != "" {
+		return path, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := wd; ; dir = filepath.Dir(dir) {
+		path := filepath.Join(dir, JournalFile)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return "", fmt.Errorf("no journal given, and no %s found in %s or its parents", JournalFile, wd)
+}
@@ -0,0 +1,84 @@
+package flags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+func TestJournalArg(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		args []string
+		env  string
+		want []string
+	}{
+		{desc: "argument", args: []string{"a.knut"}, want: []string{"a.knut"}},
+		{desc: "flag", args: []string{"--journal", "b.knut"}, want: []string{"b.knut"}},
+		{desc: "environment", env: "b.knut", want: []string{"b.knut"}},
+		{desc: "argument overrides environment", args: []string{"a.knut"}, env: "b.knut", want: []string{"a.knut"}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Setenv("KNUT_JOURNAL", test.env)
+			var got []string
+			root := &cobra.Command{
+				Use: "knut",
+				PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+					return ApplyConfig(cmd)
+				},
+			}
+			root.PersistentFlags().String("config", os.DevNull, "")
+			root.PersistentFlags().String("journal", "", "")
+			root.AddCommand(&cobra.Command{
+				Use:  "balance",
+				Args: JournalArg,
+				Run:  WithJournal(func(cmd *cobra.Command, args []string) { got = args }),
+			})
+			root.SetArgs(append([]string{"balance"}, test.args...))
+
+			if err := root.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("WithJournal() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestDefaultJournal(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, JournalFile), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cobra.Command{Use: "balance"}
+	cmd.Flags().String("journal", "", "")
+
+	got, err := DefaultJournal(cmd)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, JournalFile); got != want {
+		t.Errorf("DefaultJournal() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package flags
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// Output is the destination of the output of a command: a file given with
+// --output, or stdout.
+type Output struct {
+	path string
+}
+
+// Setup adds the --output flag to the command.
+func (o *Output) Setup(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.path, "output", "o", "", "write the output to a file instead of stdout")
+}
+
+// Write calls f with a buffered writer to the output and flushes it. A
+// file is only replaced once f has succeeded and the output has been
+// written completely, so a failed run leaves an existing file untouched.
+func (o Output) Write(cmd *cobra.Command, f func(io.Writer) error) error {
+	if o.path == "" {
+		w := bufio.NewWriter(cmd.OutOrStdout())
+		if err := f(w); err != nil {
+			w.Flush()
+			return err
+		}
+		return w.Flush()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), "."+filepath.Base(o.path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	if err := f(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Temporary files are only readable by the owner.
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), o.path)
+}
@@ -0,0 +1,58 @@
+package flags
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestOutputWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	cmd := new(cobra.Command)
+	var o Output
+	o.Setup(cmd)
+	if err := cmd.Flags().Parse([]string{"--output", path}); err != nil {
+		t.Fatal(err)
+	}
+	write := func(s string, err error) error {
+		return o.Write(cmd, func(w io.Writer) error {
+			if _, err := io.WriteString(w, s); err != nil {
+				return err
+			}
+			return err
+		})
+	}
+	read := func() string {
+		t.Helper()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if err := write("first", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "first" {
+		t.Errorf("Write() wrote %q, want %q", got, "first")
+	}
+	errWrite := errors.New("failed")
+	if err := write("second", errWrite); err != errWrite {
+		t.Errorf("Write() returned %v, want %v", err, errWrite)
+	}
+	if got := read(); got != "first" {
+		t.Errorf("failed Write() left %q, want %q", got, "first")
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Write() left %d files, want 1", len(entries))
+	}
+}
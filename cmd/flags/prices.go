@@ -0,0 +1,23 @@
+package flags
+
+import (
+	"fmt"
+
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/spf13/cobra"
+)
+
+// ResolvePrices resolves conflicting prices of the journal if the
+// persistent --last-wins flag is set: the last price of each commodity
+// and target on a day wins, and the dropped ones are reported as warnings
+// on the error output of the command. Otherwise, conflicting prices make
+// the computation of prices fail.
+func ResolvePrices(cmd *cobra.Command, j *journal.Builder) {
+	if lastWins, _ := cmd.Flags().GetBool("last-wins"); !lastWins {
+		return
+	}
+	w := cmd.ErrOrStderr()
+	j.ResolvePrices(func(pc journal.PriceConflict) {
+		fmt.Fprintf(w, "warning: %v, using the latter\n", pc)
+	})
+}
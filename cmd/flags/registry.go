@@ -0,0 +1,44 @@
+package flags
+
+import (
+	"github.com/sboehler/knut/lib/model/account"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/spf13/cobra"
+)
+
+// NewRegistry creates a registry whose special accounts are configured
+// by the persistent --equity-account, --tbd-account, --valuation-account
+// and --rounding-account flags, whose commodity symbols are configured by
+// the persistent --symbol and --symbol-suffix flags, and whose commodity
+// precisions are configured by the persistent --precision flag.
+func NewRegistry(cmd *cobra.Command) (*registry.Registry, error) {
+	reg := registry.New()
+	var s account.SpecialAccounts
+	s.Equity, _ = cmd.Flags().GetString("equity-account")
+	s.TBD, _ = cmd.Flags().GetString("tbd-account")
+	s.Valuation, _ = cmd.Flags().GetString("valuation-account")
+	if s.Rounding, _ = cmd.Flags().GetString("rounding-account"); s.Rounding == "" {
+		s.Rounding = s.Valuation
+	}
+	if err := reg.Accounts().SetSpecialAccounts(s); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		flag   string
+		suffix bool
+	}{{"symbol", false}, {"symbol-suffix", true}} {
+		symbols, _ := cmd.Flags().GetStringToString(f.flag)
+		for name, symbol := range symbols {
+			if err := reg.Commodities().SetSymbol(name, symbol, f.suffix); err != nil {
+				return nil, err
+			}
+		}
+	}
+	precisions, _ := cmd.Flags().GetStringToInt("precision")
+	for name, places := range precisions {
+		if err := reg.Commodities().SetPrecision(name, places); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
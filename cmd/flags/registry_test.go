@@ -0,0 +1,48 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNewRegistryRoundingAccount(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		args []string
+		want string
+	}{
+		{
+			desc: "default",
+			want: "Income",
+		},
+		{
+			desc: "valuation account",
+			args: []string{"--valuation-account", "Income:Bewertung"},
+			want: "Income:Bewertung",
+		},
+		{
+			desc: "rounding account",
+			args: []string{"--valuation-account", "Income:Bewertung", "--rounding-account", "Equity:Rundung"},
+			want: "Equity:Rundung",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			cmd := new(cobra.Command)
+			cmd.Flags().String("valuation-account", "Income", "")
+			cmd.Flags().String("rounding-account", "", "")
+			if err := cmd.Flags().Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+
+			reg, err := NewRegistry(cmd)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := reg.Accounts().RoundingAccount().Name(); got != test.want {
+				t.Errorf("RoundingAccount() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,39 @@
 package flags
 
 import (
+	"fmt"
+
 	"github.com/sboehler/knut/lib/common/date"
 	"github.com/spf13/cobra"
 )
 
 type Multiperiod struct {
-	period   PeriodFlag
-	last     int
-	interval IntervalFlags
+	period      PeriodFlag
+	first, last int
+	interval    IntervalFlags
 }
 
 func (mp *Multiperiod) Setup(cmd *cobra.Command) {
 	mp.period.Setup(cmd, date.Period{End: date.Today()})
+	cmd.Flags().IntVar(&mp.first, "first", 0, "first n periods")
 	cmd.Flags().IntVar(&mp.last, "last", 0, "last n periods")
+	cmd.MarkFlagsMutuallyExclusive("first", "last")
 	mp.interval.Setup(cmd, date.Once)
 }
 
 func (mp *Multiperiod) Partition(clip date.Period) date.Partition {
-	return date.NewPartition(mp.period.Value().Clip(clip), mp.interval.Value(), mp.last)
+	return date.NewPartition(mp.period.Value().Clip(clip), mp.interval.Value(), mp.last).First(mp.first)
+}
+
+// Check returns an error if the requested period lies outside of the
+// given period, which would result in an empty report.
+func (mp *Multiperiod) Check(clip date.Period) error {
+	p := mp.period.Value()
+	if !clip.End.IsZero() && p.Start.After(clip.End) {
+		return fmt.Errorf("--from %s is after the end of the journal (%s)", p.Start.Format("2006-01-02"), clip.End.Format("2006-01-02"))
+	}
+	if !clip.Start.IsZero() && p.End.Before(clip.Start) {
+		return fmt.Errorf("--to %s is before the start of the journal (%s)", p.End.Format("2006-01-02"), clip.Start.Format("2006-01-02"))
+	}
+	return nil
 }
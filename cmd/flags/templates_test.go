@@ -0,0 +1,58 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/spf13/cobra"
+)
+
+func TestMultiperiodCheck(t *testing.T) {
+	journal := date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 12, 31)}
+	for _, test := range []struct {
+		desc    string
+		args    []string
+		wantErr string
+	}{
+		{
+			desc: "default",
+		},
+		{
+			desc: "within the journal",
+			args: []string{"--from", "2020-03-01", "--to", "2020-06-30"},
+		},
+		{
+			desc: "overlapping the journal",
+			args: []string{"--from", "2019-06-01", "--to", "2021-06-30"},
+		},
+		{
+			desc:    "from after the journal",
+			args:    []string{"--from", "2021-01-01"},
+			wantErr: "--from 2021-01-01 is after the end of the journal (2020-12-31)",
+		},
+		{
+			desc:    "to before the journal",
+			args:    []string{"--to", "2019-12-31"},
+			wantErr: "--to 2019-12-31 is before the start of the journal (2020-01-01)",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var mp Multiperiod
+			cmd := new(cobra.Command)
+			mp.Setup(cmd)
+			if err := cmd.Flags().Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			if err := mp.Check(journal); err != nil {
+				got = err.Error()
+			}
+
+			if diff := cmp.Diff(test.wantErr, got); diff != "" {
+				t.Errorf("Check() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
@@ -67,11 +67,14 @@ func (r *runner) setupFlags(c *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		ctx     = registry.New()
+		ctx     *registry.Registry
 		account *model.Account
 		reader  *bufio.Reader
 		err     error
 	)
+	if ctx, err = flags.NewRegistry(cmd); err != nil {
+		return err
+	}
 	if account, err = r.account.Value(ctx.Accounts()); err != nil {
 		return err
 	}
@@ -92,7 +95,7 @@ func (r *runner) run(cmd *cobra.Command, args []string) error {
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, j.Build())
+	return importer.Print(cmd, ctx, out, j.Build())
 }
 
 type parser struct {
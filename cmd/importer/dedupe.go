@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"fmt"
+
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/dedupe"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/spf13/cobra"
+)
+
+// SetupDedupe adds the persistent flags for leaving out transactions
+// which are already in a journal to the import command.
+func SetupDedupe(c *cobra.Command) {
+	c.PersistentFlags().String("dedupe", "", "leave out transactions which duplicate a transaction of the given journal")
+	c.PersistentFlags().Int("dedupe-window", 3, "maximum number of days between duplicates")
+	c.PersistentFlags().Float64("dedupe-threshold", 0.8, "minimum similarity of the descriptions of duplicates, between 0 and 1")
+}
+
+// Dedupe returns a predicate which reports whether an imported
+// transaction is new, i.e. does not duplicate a transaction of the journal
+// given by the --dedupe flag. Duplicates are reported as warnings on the
+// error output of the command. Without --dedupe, every transaction is new.
+func Dedupe(cmd *cobra.Command, reg *model.Registry) (func(*model.Transaction) bool, error) {
+	path, _ := cmd.Flags().GetString("dedupe")
+	if path == "" {
+		return func(*model.Transaction) bool { return true }, nil
+	}
+	window, _ := cmd.Flags().GetInt("dedupe-window")
+	threshold, _ := cmd.Flags().GetFloat64("dedupe-threshold")
+	b, err := journal.FromPathWith(cmd.Context(), reg, path, flags.Resolver(cmd))
+	if err != nil {
+		return nil, err
+	}
+	ix := dedupe.NewIndex(dedupe.Detector{Window: window, Threshold: threshold}, b.Build())
+	w := cmd.ErrOrStderr()
+	return func(t *model.Transaction) bool {
+		d, ok := ix.Find(t)
+		if ok {
+			fmt.Fprintf(w, "warning: leaving out %s %q, which duplicates %q of %s (similarity %.2f)\n",
+				t.Date.Format("2006-01-02"), t.Description, d.Original.Description, d.Original.Date.Format("2006-01-02"), d.Similarity)
+		}
+		return !ok
+	}, nil
+}
@@ -1,6 +1,19 @@
 package importer
 
-import "github.com/spf13/cobra"
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/sboehler/knut/lib/syntax/parser"
+	"github.com/spf13/cobra"
+)
 
 var importers []func() *cobra.Command
 
@@ -12,3 +25,70 @@ func RegisterImporter(f func() *cobra.Command) {
 func GetImporters() []func() *cobra.Command {
 	return importers
 }
+
+// Statement parses an account statement of the given account into a
+// journal.
+type Statement func(reg *model.Registry, account *model.Account, r io.Reader) (*journal.Builder, error)
+
+var statements = make(map[string]Statement)
+
+// RegisterStatement registers the parser of an importer for statements
+// of a single account, such that other commands can read statements, for
+// example to reconcile an account. The name is the name of the importer.
+func RegisterStatement(name string, s Statement) {
+	statements[name] = s
+}
+
+// GetStatement returns the statement parser of the importer with the
+// given name.
+func GetStatement(name string) (Statement, error) {
+	if s, ok := statements[name]; ok {
+		return s, nil
+	}
+	names := dict.SortedKeys(statements, compare.Ordered[string])
+	return nil, fmt.Errorf("no statement format %q, available are: %s", name, strings.Join(names, ", "))
+}
+
+// Print prints the imported journal. It leaves out the transactions
+// which are not new according to Dedupe and splits the bookings according
+// to the rules of Splitter.
+func Print(cmd *cobra.Command, reg *model.Registry, w io.Writer, j *journal.Journal) error {
+	isNew, err := Dedupe(cmd, reg)
+	if err != nil {
+		return err
+	}
+	for _, d := range j.Days {
+		var trxs []*model.Transaction
+		for _, t := range d.Transactions {
+			if isNew(t) {
+				trxs = append(trxs, t)
+			}
+		}
+		d.Transactions = trxs
+	}
+	splitter, err := Splitter(cmd)
+	if err != nil {
+		return err
+	}
+	if splitter == nil {
+		return journal.Print(w, j)
+	}
+	// The rules work on the syntax of the bookings, as for the split
+	// command, so the imported journal is split in its printed form.
+	var buf bytes.Buffer
+	if err := journal.Print(&buf, j); err != nil {
+		return err
+	}
+	p := parser.New(buf.String(), "")
+	if err := p.Advance(); err != nil {
+		return err
+	}
+	file, err := p.ParseFile()
+	if err != nil {
+		return err
+	}
+	if err := splitter.ApplyFile(&file); err != nil {
+		return err
+	}
+	return syntax.FormatFile(w, file)
+}
@@ -76,9 +76,12 @@ func (r *runner) setupFlags(c *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		reg = registry.New()
+		reg *registry.Registry
 		err error
 	)
+	if reg, err = flags.NewRegistry(cmd); err != nil {
+		return err
+	}
 	f, err := flags.OpenFile(args[0])
 	if err != nil {
 		return err
@@ -111,7 +114,7 @@ func (r *runner) run(cmd *cobra.Command, args []string) error {
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, p.builder.Build())
+	return importer.Print(cmd, reg, out, p.builder.Build())
 }
 
 type parser struct {
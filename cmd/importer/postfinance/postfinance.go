@@ -72,30 +72,44 @@ func (r *runner) run(cmd *cobra.Command, args []string) {
 func (r *runner) runE(cmd *cobra.Command, args []string) error {
 	var (
 		reader *bufio.Reader
-		reg    = registry.New()
+		reg    *registry.Registry
 		err    error
 	)
-	if reader, err = flags.OpenFile(args[0]); err != nil {
+	if reg, err = flags.NewRegistry(cmd); err != nil {
 		return err
 	}
-	p := Parser{
-		registry: reg,
-		reader:   csv.NewReader(utfbom.SkipOnly(reader)),
-		builder:  journal.New(),
+	if reader, err = flags.OpenFile(args[0]); err != nil {
+		return err
 	}
-	if p.account, err = r.accountFlag.Value(reg.Accounts()); err != nil {
+	account, err := r.accountFlag.Value(reg.Accounts())
+	if err != nil {
 		return err
 	}
-	if err = p.parse(); err != nil {
+	builder, err := parseStatement(reg, account, reader)
+	if err != nil {
 		return err
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, p.builder.Build())
+	return importer.Print(cmd, reg, out, builder.Build())
+}
+
+func parseStatement(reg *model.Registry, account *model.Account, r io.Reader) (*journal.Builder, error) {
+	p := Parser{
+		registry: reg,
+		reader:   csv.NewReader(utfbom.SkipOnly(r)),
+		account:  account,
+		builder:  journal.New(),
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.builder, nil
 }
 
 func init() {
 	importer.RegisterImporter(CreateCmd)
+	importer.RegisterStatement("ch.postfinance", parseStatement)
 }
 
 // Parser is a parser for account statements
@@ -53,6 +53,7 @@ func CreateCmd() *cobra.Command {
 
 func init() {
 	importer.RegisterImporter(CreateCmd)
+	importer.RegisterStatement("revolut", parseStatement)
 }
 
 type runner struct {
@@ -66,27 +67,40 @@ func (r *runner) setupFlags(cmd *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		reg = registry.New()
+		reg *registry.Registry
 		f   *bufio.Reader
 		err error
 	)
-	if f, err = flags.OpenFile(args[0]); err != nil {
+	if reg, err = flags.NewRegistry(cmd); err != nil {
 		return err
 	}
-	p := parser{
-		registry: reg,
-		reader:   csv.NewReader(f),
-		builder:  journal.New(),
+	if f, err = flags.OpenFile(args[0]); err != nil {
+		return err
 	}
-	if p.account, err = r.account.Value(reg.Accounts()); err != nil {
+	account, err := r.account.Value(reg.Accounts())
+	if err != nil {
 		return err
 	}
-	if err = p.parse(); err != nil {
+	builder, err := parseStatement(reg, account, f)
+	if err != nil {
 		return err
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, p.builder.Build())
+	return importer.Print(cmd, reg, out, builder.Build())
+}
+
+func parseStatement(reg *model.Registry, account *model.Account, r io.Reader) (*journal.Builder, error) {
+	p := parser{
+		registry: reg,
+		reader:   csv.NewReader(r),
+		account:  account,
+		builder:  journal.New(),
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.builder, nil
 }
 
 type parser struct {
@@ -215,6 +229,10 @@ func (p *parser) parseBooking(r []string) error {
 		Date:        date,
 		Description: desc,
 	}
+	valuation, err := p.registry.Accounts().ValuationAccountFor(p.account)
+	if err != nil {
+		return err
+	}
 	switch {
 	case fxSellRegex.MatchString(r[bfReference]):
 		otherCommodity, otherQuantity, err := p.parseCombiField(r[bfExchangeOut])
@@ -223,13 +241,13 @@ func (p *parser) parseBooking(r []string) error {
 		}
 		t.Postings = posting.Builders{
 			{
-				Credit:    p.registry.Accounts().ValuationAccountFor(p.account),
+				Credit:    valuation,
 				Debit:     p.account,
 				Commodity: p.currency,
 				Quantity:  quantity,
 			},
 			{
-				Credit:    p.registry.Accounts().ValuationAccountFor(p.account),
+				Credit:    valuation,
 				Debit:     p.account,
 				Commodity: otherCommodity,
 				Quantity:  otherQuantity,
@@ -242,13 +260,13 @@ func (p *parser) parseBooking(r []string) error {
 		}
 		t.Postings = posting.Builders{
 			{
-				Credit:    p.registry.Accounts().ValuationAccountFor(p.account),
+				Credit:    valuation,
 				Debit:     p.account,
 				Commodity: p.currency,
 				Quantity:  quantity,
 			},
 			{
-				Credit:    p.registry.Accounts().ValuationAccountFor(p.account),
+				Credit:    valuation,
 				Debit:     p.account,
 				Commodity: otherCommodity,
 				Quantity:  otherAmount.Neg(),
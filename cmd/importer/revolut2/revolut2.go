@@ -65,10 +65,13 @@ func (r *runner) setupFlags(cmd *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		reg = registry.New()
+		reg *registry.Registry
 		f   *bufio.Reader
 		err error
 	)
+	if reg, err = flags.NewRegistry(cmd); err != nil {
+		return err
+	}
 	builder := journal.New()
 	for _, path := range args {
 		if f, err = flags.OpenFile(path); err != nil {
@@ -91,7 +94,7 @@ func (r *runner) run(cmd *cobra.Command, args []string) error {
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, builder.Build())
+	return importer.Print(cmd, reg, out, builder.Build())
 }
 
 type parser struct {
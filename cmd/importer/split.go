@@ -0,0 +1,28 @@
+package importer
+
+import (
+	"github.com/sboehler/knut/lib/syntax/split"
+	"github.com/spf13/cobra"
+)
+
+// SetupSplit adds the persistent flags for splitting the imported
+// bookings to the import command.
+func SetupSplit(c *cobra.Command) {
+	c.PersistentFlags().String("split", "", "the YAML file with rules to split the imported bookings")
+	c.PersistentFlags().String("catch-all", "", "the account for remainders of split rules without a remainder account")
+}
+
+// Splitter returns the splitter for the rules given by the --split flag,
+// or nil if there are none.
+func Splitter(cmd *cobra.Command) (*split.Splitter, error) {
+	path, _ := cmd.Flags().GetString("split")
+	if path == "" {
+		return nil, nil
+	}
+	catchAll, _ := cmd.Flags().GetString("catch-all")
+	rules, err := split.LoadRulesFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &split.Splitter{Rules: rules, CatchAll: catchAll}, nil
+}
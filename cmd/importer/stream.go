@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+
+	"github.com/sboehler/knut/lib/common/cpr"
+	"github.com/sboehler/knut/lib/journal/printer"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// Records streams the records of a CSV file, one at a time.
+func Records(r *csv.Reader) (<-chan []string, func(context.Context) error) {
+	return cpr.Produce(func(ctx context.Context, ch chan<- []string) error {
+		for {
+			rec, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := cpr.Push(ctx, ch, rec); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// Stream parses the records of a CSV file and prints the resulting
+// directives as soon as they are produced, in the order of the file.
+// Unlike journal.Print, it neither sorts the directives nor aligns them
+// up front, such that memory stays constant regardless of the size of the
+// file. Only the ch.swisscard2 importer uses it, with --stream.
+func Stream(ctx context.Context, w io.Writer, r *csv.Reader, parse func([]string) ([]model.Directive, error)) error {
+	recordCh, worker1 := Records(r)
+	directiveCh, worker2 := cpr.Produce(func(ctx context.Context, ch chan<- model.Directive) error {
+		return cpr.ForEach(ctx, recordCh, func(rec []string) error {
+			ds, err := parse(rec)
+			if err != nil {
+				return err
+			}
+			return cpr.Push(ctx, ch, ds...)
+		})
+	})
+	worker3 := func(ctx context.Context) error {
+		p := printer.New(w)
+		return cpr.ForEach(ctx, directiveCh, func(d model.Directive) error {
+			if t, ok := d.(*model.Transaction); ok {
+				p.UpdatePadding(t)
+			}
+			_, err := p.PrintDirectiveLn(d)
+			return err
+		})
+	}
+	p := pool.New().WithContext(ctx).WithCancelOnError().WithFirstError()
+	p.Go(worker1)
+	p.Go(worker2)
+	p.Go(worker3)
+	return p.Wait()
+}
@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+// records generates a CSV file with n records without holding it in
+// memory.
+type records struct {
+	n, i int
+	buf  []byte
+}
+
+func (r *records) Read(bs []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.i == r.n {
+			return 0, io.EOF
+		}
+		r.buf = fmt.Appendf(r.buf, "%s,description of booking %d,%d.%02d,CHF\n", time.Date(2020, 1, 1+r.i%3650, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), r.i, r.i%1000, r.i%100)
+		r.i++
+	}
+	n := copy(bs, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func TestStreamMemory(t *testing.T) {
+	const n = 200000
+	reg := registry.New()
+	account := reg.Accounts().MustGet("Assets:Bank")
+	var count int
+	var maxHeap uint64
+	parse := func(r []string) ([]model.Directive, error) {
+		d, err := time.Parse("2006-01-02", r[0])
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := decimal.NewFromString(r[2])
+		if err != nil {
+			return nil, err
+		}
+		count++
+		if count%20000 == 0 {
+			var stats runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			maxHeap = max(maxHeap, stats.HeapAlloc)
+		}
+		return []model.Directive{transaction.Builder{
+			Date:        d,
+			Description: r[1],
+			Postings: posting.Builder{
+				Credit:    reg.Accounts().TBDAccount(),
+				Debit:     account,
+				Commodity: reg.Commodities().MustGet(r[3]),
+				Quantity:  quantity,
+			}.Build(),
+		}.Build()}, nil
+	}
+
+	err := Stream(context.Background(), io.Discard, csv.NewReader(&records{n: n}), parse)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Errorf("parsed %d records, want %d", count, n)
+	}
+	if maxHeap > 16<<20 {
+		t.Errorf("heap grew to %d bytes, want at most %d", maxHeap, 16<<20)
+	}
+}
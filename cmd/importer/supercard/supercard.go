@@ -54,6 +54,7 @@ func CreateCmd() *cobra.Command {
 
 func init() {
 	importer.RegisterImporter(CreateCmd)
+	importer.RegisterStatement("ch.supercard", parseStatement)
 }
 
 type runner struct {
@@ -66,29 +67,41 @@ func (r *runner) setupFlags(cmd *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		reg = registry.New()
+		reg *registry.Registry
 		f   *bufio.Reader
 		err error
 	)
+	if reg, err = flags.NewRegistry(cmd); err != nil {
+		return err
+	}
 
 	if f, err = flags.OpenFile(args[0]); err != nil {
 		return err
 	}
-	p := parser{
-		registry: reg,
-		reader:   csv.NewReader(charmap.ISO8859_1.NewDecoder().Reader(f)),
-		builder:  journal.New(),
-	}
-
-	if p.account, err = r.account.Value(reg.Accounts()); err != nil {
+	account, err := r.account.Value(reg.Accounts())
+	if err != nil {
 		return err
 	}
-	if err = p.parse(); err != nil {
+	builder, err := parseStatement(reg, account, f)
+	if err != nil {
 		return err
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, p.builder.Build())
+	return importer.Print(cmd, reg, out, builder.Build())
+}
+
+func parseStatement(reg *model.Registry, account *model.Account, r io.Reader) (*journal.Builder, error) {
+	p := parser{
+		registry: reg,
+		reader:   csv.NewReader(charmap.ISO8859_1.NewDecoder().Reader(r)),
+		account:  account,
+		builder:  journal.New(),
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.builder, nil
 }
 
 type parser struct {
@@ -53,6 +53,7 @@ func CreateCmd() *cobra.Command {
 
 func init() {
 	importer.RegisterImporter(CreateCmd)
+	importer.RegisterStatement("ch.swisscard", parseStatement)
 }
 
 type runner struct {
@@ -67,27 +68,40 @@ func (r *runner) setupFlags(cmd *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		reg = registry.New()
+		reg *registry.Registry
 		f   *bufio.Reader
 		err error
 	)
-	if f, err = flags.OpenFile(args[0]); err != nil {
+	if reg, err = flags.NewRegistry(cmd); err != nil {
 		return err
 	}
-	p := parser{
-		registry: reg,
-		reader:   csv.NewReader(f),
-		builder:  journal.New(),
+	if f, err = flags.OpenFile(args[0]); err != nil {
+		return err
 	}
-	if p.account, err = r.account.Value(reg.Accounts()); err != nil {
+	account, err := r.account.Value(reg.Accounts())
+	if err != nil {
 		return err
 	}
-	if err = p.parse(); err != nil {
+	builder, err := parseStatement(reg, account, f)
+	if err != nil {
 		return err
 	}
 	w := bufio.NewWriter(cmd.OutOrStdout())
 	defer w.Flush()
-	return journal.Print(w, p.builder.Build())
+	return importer.Print(cmd, reg, w, builder.Build())
+}
+
+func parseStatement(reg *model.Registry, account *model.Account, r io.Reader) (*journal.Builder, error) {
+	p := parser{
+		registry: reg,
+		reader:   csv.NewReader(r),
+		account:  account,
+		builder:  journal.New(),
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.builder, nil
 }
 
 type parser struct {
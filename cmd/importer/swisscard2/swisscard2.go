@@ -16,6 +16,7 @@ package swisscard
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -29,7 +30,6 @@ import (
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/posting"
-	"github.com/sboehler/knut/lib/model/registry"
 	"github.com/sboehler/knut/lib/model/transaction"
 )
 
@@ -51,20 +51,26 @@ func CreateCmd() *cobra.Command {
 
 func init() {
 	importer.RegisterImporter(CreateCmd)
+	importer.RegisterStatement("ch.swisscard2", parseStatement)
 }
 
 type runner struct {
 	account flags.AccountFlag
+	stream  bool
 }
 
 func (r *runner) setupFlags(cmd *cobra.Command) {
 	cmd.Flags().VarP(&r.account, "account", "a", "account name")
+	cmd.Flags().BoolVar(&r.stream, "stream", false, "print the transactions in the order of the file, with constant memory")
 	cmd.MarkFlagRequired("account")
 
 }
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
-	reg := registry.New()
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 	f, err := flags.OpenFile(args[0])
 	if err != nil {
 		return err
@@ -79,12 +85,35 @@ func (r *runner) run(cmd *cobra.Command, args []string) error {
 		builder:  journal.New(),
 		account:  account,
 	}
+	w := bufio.NewWriter(cmd.OutOrStdout())
+	defer w.Flush()
+	if r.stream {
+		if s, _ := cmd.Flags().GetString("split"); s != "" {
+			return fmt.Errorf("--split cannot be combined with --stream")
+		}
+		isNew, err := importer.Dedupe(cmd, reg)
+		if err != nil {
+			return err
+		}
+		return p.stream(cmd.Context(), w, isNew)
+	}
 	if err = p.parse(); err != nil {
 		return err
 	}
-	w := bufio.NewWriter(cmd.OutOrStdout())
-	defer w.Flush()
-	return journal.Print(w, p.builder.Build())
+	return importer.Print(cmd, reg, w, p.builder.Build())
+}
+
+func parseStatement(reg *model.Registry, account *model.Account, r io.Reader) (*journal.Builder, error) {
+	p := parser{
+		registry: reg,
+		reader:   csv.NewReader(r),
+		builder:  journal.New(),
+		account:  account,
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.builder, nil
 }
 
 type parser struct {
@@ -112,6 +141,22 @@ func (p *parser) parse() error {
 	}
 }
 
+func (p *parser) stream(ctx context.Context, w io.Writer, isNew func(*model.Transaction) bool) error {
+	p.reader.TrimLeadingSpace = true
+	p.reader.FieldsPerRecord = 8
+
+	if err := p.readHeader(); err != nil {
+		return err
+	}
+	return importer.Stream(ctx, w, p.reader, func(r []string) ([]model.Directive, error) {
+		t, err := p.parseBooking(r)
+		if err != nil || !isNew(t) {
+			return nil, err
+		}
+		return []model.Directive{t}, nil
+	})
+}
+
 type column int
 
 const (
@@ -135,16 +180,27 @@ func (p *parser) readBooking() error {
 	if err != nil {
 		return err
 	}
+	t, err := p.parseBooking(r)
+	if err != nil {
+		return err
+	}
+	return p.builder.Add(t)
+}
+
+func (p *parser) parseBooking(r []string) (*model.Transaction, error) {
 	d, err := time.Parse("02.01.2006", r[transaktionsdatum])
 	if err != nil {
-		return fmt.Errorf("invalid date in record %v: %w", r, err)
+		return nil, fmt.Errorf("invalid date in record %v: %w", r, err)
+	}
+	c, err := p.registry.Commodities().Get(r[währung])
+	if err != nil {
+		return nil, fmt.Errorf("invalid commodity in record %v: %w", r, err)
 	}
-	c := p.registry.Commodities().MustGet(r[währung])
 	quantity, err := decimal.NewFromString(r[betrag])
 	if err != nil {
-		return fmt.Errorf("invalid amount in record %v: %w", r, err)
+		return nil, fmt.Errorf("invalid amount in record %v: %w", r, err)
 	}
-	p.builder.Add(transaction.Builder{
+	return transaction.Builder{
 		Date:        d,
 		Description: fmt.Sprintf("%s / %s / %s / %s", r[beschreibung], r[kartennummer], r[kategorie], r[debitKredit]),
 		Postings: posting.Builder{
@@ -153,6 +209,5 @@ func (p *parser) readBooking() error {
 			Commodity: c,
 			Quantity:  quantity,
 		}.Build(),
-	}.Build())
-	return nil
+	}.Build(), nil
 }
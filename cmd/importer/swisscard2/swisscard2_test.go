@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/sboehler/knut/cmd/cmdtest"
+	"github.com/sboehler/knut/cmd/importer"
 	"github.com/sebdah/goldie/v2"
 )
 
@@ -27,3 +28,28 @@ func TestGolden(t *testing.T) {
 
 	goldie.New(t).Assert(t, "example1", got)
 }
+
+func TestGoldenDedupe(t *testing.T) {
+	for name, args := range map[string][]string{
+		"example1-dedupe":        nil,
+		"example1-dedupe-stream": {"--stream"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmd := CreateCmd()
+			importer.SetupDedupe(cmd)
+
+			got := cmdtest.Run(t, cmd, append(args, "--account", "Liabilities:CreditCard", "--dedupe", "testdata/existing.knut", "testdata/example1.input")...)
+
+			goldie.New(t).Assert(t, name, got)
+		})
+	}
+}
+
+func TestGoldenSplit(t *testing.T) {
+	cmd := CreateCmd()
+	importer.SetupSplit(cmd)
+
+	got := cmdtest.Run(t, cmd, "--account", "Liabilities:CreditCard", "--split", "testdata/split.yaml", "testdata/example1.input")
+
+	goldie.New(t).Assert(t, "example1-split", got)
+}
@@ -75,10 +75,13 @@ func (r *runner) setupFlags(cmd *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		reg = registry.New()
+		reg *registry.Registry
 		f   *bufio.Reader
 		err error
 	)
+	if reg, err = flags.NewRegistry(cmd); err != nil {
+		return err
+	}
 	if f, err = flags.OpenFile(args[0]); err != nil {
 		return err
 	}
@@ -110,7 +113,7 @@ func (r *runner) run(cmd *cobra.Command, args []string) error {
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, p.builder.Build())
+	return importer.Print(cmd, reg, out, p.builder.Build())
 }
 
 type parser struct {
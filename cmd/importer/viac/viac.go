@@ -27,7 +27,6 @@ import (
 	"github.com/sboehler/knut/cmd/importer"
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/model"
-	"github.com/sboehler/knut/lib/model/registry"
 )
 
 // CreateCmd creates the command.
@@ -61,7 +60,10 @@ type runner struct {
 }
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
-	reg := registry.New()
+	reg, err := flags.NewRegistry(cmd)
+	if err != nil {
+		return err
+	}
 	account, err := r.account.Value(reg)
 	if err != nil {
 		return err
@@ -104,7 +106,7 @@ func (r *runner) run(cmd *cobra.Command, args []string) error {
 
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, j.Build())
+	return importer.Print(cmd, reg, out, j.Build())
 }
 
 type response struct {
@@ -75,10 +75,13 @@ func (r *runner) setupFlags(cmd *cobra.Command) {
 
 func (r *runner) run(cmd *cobra.Command, args []string) error {
 	var (
-		reg = registry.New()
+		reg *registry.Registry
 		f   *bufio.Reader
 		err error
 	)
+	if reg, err = flags.NewRegistry(cmd); err != nil {
+		return err
+	}
 	j := journal.New()
 	for _, path := range args {
 		if f, err = flags.OpenFile(path); err != nil {
@@ -104,7 +107,7 @@ func (r *runner) run(cmd *cobra.Command, args []string) error {
 	}
 	out := bufio.NewWriter(cmd.OutOrStdout())
 	defer out.Flush()
-	return journal.Print(out, j.Build())
+	return importer.Print(cmd, reg, out, j.Build())
 }
 
 type parser struct {
@@ -17,6 +17,8 @@ package cmd
 
 import (
 	"github.com/sboehler/knut/cmd/commands"
+	"github.com/sboehler/knut/cmd/flags"
+	"github.com/sboehler/knut/lib/model/account"
 
 	"github.com/spf13/cobra"
 )
@@ -28,18 +30,48 @@ func CreateCmd(version string) *cobra.Command {
 		Short:   "knut is a plain text accounting tool",
 		Long:    `knut is a plain text accounting tool for tracking personal finances and investments.`,
 		Version: version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return flags.ApplyConfig(cmd)
+		},
 	}
+	c.PersistentFlags().String("config", "", "the config file with flag defaults (default: .knut.yaml in the working or home directory)")
+	c.PersistentFlags().String("journal", "", "the journal, if none is given as argument (default: main.knut in the working directory or a parent)")
+	var errorFormat flags.ErrorFormatFlag
+	c.PersistentFlags().Var(&errorFormat, "error-format", "print errors as text or json")
+	c.PersistentFlags().Bool("dedupe-includes", false, "include files only once, even if they are included several times")
+	c.PersistentFlags().Int("parallelism", 0, "the maximum number of files processed concurrently (0 = number of CPUs)")
+	c.PersistentFlags().Bool("sequential", false, "read and process the journal on a single goroutine, in a fixed order")
+	c.PersistentFlags().Bool("last-wins", false, "use the last of conflicting prices on the same day, with a warning, instead of failing")
+	c.PersistentFlags().String("equity-account", account.DefaultSpecialAccounts.Equity, "the account for opening balances and closings")
+	c.PersistentFlags().String("tbd-account", account.DefaultSpecialAccounts.TBD, "the account for bookings whose account is yet to be determined")
+	c.PersistentFlags().String("valuation-account", account.DefaultSpecialAccounts.Valuation, "the parent account for valuation gains and losses")
+	c.PersistentFlags().String("rounding-account", "", "the account for the residuals of rounding values with --precision (default: the valuation account)")
+	c.PersistentFlags().StringToInt("precision", nil, "round values in the commodity to a number of decimal places, for example CHF=2")
+	c.PersistentFlags().StringToString("symbol", nil, "display the commodity with a symbol before the quantity, for example USD=$")
+	c.PersistentFlags().StringToString("symbol-suffix", nil, "display the commodity with a symbol after the quantity, for example EUR=€")
 	c.AddCommand(commands.CreateBalanceCommand())
 	c.AddCommand(commands.CreateCheckCommand())
 	c.AddCommand(commands.CreateCompletionCommand(c))
+	c.AddCommand(commands.CreateDedupeCommand())
+	c.AddCommand(commands.CreateDiffCommand())
+	c.AddCommand(commands.CreateExpensesHistogramCommand())
 	c.AddCommand(commands.CreateFormatCommand())
 	c.AddCommand(commands.CreateImportCommand())
 	c.AddCommand(commands.CreateInferCmd())
 	c.AddCommand(commands.CreatePortfolioCommand())
+	c.AddCommand(commands.CreatePricesCommand())
+	c.AddCommand(commands.CreatePriceGapsCommand())
 	c.AddCommand(commands.CreateFetchCommand())
+	c.AddCommand(commands.CreateReconcileCommand())
+	c.AddCommand(commands.CreateRenameAccountCommand())
+	c.AddCommand(commands.CreateRunwayCommand())
 	c.AddCommand(commands.CreateRegisterCmd())
+	c.AddCommand(commands.CreateServeCommand())
+	c.AddCommand(commands.CreateSplitCmd())
+	c.AddCommand(commands.CreateStatsCommand())
 	c.AddCommand(commands.CreateTranscodeCommand())
 	c.AddCommand(commands.CreatePrintCommand())
+	c.AddCommand(commands.CreateValidatePricesCommand())
 
 	return c
 }
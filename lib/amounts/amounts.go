@@ -21,6 +21,7 @@ type Key struct {
 	Commodity      *model.Commodity
 	Valuation      *model.Commodity
 	Description    string
+	Tag            model.Tag
 }
 
 func DateKey(date time.Time) Key {
@@ -102,6 +103,18 @@ func (am Amounts) CommoditiesSorted() []*model.Commodity {
 	return dict.SortedKeys(commodities, commodity.Compare)
 }
 
+func (am Amounts) Tags() set.Set[model.Tag] {
+	res := set.New[model.Tag]()
+	for k := range am {
+		res.Add(k.Tag)
+	}
+	return res
+}
+
+func (am Amounts) TagsSorted() []model.Tag {
+	return dict.SortedKeys(am.Tags(), compare.Ordered[model.Tag])
+}
+
 func (am Amounts) Dates() set.Set[time.Time] {
 	res := set.New[time.Time]()
 	for k := range am {
@@ -158,6 +171,7 @@ type KeyMapper struct {
 	Account, Other       mapper.Mapper[*model.Account]
 	Commodity, Valuation mapper.Mapper[*model.Commodity]
 	Description          mapper.Mapper[string]
+	Tag                  mapper.Mapper[model.Tag]
 }
 
 func (km KeyMapper) Build() mapper.Mapper[Key] {
@@ -181,6 +195,9 @@ func (km KeyMapper) Build() mapper.Mapper[Key] {
 		if km.Description != nil {
 			res.Description = km.Description(k.Description)
 		}
+		if km.Tag != nil {
+			res.Tag = km.Tag(k.Tag)
+		}
 		return res
 	}
 }
@@ -218,3 +235,21 @@ func OtherAccountMatches(regexes []*regexp.Regexp) predicate.Predicate[Key] {
 		return pred(k.Other)
 	}
 }
+
+// AccountExcludes is true for keys whose account matches none of the
+// regexes.
+func AccountExcludes(regexes []*regexp.Regexp) predicate.Predicate[Key] {
+	if len(regexes) == 0 {
+		return predicate.True[Key]
+	}
+	return predicate.Not(AccountMatches(regexes))
+}
+
+// CommodityExcludes is true for keys whose commodity matches none of the
+// regexes.
+func CommodityExcludes(regexes []*regexp.Regexp) predicate.Predicate[Key] {
+	if len(regexes) == 0 {
+		return predicate.True[Key]
+	}
+	return predicate.Not(CommodityMatches(regexes))
+}
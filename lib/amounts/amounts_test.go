@@ -0,0 +1,94 @@
+package amounts
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sboehler/knut/lib/common/mapper"
+	"github.com/sboehler/knut/lib/common/predicate"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/tag"
+	"github.com/shopspring/decimal"
+)
+
+func TestExcludes(t *testing.T) {
+	reg := registry.New()
+	chf, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD")
+	bank, illiquid := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Assets:Illiquid:House")
+	pred := predicate.And(
+		AccountMatches([]*regexp.Regexp{regexp.MustCompile("^Assets")}),
+		AccountExcludes([]*regexp.Regexp{regexp.MustCompile("^Assets:Illiquid")}),
+		CommodityExcludes([]*regexp.Regexp{regexp.MustCompile("USD")}),
+	)
+	for _, test := range []struct {
+		key  Key
+		want bool
+	}{
+		{AccountCommodityKey(bank, chf), true},
+		{AccountCommodityKey(bank, usd), false},
+		{AccountCommodityKey(illiquid, chf), false},
+		{AccountCommodityKey(reg.Accounts().MustGet("Expenses:Food"), chf), false},
+	} {
+		if got := pred(test.key); got != test.want {
+			t.Errorf("predicate(%s %s) = %t, want %t", test.key.Account, test.key.Commodity.Name(), got, test.want)
+		}
+	}
+	if !AccountExcludes(nil)(AccountCommodityKey(bank, chf)) || !CommodityExcludes(nil)(AccountCommodityKey(bank, chf)) {
+		t.Errorf("empty exclusions excluded a key")
+	}
+}
+
+func TestKeyMapperTag(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	food := reg.Accounts().MustGet("Expenses:Food")
+	am := make(Amounts)
+	for _, e := range []struct {
+		tag   model.Tag
+		value int64
+	}{
+		{"rome", 10},
+		{"paris", 20},
+		{"rome", 5},
+		{tag.Untagged, 7},
+	} {
+		am.Add(Key{Account: food, Commodity: chf, Tag: e.tag}, decimal.NewFromInt(e.value))
+	}
+	for _, test := range []struct {
+		desc string
+		tag  mapper.Mapper[model.Tag]
+		want map[model.Tag]int64
+	}{
+		{
+			desc: "by tag",
+			tag:  mapper.Identity[model.Tag],
+			want: map[model.Tag]int64{"paris": 20, "rome": 15, tag.Untagged: 7},
+		},
+		{
+			desc: "without tag",
+			want: map[model.Tag]int64{"": 42},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := am.SumBy(nil, KeyMapper{
+				Account:   mapper.Identity[*model.Account],
+				Commodity: mapper.Identity[*model.Commodity],
+				Tag:       test.tag,
+			}.Build())
+
+			want := make(Amounts)
+			for tg, v := range test.want {
+				want[Key{Account: food, Commodity: chf, Tag: tg}] = decimal.NewFromInt(v)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d keys, want %d", len(got), len(want))
+			}
+			for k, v := range want {
+				if !got[k].Equal(v) {
+					t.Errorf("got %s for tag %q, want %s", got[k], k.Tag, v)
+				}
+			}
+		})
+	}
+}
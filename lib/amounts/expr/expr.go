@@ -0,0 +1,302 @@
+// Package expr implements a small expression language for selecting
+// postings, for example:
+//
+//	account =~ 'Expenses' and commodity = 'USD' and amount > 100
+//
+// Comparisons are combined with `and`, `or` and `not`, and can be grouped
+// with parentheses. The fields account, other, commodity and description
+// support `=`, `!=`, `=~` (matches regex) and `!~`. The fields date and
+// amount support `=`, `!=`, `<`, `<=`, `>` and `>=`.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/predicate"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/shopspring/decimal"
+)
+
+// Posting is the input of an expression: the key of a posting and its
+// amount.
+type Posting struct {
+	amounts.Key
+	Amount decimal.Decimal
+}
+
+// Parse parses an expression into a predicate.
+func Parse(s string) (predicate.Predicate[Posting], error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.current(); t.kind != eof {
+		return nil, p.errorf("unexpected %s", t)
+	}
+	return pred, nil
+}
+
+type kind int
+
+const (
+	eof kind = iota
+	ident
+	str
+	literal
+	operator
+	lparen
+	rparen
+)
+
+type token struct {
+	kind kind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == eof {
+		return "end of input"
+	}
+	return fmt.Sprintf("`%s`", t.text)
+}
+
+var operators = []string{"=~", "!~", "!=", "<=", ">=", "=", "<", ">"}
+
+func lex(s string) ([]token, error) {
+	var res []token
+	for i := 0; i < len(s); {
+		ch := rune(s[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(':
+			res = append(res, token{lparen, "(", i})
+			i++
+		case ch == ')':
+			res = append(res, token{rparen, ")", i})
+			i++
+		case ch == '\'' || ch == '"':
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				return nil, fmt.Errorf("position %d: unterminated string", i)
+			}
+			res = append(res, token{str, s[i+1 : i+1+end], i})
+			i += end + 2
+		case unicode.IsLetter(ch):
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			res = append(res, token{ident, s[i:j], i})
+			i = j
+		case unicode.IsDigit(ch) || ch == '-' || ch == '.':
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '-' || s[j] == '.') {
+				j++
+			}
+			res = append(res, token{literal, s[i:j], i})
+			i = j
+		default:
+			var op string
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("position %d: unexpected character `%c`", i, ch)
+			}
+			res = append(res, token{operator, op, i})
+			i += len(op)
+		}
+	}
+	return append(res, token{eof, "", len(s)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != eof {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("position %d: %s", p.current().pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) keyword(kw string) bool {
+	t := p.current()
+	if t.kind == ident && strings.EqualFold(t.text, kw) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (predicate.Predicate[Posting], error) {
+	pred, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	preds := []predicate.Predicate[Posting]{pred}
+	for p.keyword("or") {
+		pred, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+	if len(preds) == 1 {
+		return preds[0], nil
+	}
+	return predicate.Or(preds...), nil
+}
+
+func (p *parser) parseAnd() (predicate.Predicate[Posting], error) {
+	pred, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	preds := []predicate.Predicate[Posting]{pred}
+	for p.keyword("and") {
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+	if len(preds) == 1 {
+		return preds[0], nil
+	}
+	return predicate.And(preds...), nil
+}
+
+func (p *parser) parseUnary() (predicate.Predicate[Posting], error) {
+	if p.keyword("not") {
+		pred, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return predicate.Not(pred), nil
+	}
+	if p.current().kind == lparen {
+		p.advance()
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.current().kind != rparen {
+			return nil, p.errorf("unexpected %s, want `)`", p.current())
+		}
+		p.advance()
+		return pred, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (predicate.Predicate[Posting], error) {
+	if p.current().kind != ident {
+		return nil, p.errorf("unexpected %s, want a field", p.current())
+	}
+	field := p.advance()
+	if p.current().kind != operator {
+		return nil, p.errorf("unexpected %s, want an operator", p.current())
+	}
+	op := p.advance()
+	if k := p.current().kind; k != str && k != literal {
+		return nil, p.errorf("unexpected %s, want a value", p.current())
+	}
+	value := p.advance()
+	switch strings.ToLower(field.text) {
+	case "account":
+		return compareString(op, value, func(pst Posting) string { return name(pst.Account) })
+	case "other":
+		return compareString(op, value, func(pst Posting) string { return name(pst.Other) })
+	case "commodity":
+		return compareString(op, value, func(pst Posting) string {
+			if pst.Commodity == nil {
+				return ""
+			}
+			return pst.Commodity.Name()
+		})
+	case "description":
+		return compareString(op, value, func(pst Posting) string { return pst.Description })
+	case "date":
+		d, err := time.Parse("2006-01-02", value.text)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: invalid date %s", value.pos, value)
+		}
+		return compareOrdered(op, func(pst Posting) int { return pst.Date.Compare(d) })
+	case "amount":
+		a, err := decimal.NewFromString(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: invalid number %s", value.pos, value)
+		}
+		return compareOrdered(op, func(pst Posting) int { return pst.Amount.Cmp(a) })
+	}
+	return nil, fmt.Errorf("position %d: unknown field %s", field.pos, field)
+}
+
+func compareString(op, value token, f func(Posting) string) (predicate.Predicate[Posting], error) {
+	switch op.text {
+	case "=":
+		return func(pst Posting) bool { return f(pst) == value.text }, nil
+	case "!=":
+		return func(pst Posting) bool { return f(pst) != value.text }, nil
+	case "=~", "!~":
+		rx, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %w", value.pos, err)
+		}
+		neg := op.text == "!~"
+		return func(pst Posting) bool { return rx.MatchString(f(pst)) != neg }, nil
+	}
+	return nil, fmt.Errorf("position %d: operator %s is not supported for strings", op.pos, op)
+}
+
+func compareOrdered(op token, cmp func(Posting) int) (predicate.Predicate[Posting], error) {
+	switch op.text {
+	case "=":
+		return func(pst Posting) bool { return cmp(pst) == 0 }, nil
+	case "!=":
+		return func(pst Posting) bool { return cmp(pst) != 0 }, nil
+	case "<":
+		return func(pst Posting) bool { return cmp(pst) < 0 }, nil
+	case "<=":
+		return func(pst Posting) bool { return cmp(pst) <= 0 }, nil
+	case ">":
+		return func(pst Posting) bool { return cmp(pst) > 0 }, nil
+	case ">=":
+		return func(pst Posting) bool { return cmp(pst) >= 0 }, nil
+	}
+	return nil, fmt.Errorf("position %d: operator %s is not supported for dates and amounts", op.pos, op)
+}
+
+func name(a *model.Account) string {
+	if a == nil {
+		return ""
+	}
+	return a.Name()
+}
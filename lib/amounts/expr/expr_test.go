@@ -0,0 +1,83 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
+)
+
+func TestParse(t *testing.T) {
+	reg := registry.New()
+	posting := Posting{
+		Key: amounts.Key{
+			Date:        date.Date(2023, 5, 1),
+			Account:     reg.Accounts().MustGet("Expenses:Groceries"),
+			Other:       reg.Accounts().MustGet("Assets:Bank"),
+			Commodity:   reg.Commodities().MustGet("USD"),
+			Description: "Weekly shopping",
+		},
+		Amount: decimal.NewFromInt(120),
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"account =~ 'Expenses'", true},
+		{"account !~ 'Expenses'", false},
+		{"account = 'Expenses:Groceries'", true},
+		{"other != \"Assets:Bank\"", false},
+		{"commodity = 'USD' and amount > 100", true},
+		{"commodity = 'USD' and amount > 120", false},
+		{"amount >= 120 and amount <= 120.0", true},
+		{"amount < -5 or description =~ '(?i)shopping'", true},
+		{"not (amount = 120)", false},
+		{"date >= 2023-05-01 and date < '2023-06-01'", true},
+		{"date != 2023-05-01", false},
+		{"ACCOUNT =~ 'Assets' OR NOT commodity = 'CHF' AND amount > 1000", false},
+		{"(account =~ 'Assets' or commodity = 'USD') and amount > 100", true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			pred, err := Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned unexpected error: %v", test.expr, err)
+			}
+			if got := pred(posting); got != test.want {
+				t.Errorf("Parse(%q)() = %t, want %t", test.expr, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"account", "position 7: unexpected end of input, want an operator"},
+		{"account = ", "position 10: unexpected end of input, want a value"},
+		{"account < 'A'", "position 8: operator `<` is not supported for strings"},
+		{"amount =~ 1", "position 7: operator `=~` is not supported for dates and amounts"},
+		{"amount > abc", "position 9: unexpected `abc`, want a value"},
+		{"foo = 'bar'", "position 0: unknown field `foo`"},
+		{"date > 2023-13-01", "position 7: invalid date `2023-13-01`"},
+		{"(amount > 1", "position 11: unexpected end of input, want `)`"},
+		{"amount > 1 amount", "position 11: unexpected `amount`"},
+		{"account = 'A", "position 10: unterminated string"},
+		{"account =~ '('", "position 11: error parsing regexp: missing closing ): `(`"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := Parse(test.expr)
+			if err == nil {
+				t.Fatalf("Parse(%q) returned no error, want %q", test.expr, test.want)
+			}
+			if err.Error() != test.want {
+				t.Errorf("Parse(%q) returned error %q, want %q", test.expr, err.Error(), test.want)
+			}
+		})
+	}
+}
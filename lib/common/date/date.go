@@ -187,6 +187,19 @@ func NewPartition(period Period, interval Interval, last int) Partition {
 		periods:  periods,
 	}
 }
+
+// First returns the partition restricted to its first n periods, or the
+// partition itself if n is not positive. The span of the partition ends
+// with the last remaining period.
+func (part Partition) First(n int) Partition {
+	if n <= 0 || n >= len(part.periods) {
+		return part
+	}
+	part.periods = part.periods[:n]
+	part.span.End = part.periods[n-1].End
+	return part
+}
+
 func (part Partition) Size() int {
 	return len(part.periods)
 }
@@ -194,3 +194,43 @@ func TestPartitionEndDates(t *testing.T) {
 		})
 	}
 }
+
+func TestPartitionFirst(t *testing.T) {
+	period := Period{Start: Date(2020, 1, 15), End: Date(2020, 5, 10)}
+	tests := []struct {
+		first    int
+		want     []time.Time
+		contains time.Time
+		wantIn   bool
+	}{
+		{
+			first:    2,
+			want:     []time.Time{Date(2020, 1, 31), Date(2020, 2, 29)},
+			contains: Date(2020, 3, 1),
+		},
+		{
+			first:    0,
+			want:     []time.Time{Date(2020, 1, 31), Date(2020, 2, 29), Date(2020, 3, 31), Date(2020, 4, 30), Date(2020, 5, 10)},
+			contains: Date(2020, 3, 1),
+			wantIn:   true,
+		},
+		{
+			first:    10,
+			want:     []time.Time{Date(2020, 1, 31), Date(2020, 2, 29), Date(2020, 3, 31), Date(2020, 4, 30), Date(2020, 5, 10)},
+			contains: Date(2020, 5, 10),
+			wantIn:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("first %d", test.first), func(t *testing.T) {
+			part := NewPartition(period, Monthly, 0).First(test.first)
+
+			if diff := cmp.Diff(test.want, part.EndDates()); diff != "" {
+				t.Errorf("EndDates() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+			if got := part.Contains(test.contains); got != test.wantIn {
+				t.Errorf("Contains(%s) = %t, want %t", test.contains.Format("2006-01-02"), got, test.wantIn)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package date
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// anchors maps the names of anchors to the interval of which they select
+// the start or the end, and a number of days by which they shift the
+// result.
+var anchors = map[string]struct {
+	interval Interval
+	end      bool
+	days     int
+}{
+	"today":            {interval: Daily},
+	"yesterday":        {interval: Daily, days: -1},
+	"tomorrow":         {interval: Daily, days: 1},
+	"start-of-week":    {interval: Weekly},
+	"sow":              {interval: Weekly},
+	"end-of-week":      {interval: Weekly, end: true},
+	"eow":              {interval: Weekly, end: true},
+	"start-of-month":   {interval: Monthly},
+	"som":              {interval: Monthly},
+	"end-of-month":     {interval: Monthly, end: true},
+	"eom":              {interval: Monthly, end: true},
+	"start-of-quarter": {interval: Quarterly},
+	"soq":              {interval: Quarterly},
+	"end-of-quarter":   {interval: Quarterly, end: true},
+	"eoq":              {interval: Quarterly, end: true},
+	"start-of-year":    {interval: Yearly},
+	"soy":              {interval: Yearly},
+	"end-of-year":      {interval: Yearly, end: true},
+	"eoy":              {interval: Yearly, end: true},
+}
+
+var offsetRegex = regexp.MustCompile(`^([+-])(\d+)([dwmqy])$`)
+
+// Parse parses an absolute or a relative date. The grammar is:
+//
+//	date     = absolute | anchor [offset] | offset
+//	absolute = YYYY-MM-DD
+//	anchor   = "today" | "yesterday" | "tomorrow"
+//	         | ("start-of-" | "end-of-") unit
+//	         | "sow" | "eow" | "som" | "eom" | "soq" | "eoq" | "soy" | "eoy"
+//	unit     = "week" | "month" | "quarter" | "year"
+//	offset   = ("+" | "-") digits ("d" | "w" | "m" | "q" | "y")
+//
+// The offset moves today by the given number of days, weeks, months,
+// quarters (3 months) or years. The anchor then selects the start or the
+// end of the week (Monday to Sunday), month, quarter or year which
+// contains the moved date. For example, "eom-1m" is the end of the
+// previous month, and "-30d" is the day 30 days ago. Adding months keeps
+// the day of the month, unless the target month is shorter, in which case
+// the result is its last day: "-1m" on 2020-03-31 is 2020-02-29.
+func Parse(s string, today time.Time) (time.Time, error) {
+	if len(s) > 0 && unicode.IsDigit(rune(s[0])) {
+		return time.Parse("2006-01-02", s)
+	}
+	var name string
+	for a := range anchors {
+		if strings.HasPrefix(s, a) && len(a) > len(name) {
+			name = a
+		}
+	}
+	offset := s[len(name):]
+	if name == "" && offset == "" {
+		return time.Time{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD or a relative date", s)
+	}
+	d := today
+	if offset != "" {
+		var err error
+		if d, err = applyOffset(d, offset); err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q: %w", s, err)
+		}
+	}
+	if name == "" {
+		return d, nil
+	}
+	a := anchors[name]
+	if a.end {
+		d = EndOf(d, a.interval)
+	} else {
+		d = StartOf(d, a.interval)
+	}
+	return d.AddDate(0, 0, a.days), nil
+}
+
+func applyOffset(d time.Time, offset string) (time.Time, error) {
+	m := offsetRegex.FindStringSubmatch(offset)
+	if m == nil {
+		return d, fmt.Errorf("invalid offset %q, want for example -3d, +1w, -2m, -1q or +1y", offset)
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return d, err
+	}
+	if m[1] == "-" {
+		n = -n
+	}
+	switch m[3] {
+	case "d":
+		return d.AddDate(0, 0, n), nil
+	case "w":
+		return d.AddDate(0, 0, 7*n), nil
+	case "m":
+		return addMonths(d, n), nil
+	case "q":
+		return addMonths(d, 3*n), nil
+	}
+	return addMonths(d, 12*n), nil
+}
+
+// addMonths adds n months to d, clamping the day to the last day of the
+// target month.
+func addMonths(d time.Time, n int) time.Time {
+	first := Date(d.Year(), d.Month()+time.Month(n), 1)
+	day := d.Day()
+	if last := EndOf(first, Monthly).Day(); day > last {
+		day = last
+	}
+	return Date(first.Year(), first.Month(), day)
+}
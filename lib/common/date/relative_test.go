@@ -0,0 +1,60 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		today   time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{input: "2020-02-29", want: Date(2020, 2, 29)},
+		{input: "2020-02-30", wantErr: true},
+		{input: "today", today: Date(2020, 5, 17), want: Date(2020, 5, 17)},
+		{input: "yesterday", today: Date(2020, 3, 1), want: Date(2020, 2, 29)},
+		{input: "tomorrow", today: Date(2020, 12, 31), want: Date(2021, 1, 1)},
+		{input: "-30d", today: Date(2020, 3, 15), want: Date(2020, 2, 14)},
+		{input: "+2w", today: Date(2020, 12, 25), want: Date(2021, 1, 8)},
+		{input: "today-3m", today: Date(2020, 5, 17), want: Date(2020, 2, 17)},
+		{input: "-1m", today: Date(2020, 3, 31), want: Date(2020, 2, 29)},
+		{input: "-1m", today: Date(2021, 3, 31), want: Date(2021, 2, 28)},
+		{input: "+1m", today: Date(2020, 1, 31), want: Date(2020, 2, 29)},
+		{input: "-1q", today: Date(2020, 5, 31), want: Date(2020, 2, 29)},
+		{input: "+1y", today: Date(2020, 2, 29), want: Date(2021, 2, 28)},
+		{input: "sow", today: Date(2020, 5, 17), want: Date(2020, 5, 11)},
+		{input: "end-of-week", today: Date(2020, 5, 11), want: Date(2020, 5, 17)},
+		{input: "som", today: Date(2020, 5, 17), want: Date(2020, 5, 1)},
+		{input: "eom", today: Date(2020, 2, 10), want: Date(2020, 2, 29)},
+		{input: "eom", today: Date(2020, 2, 29), want: Date(2020, 2, 29)},
+		{input: "eom-1m", today: Date(2020, 3, 31), want: Date(2020, 2, 29)},
+		{input: "end-of-month-1m", today: Date(2020, 5, 31), want: Date(2020, 4, 30)},
+		{input: "soq", today: Date(2020, 5, 17), want: Date(2020, 4, 1)},
+		{input: "eoq-1q", today: Date(2020, 5, 17), want: Date(2020, 3, 31)},
+		{input: "start-of-year", today: Date(2020, 5, 17), want: Date(2020, 1, 1)},
+		{input: "eoy+1y", today: Date(2020, 5, 17), want: Date(2021, 12, 31)},
+		{input: "", wantErr: true},
+		{input: "now", wantErr: true},
+		{input: "-3x", wantErr: true},
+		{input: "eom+", wantErr: true},
+		{input: "eom-1m-1d", wantErr: true},
+		{input: "start-of-decade", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := Parse(test.input, test.today)
+
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("Parse(%q) returned error %v, want error: %t", test.input, err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Parse(%q) returned unexpected diff (-want/+got):\n%s\n", test.input, diff)
+			}
+		})
+	}
+}
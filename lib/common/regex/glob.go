@@ -0,0 +1,55 @@
+package regex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FromGlob translates a glob pattern over colon-separated segments, such
+// as an account name, into a regex which matches the whole name. Within a
+// segment, `*` matches any characters and `?` matches a single character.
+// A segment consisting of `**` matches any number of segments, including
+// none. For example, `Assets:*:Cash` matches `Assets:Bank:Cash`, and
+// `Expenses:**` matches `Expenses` and all its subaccounts.
+func FromGlob(glob string) (*regexp.Regexp, error) {
+	if glob == "" {
+		return nil, fmt.Errorf("empty glob pattern")
+	}
+	if glob == "**" {
+		return regexp.Compile("^.*$")
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	// sep is true if the next segment must be preceded by a colon.
+	var sep bool
+	for _, seg := range strings.Split(glob, ":") {
+		switch {
+		case seg == "**" && sep:
+			b.WriteString(`(?::[^:]+)*`)
+		case seg == "**":
+			b.WriteString(`(?:[^:]+:)*`)
+		case seg == "":
+			return nil, fmt.Errorf("glob pattern %q has an empty segment", glob)
+		case strings.Contains(seg, "**"):
+			return nil, fmt.Errorf("glob pattern %q: `**` must be a segment of its own", glob)
+		default:
+			if sep {
+				b.WriteString(":")
+			}
+			for _, ch := range seg {
+				switch ch {
+				case '*':
+					b.WriteString(`[^:]*`)
+				case '?':
+					b.WriteString(`[^:]`)
+				default:
+					b.WriteString(regexp.QuoteMeta(string(ch)))
+				}
+			}
+			sep = true
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,95 @@
+package regex
+
+import "testing"
+
+func TestFromGlob(t *testing.T) {
+	for _, test := range []struct {
+		glob    string
+		matches map[string]bool
+	}{
+		{
+			glob: "Assets:Bank",
+			matches: map[string]bool{
+				"Assets:Bank":         true,
+				"Assets:Bank:Savings": false,
+				"OtherAssets:Bank":    false,
+				"Assets:Banking":      false,
+			},
+		},
+		{
+			glob: "Assets:Bank:*",
+			matches: map[string]bool{
+				"Assets:Bank":             false,
+				"Assets:Bank:Savings":     true,
+				"Assets:Bank:Savings:USD": false,
+			},
+		},
+		{
+			glob: "Expenses:**",
+			matches: map[string]bool{
+				"Expenses":           true,
+				"Expenses:Food":      true,
+				"Expenses:Food:Rest": true,
+				"ExpensesX":          false,
+				"Income:Expenses":    false,
+			},
+		},
+		{
+			glob: "**:Cash",
+			matches: map[string]bool{
+				"Cash":             true,
+				"Assets:Cash":      true,
+				"Assets:Bank:Cash": true,
+				"Assets:PettyCash": false,
+			},
+		},
+		{
+			glob: "Assets:**:Cash",
+			matches: map[string]bool{
+				"Assets:Cash":      true,
+				"Assets:Bank:Cash": true,
+				"Assets:Bank":      false,
+			},
+		},
+		{
+			glob: "Assets:Bank?:*Acc*",
+			matches: map[string]bool{
+				"Assets:Bank1:MyAccount": true,
+				"Assets:Bank:MyAccount":  false,
+				"Assets:Bank1:Savings":   false,
+			},
+		},
+		{
+			glob: "Assets.(x)",
+			matches: map[string]bool{
+				"Assets.(x)": true,
+				"AssetsX(x)": false,
+			},
+		},
+		{
+			glob: "**",
+			matches: map[string]bool{
+				"Assets":      true,
+				"Assets:Bank": true,
+			},
+		},
+	} {
+		t.Run(test.glob, func(t *testing.T) {
+			rx, err := FromGlob(test.glob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range test.matches {
+				if got := rx.MatchString(name); got != want {
+					t.Errorf("FromGlob(%q).MatchString(%q) = %t, want %t", test.glob, name, got, want)
+				}
+			}
+		})
+	}
+
+	for _, glob := range []string{"", "Assets::Bank", "Assets:Bank**"} {
+		if _, err := FromGlob(glob); err == nil {
+			t.Errorf("FromGlob(%q) returned no error", glob)
+		}
+	}
+}
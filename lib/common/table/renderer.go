@@ -124,7 +124,7 @@ func (r *TextRenderer) renderCell(c cell, l int, w io.Writer) error {
 		return writeSpace(w, l-before-utf8.RuneCountInString(t.Content))
 
 	case numberCell:
-		s := r.numToString(t.n)
+		s := r.amountToString(t)
 		var err error
 		switch {
 		case t.n.LessThan(decimal.Zero):
@@ -179,7 +179,7 @@ func (r *TextRenderer) minLengthCell(c cell) int {
 		}
 		return utf8.RuneCountInString(t.Content)
 	case numberCell:
-		return utf8.RuneCountInString(r.numToString(t.n))
+		return utf8.RuneCountInString(r.amountToString(t))
 	case percentCell:
 		return utf8.RuneCountInString(fmt.Sprintf("%.2f%%", t.n))
 	}
@@ -208,6 +208,16 @@ func (r *TextRenderer) numToString(d decimal.Decimal) string {
 	return addThousandsSep(d.StringFixed(r.Round))
 }
 
+// amountToString formats the number of the cell with its prefix and
+// suffix. The sign of negative numbers precedes the prefix.
+func (r *TextRenderer) amountToString(c numberCell) string {
+	s := r.numToString(c.n)
+	if c.prefix != "" && strings.HasPrefix(s, "-") {
+		return "-" + c.prefix + s[1:] + c.suffix
+	}
+	return c.prefix + s + c.suffix
+}
+
 func addThousandsSep(e string) string {
 	index := strings.Index(e, ".")
 	if index < 0 {
@@ -69,11 +69,62 @@ func (t *Table) AddEmptyRow() {
 	}
 }
 
+// Transpose returns a new table whose rows are the columns of this table.
+// Separator and empty rows are dropped, and the first row of the new table
+// is followed by a separator. The first column usually holds indented row
+// labels, which become column headers: they are centered and their
+// indentation is removed.
+func (t *Table) Transpose() *Table {
+	var rows []*Row
+	for _, r := range t.rows {
+		if !r.isLayout() {
+			rows = append(rows, r)
+		}
+	}
+	groups := make([]int, len(rows))
+	for i := range groups {
+		groups[i] = 1
+	}
+	res := New(groups...)
+	res.AddSeparatorRow()
+	for i := 0; i < t.Width(); i++ {
+		row := res.AddRow()
+		for _, r := range rows {
+			if i >= len(r.cells) {
+				row.AddEmpty()
+				continue
+			}
+			c := r.cells[i]
+			if tc, ok := c.(textCell); ok && i == 0 {
+				tc.Indent, tc.Align = 0, Center
+				c = tc
+			}
+			row.addCell(c)
+		}
+		if i == 0 {
+			res.AddSeparatorRow()
+		}
+	}
+	res.AddSeparatorRow()
+	return res
+}
+
 // Row is a table row.
 type Row struct {
 	cells []cell
 }
 
+// isLayout returns whether the row consists of separators or empty cells
+// only.
+func (r *Row) isLayout() bool {
+	for _, c := range r.cells {
+		if _, ok := c.(emptyCell); !ok && !c.isSep() {
+			return false
+		}
+	}
+	return true
+}
+
 func (r *Row) addCell(c cell) {
 	r.cells = append(r.cells, c)
 }
@@ -96,7 +147,14 @@ func (r *Row) AddText(content string, align Alignment) *Row {
 
 // AddDecimal adds a number cell.
 func (r *Row) AddDecimal(n decimal.Decimal) *Row {
-	r.addCell(numberCell{n})
+	r.addCell(numberCell{n: n})
+	return r
+}
+
+// AddAmount adds a number cell which the text renderer surrounds with the
+// given prefix and suffix, for example a currency symbol.
+func (r *Row) AddAmount(n decimal.Decimal, prefix, suffix string) *Row {
+	r.addCell(numberCell{n: n, prefix: prefix, suffix: suffix})
 	return r
 }
 
@@ -149,9 +207,10 @@ func (t textCell) isSep() bool {
 	return false
 }
 
-// textCell is a cell containing text.
+// numberCell is a cell containing a number.
 type numberCell struct {
-	n decimal.Decimal
+	n              decimal.Decimal
+	prefix, suffix string
 }
 
 func (t numberCell) isSep() bool {
@@ -14,7 +14,12 @@
 
 package table
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
 
 func TestAddThousandsSep(t *testing.T) {
 	tests := []struct {
@@ -46,3 +51,55 @@ func TestAddThousandsSep(t *testing.T) {
 		})
 	}
 }
+
+func TestTranspose(t *testing.T) {
+	tbl := New(1, 2)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().AddText("Account", Center).AddText("Jan", Center).AddText("Feb", Center)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().AddIndented("Assets", 0).AddDecimal(decimal.NewFromInt(1)).AddDecimal(decimal.NewFromInt(2))
+	tbl.AddRow().AddIndented("Bank", 2).AddDecimal(decimal.NewFromInt(1)).AddDecimal(decimal.NewFromInt(2))
+	tbl.AddEmptyRow()
+	tbl.AddRow().AddIndented("Total", 0).AddDecimal(decimal.NewFromInt(1)).AddDecimal(decimal.NewFromInt(2))
+	tbl.AddSeparatorRow()
+	var buf strings.Builder
+
+	if err := new(TextRenderer).Render(tbl.Transpose(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"+---------+--------+------+-------+",
+		"| Account | Assets | Bank | Total |",
+		"+---------+--------+------+-------+",
+		"|   Jan   |      1 |    1 |     1 |",
+		"|   Feb   |      2 |    2 |     2 |",
+		"+---------+--------+------+-------+",
+		"",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("Transpose() rendered\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderAmount(t *testing.T) {
+	tbl := New(1, 1)
+	tbl.AddRow().AddText("USD", Left).AddAmount(decimal.RequireFromString("-1000.5"), "$", "")
+	tbl.AddRow().AddText("EUR", Left).AddAmount(decimal.RequireFromString("1000.5"), "", " €")
+	var buf strings.Builder
+
+	if err := (&TextRenderer{Round: 2}).Render(tbl, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"| USD | -$1,000.50 |",
+		"| EUR | 1,000.50 € |",
+		"",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("Render() rendered\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+// Package diagnostic converts errors into a serializable form, for
+// consumption by other tools.
+package diagnostic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sboehler/knut/lib/journal/check"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// Position is a position in a file. Line and column are 1-based.
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// Diagnostic is the serializable form of an error. The file and the
+// positions are empty if the error does not refer to a location.
+type Diagnostic struct {
+	File     string    `json:"file,omitempty"`
+	Start    *Position `json:"start,omitempty"`
+	End      *Position `json:"end,omitempty"`
+	Severity string    `json:"severity"`
+	Message  string    `json:"message"`
+
+	// Context holds the messages of enclosing errors, from the innermost
+	// to the outermost, for example "while parsing transaction".
+	Context []string `json:"context,omitempty"`
+}
+
+// FromError converts an error into a diagnostic.
+func FromError(err error) Diagnostic {
+	d := Diagnostic{Severity: "error", Message: err.Error()}
+	var syntaxErr syntax.Error
+	var checkErr check.Error
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The innermost error carries the most precise location.
+		var context []string
+		for {
+			var inner syntax.Error
+			if syntaxErr.Wrapped == nil || !errors.As(syntaxErr.Wrapped, &inner) {
+				break
+			}
+			context = append(context, syntaxErr.Message)
+			syntaxErr = inner
+		}
+		for i := len(context) - 1; i >= 0; i-- {
+			d.Context = append(d.Context, context[i])
+		}
+		d.Message = syntaxErr.Message
+		if syntaxErr.Wrapped != nil {
+			d.Message = fmt.Sprintf("%s: %v", syntaxErr.Message, syntaxErr.Wrapped)
+		}
+		d.setRange(syntaxErr.Range)
+	case errors.As(err, &checkErr):
+		d.Message = checkErr.Msg
+		if rng, ok := source(checkErr.Directive); ok {
+			d.setRange(rng)
+		}
+	}
+	return d
+}
+
+func (d *Diagnostic) setRange(rng syntax.Range) {
+	if rng.Text == "" {
+		return
+	}
+	end := rng.Location()
+	rng.End = rng.Start
+	start := rng.Location()
+	d.File = rng.Path
+	d.Start = &Position{Line: start.Line, Col: start.Col}
+	d.End = &Position{Line: end.Line, Col: end.Col}
+}
+
+// source returns the source range of a directive.
+func source(d model.Directive) (syntax.Range, bool) {
+	switch d := d.(type) {
+	case *model.Transaction:
+		if d.Src != nil {
+			return d.Src.Range, true
+		}
+	case *model.Open:
+		if d.Src != nil {
+			return d.Src.Range, true
+		}
+	case *model.Close:
+		if d.Src != nil {
+			return d.Src.Range, true
+		}
+	case *model.Assertion:
+		if d.Src != nil {
+			return d.Src.Range, true
+		}
+	case *model.Price:
+		if d.Src != nil {
+			return d.Src.Range, true
+		}
+	}
+	return syntax.Range{}, false
+}
+
+// Write writes the error to w, either as text or as a JSON object on a
+// single line, depending on the format. In JSON, an error which joins
+// several errors is written as one object per error.
+func Write(w io.Writer, format string, err error) error {
+	if format != "json" {
+		_, err := fmt.Fprintf(w, "%+v\n", err)
+		return err
+	}
+	enc := json.NewEncoder(w)
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if err := enc.Encode(FromError(e)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return enc.Encode(FromError(err))
+}
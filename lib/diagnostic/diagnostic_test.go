@@ -0,0 +1,51 @@
+package diagnostic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/syntax/parser"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		desc string
+		err  func() error
+		want Diagnostic
+	}{
+		{
+			desc: "syntax error",
+			err: func() error {
+				p := parser.New("2020-01-01 open A:B\n2020-01-02 foo\n", "journal.knut")
+				if err := p.Advance(); err != nil {
+					return err
+				}
+				_, err := p.ParseFile()
+				return err
+			},
+			want: Diagnostic{
+				File:     "journal.knut",
+				Start:    &Position{Line: 2, Col: 12},
+				End:      &Position{Line: 2, Col: 12},
+				Severity: "error",
+				Message:  "unexpected input, want one of {`opening`, `open`, `close`, `balance`, `price`, `apply`}",
+				Context:  []string{"while parsing directive", "while parsing file `journal.knut`"},
+			},
+		},
+		{
+			desc: "other error",
+			err:  func() error { return errors.New("boom") },
+			want: Diagnostic{Severity: "error", Message: "boom"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := FromError(test.err())
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("FromError() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
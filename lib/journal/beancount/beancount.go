@@ -29,8 +29,9 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-// Transcode transcodes the given journal to beancount.
-func Transcode(w io.Writer, j *journal.Journal, c *model.Commodity) error {
+// Transcode transcodes the given journal to beancount. The rounding
+// account is opened when it is first used, as the journal does not open it.
+func Transcode(w io.Writer, j *journal.Journal, c *model.Commodity, rounding *model.Account) error {
 	if _, err := fmt.Fprintf(w, `option "operating_currency" "%s"`, c.Name()); err != nil {
 		return err
 	}
@@ -41,6 +42,7 @@ func Transcode(w io.Writer, j *journal.Journal, c *model.Commodity) error {
 	openValAccounts := set.New[*model.Account]()
 	for _, day := range j.Days {
 		for _, open := range day.Openings {
+			openValAccounts.Add(open.Account)
 			if _, err := p.PrintDirective(open); err != nil {
 				return err
 			}
@@ -52,7 +54,8 @@ func Transcode(w io.Writer, j *journal.Journal, c *model.Commodity) error {
 
 		for _, trx := range day.Transactions {
 			for _, pst := range trx.Postings {
-				if strings.HasPrefix(pst.Account.Name(), "Equity:Valuation:") && !openValAccounts.Has(pst.Account) {
+				name := pst.Account.Name()
+				if (pst.Account == rounding || strings.HasPrefix(name, "Equity:Valuation:")) && !openValAccounts.Has(pst.Account) {
 					openValAccounts.Add(pst.Account)
 					if _, err := p.PrintDirective(&model.Open{Date: trx.Date, Account: pst.Account}); err != nil {
 						return err
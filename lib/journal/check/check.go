@@ -3,13 +3,15 @@ package check
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/sboehler/knut/lib/amounts"
-	"github.com/sboehler/knut/lib/common/set"
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/journal/printer"
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/assertion"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/syntax"
 	"golang.org/x/exp/slices"
 )
 
@@ -34,8 +36,25 @@ type Checker struct {
 	Write   bool
 	NoCheck bool
 
+	// AssertOn creates a single set of assertions with the balances as
+	// of the given date, if it is not zero.
+	AssertOn time.Time
+
+	// SkipZero omits zero balances from created assertions.
+	SkipZero bool
+
+	// IgnorePending excludes pending postings from balance assertions.
+	IgnorePending bool
+
+	// Valued skips assertions in the given commodity, if it is not nil.
+	// They assert values instead of quantities and are checked by
+	// ValueAssertions.
+	Valued *model.Commodity
+
 	quantities amounts.Amounts
-	accounts   set.Set[*model.Account]
+	accounts   map[*model.Account]*model.Open
+	closed     map[*model.Account]*model.Close
+	restricted map[*model.Account]*model.Open
 	assertions []*model.Assertion
 }
 
@@ -44,17 +63,48 @@ func (ch *Checker) Assertions() []*model.Assertion {
 }
 
 func (ch *Checker) open(o *model.Open) error {
-	if ch.accounts.Has(o.Account) {
-		return Error{Directive: o, Msg: "account is already open"}
+	if prev, ok := ch.accounts[o.Account]; ok {
+		return Error{Directive: o, Msg: fmt.Sprintf("account %s opened at %s is already open since %s at %s", o.Account, openRange(o).Position(), prev.Date.Format("2006-01-02"), openRange(prev).Position())}
+	}
+	ch.accounts[o.Account] = o
+	delete(ch.closed, o.Account)
+	if len(o.Commodities) > 0 {
+		ch.restricted[o.Account] = o
+	} else {
+		delete(ch.restricted, o.Account)
 	}
-	ch.accounts.Add(o.Account)
 	return nil
 }
 
 func (ch *Checker) posting(t *model.Transaction, p *model.Posting) error {
-	if !ch.accounts.Has(p.Account) {
+	if _, ok := ch.accounts[p.Account]; !ok {
+		if c, ok := ch.closed[p.Account]; ok {
+			var trxRng syntax.Range
+			if t.Src != nil {
+				trxRng = t.Src.Range
+			}
+			return Error{Directive: t, Msg: fmt.Sprintf("transaction at %s books into account %s, which was closed on %s at %s", trxRng.Position(), p.Account, c.Date.Format("2006-01-02"), closeRange(c).Position())}
+		}
 		return Error{Directive: t, Msg: fmt.Sprintf("account %s is not open", p.Account)}
 	}
+	if o, ok := ch.restricted[p.Account]; ok && !slices.Contains(o.Commodities, p.Commodity) {
+		var trxRng syntax.Range
+		if t.Src != nil {
+			trxRng = t.Src.Range
+		}
+		var names []string
+		for _, c := range o.Commodities {
+			names = append(names, c.Name())
+		}
+		return Error{Directive: t, Msg: fmt.Sprintf("transaction at %s books %s into account %s, which is restricted to %s at %s", trxRng.Position(), p.Commodity.Name(), p.Account, strings.Join(names, ", "), openRange(o).Position())}
+	}
+	if ch.IgnorePending && p.State == posting.Pending {
+		return nil
+	}
+	// Virtual postings are not part of the asserted balances.
+	if p.Virtual {
+		return nil
+	}
 	if p.Account.IsAL() {
 		ch.quantities.Add(amounts.AccountCommodityKey(p.Account, p.Commodity), p.Quantity)
 	}
@@ -62,11 +112,11 @@ func (ch *Checker) posting(t *model.Transaction, p *model.Posting) error {
 }
 
 func (ch *Checker) balance(a *model.Assertion, bal *model.Balance) error {
-	if !ch.accounts.Has(bal.Account) {
+	if _, ok := ch.accounts[bal.Account]; !ok {
 		return Error{Directive: a, Msg: "account is not open"}
 	}
 	position := amounts.AccountCommodityKey(bal.Account, bal.Commodity)
-	if ch.NoCheck {
+	if ch.NoCheck || bal.Commodity == ch.Valued {
 		return nil
 	}
 	if qty, ok := ch.quantities[position]; !ok || !qty.Equal(bal.Quantity) {
@@ -85,28 +135,57 @@ func (ch *Checker) close(c *model.Close) error {
 		}
 		delete(ch.quantities, pos)
 	}
-	if !ch.accounts.Has(c.Account) {
-		return Error{Directive: c, Msg: "account is not open"}
+	if _, ok := ch.accounts[c.Account]; !ok {
+		if prev, ok := ch.closed[c.Account]; ok {
+			return Error{Directive: c, Msg: fmt.Sprintf("account %s closed at %s is already closed since %s at %s", c.Account, closeRange(c).Position(), prev.Date.Format("2006-01-02"), closeRange(prev).Position())}
+		}
+		return Error{Directive: c, Msg: fmt.Sprintf("account %s closed at %s has not been opened", c.Account, closeRange(c).Position())}
 	}
-	ch.accounts.Remove(c.Account)
+	delete(ch.accounts, c.Account)
+	ch.closed[c.Account] = c
 	return nil
 }
 
+func openRange(o *model.Open) syntax.Range {
+	if o.Src == nil {
+		return syntax.Range{}
+	}
+	return o.Src.Range
+}
+
+func closeRange(c *model.Close) syntax.Range {
+	if c.Src == nil {
+		return syntax.Range{}
+	}
+	return c.Src.Range
+}
+
 func (ch *Checker) dayEnd(d *journal.Day) error {
-	if len(ch.quantities) == 0 {
-		return nil
+	date := d.Date
+	if !ch.AssertOn.IsZero() {
+		if d.Date.After(ch.AssertOn) {
+			return nil
+		}
+		date = ch.AssertOn
+		ch.assertions = nil
 	}
 	bal := make([]model.Balance, 0, len(ch.quantities))
 	for pos, qty := range ch.quantities {
+		if ch.SkipZero && qty.IsZero() {
+			continue
+		}
 		bal = append(bal, model.Balance{
 			Account:   pos.Account,
 			Quantity:  qty,
 			Commodity: pos.Commodity,
 		})
 	}
+	if len(bal) == 0 {
+		return nil
+	}
 	slices.SortFunc(bal, assertion.CompareBalance)
 	ch.assertions = append(ch.assertions, &model.Assertion{
-		Date:     d.Date,
+		Date:     date,
 		Balances: bal,
 	})
 	return nil
@@ -114,11 +193,13 @@ func (ch *Checker) dayEnd(d *journal.Day) error {
 
 func (ch *Checker) Check() *journal.Processor {
 	ch.quantities = make(amounts.Amounts)
-	ch.accounts = set.New[*model.Account]()
+	ch.accounts = make(map[*model.Account]*model.Open)
+	ch.closed = make(map[*model.Account]*model.Close)
+	ch.restricted = make(map[*model.Account]*model.Open)
 	ch.assertions = nil
 
 	var dayEnd func(*journal.Day) error
-	if ch.Write {
+	if ch.Write || !ch.AssertOn.IsZero() {
 		dayEnd = ch.dayEnd
 	}
 
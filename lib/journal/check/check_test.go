@@ -0,0 +1,273 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/syntax/parser"
+)
+
+func TestCheckOpenClose(t *testing.T) {
+	tests := []struct {
+		desc string
+		text []string
+		want string
+	}{
+		{
+			desc: "reopen after close",
+			text: []string{
+				"2020-01-01 open Assets:Bank",
+				"2020-02-01 close Assets:Bank",
+				"2020-03-01 open Assets:Bank",
+			},
+		},
+		{
+			desc: "books into closed account",
+			text: []string{
+				"2020-01-01 open Assets:Bank",
+				"2020-01-01 open Equity:Equity",
+				"2020-02-01 close Assets:Bank",
+				"2020-03-01 \"Deposit\"",
+				"Equity:Equity Assets:Bank 10 CHF",
+			},
+			want: "transaction at journal.knut:4:1 books into account Assets:Bank, which was closed on 2020-02-01 at journal.knut:3:1",
+		},
+		{
+			desc: "books into reopened account",
+			text: []string{
+				"2020-01-01 open Assets:Bank",
+				"2020-01-01 open Equity:Equity",
+				"2020-02-01 close Assets:Bank",
+				"2020-03-01 open Assets:Bank",
+				"2020-03-02 \"Deposit\"",
+				"Equity:Equity Assets:Bank 10 CHF",
+			},
+		},
+		{
+			desc: "books into unopened account",
+			text: []string{
+				"2020-01-01 open Equity:Equity",
+				"2020-03-01 \"Deposit\"",
+				"Equity:Equity Assets:Bank 10 CHF",
+			},
+			want: "account Assets:Bank is not open",
+		},
+		{
+			desc: "virtual booking",
+			text: []string{
+				"2020-01-01 open Assets:Bank",
+				"2020-01-01 open Equity:Equity",
+				"2020-01-02 \"Deposit\"",
+				"Equity:Equity Assets:Bank 100 USD",
+				"",
+				"2020-01-03 \"Envelope\"",
+				"(Equity:Equity) (Assets:Bank) 40 USD",
+				"",
+				"2020-01-04 balance Assets:Bank 100 USD",
+			},
+		},
+		{
+			desc: "duplicate open",
+			text: []string{
+				"2020-01-01 open Assets:Bank",
+				"2020-03-01 open Assets:Bank",
+			},
+			want: "account Assets:Bank opened at journal.knut:2:1 is already open since 2020-01-01 at journal.knut:1:1",
+		},
+		{
+			desc: "close without open",
+			text: []string{
+				"2020-01-01 close Assets:Bank",
+			},
+			want: "account Assets:Bank closed at journal.knut:1:1 has not been opened",
+		},
+		{
+			desc: "duplicate close",
+			text: []string{
+				"2020-01-01 open Assets:Bank",
+				"2020-02-01 close Assets:Bank",
+				"2020-03-01 close Assets:Bank",
+			},
+			want: "account Assets:Bank closed at journal.knut:3:1 is already closed since 2020-02-01 at journal.knut:2:1",
+		},
+		{
+			desc: "books allowed commodity",
+			text: []string{
+				"2020-01-01 open Assets:Bank CHF USD",
+				"2020-01-01 open Equity:Equity",
+				"2020-03-01 \"Deposit\"",
+				"Equity:Equity Assets:Bank 10 USD",
+			},
+		},
+		{
+			desc: "books restricted commodity",
+			text: []string{
+				"2020-01-01 open Assets:Bank CHF USD",
+				"2020-01-01 open Equity:Equity",
+				"2020-03-01 \"Deposit\"",
+				"Equity:Equity Assets:Bank 10 EUR",
+			},
+			want: "transaction at journal.knut:3:1 books EUR into account Assets:Bank, which is restricted to CHF, USD at journal.knut:1:1",
+		},
+		{
+			desc: "restricts only the opened account",
+			text: []string{
+				"2020-01-01 open Assets:Bank CHF",
+				"2020-01-01 open Assets:Bank:Savings",
+				"2020-01-01 open Equity:Equity",
+				"2020-03-01 \"Deposit\"",
+				"Equity:Equity Assets:Bank:Savings 10 EUR",
+			},
+		},
+		{
+			desc: "reopen without restriction",
+			text: []string{
+				"2020-01-01 open Assets:Bank CHF",
+				"2020-01-01 open Equity:Equity",
+				"2020-02-01 close Assets:Bank",
+				"2020-02-02 open Assets:Bank",
+				"2020-03-01 \"Deposit\"",
+				"Equity:Equity Assets:Bank 10 EUR",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			j := parse(t, test.text)
+
+			err := j.Build().Process(Check())
+
+			var got string
+			if e := (Error{}); errors.As(err, &e) {
+				got = e.Msg
+			} else if err != nil {
+				t.Fatalf("Process() returned unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Process() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestCheckerAssertions(t *testing.T) {
+	text := []string{
+		"2020-01-01 open Assets:Bank",
+		"2020-01-01 open Assets:Cash",
+		"2020-01-01 open Equity:Equity",
+		"2020-01-01 \"Deposit\"",
+		"Equity:Equity Assets:Bank 10 CHF",
+		"",
+		"2020-01-02 \"Withdrawal\"",
+		"Assets:Bank Assets:Cash 10 CHF",
+		"",
+		"2020-01-03 \"Deposit\"",
+		"Equity:Equity Assets:Bank 5 CHF",
+	}
+	tests := []struct {
+		desc    string
+		checker Checker
+		want    []string
+	}{
+		{
+			desc:    "write",
+			checker: Checker{Write: true},
+			want: []string{
+				"2020-01-01 Assets:Bank 10 CHF",
+				"2020-01-02 Assets:Bank 0 CHF",
+				"2020-01-02 Assets:Cash 10 CHF",
+				"2020-01-03 Assets:Bank 5 CHF",
+				"2020-01-03 Assets:Cash 10 CHF",
+			},
+		},
+		{
+			desc:    "skip zero",
+			checker: Checker{Write: true, SkipZero: true},
+			want: []string{
+				"2020-01-01 Assets:Bank 10 CHF",
+				"2020-01-02 Assets:Cash 10 CHF",
+				"2020-01-03 Assets:Bank 5 CHF",
+				"2020-01-03 Assets:Cash 10 CHF",
+			},
+		},
+		{
+			desc:    "assert on",
+			checker: Checker{AssertOn: date.Date(2020, 1, 2)},
+			want: []string{
+				"2020-01-02 Assets:Bank 0 CHF",
+				"2020-01-02 Assets:Cash 10 CHF",
+			},
+		},
+		{
+			desc:    "assert on with skip zero",
+			checker: Checker{AssertOn: date.Date(2020, 1, 2), SkipZero: true},
+			want: []string{
+				"2020-01-02 Assets:Cash 10 CHF",
+			},
+		},
+		{
+			desc:    "assert on after the last day",
+			checker: Checker{AssertOn: date.Date(2020, 2, 1)},
+			want: []string{
+				"2020-02-01 Assets:Bank 5 CHF",
+				"2020-02-01 Assets:Cash 10 CHF",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			j := parse(t, text)
+
+			if err := j.Build().Process(test.checker.Check()); err != nil {
+				t.Fatalf("Process() returned unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, a := range test.checker.Assertions() {
+				for _, bal := range a.Balances {
+					got = append(got, fmt.Sprintf("%s %s %s %s", a.Date.Format("2006-01-02"), bal.Account.Name(), bal.Quantity, bal.Commodity.Name()))
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Assertions() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func parse(t *testing.T, text []string) *journal.Builder {
+	t.Helper()
+	reg := registry.New()
+	p := parser.New(strings.Join(text, "\n")+"\n", "journal.knut")
+	if err := p.Advance(); err != nil {
+		t.Fatalf("p.Advance() returned unexpected error: %v", err)
+	}
+	f, err := p.ParseFile()
+	if err != nil {
+		t.Fatalf("p.ParseFile() returned unexpected error: %v", err)
+	}
+	scope, err := model.NewScope(f.Directives)
+	if err != nil {
+		t.Fatalf("model.NewScope() returned unexpected error: %v", err)
+	}
+	j := journal.New()
+	for _, d := range f.Directives {
+		ds, err := model.ParseDirective(reg, scope, d)
+		if err != nil {
+			t.Fatalf("model.ParseDirective() returned unexpected error: %v", err)
+		}
+		for _, d := range ds {
+			if err := j.Add(d); err != nil {
+				t.Fatalf("j.Add() returned unexpected error: %v", err)
+			}
+		}
+	}
+	return j
+}
@@ -0,0 +1,69 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sboehler/knut/lib/common/set"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/shopspring/decimal"
+)
+
+// Residuals finds transactions which exchange two or more commodities
+// through the equity account, such as trades, and whose postings to the
+// equity account do not net to zero in value. As every booking balances
+// in quantity, the residual stems from the prices of the exchanged
+// commodities. A residual larger than Tolerance times the exchanged value
+// usually indicates a mispriced trade. The processor returned by Process
+// must run after journal.Valuate.
+type Residuals struct {
+	Equity    *model.Account
+	Valuation *model.Commodity
+	Tolerance decimal.Decimal
+
+	errs []error
+}
+
+// Process returns a processor which checks every transaction.
+func (r *Residuals) Process() *journal.Processor {
+	return &journal.Processor{
+		Transaction: func(t *model.Transaction) error {
+			var (
+				commodities     = set.New[*model.Commodity]()
+				residual, gross decimal.Decimal
+			)
+			for _, p := range t.Postings {
+				if p.Account != r.Equity {
+					continue
+				}
+				commodities.Add(p.Commodity)
+				residual = residual.Add(p.Value)
+				gross = gross.Add(p.Value.Abs())
+			}
+			if len(commodities) < 2 || gross.IsZero() {
+				return nil
+			}
+			exchanged := gross.Sub(residual.Abs()).Div(decimal.NewFromInt(2))
+			if residual.Abs().LessThanOrEqual(r.Tolerance.Mul(exchanged)) {
+				return nil
+			}
+			var rng syntax.Range
+			if t.Src != nil {
+				rng = t.Src.Range
+			}
+			r.errs = append(r.errs, Error{
+				Directive: t,
+				Msg: fmt.Sprintf("transaction at %s has a value residual of %s %s in account %s",
+					rng.Position(), residual.StringFixed(2), r.Valuation.Name(), r.Equity.Name()),
+			})
+			return nil
+		},
+	}
+}
+
+// Err returns the errors of all transactions with a residual.
+func (r *Residuals) Err() error {
+	return errors.Join(r.errs...)
+}
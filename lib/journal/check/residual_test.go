@@ -0,0 +1,58 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestResiduals(t *testing.T) {
+	reg := registry.New()
+	equity := reg.Accounts().EquityAccount()
+	portfolio := reg.Accounts().MustGet("Assets:Portfolio")
+	usd, aapl := reg.Commodities().MustGet("USD"), reg.Commodities().MustGet("AAPL")
+	trade := func(paid int64) *model.Transaction {
+		return transaction.Builder{
+			Postings: posting.Builders{
+				{Credit: equity, Debit: portfolio, Commodity: aapl, Quantity: decimal.NewFromInt(10), Value: decimal.NewFromInt(1000)},
+				{Credit: portfolio, Debit: equity, Commodity: usd, Quantity: decimal.NewFromInt(paid), Value: decimal.NewFromInt(paid)},
+			}.Build(),
+		}.Build()
+	}
+	tests := []struct {
+		desc    string
+		trx     *model.Transaction
+		wantErr bool
+	}{
+		{desc: "consistent", trx: trade(1010)},
+		{desc: "mispriced", trx: trade(1500), wantErr: true},
+		{
+			// A residual in a single commodity stems from valuation, not
+			// from an exchange.
+			desc: "single commodity",
+			trx: transaction.Builder{
+				Postings: posting.Builders{
+					{Credit: equity, Debit: portfolio, Commodity: aapl, Quantity: decimal.NewFromInt(10), Value: decimal.NewFromInt(1000)},
+					{Credit: portfolio, Debit: equity, Commodity: aapl, Quantity: decimal.NewFromInt(5), Value: decimal.NewFromInt(800)},
+				}.Build(),
+			}.Build(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			r := &Residuals{Equity: equity, Valuation: usd, Tolerance: decimal.RequireFromString("0.05")}
+
+			if err := r.Process().Transaction(test.trx); err != nil {
+				t.Fatal(err)
+			}
+
+			if gotErr := r.Err() != nil; gotErr != test.wantErr {
+				t.Errorf("Err() = %v, want error: %t", r.Err(), test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package check
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/account"
+)
+
+// StaleAssertions finds accounts whose most recent balance assertion is
+// more than MaxAge days older than their most recent posting. Accounts
+// without any assertion are not considered, and neither are virtual
+// postings, which assertions do not cover. It must process the journal
+// before the valuation, as the transactions which the valuation generates
+// would count as postings otherwise.
+type StaleAssertions struct {
+	MaxAge int
+
+	assertions, postings map[*model.Account]time.Time
+}
+
+// Process returns a processor which records the dates of the assertions
+// and postings of every account.
+func (s *StaleAssertions) Process() *journal.Processor {
+	s.assertions = make(map[*model.Account]time.Time)
+	s.postings = make(map[*model.Account]time.Time)
+	return &journal.Processor{
+		Posting: func(t *model.Transaction, p *model.Posting) error {
+			if p.Account.IsAL() && !p.Virtual {
+				s.postings[p.Account] = t.Date
+			}
+			return nil
+		},
+		Balance: func(a *model.Assertion, b *model.Balance) error {
+			s.assertions[b.Account] = a.Date
+			return nil
+		},
+	}
+}
+
+// StaleAssertion is an account whose last assertion is stale.
+type StaleAssertion struct {
+	Account            *model.Account
+	Assertion, Posting time.Time
+}
+
+// Days returns the number of days between the last assertion and the
+// last posting.
+func (s StaleAssertion) Days() int {
+	return int(s.Posting.Sub(s.Assertion).Hours() / 24)
+}
+
+func (s StaleAssertion) String() string {
+	return fmt.Sprintf("account %s was last asserted on %s, %d days before its last posting on %s",
+		s.Account, s.Assertion.Format("2006-01-02"), s.Days(), s.Posting.Format("2006-01-02"))
+}
+
+// Stale returns the accounts with a stale assertion, sorted by account.
+func (s *StaleAssertions) Stale() []StaleAssertion {
+	var res []StaleAssertion
+	for acc, asserted := range s.assertions {
+		posted, ok := s.postings[acc]
+		if !ok {
+			continue
+		}
+		st := StaleAssertion{Account: acc, Assertion: asserted, Posting: posted}
+		if st.Days() > s.MaxAge {
+			res = append(res, st)
+		}
+	}
+	compare.Sort(res, func(s1, s2 StaleAssertion) compare.Order {
+		return account.Compare(s1.Account, s2.Account)
+	})
+	return res
+}
@@ -0,0 +1,70 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestStaleAssertions(t *testing.T) {
+	reg := registry.New()
+	usd := reg.Commodities().MustGet("USD")
+	income := reg.Accounts().MustGet("Income:Salary")
+	bank := reg.Accounts().MustGet("Assets:Bank")
+	cash := reg.Accounts().MustGet("Assets:Cash")
+	savings := reg.Accounts().MustGet("Assets:Savings")
+	wallet := reg.Accounts().MustGet("Assets:Wallet")
+	s := &StaleAssertions{MaxAge: 30}
+	proc := s.Process()
+	for _, e := range []struct {
+		account *model.Account
+		posting int
+		assert  int
+		virtual bool
+	}{
+		{account: bank, assert: 1, posting: 60},
+		{account: cash, assert: 31, posting: 60},
+		{account: savings, posting: 60},
+		{account: wallet, assert: 1, posting: 60, virtual: true},
+	} {
+		trx := transaction.Builder{
+			Date: date.Date(2020, 1, e.posting),
+			Postings: posting.Builders{
+				{Credit: income, Debit: e.account, Commodity: usd, Quantity: decimal.NewFromInt(1), Virtual: e.virtual},
+			}.Build(),
+		}.Build()
+		for _, p := range trx.Postings {
+			if err := proc.Posting(trx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if e.assert == 0 {
+			continue
+		}
+		a := &model.Assertion{
+			Date:     date.Date(2020, 1, e.assert),
+			Balances: []model.Balance{{Account: e.account, Commodity: usd}},
+		}
+		if err := proc.Balance(a, &a.Balances[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, st := range s.Stale() {
+		got = append(got, st.String())
+	}
+
+	want := []string{
+		"account Assets:Bank was last asserted on 2020-01-01, 59 days before its last posting on 2020-02-29",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Stale() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
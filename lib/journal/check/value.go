@@ -0,0 +1,44 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/shopspring/decimal"
+)
+
+// ValueAssertions checks balance assertions in the valuation commodity
+// against the value of the account in all commodities, rather than
+// against the quantity of the valuation commodity. The value may differ
+// from the asserted amount by at most Tolerance, to allow for rounding.
+// The processor returned by Process must run after journal.Valuate.
+type ValueAssertions struct {
+	Valuation *model.Commodity
+	Tolerance decimal.Decimal
+
+	values map[*model.Account]decimal.Decimal
+}
+
+// Process returns a processor which checks the assertions.
+func (va *ValueAssertions) Process() *journal.Processor {
+	va.values = make(map[*model.Account]decimal.Decimal)
+	return &journal.Processor{
+		Posting: func(_ *model.Transaction, p *model.Posting) error {
+			if p.Account.IsAL() && !p.Virtual {
+				va.values[p.Account] = va.values[p.Account].Add(p.Value)
+			}
+			return nil
+		},
+		Balance: func(a *model.Assertion, bal *model.Balance) error {
+			if bal.Commodity != va.Valuation {
+				return nil
+			}
+			value := va.values[bal.Account]
+			if value.Sub(bal.Quantity).Abs().GreaterThan(va.Tolerance) {
+				return Error{Directive: a, Msg: fmt.Sprintf("failed value assertion: %s has value: %s %s", bal.Account.Name(), value, va.Valuation.Name())}
+			}
+			return nil
+		},
+	}
+}
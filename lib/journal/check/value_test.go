@@ -0,0 +1,55 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestValueAssertions(t *testing.T) {
+	reg := registry.New()
+	equity := reg.Accounts().EquityAccount()
+	portfolio := reg.Accounts().MustGet("Assets:Portfolio")
+	usd, aapl := reg.Commodities().MustGet("USD"), reg.Commodities().MustGet("AAPL")
+	tests := []struct {
+		desc      string
+		commodity *model.Commodity
+		asserted  string
+		wantErr   bool
+	}{
+		{desc: "exact value", commodity: usd, asserted: "1500"},
+		{desc: "within tolerance", commodity: usd, asserted: "1500.01"},
+		{desc: "beyond tolerance", commodity: usd, asserted: "1500.02", wantErr: true},
+		{desc: "other commodity", commodity: aapl, asserted: "1"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			va := &ValueAssertions{Valuation: usd, Tolerance: decimal.RequireFromString("0.01")}
+			proc := va.Process()
+			trx := transaction.Builder{
+				Postings: posting.Builders{
+					{Credit: equity, Debit: portfolio, Commodity: aapl, Quantity: decimal.NewFromInt(10), Value: decimal.NewFromInt(1000)},
+					{Credit: equity, Debit: portfolio, Commodity: usd, Quantity: decimal.NewFromInt(500), Value: decimal.NewFromInt(500)},
+				}.Build(),
+			}.Build()
+			for _, p := range trx.Postings {
+				if err := proc.Posting(trx, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			a := &model.Assertion{
+				Balances: []model.Balance{{Account: portfolio, Commodity: test.commodity, Quantity: decimal.RequireFromString(test.asserted)}},
+			}
+
+			err := proc.Balance(a, &a.Balances[0])
+
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("Balance() = %v, want error: %t", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package dedupe
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sboehler/knut/lib/common/set"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// Duplicate is a transaction which likely duplicates an earlier one.
+type Duplicate struct {
+	Original, Duplicate *model.Transaction
+	Similarity          float64
+}
+
+// Detector detects likely duplicate transactions. Two transactions are
+// considered duplicates if they book the same amounts in the same
+// commodities, are at most Window days apart and their descriptions have
+// a similarity of at least Threshold.
+type Detector struct {
+	Window    int
+	Threshold float64
+
+	recent     []*model.Transaction
+	duplicates []Duplicate
+	ids        set.Set[*model.Transaction]
+}
+
+// Duplicates returns the detected duplicates.
+func (dt *Detector) Duplicates() []Duplicate {
+	return dt.duplicates
+}
+
+// IsDuplicate returns whether the given transaction duplicates an earlier one.
+func (dt *Detector) IsDuplicate(t *model.Transaction) bool {
+	return dt.ids.Has(t)
+}
+
+func (dt *Detector) transaction(t *model.Transaction) error {
+	cutoff := t.Date.AddDate(0, 0, -dt.Window)
+	var recent []*model.Transaction
+	for _, r := range dt.recent {
+		if !r.Date.Before(cutoff) {
+			recent = append(recent, r)
+		}
+	}
+	dt.recent = recent
+	for _, r := range dt.recent {
+		if dt.ids.Has(r) || !sameAmounts(r, t) {
+			continue
+		}
+		if s := Similarity(r.Description, t.Description); s >= dt.Threshold {
+			dt.duplicates = append(dt.duplicates, Duplicate{Original: r, Duplicate: t, Similarity: s})
+			dt.ids.Add(t)
+			break
+		}
+	}
+	dt.recent = append(dt.recent, t)
+	return nil
+}
+
+// Detect returns a processor which detects duplicates.
+func (dt *Detector) Detect() *journal.Processor {
+	dt.recent = nil
+	dt.duplicates = nil
+	dt.ids = set.New[*model.Transaction]()
+	return &journal.Processor{
+		Transaction: dt.transaction,
+	}
+}
+
+func sameAmounts(t1, t2 *model.Transaction) bool {
+	a1, a2 := amounts(t1), amounts(t2)
+	if len(a1) != len(a2) {
+		return false
+	}
+	for i := range a1 {
+		if a1[i] != a2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func amounts(t *model.Transaction) []string {
+	var res []string
+	for _, p := range t.Postings {
+		if p.Quantity.IsPositive() {
+			res = append(res, p.Quantity.String()+" "+p.Commodity.Name())
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Similarity computes the similarity of two descriptions as the Jaccard
+// index of their lowercased words. It is 1 for identical descriptions
+// and 0 for descriptions without a common word.
+func Similarity(s1, s2 string) float64 {
+	w1, w2 := words(s1), words(s2)
+	if len(w1) == 0 && len(w2) == 0 {
+		return 1
+	}
+	var common int
+	for w := range w1 {
+		if w2.Has(w) {
+			common++
+		}
+	}
+	return float64(common) / float64(len(w1)+len(w2)-common)
+}
+
+func words(s string) set.Set[string] {
+	res := set.New[string]()
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		res.Add(w)
+	}
+	return res
+}
+
+// Index holds the transactions of a journal, to find out whether new
+// transactions, for example those of a re-imported statement, duplicate
+// one of them.
+type Index struct {
+	Detector Detector
+
+	trxs []*model.Transaction
+}
+
+// NewIndex creates an index of the transactions of the journal, which
+// uses the window and the threshold of the detector.
+func NewIndex(dt Detector, j *journal.Journal) *Index {
+	ix := &Index{Detector: dt}
+	for _, d := range j.Days {
+		ix.trxs = append(ix.trxs, d.Transactions...)
+	}
+	return ix
+}
+
+// Find returns the transaction of the index which the given transaction
+// duplicates, if any. Unlike the transactions of a single journal, the
+// transaction may lie up to Window days before or after its original.
+func (ix *Index) Find(t *model.Transaction) (Duplicate, bool) {
+	from, to := t.Date.AddDate(0, 0, -ix.Detector.Window), t.Date.AddDate(0, 0, ix.Detector.Window)
+	// The transactions are sorted by date, as the days of the journal.
+	i := sort.Search(len(ix.trxs), func(i int) bool { return !ix.trxs[i].Date.Before(from) })
+	for ; i < len(ix.trxs) && !ix.trxs[i].Date.After(to); i++ {
+		r := ix.trxs[i]
+		if !sameAmounts(r, t) {
+			continue
+		}
+		if s := Similarity(r.Description, t.Description); s >= ix.Detector.Threshold {
+			return Duplicate{Original: r, Duplicate: t, Similarity: s}, true
+		}
+	}
+	return Duplicate{}, false
+}
+
+// Remove returns the text of the file without the given transactions,
+// along with the transactions which are not directives of the file and
+// have therefore been kept, such as those of templates. The text outside
+// of the removed transactions is preserved.
+func Remove(f syntax.File, trxs []*syntax.Transaction) (string, []*syntax.Transaction) {
+	remove := make(map[int]bool)
+	for _, t := range trxs {
+		remove[t.Start] = true
+	}
+	var (
+		b   strings.Builder
+		pos int
+	)
+	for _, d := range f.Directives {
+		t, ok := d.Directive.(syntax.Transaction)
+		if !ok || !remove[t.Start] {
+			continue
+		}
+		delete(remove, t.Start)
+		b.WriteString(f.Text[pos:d.Start])
+		pos = d.End
+		// Drop the blank line which separated the transaction from the
+		// next directive.
+		if strings.HasPrefix(f.Text[pos:], "\n") {
+			pos++
+		}
+	}
+	b.WriteString(f.Text[pos:])
+	text := b.String()
+	if pos > 0 && pos == len(f.Text) && strings.HasSuffix(text, "\n\n") {
+		// The last directive was removed: drop the blank line before it.
+		text = text[:len(text)-1]
+	}
+	var kept []*syntax.Transaction
+	for _, t := range trxs {
+		if remove[t.Start] {
+			kept = append(kept, t)
+		}
+	}
+	return text, kept
+}
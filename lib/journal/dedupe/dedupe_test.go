@@ -0,0 +1,189 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/sboehler/knut/lib/syntax/parser"
+)
+
+const header = "2020-01-01 open Assets:Bank\n2020-01-01 open Expenses:Food\n\n"
+
+func trx(day int, desc string, amount int) string {
+	return fmt.Sprintf("2020-01-%02d %q\nAssets:Bank Expenses:Food %d CHF\n\n", day, desc, amount)
+}
+
+func TestSimilarity(t *testing.T) {
+	for _, test := range []struct {
+		s1, s2 string
+		want   float64
+	}{
+		{"", "", 1},
+		{"Coop Zurich", "coop zurich", 1},
+		{"Coop Zurich", "Migros Bern", 0},
+		{"Coop Zurich", "Coop Zurich HB", 2.0 / 3},
+		{"Coop Zurich Card 1234", "Coop Zurich", 0.5},
+	} {
+		t.Run(fmt.Sprintf("%s/%s", test.s1, test.s2), func(t *testing.T) {
+			if got := Similarity(test.s1, test.s2); got != test.want {
+				t.Errorf("Similarity(%q, %q) = %v, want %v", test.s1, test.s2, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		trxs []string
+		want []string
+	}{
+		{
+			desc: "identical",
+			trxs: []string{trx(5, "Coop Zurich", 10), trx(5, "Coop Zurich", 10)},
+			want: []string{"2020-01-05 Coop Zurich"},
+		},
+		{
+			desc: "different amounts",
+			trxs: []string{trx(5, "Coop Zurich", 10), trx(5, "Coop Zurich", 11)},
+		},
+		{
+			desc: "at threshold",
+			trxs: []string{trx(5, "Coop Zurich Card 1234", 10), trx(6, "Coop Zurich HB Card 1234", 10)},
+			want: []string{"2020-01-06 Coop Zurich HB Card 1234"},
+		},
+		{
+			desc: "below threshold",
+			trxs: []string{trx(5, "Coop Zurich", 10), trx(6, "Coop Zurich HB", 10)},
+		},
+		{
+			desc: "at end of window",
+			trxs: []string{trx(5, "Coop Zurich", 10), trx(8, "Coop Zurich", 10)},
+			want: []string{"2020-01-08 Coop Zurich"},
+		},
+		{
+			desc: "outside of window",
+			trxs: []string{trx(5, "Coop Zurich", 10), trx(9, "Coop Zurich", 10)},
+		},
+		{
+			desc: "duplicate is no original",
+			trxs: []string{trx(5, "Coop Zurich", 10), trx(6, "Coop Zurich", 10), trx(9, "Coop Zurich", 10)},
+			want: []string{"2020-01-06 Coop Zurich"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			j := load(t, test.trxs)
+			dt := Detector{Window: 3, Threshold: 0.8}
+
+			if err := j.Process(journal.Sort(), dt.Detect()); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, d := range dt.Duplicates() {
+				got = append(got, d.Duplicate.Date.Format("2006-01-02")+" "+d.Duplicate.Description)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Duplicates() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestIndexFind(t *testing.T) {
+	ix := NewIndex(Detector{Window: 3, Threshold: 0.8}, load(t, []string{
+		trx(10, "Coop Zurich", 10),
+		trx(20, "Migros Bern", 20),
+	}))
+	imported := load(t, []string{
+		trx(7, "Coop Zurich", 10),
+		trx(13, "coop zurich", 10),
+		trx(14, "Coop Zurich", 10),
+		trx(20, "Migros Bern", 21),
+		trx(20, "Migros", 20),
+		trx(22, "Migros Bern", 20),
+	})
+	var got []string
+	for _, d := range imported.Days {
+		for _, t := range d.Transactions {
+			if dup, ok := ix.Find(t); ok {
+				got = append(got, fmt.Sprintf("%s %s", t.Date.Format("2006-01-02"), dup.Original.Date.Format("2006-01-02")))
+			}
+		}
+	}
+
+	want := []string{"2020-01-07 2020-01-10", "2020-01-13 2020-01-10", "2020-01-22 2020-01-20"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Find() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func load(t *testing.T, trxs []string) *journal.Journal {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.knut")
+	if err := os.WriteFile(path, []byte(header+strings.Join(trxs, "")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := journal.FromPath(context.Background(), registry.New(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Build()
+}
+
+func TestRemove(t *testing.T) {
+	text := strings.Join([]string{
+		`include "other.knut"`,
+		`* Groceries`,
+		trx(1, "Coop", 10) + trx(2, "Coop", 10) + `2020-01-03 apply split-bill 10`,
+		trx(4, "Migros", 20) + trx(5, "Migros", 20),
+	}, "\n")
+	p := parser.New(text, "main.knut")
+	if err := p.Advance(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := p.ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		trxs  []*syntax.Transaction
+		apply *syntax.Transaction
+	)
+	for _, d := range f.Directives {
+		switch t := d.Directive.(type) {
+		case syntax.Transaction:
+			if date := t.Date.Extract(); date == "2020-01-02" || date == "2020-01-05" {
+				trxs = append(trxs, &t)
+			}
+		case syntax.Apply:
+			// A transaction created by a template refers to the apply
+			// directive.
+			apply = &syntax.Transaction{Range: t.Range}
+			trxs = append(trxs, apply)
+		}
+	}
+
+	got, kept := Remove(f, trxs)
+
+	want := strings.Join([]string{
+		`include "other.knut"`,
+		`* Groceries`,
+		trx(1, "Coop", 10) + `2020-01-03 apply split-bill 10`,
+		strings.TrimSuffix(trx(4, "Migros", 20), "\n"),
+	}, "\n")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Remove() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if len(kept) != 1 || kept[0] != apply {
+		t.Errorf("Remove() kept %v, want the transaction of the apply directive", kept)
+	}
+}
@@ -0,0 +1,171 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/printer"
+	"github.com/sboehler/knut/lib/model"
+)
+
+// Kind is the kind of a change.
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("unknown kind %d", int(k))
+}
+
+// Change is a difference between two journals. Old is empty for added
+// directives, New is empty for removed directives.
+type Change struct {
+	Kind     Kind
+	Date     time.Time
+	Identity string
+	Old, New string
+}
+
+// Compare computes the changes from one journal to another. Directives
+// are identified by their date, their type and a type-specific key, for
+// example the description of a transaction or the account of an open
+// directive. Identical directives are matched first, remaining directives
+// with the same identity are reported as changed.
+func Compare(from, to *journal.Journal) ([]Change, error) {
+	olds, err := index(from)
+	if err != nil {
+		return nil, err
+	}
+	news, err := index(to)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[identity]struct{})
+	for id := range olds {
+		ids[id] = struct{}{}
+	}
+	for id := range news {
+		ids[id] = struct{}{}
+	}
+	var res []Change
+	for _, id := range dict.SortedKeys(ids, compareIdentities) {
+		res = append(res, compareTexts(id, olds[id], news[id])...)
+	}
+	return res, nil
+}
+
+type identity struct {
+	date time.Time
+	key  string
+}
+
+func compareIdentities(i1, i2 identity) compare.Order {
+	if o := compare.Time(i1.date, i2.date); o != compare.Equal {
+		return o
+	}
+	return compare.Ordered(i1.key, i2.key)
+}
+
+func compareTexts(id identity, olds, news []string) []Change {
+	var res []Change
+	olds, news = removeCommon(olds, news)
+	for i := 0; i < len(olds) || i < len(news); i++ {
+		c := Change{Date: id.date, Identity: id.key}
+		switch {
+		case i >= len(olds):
+			c.Kind, c.New = Added, news[i]
+		case i >= len(news):
+			c.Kind, c.Old = Removed, olds[i]
+		default:
+			c.Kind, c.Old, c.New = Changed, olds[i], news[i]
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// removeCommon removes the texts which occur in both slices, respecting
+// multiplicities. Both slices must be sorted.
+func removeCommon(olds, news []string) ([]string, []string) {
+	var remOld, remNew []string
+	i, j := 0, 0
+	for i < len(olds) && j < len(news) {
+		switch {
+		case olds[i] == news[j]:
+			i++
+			j++
+		case olds[i] < news[j]:
+			remOld = append(remOld, olds[i])
+			i++
+		default:
+			remNew = append(remNew, news[j])
+			j++
+		}
+	}
+	return append(remOld, olds[i:]...), append(remNew, news[j:]...)
+}
+
+func index(j *journal.Journal) (map[identity][]string, error) {
+	res := make(map[identity][]string)
+	add := func(date time.Time, key string, d model.Directive) error {
+		var s strings.Builder
+		if _, err := printer.New(&s).PrintDirective(d); err != nil {
+			return err
+		}
+		id := identity{date: date, key: key}
+		res[id] = append(res[id], s.String())
+		return nil
+	}
+	for _, day := range j.Days {
+		for _, p := range day.Prices {
+			if err := add(day.Date, fmt.Sprintf("price %s %s", p.Commodity.Name(), p.Target.Name()), p); err != nil {
+				return nil, err
+			}
+		}
+		for _, o := range day.Openings {
+			if err := add(day.Date, fmt.Sprintf("open %s", o.Account.Name()), o); err != nil {
+				return nil, err
+			}
+		}
+		for _, t := range day.Transactions {
+			if err := add(day.Date, fmt.Sprintf("transaction %q", t.Description), t); err != nil {
+				return nil, err
+			}
+		}
+		for _, a := range day.Assertions {
+			var accounts []string
+			for _, b := range a.Balances {
+				accounts = append(accounts, b.Account.Name())
+			}
+			if err := add(day.Date, fmt.Sprintf("balance %s", strings.Join(accounts, ",")), a); err != nil {
+				return nil, err
+			}
+		}
+		for _, c := range day.Closings {
+			if err := add(day.Date, fmt.Sprintf("close %s", c.Account.Name()), c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, texts := range res {
+		sort.Strings(texts)
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model/registry"
+)
+
+const header = "2020-01-01 open Assets:Bank\n2020-01-01 open Expenses:Food\n\n"
+
+func trx(desc string, amount int) string {
+	return fmt.Sprintf("2020-01-05 %q\nAssets:Bank Expenses:Food %d CHF\n\n", desc, amount)
+}
+
+func TestCompare(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		from, to []string
+		want     []string
+	}{
+		{
+			desc: "unchanged",
+			from: []string{trx("lunch", 10)},
+			to:   []string{trx("lunch", 10)},
+		},
+		{
+			desc: "added",
+			from: []string{trx("lunch", 10)},
+			to:   []string{trx("lunch", 10), trx("dinner", 20)},
+			want: []string{`added transaction "dinner"`},
+		},
+		{
+			desc: "removed",
+			from: []string{trx("lunch", 10), trx("dinner", 20)},
+			to:   []string{trx("lunch", 10)},
+			want: []string{`removed transaction "dinner"`},
+		},
+		{
+			desc: "changed",
+			from: []string{trx("lunch", 10)},
+			to:   []string{trx("lunch", 12)},
+			want: []string{`changed transaction "lunch"`},
+		},
+		{
+			desc: "duplicate removed",
+			from: []string{trx("lunch", 10), trx("lunch", 10)},
+			to:   []string{trx("lunch", 10)},
+			want: []string{`removed transaction "lunch"`},
+		},
+		{
+			desc: "duplicate added",
+			from: []string{trx("lunch", 10)},
+			to:   []string{trx("lunch", 10), trx("lunch", 10), trx("lunch", 10)},
+			want: []string{`added transaction "lunch"`, `added transaction "lunch"`},
+		},
+		{
+			desc: "duplicate changed",
+			from: []string{trx("lunch", 10), trx("lunch", 10)},
+			to:   []string{trx("lunch", 10), trx("lunch", 11)},
+			want: []string{`changed transaction "lunch"`},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			from := load(t, test.from)
+			to := load(t, test.to)
+
+			changes, err := Compare(from, to)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.Kind.String()+" "+c.Identity)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Compare() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestRemoveCommon(t *testing.T) {
+	olds, news := removeCommon([]string{"a", "a", "b", "c"}, []string{"a", "c", "c", "d"})
+
+	if diff := cmp.Diff([]string{"a", "b"}, olds); diff != "" {
+		t.Errorf("removeCommon() returned unexpected old texts (-want/+got):\n%s\n", diff)
+	}
+	if diff := cmp.Diff([]string{"c", "d"}, news); diff != "" {
+		t.Errorf("removeCommon() returned unexpected new texts (-want/+got):\n%s\n", diff)
+	}
+}
+
+func load(t *testing.T, trxs []string) *journal.Journal {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.knut")
+	if err := os.WriteFile(path, []byte(header+strings.Join(trxs, "")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := journal.FromPath(context.Background(), registry.New(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Build()
+}
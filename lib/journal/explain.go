@@ -0,0 +1,112 @@
+package journal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sboehler/knut/lib/model"
+)
+
+// Explainer observes the processors of a pipeline and records what each
+// of them did. A nil Explainer does not observe anything.
+type Explainer struct {
+	stages []*Stage
+}
+
+// Stage holds the activity of a single processor.
+type Stage struct {
+	Name string
+
+	// Days, Prices, Transactions and Postings count the directives the
+	// processor has seen.
+	Days, Prices, Transactions, Postings int
+
+	// Added and Removed count the transactions which the processor has
+	// added to or removed from the journal.
+	Added, Removed int
+
+	// Updated counts the postings whose quantity or value the processor
+	// has changed.
+	Updated int
+}
+
+// Stages returns the observed stages, in the order in which they were
+// registered.
+func (e *Explainer) Stages() []*Stage {
+	return e.stages
+}
+
+// Observe returns a processor which behaves like proc and records its
+// activity under the given name. It returns proc itself if e or proc is
+// nil.
+func (e *Explainer) Observe(name string, proc *Processor) *Processor {
+	if e == nil || proc == nil {
+		return proc
+	}
+	s := &Stage{Name: name}
+	e.stages = append(e.stages, s)
+	var before int
+	return &Processor{
+		DayStart: func(d *Day) error {
+			s.Days++
+			s.Prices += len(d.Prices)
+			before = len(d.Transactions)
+			if proc.DayStart != nil {
+				return proc.DayStart(d)
+			}
+			return nil
+		},
+		Price:     proc.Price,
+		Open:      proc.Open,
+		Assertion: proc.Assertion,
+		Balance:   proc.Balance,
+		Close:     proc.Close,
+		Transaction: func(t *model.Transaction) error {
+			s.Transactions++
+			if proc.Transaction != nil {
+				return proc.Transaction(t)
+			}
+			return nil
+		},
+		Posting: func(t *model.Transaction, p *model.Posting) error {
+			s.Postings++
+			if proc.Posting == nil {
+				return nil
+			}
+			qty, val := p.Quantity, p.Value
+			if err := proc.Posting(t, p); err != nil {
+				return err
+			}
+			if !qty.Equal(p.Quantity) || !val.Equal(p.Value) {
+				s.Updated++
+			}
+			return nil
+		},
+		DayEnd: func(d *Day) error {
+			if proc.DayEnd != nil {
+				if err := proc.DayEnd(d); err != nil {
+					return err
+				}
+			}
+			if after := len(d.Transactions); after > before {
+				s.Added += after - before
+			} else {
+				s.Removed += before - after
+			}
+			return nil
+		},
+	}
+}
+
+// Report writes a summary of the observed stages to w.
+func (e *Explainer) Report(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%-10s %8s %8s %12s %10s %8s %8s %8s\n", "Stage", "Days", "Prices", "Transactions", "Postings", "Updated", "Added", "Removed"); err != nil {
+		return err
+	}
+	for _, s := range e.stages {
+		if _, err := fmt.Fprintf(w, "%-10s %8d %8d %12d %10d %8d %8d %8d\n", s.Name, s.Days, s.Prices, s.Transactions, s.Postings, s.Updated, s.Added, s.Removed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
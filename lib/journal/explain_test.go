@@ -0,0 +1,89 @@
+package journal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestExplainer(t *testing.T) {
+	reg := registry.New()
+	chf, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD")
+	bank, food := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Expenses:Food")
+	trx := func(d int) *model.Transaction {
+		return transaction.Builder{
+			Date: date.Date(2020, 1, d),
+			Postings: posting.Builder{
+				Credit:    bank,
+				Debit:     food,
+				Commodity: chf,
+				Quantity:  decimal.NewFromInt(10),
+			}.Build(),
+		}.Build()
+	}
+	j := New()
+	j.Add(&model.Price{Date: date.Date(2020, 1, 1), Commodity: usd, Target: chf, Price: decimal.NewFromInt(2)})
+	j.Add(trx(1))
+	j.Add(trx(2))
+	var e Explainer
+
+	err := j.Build().Process(
+		e.Observe("add", &Processor{
+			DayStart: func(d *Day) error {
+				d.Transactions = append(d.Transactions, trx(d.Date.Day()))
+				return nil
+			},
+		}),
+		e.Observe("double", &Processor{
+			Posting: func(_ *model.Transaction, p *model.Posting) error {
+				if p.Account == bank {
+					p.Quantity = p.Quantity.Mul(decimal.NewFromInt(2))
+				}
+				return nil
+			},
+		}),
+		e.Observe("remove", &Processor{
+			DayEnd: func(d *Day) error {
+				if d.Date.Day() == 2 {
+					d.Transactions = nil
+				}
+				return nil
+			},
+		}),
+		e.Observe("nil", nil),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	if err := e.Report(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"Stage          Days   Prices Transactions   Postings  Updated    Added  Removed",
+		"add               2        1            4          8        0        2        0",
+		"double            2        1            4          8        4        0        0",
+		"remove            2        1            4          8        0        0        2",
+		"",
+	}, "\n")
+	if diff := cmp.Diff(want, got.String()); diff != "" {
+		t.Errorf("Report() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestExplainerNil(t *testing.T) {
+	var e *Explainer
+	proc := new(Processor)
+
+	if got := e.Observe("stage", proc); got != proc {
+		t.Errorf("Observe() on a nil Explainer returned %v, want the processor itself", got)
+	}
+}
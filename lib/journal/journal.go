@@ -16,8 +16,10 @@ package journal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -28,22 +30,34 @@ import (
 	"github.com/sboehler/knut/lib/journal/printer"
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/price"
+	"github.com/sboehler/knut/lib/model/transaction"
 	"github.com/sboehler/knut/lib/syntax"
+	"github.com/shopspring/decimal"
 	"github.com/sourcegraph/conc/pool"
+	"golang.org/x/exp/slices"
 )
 
 // Builder represents an unprocessed
 type Builder struct {
+	// Sequential makes the journals built by this builder process their
+	// days on the calling goroutine.
+	Sequential bool
+
 	days     map[time.Time]*Day
 	min, max time.Time
+
+	// compareRanges orders the sources of directives in the order of the
+	// journal, across the included files.
+	compareRanges compare.Compare[syntax.Range]
 }
 
 // New creates a new Journal.
 func New() *Builder {
 	return &Builder{
-		days: make(map[time.Time]*Day),
-		min:  date.Date(9999, 12, 31),
-		max:  time.Time{},
+		days:          make(map[time.Time]*Day),
+		min:           date.Date(9999, 12, 31),
+		max:           time.Time{},
+		compareRanges: new(syntax.Resolver).Compare,
 	}
 }
 
@@ -52,10 +66,45 @@ func (j *Builder) Day(d time.Time) *Day {
 	return dict.GetDefault(j.days, d, func() *Day { return &Day{Date: d} })
 }
 
+// Build returns the journal. The prices of each day are sorted in the
+// order of the journal.
 func (j *Builder) Build() *Journal {
+	for _, d := range j.days {
+		compare.Sort(d.Prices, j.comparePrices)
+	}
 	return &Journal{
-		Days: dict.SortedValues(j.days, CompareDays),
+		Days:       dict.SortedValues(j.days, CompareDays),
+		Sequential: j.Sequential,
+	}
+}
+
+// Clone returns a copy of the journal, which can be processed without
+// modifying the journal. As processing modifies the days, the
+// transactions and their postings, these are copied. The other
+// directives are shared.
+func (j *Builder) Clone() *Builder {
+	res := *j
+	res.days = make(map[time.Time]*Day, len(j.days))
+	for k, d := range j.days {
+		day := &Day{
+			Date:       d.Date,
+			Prices:     slices.Clone(d.Prices),
+			Assertions: slices.Clone(d.Assertions),
+			Openings:   slices.Clone(d.Openings),
+			Closings:   slices.Clone(d.Closings),
+		}
+		for _, t := range d.Transactions {
+			c := *t
+			c.Postings = make([]*model.Posting, 0, len(t.Postings))
+			for _, p := range t.Postings {
+				p := *p
+				c.Postings = append(c.Postings, &p)
+			}
+			day.Transactions = append(day.Transactions, &c)
+		}
+		res.days[k] = day
 	}
+	return &res
 }
 
 func (j *Builder) Add(d model.Directive) error {
@@ -96,6 +145,25 @@ func (j *Builder) Add(d model.Directive) error {
 	return nil
 }
 
+// UseEffectiveDates moves transactions with an effective date to the day
+// of their effective date, so that reports are based on the effective
+// dates instead of the booking dates.
+func (j *Builder) UseEffectiveDates() {
+	days := dict.SortedValues(j.days, CompareDays)
+	for _, day := range days {
+		var keep []*model.Transaction
+		for _, t := range day.Transactions {
+			if t.Effective.IsZero() || t.Effective.Equal(day.Date) {
+				keep = append(keep, t)
+				continue
+			}
+			t.Date = t.Effective
+			j.Add(t)
+		}
+		day.Transactions = keep
+	}
+}
+
 func (j *Builder) Period() date.Period {
 	return date.Period{Start: j.min, End: j.max}
 }
@@ -109,8 +177,20 @@ func (j *Builder) Days(dates []time.Time) []*Day {
 }
 
 func FromPath(ctx context.Context, reg *model.Registry, path string) (*Builder, error) {
-	syntaxCh, worker1 := syntax.ParseFileRecursively(path)
-	modelCh, worker2 := model.FromStream(reg, syntaxCh)
+	return FromPathWith(ctx, reg, path, new(syntax.Resolver))
+}
+
+// FromPathWith is like FromPath, but resolves includes with the given
+// resolver. The parallelism of the resolver also limits the number of
+// files which are converted to the model concurrently. If the resolver is
+// sequential, the journal is read on the calling goroutine and is
+// sequential, too.
+func FromPathWith(ctx context.Context, reg *model.Registry, path string, r *syntax.Resolver) (*Builder, error) {
+	if r.Sequential {
+		return fromPathSequential(reg, path, r)
+	}
+	syntaxCh, worker1 := r.ParseFileRecursively(path)
+	modelCh, worker2 := model.FromStream(reg, syntaxCh, r.Parallelism)
 	journalCh, worker3 := FromModelStream(modelCh)
 	p := pool.New().WithErrors().WithFirstError().WithContext(ctx)
 	p.Go(worker1)
@@ -119,7 +199,124 @@ func FromPath(ctx context.Context, reg *model.Registry, path string) (*Builder,
 	if err := p.Wait(); err != nil {
 		return nil, err
 	}
-	return <-journalCh, nil
+	j := <-journalCh
+	j.compareRanges = r.Compare
+	return j, nil
+}
+
+func fromPathSequential(reg *model.Registry, path string, r *syntax.Resolver) (*Builder, error) {
+	files, err := r.ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromFiles(reg, files, r)
+}
+
+// FromFiles creates a journal from files which the given resolver has
+// parsed, for example with ParseFiles. The files are not modified, so
+// they can be used to create several journals.
+func FromFiles(reg *model.Registry, files []syntax.File, r *syntax.Resolver) (*Builder, error) {
+	j := New()
+	j.Sequential = r.Sequential
+	j.compareRanges = r.Compare
+	for _, f := range files {
+		scope, err := model.NewScope(f.Directives)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range f.Directives {
+			ds, err := model.ParseDirective(reg, scope, d)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range ds {
+				if err := j.Add(d); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return j, nil
+}
+
+// FromPathAllErrors is like FromPathWith, but does not stop at the first
+// error. It collects the errors of all files and directives and returns
+// them together, sorted by file and position.
+func FromPathAllErrors(reg *model.Registry, path string, r *syntax.Resolver) (*Builder, error) {
+	files, errs := r.ParseAll(path)
+	j := New()
+	j.Sequential = r.Sequential
+	j.compareRanges = r.Compare
+	for _, f := range files {
+		scope, err := model.NewScope(f.Directives)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, d := range f.Directives {
+			ds, err := model.ParseDirective(reg, scope, d)
+			if err != nil {
+				errs = append(errs, locate(d.Range, err))
+				continue
+			}
+			for _, d := range ds {
+				if err := j.Add(d); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, k int) bool {
+			return compareErrors(errs[i], errs[k]) == compare.Smaller
+		})
+		return nil, errors.Join(errs...)
+	}
+	return j, nil
+}
+
+// locate attaches the range of the directive to errors which do not carry
+// a location.
+func locate(rng syntax.Range, err error) error {
+	var serr syntax.Error
+	if errors.As(err, &serr) {
+		return err
+	}
+	return syntax.Error{Range: rng, Message: "invalid directive", Wrapped: err}
+}
+
+// compareErrors orders errors by file and position. Errors without a
+// position come last.
+func compareErrors(e1, e2 error) compare.Order {
+	s1, ok1 := innermost(e1)
+	s2, ok2 := innermost(e2)
+	switch {
+	case !ok1 && !ok2:
+		return compare.Equal
+	case !ok1:
+		return compare.Greater
+	case !ok2:
+		return compare.Smaller
+	}
+	if o := compare.Ordered(s1.Path, s2.Path); o != compare.Equal {
+		return o
+	}
+	return compare.Ordered(s1.Start, s2.Start)
+}
+
+// innermost returns the innermost syntax error, which carries the most
+// precise position.
+func innermost(err error) (syntax.Error, bool) {
+	var serr syntax.Error
+	if !errors.As(err, &serr) {
+		return serr, false
+	}
+	for {
+		var inner syntax.Error
+		if serr.Wrapped == nil || !errors.As(serr.Wrapped, &inner) {
+			return serr, true
+		}
+		serr = inner
+	}
 }
 
 func FromModelStream(modelCh <-chan []model.Directive) (<-chan *Builder, func(context.Context) error) {
@@ -142,6 +339,10 @@ func FromModelStream(modelCh <-chan []model.Directive) (<-chan *Builder, func(co
 
 type Journal struct {
 	Days []*Day
+
+	// Sequential makes Process run all processors on the calling
+	// goroutine, one day after the other, instead of as a pipeline.
+	Sequential bool
 }
 
 func (j *Journal) Process(ps ...*Processor) error {
@@ -151,10 +352,40 @@ func (j *Journal) Process(ps ...*Processor) error {
 			fs = append(fs, proc.Process)
 		}
 	}
+	if j.Sequential {
+		for _, d := range j.Days {
+			for _, f := range fs {
+				if err := f(d); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
 	_, err := cpr.Seq(context.Background(), j.Days, fs...)
 	return err
 }
 
+// EachPosting calls f for every posting of the journal, along with its
+// transaction and day. Days are visited in date order and the transactions
+// of a day in the order of the Sort processor, which is the order of the
+// reports. Iteration stops at the first error, which is returned. The
+// journal is not modified.
+func (j *Journal) EachPosting(f func(*Day, *model.Transaction, *model.Posting) error) error {
+	for _, d := range j.Days {
+		trxs := slices.Clone(d.Transactions)
+		compare.Sort(trxs, transaction.Compare)
+		for _, t := range trxs {
+			for _, p := range t.Postings {
+				if err := f(d, t, p); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // Day groups all commands for a given date.
 type Day struct {
 	Date         time.Time
@@ -177,39 +408,53 @@ func CompareDays(d *Day, d2 *Day) compare.Order {
 // Performance holds aggregate information used to compute
 // portfolio performance.
 type Performance struct {
-	V0, V1, Inflow, Outflow, InternalInflow, InternalOutflow map[*model.Commodity]float64
-	PortfolioInflow, PortfolioOutflow                        float64
+	V0, V1, Inflow, Outflow, InternalInflow, InternalOutflow map[*model.Commodity]decimal.Decimal
+	PortfolioInflow, PortfolioOutflow                        decimal.Decimal
 }
 
 func (p Performance) String() string {
 	var buf strings.Builder
 	for c, v := range p.V0 {
-		fmt.Fprintf(&buf, "V0: %20s %f\n", c, v)
+		fmt.Fprintf(&buf, "V0: %20s %s\n", c, v)
 	}
 	for c, f := range p.Inflow {
-		fmt.Fprintf(&buf, "Inflow: %20s %f\n", c, f)
+		fmt.Fprintf(&buf, "Inflow: %20s %s\n", c, f)
 	}
 	for c, f := range p.Outflow {
-		fmt.Fprintf(&buf, "Outflow: %20s %f\n", c, f)
+		fmt.Fprintf(&buf, "Outflow: %20s %s\n", c, f)
 	}
 	for c, f := range p.InternalInflow {
-		fmt.Fprintf(&buf, "InternalInflow: %20s %f\n", c, f)
+		fmt.Fprintf(&buf, "InternalInflow: %20s %s\n", c, f)
 	}
 	for c, f := range p.InternalOutflow {
-		fmt.Fprintf(&buf, "InternalOutflow: %20s %f\n", c, f)
+		fmt.Fprintf(&buf, "InternalOutflow: %20s %s\n", c, f)
 	}
 	for c, v := range p.V1 {
-		fmt.Fprintf(&buf, "V1: %20s %f\n", c, v)
+		fmt.Fprintf(&buf, "V1: %20s %s\n", c, v)
 	}
 	return buf.String()
 }
 
 // PrintJournal prints a journal.
 func Print(w io.Writer, j *Journal) error {
+	return printJournal(printer.New(w), j)
+}
+
+// PrintAnnotated prints a journal, annotating open and close directives
+// with the first and last activity of the respective account.
+func PrintAnnotated(w io.Writer, j *Journal) error {
 	p := printer.New(w)
+	p.Annotate = true
+	return printJournal(p, j)
+}
+
+func printJournal(p *printer.Printer, j *Journal) error {
 	paddingUpdater := &Processor{
 		Transaction: func(t *model.Transaction) error {
 			p.UpdatePadding(t)
+			if p.Annotate {
+				p.UpdateActivity(t)
+			}
 			return nil
 		},
 	}
@@ -0,0 +1,345 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/shopspring/decimal"
+)
+
+func TestFromPathAllErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.knut": "include \"b.knut\"\n2020-01-01 open Assets:A\n2020-01-02 foo\n2020-01-03 open A\n",
+		"b.knut":    "2020-01-01 open Assets:B\n2020-01-03 bar\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := FromPathAllErrors(registry.New(), filepath.Join(dir, "main.knut"), new(syntax.Resolver))
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("FromPathAllErrors() returned %v, want joined errors", err)
+	}
+	var got []string
+	for _, e := range joined.Unwrap() {
+		var serr syntax.Error
+		if !errors.As(e, &serr) {
+			t.Fatalf("FromPathAllErrors() returned %v, want a syntax error", e)
+		}
+		got = append(got, filepath.Base(serr.Path))
+	}
+	if diff := cmp.Diff([]string{"b.knut", "main.knut", "main.knut"}, got); diff != "" {
+		t.Errorf("FromPathAllErrors() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestFromPathAllErrorsResolver(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.knut": "include \"b.knut\"\ninclude \"c.knut\"\n2020-01-02 foo\n",
+		"b.knut":    "2020-01-03 bar\n",
+		"c.knut":    "include \"b.knut\"\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		desc      string
+		resolver  func() *syntax.Resolver
+		want      []string
+		wantWarns int
+	}{
+		{
+			desc:      "duplicate include",
+			resolver:  func() *syntax.Resolver { return &syntax.Resolver{Parallelism: 1} },
+			want:      []string{"b.knut", "b.knut", "main.knut"},
+			wantWarns: 1,
+		},
+		{
+			desc:     "deduped include",
+			resolver: func() *syntax.Resolver { return &syntax.Resolver{Dedupe: true} },
+			want:     []string{"b.knut", "main.knut"},
+		},
+		{
+			desc:     "sequential",
+			resolver: func() *syntax.Resolver { return &syntax.Resolver{Dedupe: true, Sequential: true} },
+			want:     []string{"b.knut", "main.knut"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var warns atomic.Int32
+			r := test.resolver()
+			r.Warn = func(syntax.Duplicate) { warns.Add(1) }
+
+			_, err := FromPathAllErrors(registry.New(), filepath.Join(dir, "main.knut"), r)
+
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("FromPathAllErrors() returned %v, want joined errors", err)
+			}
+			var got []string
+			for _, e := range joined.Unwrap() {
+				var serr syntax.Error
+				if !errors.As(e, &serr) {
+					t.Fatalf("FromPathAllErrors() returned %v, want a syntax error", e)
+				}
+				got = append(got, filepath.Base(serr.Path))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("FromPathAllErrors() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+			if got := int(warns.Load()); got != test.wantWarns {
+				t.Errorf("got %d warnings, want %d", got, test.wantWarns)
+			}
+		})
+	}
+}
+
+// overlappingJournal writes a journal with many included files which
+// book to overlapping accounts and commodities, and returns its path.
+func overlappingJournal(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	var main strings.Builder
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("f%d.knut", i)
+		fmt.Fprintf(&main, "include %q\n", name)
+		var b strings.Builder
+		for k := 0; k < 20; k++ {
+			fmt.Fprintf(&b, "2020-01-%02d \"Trade\"\nAssets:Shared:Acc%d Expenses:Shared:Acc%d 1.%0*d COM%d\n\n", k+1, k%5, (i+k)%7, i%4+1, 0, k%3)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(b.String()), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "main.knut")
+	if err := os.WriteFile(path, []byte(main.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFromPathConcurrent(t *testing.T) {
+	path := overlappingJournal(t)
+	reg := registry.New()
+
+	j, err := FromPath(context.Background(), reg, path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trx int
+	for _, d := range j.Build().Days {
+		trx += len(d.Transactions)
+	}
+	if trx != 1000 {
+		t.Errorf("got %d transactions, want 1000", trx)
+	}
+	if got, want := reg.Accounts().MustGet("Assets:Shared:Acc0"), reg.Accounts().MustGet("Assets:Shared:Acc0"); got != want {
+		t.Errorf("got different accounts for the same name")
+	}
+}
+
+func TestFromPathSequential(t *testing.T) {
+	path := overlappingJournal(t)
+	print := func(r *syntax.Resolver) string {
+		t.Helper()
+		j, err := FromPathWith(context.Background(), registry.New(), path, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf strings.Builder
+		if err := Print(&buf, j.Build()); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	got := print(&syntax.Resolver{Sequential: true})
+
+	if diff := cmp.Diff(print(new(syntax.Resolver)), got); diff != "" {
+		t.Errorf("FromPathWith() returned unexpected diff (-concurrent/+sequential):\n%s\n", diff)
+	}
+}
+
+func TestUseEffectiveDates(t *testing.T) {
+	booked, effective := date.Date(2020, 4, 2), date.Date(2020, 3, 31)
+	j := New()
+	j.Add(&model.Transaction{Date: booked, Effective: effective, Description: "bill"})
+	j.Add(&model.Transaction{Date: booked, Description: "other"})
+
+	j.UseEffectiveDates()
+
+	got := make(map[string]time.Time)
+	for _, d := range j.Build().Days {
+		for _, trx := range d.Transactions {
+			if !trx.Date.Equal(d.Date) {
+				t.Errorf("transaction %q has date %s on day %s", trx.Description, trx.Date, d.Date)
+			}
+			got[trx.Description] = d.Date
+		}
+	}
+	want := map[string]time.Time{"bill": effective, "other": booked}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("UseEffectiveDates() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if got := j.Period(); !got.Start.Equal(effective) {
+		t.Errorf("Period().Start = %s, want %s", got.Start, effective)
+	}
+}
+
+func TestClone(t *testing.T) {
+	reg := registry.New()
+	chf, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD")
+	j := New()
+	j.Add(&model.Price{Date: date.Date(2020, 1, 1), Commodity: usd, Target: chf, Price: decimal.RequireFromString("0.9")})
+	j.Add(transaction.Builder{
+		Date: date.Date(2020, 1, 2),
+		Postings: posting.Builder{
+			Credit:    reg.Accounts().MustGet("Income:Salary"),
+			Debit:     reg.Accounts().MustGet("Assets:Bank"),
+			Commodity: usd,
+			Quantity:  decimal.NewFromInt(100),
+		}.Build(),
+	}.Build())
+
+	c := j.Clone()
+	c.Day(date.Date(2020, 1, 31))
+	if err := c.Build().Process(ComputePrices(chf), Valuate(reg, chf)); err != nil {
+		t.Fatal(err)
+	}
+
+	days := j.Build().Days
+	if len(days) != 2 {
+		t.Fatalf("journal has %d days after processing the clone, want 2", len(days))
+	}
+	trx := days[1].Transactions[0]
+	if days[1].Normalized != nil || !trx.Postings[0].Value.IsZero() {
+		t.Errorf("processing the clone modified the journal: %v", trx)
+	}
+}
+
+func TestEachPosting(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	bank, food := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Expenses:Food")
+	j := New()
+	for _, trx := range []struct {
+		date time.Time
+		desc string
+	}{
+		{date.Date(2020, 1, 2), "b"},
+		{date.Date(2020, 1, 1), "z"},
+		{date.Date(2020, 1, 2), "a"},
+	} {
+		j.Add(transaction.Builder{
+			Date:        trx.date,
+			Description: trx.desc,
+			Postings: posting.Builder{
+				Credit:    bank,
+				Debit:     food,
+				Commodity: chf,
+				Quantity:  decimal.NewFromInt(1),
+			}.Build(),
+		}.Build())
+	}
+	var got []string
+	errStop := errors.New("stop")
+
+	err := j.Build().EachPosting(func(d *Day, trx *model.Transaction, p *model.Posting) error {
+		if !trx.Date.Equal(d.Date) {
+			t.Errorf("transaction %q has date %s on day %s", trx.Description, trx.Date, d.Date)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s", d.Date.Format("2006-01-02"), trx.Description, p.Account))
+		if len(got) == 5 {
+			return errStop
+		}
+		return nil
+	})
+
+	if err != errStop {
+		t.Errorf("EachPosting() returned %v, want %v", err, errStop)
+	}
+	want := []string{
+		"2020-01-01 z Assets:Bank",
+		"2020-01-01 z Expenses:Food",
+		"2020-01-02 a Assets:Bank",
+		"2020-01-02 a Expenses:Food",
+		"2020-01-02 b Assets:Bank",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("EachPosting() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestFromPathIncludedTemplate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.knut":      "include \"templates.knut\"\n2020-01-02 apply deposit 100\n",
+		"templates.knut": "template deposit amount \"Deposit\"\nEquity:Equity Assets:Bank $amount CHF\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		desc string
+		load func(string) error
+	}{
+		{
+			desc: "pipeline",
+			load: func(path string) error {
+				_, err := FromPath(context.Background(), registry.New(), path)
+				return err
+			},
+		},
+		{
+			desc: "sequential",
+			load: func(path string) error {
+				_, err := FromPathWith(context.Background(), registry.New(), path, &syntax.Resolver{Sequential: true})
+				return err
+			},
+		},
+		{
+			desc: "all errors",
+			load: func(path string) error {
+				_, err := FromPathAllErrors(registry.New(), path, new(syntax.Resolver))
+				return err
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			err := test.load(filepath.Join(dir, "main.knut"))
+
+			var serr syntax.Error
+			if !errors.As(err, &serr) {
+				t.Fatalf("returned %v, want a syntax error", err)
+			}
+			if want := "unknown template `deposit` (templates are only visible in the file which defines them)"; serr.Message != want {
+				t.Errorf("returned %q, want %q", serr.Message, want)
+			}
+		})
+	}
+}
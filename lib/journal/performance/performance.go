@@ -2,7 +2,6 @@ package performance
 
 import (
 	"fmt"
-	"math"
 
 	"github.com/sboehler/knut/lib/amounts"
 	"github.com/sboehler/knut/lib/common/date"
@@ -11,6 +10,7 @@ import (
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
 )
 
 // Calculator calculates portfolio performance
@@ -53,8 +53,8 @@ func (calc *Calculator) ComputeValues() *journal.Processor {
 		DayEnd: func(d *journal.Day) error {
 			prev = nil
 			for k, v := range values {
-				f, _ := v.Float64()
-				get(&prev)[k.Commodity] += f
+				pv := get(&prev)
+				pv[k.Commodity] = pv[k.Commodity].Add(v)
 			}
 			d.Performance.V1 = prev
 			return nil
@@ -63,16 +63,16 @@ func (calc *Calculator) ComputeValues() *journal.Processor {
 }
 
 // pcv is a per-commodity value.
-type pcv = map[*model.Commodity]float64
+type pcv = map[*model.Commodity]decimal.Decimal
 
 func (calc *Calculator) ComputeFlows() *journal.Processor {
-	var portfolioFlows float64
+	var portfolioFlows decimal.Decimal
 	var performance *journal.Performance
 
 	return &journal.Processor{
 
 		DayStart: func(d *journal.Day) error {
-			portfolioFlows = 0
+			portfolioFlows = decimal.Zero
 			if d.Performance != nil {
 				performance = d.Performance
 			} else {
@@ -107,22 +107,23 @@ func (calc *Calculator) ComputeFlows() *journal.Processor {
 					continue
 				}
 
-				value, _ := p.Value.Float64()
+				value := p.Value
 				if tgts == nil {
 					// regular flow into or out of the portfolio
-					get(&flows)[p.Commodity] += value
+					fl := get(&flows)
+					fl[p.Commodity] = fl[p.Commodity].Add(value)
 					continue
 				}
 				intf := get(&internalFlows)
-				intf[p.Commodity] += value
+				intf[p.Commodity] = intf[p.Commodity].Add(value)
 				if len(tgts) == 0 {
 					// performance effect on portfolio, not allocated to a specific commodity
-					portfolioFlows -= value
+					portfolioFlows = portfolioFlows.Sub(value)
 				} else {
 					// effect on multiple commodities: re-allocate the flows among the target commodities
-					l := float64(len(tgts))
+					share := value.Div(decimal.NewFromInt(int64(len(tgts))))
 					for _, com := range tgts {
-						intf[com] -= value / l
+						intf[com] = intf[com].Sub(share)
 					}
 				}
 			}
@@ -133,8 +134,8 @@ func (calc *Calculator) ComputeFlows() *journal.Processor {
 		},
 
 		DayEnd: func(d *journal.Day) error {
-			performance.PortfolioInflow = math.Max(0, portfolioFlows)
-			performance.PortfolioOutflow = math.Min(0, portfolioFlows)
+			performance.PortfolioInflow = decimal.Max(decimal.Zero, portfolioFlows)
+			performance.PortfolioOutflow = decimal.Min(decimal.Zero, portfolioFlows)
 			d.Performance = performance
 			return nil
 		},
@@ -143,10 +144,12 @@ func (calc *Calculator) ComputeFlows() *journal.Processor {
 
 func split(flows pcv, in, out *pcv) {
 	for c, f := range flows {
-		if f > 0 {
-			get(in)[c] += f
-		} else if f < 0 {
-			get(out)[c] += f
+		if f.IsPositive() {
+			m := get(in)
+			m[c] = m[c].Add(f)
+		} else if f.IsNegative() {
+			m := get(out)
+			m[c] = m[c].Add(f)
 		}
 	}
 }
@@ -194,42 +197,52 @@ func (calc Calculator) isPortfolioAccount(a *model.Account) bool {
 
 // perf = ( V1 - Outflow ) / ( V0 + Inflow )
 
+// precision is the number of decimal places to which performance ratios
+// are rounded, such that their products do not grow without bounds.
+const precision = 16
+
 // Performance computes the portfolio performance.
-func Performance(dpv *journal.Performance) float64 {
+func Performance(dpv *journal.Performance) decimal.Decimal {
 	var (
-		v0, v1          float64
+		v0, v1          decimal.Decimal
 		inflow, outflow = dpv.PortfolioInflow, dpv.PortfolioOutflow
 	)
 	for _, v := range dpv.V0 {
-		v0 += v
+		v0 = v0.Add(v)
 	}
 	for _, v := range dpv.V1 {
-		v1 += v
+		v1 = v1.Add(v)
 	}
 	for _, v := range dpv.Inflow {
-		inflow += v
+		inflow = inflow.Add(v)
 	}
 	for _, v := range dpv.Outflow {
-		outflow += v
+		outflow = outflow.Add(v)
+	}
+	if v0.Equal(v1) && inflow.IsZero() && outflow.IsZero() {
+		return decimal.NewFromInt(1)
 	}
-	if v0 == v1 && inflow == 0 && outflow == 0 {
-		return 1
+	if v0.Add(inflow).IsZero() {
+		// There is no invested value to measure the performance against,
+		// for example if a position shows up without a flow.
+		return decimal.NewFromInt(1)
 	}
-	return (v1 - outflow) / (v0 + inflow)
+	return v1.Sub(outflow).DivRound(v0.Add(inflow), precision)
 }
 
 func Perf(j *journal.Builder, part date.Partition) *journal.Processor {
 	ds := set.FromSlice(j.Days(part.EndDates()))
-	running := 1.0
+	one := decimal.NewFromInt(1)
+	running := one
 	return &journal.Processor{
 		DayEnd: func(d *journal.Day) error {
 			if !part.Contains(d.Date) {
 				return nil
 			}
-			running *= Performance(d.Performance)
+			running = running.Mul(Performance(d.Performance)).Round(precision)
 			if ds.Has(d) {
-				fmt.Printf("%v: %0.1f%%\n", d.Date, 100*(running-1))
-				running = 1.0
+				fmt.Printf("%v: %s%%\n", d.Date, running.Sub(one).Mul(decimal.NewFromInt(100)).StringFixed(1))
+				running = one
 			}
 			return nil
 		},
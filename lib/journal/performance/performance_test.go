@@ -48,7 +48,7 @@ func TestComputeFlows(t *testing.T) {
 					Commodity: usd,
 				}.Build(),
 			}.Build(),
-			want: &journal.Performance{Outflow: pcv{usd: -1.0}},
+			want: &journal.Performance{Outflow: pcv{usd: decimal.NewFromInt(-1)}},
 		},
 		{
 			desc: "inflow",
@@ -60,7 +60,7 @@ func TestComputeFlows(t *testing.T) {
 					Commodity: usd,
 				}.Build(),
 			}.Build(),
-			want: &journal.Performance{Inflow: pcv{usd: 1.0}},
+			want: &journal.Performance{Inflow: pcv{usd: decimal.NewFromInt(1)}},
 		},
 		{
 			desc: "dividend",
@@ -74,8 +74,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalInflow:  pcv{usd: 1.0},
-				InternalOutflow: pcv{aapl: -1.0},
+				InternalInflow:  pcv{usd: decimal.NewFromInt(1)},
+				InternalOutflow: pcv{aapl: decimal.NewFromInt(-1)},
 			},
 		},
 		{
@@ -90,8 +90,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalInflow:  pcv{aapl: 1.0},
-				InternalOutflow: pcv{usd: -1.0},
+				InternalInflow:  pcv{aapl: decimal.NewFromInt(1)},
+				InternalOutflow: pcv{usd: decimal.NewFromInt(-1)},
 			},
 		},
 		{
@@ -106,8 +106,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalOutflow: pcv{usd: -1.0},
-				PortfolioInflow: 1.0,
+				InternalOutflow: pcv{usd: decimal.NewFromInt(-1)},
+				PortfolioInflow: decimal.NewFromInt(1),
 			},
 		},
 		{
@@ -131,8 +131,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalInflow:  pcv{aapl: 1010.0},
-				InternalOutflow: pcv{usd: -1010.0},
+				InternalInflow:  pcv{aapl: decimal.NewFromInt(1010)},
+				InternalOutflow: pcv{usd: decimal.NewFromInt(-1010)},
 			},
 		},
 		{
@@ -161,8 +161,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalInflow:  pcv{aapl: 1020.0},
-				InternalOutflow: pcv{usd: -1020.0},
+				InternalInflow:  pcv{aapl: decimal.NewFromInt(1020)},
+				InternalOutflow: pcv{usd: decimal.NewFromInt(-1020)},
 			},
 		},
 		{
@@ -185,8 +185,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalInflow:  pcv{usd: 990.0},
-				InternalOutflow: pcv{aapl: -990.0},
+				InternalInflow:  pcv{usd: decimal.NewFromInt(990)},
+				InternalOutflow: pcv{aapl: decimal.NewFromInt(-990)},
 			},
 		},
 
@@ -210,8 +210,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalOutflow: pcv{gbp: -1375.0},
-				InternalInflow:  pcv{usd: 1375.0},
+				InternalOutflow: pcv{gbp: decimal.NewFromInt(-1375)},
+				InternalInflow:  pcv{usd: decimal.NewFromInt(1375)},
 			},
 		},
 		{
@@ -240,8 +240,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalOutflow: pcv{gbp: -1370.0, chf: -10},
-				InternalInflow:  pcv{usd: 1380.0},
+				InternalOutflow: pcv{gbp: decimal.NewFromInt(-1370), chf: decimal.NewFromInt(-10)},
+				InternalInflow:  pcv{usd: decimal.NewFromInt(1380)},
 			},
 		},
 		{
@@ -270,8 +270,8 @@ func TestComputeFlows(t *testing.T) {
 				}.Build(),
 			}.Build(),
 			want: &journal.Performance{
-				InternalOutflow: pcv{gbp: -1370.0},
-				InternalInflow:  pcv{usd: 1370.0},
+				InternalOutflow: pcv{gbp: decimal.NewFromInt(-1370)},
+				InternalInflow:  pcv{usd: decimal.NewFromInt(1370)},
 			},
 		},
 	}
@@ -298,3 +298,73 @@ func TestComputeFlows(t *testing.T) {
 	}
 
 }
+
+func TestPerformancePrecision(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	portfolio := reg.Accounts().MustGet("Assets:Portfolio")
+	bank := reg.Accounts().MustGet("Assets:Bank")
+	calc := Calculator{
+		AccountFilter:   predicate.ByName[*model.Account]([]*regexp.Regexp{regexp.MustCompile("Assets:Portfolio")}),
+		CommodityFilter: predicate.True[*model.Commodity],
+		Valuation:       chf,
+	}
+	deposit := func(v string) *model.Transaction {
+		return transaction.Builder{
+			Postings: posting.Builder{
+				Credit:    bank,
+				Debit:     portfolio,
+				Value:     decimal.RequireFromString(v),
+				Commodity: chf,
+			}.Build(),
+		}.Build()
+	}
+	day1 := &journal.Day{Date: date.Date(2021, 1, 1), Transactions: []*model.Transaction{deposit("999999999999999.9")}}
+	day2 := &journal.Day{Date: date.Date(2021, 1, 2)}
+	for i := 0; i < 10; i++ {
+		day2.Transactions = append(day2.Transactions, deposit("0.1"))
+	}
+	values, flows := calc.ComputeValues(), calc.ComputeFlows()
+	for _, d := range []*journal.Day{day1, day2} {
+		if err := values.Process(d); err != nil {
+			t.Fatal(err)
+		}
+		if err := flows.Process(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The same computation with float64 loses the deposits.
+	var inflow float64
+	for range day2.Transactions {
+		inflow += 0.1
+	}
+	if inflow == 1 {
+		t.Fatalf("float64 sum of deposits is exact, want a rounding error")
+	}
+	want := &journal.Performance{
+		V0:     pcv{chf: decimal.RequireFromString("999999999999999.9")},
+		V1:     pcv{chf: decimal.RequireFromString("1000000000000000.9")},
+		Inflow: pcv{chf: decimal.NewFromInt(1)},
+	}
+	if diff := cmp.Diff(want, day2.Performance); diff != "" {
+		t.Fatalf("unexpected diff (-want, +got):\n%s", diff)
+	}
+	if got := Performance(day2.Performance); !got.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Performance() = %s, want 1", got)
+	}
+}
+
+func TestPerformanceWithoutBase(t *testing.T) {
+	reg := registry.New()
+	aapl := reg.Commodities().MustGet("AAPL")
+	dpv := &journal.Performance{
+		V1: map[*model.Commodity]decimal.Decimal{aapl: decimal.NewFromInt(100)},
+	}
+
+	got := Performance(dpv)
+
+	if want := decimal.NewFromInt(1); !got.Equal(want) {
+		t.Errorf("Performance() = %s, want %s", got, want)
+	}
+}
@@ -58,3 +58,9 @@ func (un Universe) Locate(c *model.Commodity) []string {
 	}
 	return []string{"Other", c.Name()}
 }
+
+// Class returns the top-level class of the commodity, or "Other" if the
+// commodity is not classified.
+func (un Universe) Class(c *model.Commodity) string {
+	return un.Locate(c)[0]
+}
@@ -0,0 +1,83 @@
+package journal
+
+import (
+	"sync"
+
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model"
+	"golang.org/x/exp/slices"
+)
+
+// PipelineContext holds the inputs of a pipeline which processor
+// factories may need.
+type PipelineContext struct {
+	Registry  *model.Registry
+	Valuation *model.Commodity
+	Partition date.Partition
+}
+
+// ProcessorFactory creates a processor for a pipeline. It may return a
+// nil processor if the processor is not needed.
+type ProcessorFactory func(PipelineContext) (*Processor, error)
+
+type extension struct {
+	name, after string
+	factory     ProcessorFactory
+}
+
+var (
+	extensionsMu sync.Mutex
+	extensions   []extension
+)
+
+// RegisterProcessor registers a processor factory under the given name.
+// Every pipeline built afterwards contains the processor created by the
+// factory directly after the stage named after, or at the end if the
+// pipeline has no such stage. It is meant to be called from init
+// functions.
+func RegisterProcessor(name, after string, f ProcessorFactory) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	extensions = append(extensions, extension{name: name, after: after, factory: f})
+}
+
+// Pipeline is an ordered list of named processors.
+type Pipeline struct {
+	Context PipelineContext
+
+	names []string
+	procs []*Processor
+}
+
+// Add appends a named processor to the pipeline.
+func (p *Pipeline) Add(name string, proc *Processor) *Pipeline {
+	p.names = append(p.names, name)
+	p.procs = append(p.procs, proc)
+	return p
+}
+
+// Build returns the processors of the pipeline, including the registered
+// ones. Every processor is observed by the explainer, which may be nil.
+func (p *Pipeline) Build(e *Explainer) ([]*Processor, error) {
+	names, procs := slices.Clone(p.names), slices.Clone(p.procs)
+	extensionsMu.Lock()
+	exts := slices.Clone(extensions)
+	extensionsMu.Unlock()
+	for _, ext := range exts {
+		proc, err := ext.factory(p.Context)
+		if err != nil {
+			return nil, err
+		}
+		pos := slices.Index(names, ext.after) + 1
+		if pos == 0 {
+			pos = len(names)
+		}
+		names = slices.Insert(names, pos, ext.name)
+		procs = slices.Insert(procs, pos, proc)
+	}
+	res := make([]*Processor, 0, len(procs))
+	for i, proc := range procs {
+		res = append(res, e.Observe(names[i], proc))
+	}
+	return res, nil
+}
@@ -0,0 +1,44 @@
+package journal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPipelineBuild(t *testing.T) {
+	defer func(exts []extension) { extensions = exts }(extensions)
+	extensions = nil
+
+	var got []string
+	proc := func(name string) *Processor {
+		return &Processor{
+			DayStart: func(*Day) error {
+				got = append(got, name)
+				return nil
+			},
+		}
+	}
+	factory := func(name string) ProcessorFactory {
+		return func(PipelineContext) (*Processor, error) { return proc(name), nil }
+	}
+	RegisterProcessor("x", "a", factory("x"))
+	RegisterProcessor("y", "unknown", factory("y"))
+	RegisterProcessor("z", "x", factory("z"))
+
+	var p Pipeline
+	p.Add("a", proc("a")).Add("b", proc("b"))
+	procs, err := p.Build(nil)
+	if err != nil {
+		t.Fatalf("p.Build() returned unexpected error: %v", err)
+	}
+	for _, proc := range procs {
+		if err := proc.Process(new(Day)); err != nil {
+			t.Fatalf("proc.Process() returned unexpected error: %v", err)
+		}
+	}
+
+	if diff := cmp.Diff([]string{"a", "x", "z", "b", "y"}, got); diff != "" {
+		t.Errorf("p.Build() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
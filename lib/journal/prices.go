@@ -0,0 +1,103 @@
+package journal
+
+import (
+	"fmt"
+
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/price"
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// PriceConflict is an error about two price directives which give
+// different prices for the same pair of commodities on the same day,
+// either in the same direction or inversely. First comes before Second in
+// the journal.
+type PriceConflict struct {
+	First, Second *model.Price
+}
+
+func (pc PriceConflict) Error() string {
+	return fmt.Sprintf("conflicting prices on %s: %s at %s and %s at %s",
+		pc.First.Date.Format("2006-01-02"),
+		describePrice(pc.First), priceRange(pc.First).Position(),
+		describePrice(pc.Second), priceRange(pc.Second).Position())
+}
+
+func describePrice(p *model.Price) string {
+	return fmt.Sprintf("%s %s %s", p.Commodity.Name(), p.Price, p.Target.Name())
+}
+
+func priceRange(p *model.Price) syntax.Range {
+	if p.Src == nil {
+		return syntax.Range{}
+	}
+	return p.Src.Range
+}
+
+// comparePrices orders prices by their position in the journal. Prices
+// without a source come last.
+func (j *Builder) comparePrices(p1, p2 *model.Price) compare.Order {
+	switch {
+	case p1.Src == nil && p2.Src == nil:
+		return compare.Equal
+	case p1.Src == nil:
+		return compare.Greater
+	case p2.Src == nil:
+		return compare.Smaller
+	}
+	return j.compareRanges(p1.Src.Range, p2.Src.Range)
+}
+
+// pricePair is a pair of commodities, regardless of the direction of the
+// price.
+type pricePair struct {
+	commodity, target *model.Commodity
+}
+
+func pairOf(p *model.Price) pricePair {
+	if p.Commodity.Name() > p.Target.Name() {
+		return pricePair{p.Target, p.Commodity}
+	}
+	return pricePair{p.Commodity, p.Target}
+}
+
+// samePrice returns whether two prices for the same pair of commodities
+// agree. An inverse price agrees if it is the inverse which prices are
+// stored with.
+func samePrice(p1, p2 *model.Price) bool {
+	if p1.Commodity == p2.Commodity {
+		return p1.Price.Equal(p2.Price)
+	}
+	return p1.Price.Equal(price.Inverse(p2.Price)) || p2.Price.Equal(price.Inverse(p1.Price))
+}
+
+// ResolvePrices keeps the last of the price directives for the same pair
+// of commodities on each day, in either direction and in the order of the
+// journal, and drops the others. Each dropped price which differs from
+// the kept one is reported to warn.
+func (j *Builder) ResolvePrices(warn func(PriceConflict)) {
+	for _, d := range dict.SortedValues(j.days, CompareDays) {
+		if len(d.Prices) < 2 {
+			continue
+		}
+		compare.Sort(d.Prices, j.comparePrices)
+		index := make(map[pricePair]int)
+		var res []*model.Price
+		for _, p := range d.Prices {
+			k := pairOf(p)
+			i, ok := index[k]
+			if !ok {
+				index[k] = len(res)
+				res = append(res, p)
+				continue
+			}
+			if !samePrice(res[i], p) {
+				warn(PriceConflict{First: res[i], Second: p})
+			}
+			res[i] = p
+		}
+		d.Prices = res
+	}
+}
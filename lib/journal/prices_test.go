@@ -0,0 +1,131 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
+)
+
+func TestConflictingPrices(t *testing.T) {
+	tests := []struct {
+		desc string
+		// files maps file names to their text. The journal is main.knut.
+		files map[string]string
+		// want is the error of ComputePrices, with %[1]s standing for
+		// the directory.
+		want string
+		// wantWarnings is the number of warnings of ResolvePrices.
+		wantWarnings int
+		// wantPrice is the price of USD in CHF on 2020-01-01 if the last
+		// price wins.
+		wantPrice string
+	}{
+		{
+			desc: "same direction",
+			files: map[string]string{
+				"main.knut": "2020-01-01 price USD 0.9 CHF\n2020-01-01 price USD 0.9 CHF\n2020-01-01 price USD 0.95 CHF\n2020-01-02 price USD 0.8 CHF\n",
+			},
+			want:         "conflicting prices on 2020-01-01: USD 0.9 CHF at %[1]s/main.knut:2:1 and USD 0.95 CHF at %[1]s/main.knut:3:1",
+			wantWarnings: 1,
+			wantPrice:    "0.95",
+		},
+		{
+			desc: "inverse",
+			files: map[string]string{
+				"main.knut": "2020-01-01 price USD 0.8 CHF\n2020-01-01 price CHF 1.2 USD\n",
+			},
+			want:         "conflicting prices on 2020-01-01: USD 0.8 CHF at %[1]s/main.knut:1:1 and CHF 1.2 USD at %[1]s/main.knut:2:1",
+			wantWarnings: 1,
+			wantPrice:    "0.83333333",
+		},
+		{
+			desc: "consistent inverse",
+			files: map[string]string{
+				"main.knut": "2020-01-01 price USD 0.8 CHF\n2020-01-01 price CHF 1.25 USD\n",
+			},
+			wantPrice: "0.8",
+		},
+		{
+			desc: "include order",
+			files: map[string]string{
+				"main.knut": "2020-01-01 price USD 0.9 CHF\ninclude \"a.knut\"\n",
+				"a.knut":    "2020-01-01 price USD 0.95 CHF\n",
+			},
+			want:         "conflicting prices on 2020-01-01: USD 0.9 CHF at %[1]s/main.knut:1:1 and USD 0.95 CHF at %[1]s/a.knut:1:1",
+			wantWarnings: 1,
+			wantPrice:    "0.95",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, text := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := filepath.Join(dir, "main.knut")
+
+			t.Run("fail", func(t *testing.T) {
+				reg := registry.New()
+				b, err := FromPath(context.Background(), reg, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = b.Build().Process(ComputePrices(reg.Commodities().MustGet("CHF")))
+
+				var got string
+				if pc := (PriceConflict{}); errors.As(err, &pc) {
+					got = pc.Error()
+				} else if err != nil {
+					t.Fatalf("ComputePrices() returned unexpected error: %v", err)
+				}
+				var want string
+				if test.want != "" {
+					want = fmt.Sprintf(test.want, dir)
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("ComputePrices() returned unexpected diff (-want/+got):\n%s\n", diff)
+				}
+			})
+
+			t.Run("last wins", func(t *testing.T) {
+				reg := registry.New()
+				chf, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD")
+				b, err := FromPath(context.Background(), reg, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var warnings []string
+				b.ResolvePrices(func(pc PriceConflict) {
+					warnings = append(warnings, pc.Error())
+				})
+				j := b.Build()
+
+				if err := j.Process(ComputePrices(chf)); err != nil {
+					t.Fatal(err)
+				}
+
+				if len(warnings) != test.wantWarnings {
+					t.Errorf("ResolvePrices() warned %v, want %d warnings", warnings, test.wantWarnings)
+				}
+				for _, d := range j.Days {
+					if d.Date.Equal(date.Date(2020, 1, 1)) {
+						if got, want := d.Normalized[usd], decimal.RequireFromString(test.wantPrice); !got.Equal(want) {
+							t.Errorf("price of USD = %s, want %s", got, want)
+						}
+					}
+				}
+			})
+		})
+	}
+}
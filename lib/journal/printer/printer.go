@@ -18,9 +18,12 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 	"unicode/utf8"
 
+	"github.com/sboehler/knut/lib/common/date"
 	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
 )
 
 // Printer prints directives.
@@ -28,6 +31,11 @@ type Printer struct {
 	writer  io.Writer
 	padding int
 	count   int
+
+	// Annotate adds a trailing comment to open and close directives with
+	// the first or last activity of the account, respectively.
+	Annotate bool
+	activity map[*model.Account]date.Period
 }
 
 // New creates a new Printer.
@@ -79,7 +87,23 @@ func (p *Printer) printTransaction(t *model.Transaction) (n int, err error) {
 			return p.count - start, err
 		}
 	}
-	if _, err := fmt.Fprintf(p, "%s \"%s\"", t.Date.Format("2006-01-02"), t.Description); err != nil {
+	if _, err := io.WriteString(p, t.Date.Format("2006-01-02")); err != nil {
+		return p.count - start, err
+	}
+	if !t.Effective.IsZero() {
+		if _, err := io.WriteString(p, t.Effective.Format("=2006-01-02")); err != nil {
+			return p.count - start, err
+		}
+	}
+	if t.Time != 0 {
+		if _, err := io.WriteString(p, t.Date.Add(t.Time).Format(" 15:04")); err != nil {
+			return p.count - start, err
+		}
+	}
+	if _, err := fmt.Fprintf(p, " \"%s\"", t.Description); err != nil {
+		return p.count - start, err
+	}
+	if _, err := p.printTags(t.Tags); err != nil {
 		return p.count - start, err
 	}
 	if _, err := io.WriteString(p, "\n"); err != nil {
@@ -100,15 +124,84 @@ func (p *Printer) printTransaction(t *model.Transaction) (n int, err error) {
 }
 
 func (p *Printer) printPosting(t *model.Posting) (int, error) {
-	return fmt.Fprintf(p, "%-*s %-*s %10s %s", p.padding, t.Other.String(), p.padding, t.Account.String(), t.Quantity.String(), t.Commodity.Name())
+	start := p.count
+	if t.State != posting.Unmarked {
+		if _, err := fmt.Fprintf(p, "%s ", t.State); err != nil {
+			return p.count - start, err
+		}
+	}
+	credit, debit := t.Other.String(), t.Account.String()
+	if t.Virtual {
+		credit, debit = "("+credit+")", "("+debit+")"
+	}
+	if _, err := fmt.Fprintf(p, "%-*s %-*s %10s %s", p.padding, credit, p.padding, debit, t.Quantity.String(), t.Commodity.Name()); err != nil {
+		return p.count - start, err
+	}
+	if _, err := p.printTags(t.Tags); err != nil {
+		return p.count - start, err
+	}
+	return p.count - start, nil
+}
+
+func (p *Printer) printTags(ts []model.Tag) (int, error) {
+	start := p.count
+	for _, t := range ts {
+		if _, err := fmt.Fprintf(p, " #%s", t); err != nil {
+			return p.count - start, err
+		}
+	}
+	return p.count - start, nil
 }
 
 func (p *Printer) printOpen(o *model.Open) (int, error) {
-	return fmt.Fprintf(p, "%s open %s", o.Date.Format("2006-01-02"), o.Account)
+	start := p.count
+	if _, err := fmt.Fprintf(p, "%s open %s", o.Date.Format("2006-01-02"), o.Account); err != nil {
+		return p.count - start, err
+	}
+	for _, c := range o.Commodities {
+		if _, err := fmt.Fprintf(p, " %s", c.Name()); err != nil {
+			return p.count - start, err
+		}
+	}
+	if o.Note != "" {
+		if _, err := fmt.Fprintf(p, ` "%s"`, o.Note); err != nil {
+			return p.count - start, err
+		}
+	}
+	if !p.Annotate {
+		return p.count - start, nil
+	}
+	if _, err := p.printActivity(o.Account, "first", func(a date.Period) time.Time { return a.Start }); err != nil {
+		return p.count - start, err
+	}
+	return p.count - start, nil
 }
 
 func (p *Printer) printClose(c *model.Close) (int, error) {
-	return fmt.Fprintf(p, "%s close %s", c.Date.Format("2006-01-02"), c.Account)
+	start := p.count
+	if _, err := fmt.Fprintf(p, "%s close %s", c.Date.Format("2006-01-02"), c.Account); err != nil {
+		return p.count - start, err
+	}
+	if c.Note != "" {
+		if _, err := fmt.Fprintf(p, ` "%s"`, c.Note); err != nil {
+			return p.count - start, err
+		}
+	}
+	if !p.Annotate {
+		return p.count - start, nil
+	}
+	if _, err := p.printActivity(c.Account, "last", func(a date.Period) time.Time { return a.End }); err != nil {
+		return p.count - start, err
+	}
+	return p.count - start, nil
+}
+
+func (p *Printer) printActivity(a *model.Account, label string, f func(date.Period) time.Time) (int, error) {
+	activity, ok := p.activity[a]
+	if !ok {
+		return io.WriteString(p, " // no activity")
+	}
+	return fmt.Fprintf(p, " // %s activity on %s", label, f(activity).Format("2006-01-02"))
 }
 
 func (p *Printer) printPrice(pr *model.Price) (int, error) {
@@ -144,9 +237,30 @@ func (p *Printer) Initialize(directive []model.Directive) {
 	}
 }
 
+// UpdateActivity records the date of the given transaction as an
+// activity of the accounts it books to.
+func (p *Printer) UpdateActivity(t *model.Transaction) {
+	if p.activity == nil {
+		p.activity = make(map[*model.Account]date.Period)
+	}
+	for _, pt := range t.Postings {
+		activity, ok := p.activity[pt.Account]
+		if !ok || t.Date.Before(activity.Start) {
+			activity.Start = t.Date
+		}
+		if !ok || t.Date.After(activity.End) {
+			activity.End = t.Date
+		}
+		p.activity[pt.Account] = activity
+	}
+}
+
 func (p *Printer) UpdatePadding(t *model.Transaction) {
 	for _, pt := range t.Postings {
 		cr, dr := utf8.RuneCountInString(pt.Account.String()), utf8.RuneCountInString(pt.Other.String())
+		if pt.Virtual {
+			cr, dr = cr+2, dr+2
+		}
 		if p.padding < cr {
 			p.padding = cr
 		}
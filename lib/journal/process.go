@@ -4,27 +4,45 @@ import (
 	"fmt"
 
 	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/amounts/expr"
 	"github.com/sboehler/knut/lib/common/compare"
 	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/dict"
 	"github.com/sboehler/knut/lib/common/mapper"
 	"github.com/sboehler/knut/lib/common/predicate"
+	"github.com/sboehler/knut/lib/common/regex"
 	"github.com/sboehler/knut/lib/common/set"
 	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/commodity"
 	"github.com/sboehler/knut/lib/model/posting"
 	"github.com/sboehler/knut/lib/model/price"
+	"github.com/sboehler/knut/lib/model/tag"
 	"github.com/sboehler/knut/lib/model/transaction"
 	"github.com/shopspring/decimal"
+	"golang.org/x/exp/slices"
 )
 
-// ComputePrices updates prices.
+// ComputePrices updates prices. It fails with a PriceConflict if a day
+// has different prices for the same pair of commodities, in either
+// direction.
 func ComputePrices(v *model.Commodity) *Processor {
 	if v == nil {
 		return nil
 	}
 	var previous price.NormalizedPrices
 	prc := make(price.Prices)
+	var day map[pricePair]*model.Price
 	return &Processor{
+		DayStart: func(d *Day) error {
+			day = make(map[pricePair]*model.Price)
+			return nil
+		},
 		Price: func(p *model.Price) error {
+			k := pairOf(p)
+			if prev, ok := day[k]; ok && !samePrice(prev, p) {
+				return PriceConflict{First: prev, Second: p}
+			}
+			day[k] = p
 			prc.Insert(p.Commodity, p.Price, p.Target)
 			return nil
 		},
@@ -38,7 +56,76 @@ func ComputePrices(v *model.Commodity) *Processor {
 	}
 }
 
-// Balance balances the journal.
+// ImplyPrices adds the prices implied by transactions which exchange two
+// commodities, for example the purchase of a stock, to the journal. If
+// there are several such transactions for a pair of commodities on a day,
+// the price is their weighted average. Explicit price directives on the
+// same day take precedence. The processor must run before ComputePrices.
+func ImplyPrices(enable bool) *Processor {
+	if !enable {
+		return nil
+	}
+	type pair struct{ commodity, target *model.Commodity }
+	comparePairs := func(p1, p2 pair) compare.Order {
+		if o := commodity.Compare(p1.commodity, p2.commodity); o != compare.Equal {
+			return o
+		}
+		return commodity.Compare(p1.target, p2.target)
+	}
+	return &Processor{
+		DayStart: func(d *Day) error {
+			explicit := set.New[pair]()
+			for _, p := range d.Prices {
+				explicit.Add(pair{p.Commodity, p.Target})
+				explicit.Add(pair{p.Target, p.Commodity})
+			}
+			quantities, totals := make(map[pair]decimal.Decimal), make(map[pair]decimal.Decimal)
+			for _, t := range d.Transactions {
+				flows := make(map[*model.Commodity]decimal.Decimal)
+				for _, p := range t.Postings {
+					if p.Account.IsAL() {
+						flows[p.Commodity] = flows[p.Commodity].Add(p.Quantity)
+					}
+				}
+				var cs []*model.Commodity
+				for c, f := range flows {
+					if !f.IsZero() {
+						cs = append(cs, c)
+					}
+				}
+				if len(cs) != 2 || flows[cs[0]].Sign() == flows[cs[1]].Sign() {
+					continue
+				}
+				if commodity.Compare(cs[0], cs[1]) == compare.Greater {
+					cs[0], cs[1] = cs[1], cs[0]
+				}
+				k := pair{cs[0], cs[1]}
+				if explicit.Has(k) {
+					continue
+				}
+				quantities[k] = quantities[k].Add(flows[k.commodity].Abs())
+				totals[k] = totals[k].Add(flows[k.target].Abs())
+			}
+			for _, k := range dict.SortedKeys(quantities, comparePairs) {
+				d.Prices = append(d.Prices, &model.Price{
+					Date:      d.Date,
+					Commodity: k.commodity,
+					Target:    k.target,
+					Price:     totals[k].Div(quantities[k]),
+				})
+			}
+			return nil
+		},
+	}
+}
+
+// Valuate computes the value of all postings in the valuation commodity.
+// Valuation gains and losses of asset and liability positions are booked
+// daily to the valuation accounts. If the valuation commodity has a
+// precision, values are rounded to it, and the rounding residuals, which
+// keep the value of each position at its rounded market value, are booked
+// to the rounding account. Virtual postings are valued, but their
+// positions are not adjusted.
 func Valuate(reg *model.Registry, valuation *model.Commodity) *Processor {
 	if valuation == nil {
 		return nil
@@ -46,6 +133,28 @@ func Valuate(reg *model.Registry, valuation *model.Commodity) *Processor {
 
 	var prevPrices, prices price.NormalizedPrices
 	quantities := make(amounts.Amounts)
+	values := make(amounts.Amounts)
+	places, rounded := valuation.Precision()
+	round := func(d decimal.Decimal) decimal.Decimal {
+		if rounded {
+			return d.Round(places)
+		}
+		return d
+	}
+	adjust := func(d *Day, desc string, pos amounts.Key, credit *model.Account, value decimal.Decimal) {
+		values.Add(pos, value)
+		d.Transactions = append(d.Transactions, transaction.Builder{
+			Date:        d.Date,
+			Description: fmt.Sprintf("%s of %s in account %s", desc, pos.Commodity.Name(), pos.Account.Name()),
+			Postings: posting.Builder{
+				Credit:    credit,
+				Debit:     pos.Account,
+				Commodity: pos.Commodity,
+				Value:     value,
+			}.Build(),
+			Targets: []*model.Commodity{pos.Commodity},
+		}.Build())
+	}
 
 	return &Processor{
 
@@ -59,34 +168,32 @@ func Valuate(reg *model.Registry, valuation *model.Commodity) *Processor {
 				if !pos.Account.IsAL() {
 					continue
 				}
-				if qty.IsZero() {
+				if qty.IsZero() && (!rounded || values[pos].IsZero()) {
 					continue
 				}
-				prevPrice, err := prevPrices.Price(pos.Commodity)
-				if err != nil {
-					return err
-				}
 				currentPrice, err := prices.Price(pos.Commodity)
 				if err != nil {
 					return err
 				}
-				delta := currentPrice.Sub(prevPrice)
-				if delta.IsZero() {
+				if !qty.IsZero() {
+					prevPrice, err := prevPrices.Price(pos.Commodity)
+					if err != nil {
+						return err
+					}
+					if gain := round(price.Multiply(currentPrice.Sub(prevPrice), qty)); !gain.IsZero() {
+						credit, err := reg.Accounts().ValuationAccountFor(pos.Account)
+						if err != nil {
+							return err
+						}
+						adjust(d, "Adjust value", pos, credit, gain)
+					}
+				}
+				if !rounded {
 					continue
 				}
-				gain := price.Multiply(delta, qty)
-				credit := reg.Accounts().ValuationAccountFor(pos.Account)
-				d.Transactions = append(d.Transactions, transaction.Builder{
-					Date:        d.Date,
-					Description: fmt.Sprintf("Adjust value of %s in account %s", pos.Commodity.Name(), pos.Account.Name()),
-					Postings: posting.Builder{
-						Credit:    credit,
-						Debit:     pos.Account,
-						Commodity: pos.Commodity,
-						Value:     gain,
-					}.Build(),
-					Targets: []*model.Commodity{pos.Commodity},
-				}.Build())
+				if residual := round(price.Multiply(currentPrice, qty)).Sub(values[pos]); !residual.IsZero() {
+					adjust(d, "Adjust rounding", pos, reg.Accounts().RoundingAccount(), residual)
+				}
 			}
 			return nil
 		},
@@ -95,18 +202,20 @@ func Valuate(reg *model.Registry, valuation *model.Commodity) *Processor {
 			if p.Quantity.IsZero() {
 				return nil
 			}
-			if p.Account.IsAL() {
-				quantities.Add(amounts.AccountCommodityKey(p.Account, p.Commodity), p.Quantity)
-			}
 			if valuation == p.Commodity {
 				p.Value = p.Quantity
-				return nil
+			} else {
+				v, err := prices.Valuate(p.Commodity, p.Quantity)
+				if err != nil {
+					return err
+				}
+				p.Value = round(v)
 			}
-			v, err := prices.Valuate(p.Commodity, p.Quantity)
-			if err != nil {
-				return err
+			if p.Account.IsAL() && !p.Virtual {
+				key := amounts.AccountCommodityKey(p.Account, p.Commodity)
+				quantities.Add(key, p.Quantity)
+				values.Add(key, p.Value)
 			}
-			p.Value = v
 			return nil
 		},
 
@@ -128,48 +237,79 @@ func Filter(part date.Partition) *Processor {
 	}
 }
 
-// Balance balances the journal.
+// closingKey identifies the amounts which are closed together. The tags
+// are those of the closed postings and their transactions, such that
+// the closings can be grouped and filtered by tag like the postings.
+type closingKey struct {
+	account   *model.Account
+	commodity *model.Commodity
+	tags      string
+	virtual   bool
+}
+
+type closing struct {
+	quantity, value decimal.Decimal
+	tags            []model.Tag
+}
+
+// CloseAccounts closes the income and expense accounts into the equity
+// account at the start of each period.
 func CloseAccounts(j *Builder, reg *model.Registry, enable bool, partition date.Partition) *Processor {
 	if !enable {
 		return nil
 	}
 	closingDays := set.FromSlice(j.Days(partition.StartDates()))
-	equityAccount := reg.Accounts().MustGet("Equity:Equity")
+	equityAccount := reg.Accounts().EquityAccount()
 
-	quantities, values := make(amounts.Amounts), make(amounts.Amounts)
+	// Virtual postings are closed separately, by virtual postings.
+	closings := make(map[closingKey]*closing)
 
 	return &Processor{
 		DayStart: func(d *Day) error {
 			if !closingDays.Has(d) {
 				return nil
 			}
-			for k, quantity := range quantities {
-				if quantity.IsZero() && values[k].IsZero() {
+			for k, c := range closings {
+				if c.quantity.IsZero() && c.value.IsZero() {
 					continue
 				}
 				d.Transactions = append(d.Transactions, transaction.Builder{
 					Date:        d.Date,
-					Description: fmt.Sprintf("Closing account %s in %s", k.Account.Name(), k.Commodity.Name()),
+					Description: fmt.Sprintf("Closing account %s in %s", k.account.Name(), k.commodity.Name()),
 					Postings: posting.Builder{
-						Credit:    k.Account,
+						Credit:    k.account,
 						Debit:     equityAccount,
-						Commodity: k.Commodity,
-						Quantity:  quantity,
-						Value:     values[k],
+						Commodity: k.commodity,
+						Quantity:  c.quantity,
+						Value:     c.value,
+						Tags:      c.tags,
+						Virtual:   k.virtual,
 					}.Build(),
 				}.Build())
 			}
 			return nil
 		},
-		Posting: func(_ *model.Transaction, p *model.Posting) error {
+		Posting: func(t *model.Transaction, p *model.Posting) error {
 			if p.Account.IsAL() {
 				return nil
 			}
 			if p.Account == equityAccount {
 				return nil
 			}
-			quantities.Add(amounts.AccountCommodityKey(p.Account, p.Commodity), p.Quantity)
-			values.Add(amounts.AccountCommodityKey(p.Account, p.Commodity), p.Value)
+			tags := tag.Merge(p.Tags, t.Tags)
+			k := closingKey{
+				account:   p.Account,
+				commodity: p.Commodity,
+				tags:      fmt.Sprint(tags),
+				virtual:   p.Virtual,
+			}
+			c, ok := closings[k]
+			if !ok {
+				c = &closing{tags: tags}
+				closings[k] = c
+			}
+			c.quantity = c.quantity.Add(p.Quantity)
+			c.value = c.value.Add(p.Value)
 			return nil
 		},
 	}
@@ -185,6 +325,70 @@ func Sort() *Processor {
 	}
 }
 
+// GroupPostings merges the bookings of each transaction which move the
+// same commodity between the same two accounts into a single booking,
+// netting bookings in opposite directions. Both postings of a booking are
+// merged alike, so transactions stay balanced. Bookings which cancel out
+// are dropped. The merged booking keeps the state of the first booking
+// and the tags of all of them.
+func GroupPostings(enable bool) *Processor {
+	if !enable {
+		return nil
+	}
+	type key struct {
+		credit, debit *model.Account
+		commodity     *model.Commodity
+		virtual       bool
+	}
+	return &Processor{
+		Transaction: func(t *model.Transaction) error {
+			var builders []*posting.Builder
+			index := make(map[key]*posting.Builder)
+			// Postings come in pairs, the second one being the debit.
+			for i := 1; i < len(t.Postings); i += 2 {
+				p := t.Postings[i]
+				k := key{credit: p.Other, debit: p.Account, commodity: p.Commodity, virtual: p.Virtual}
+				qty, val := p.Quantity, p.Value
+				if _, ok := index[k]; !ok {
+					rev := key{credit: p.Account, debit: p.Other, commodity: p.Commodity, virtual: p.Virtual}
+					if _, ok := index[rev]; ok {
+						k, qty, val = rev, qty.Neg(), val.Neg()
+					}
+				}
+				b, ok := index[k]
+				if !ok {
+					b = &posting.Builder{
+						Src:       p.Src,
+						State:     p.State,
+						Credit:    k.credit,
+						Debit:     k.debit,
+						Commodity: p.Commodity,
+						Virtual:   p.Virtual,
+					}
+					index[k] = b
+					builders = append(builders, b)
+				}
+				b.Quantity = b.Quantity.Add(qty)
+				b.Value = b.Value.Add(val)
+				for _, tg := range p.Tags {
+					if !slices.Contains(b.Tags, tg) {
+						b.Tags = append(b.Tags, tg)
+					}
+				}
+			}
+			postings := make([]*model.Posting, 0, 2*len(builders))
+			for _, b := range builders {
+				if b.Quantity.IsZero() && b.Value.IsZero() {
+					continue
+				}
+				postings = append(postings, b.Build()...)
+			}
+			t.Postings = postings
+			return nil
+		},
+	}
+}
+
 type Collection interface {
 	Insert(k amounts.Key, v decimal.Decimal)
 }
@@ -193,6 +397,25 @@ type Query struct {
 	Select    mapper.Mapper[amounts.Key]
 	Where     predicate.Predicate[amounts.Key]
 	Valuation *model.Commodity
+
+	// Tags restricts the query to postings which are tagged with a
+	// matching tag, either on the posting itself or on its transaction.
+	Tags regex.Regexes
+
+	// States restricts the query to postings in one of the given states.
+	States []posting.State
+
+	// Virtual includes virtual postings, which are excluded otherwise.
+	Virtual bool
+
+	// GroupTags sets the tag of the keys to the first tag of the posting
+	// or its transaction which matches the regexes, or to tag.Untagged.
+	// The tag is not set if GroupTags is empty.
+	GroupTags regex.Regexes
+
+	// Filter restricts the query to postings matching the expression, if
+	// it is not nil. The amount is the value if a valuation is given.
+	Filter predicate.Predicate[expr.Posting]
 }
 
 func (query Query) Into(c Collection) *Processor {
@@ -202,8 +425,19 @@ func (query Query) Into(c Collection) *Processor {
 	if query.Select == nil {
 		query.Select = mapper.Identity[amounts.Key]
 	}
+	tagged := tag.Matches(query.Tags)
+	extract := tag.Extract(query.GroupTags)
 	return &Processor{
 		Posting: func(t *model.Transaction, b *model.Posting) error {
+			if !tagged(b.Tags) && !tagged(t.Tags) {
+				return nil
+			}
+			if len(query.States) > 0 && !slices.Contains(query.States, b.State) {
+				return nil
+			}
+			if b.Virtual && !query.Virtual {
+				return nil
+			}
 			amount := b.Quantity
 			if query.Valuation != nil {
 				amount = b.Value
@@ -216,6 +450,12 @@ func (query Query) Into(c Collection) *Processor {
 				Valuation:   query.Valuation,
 				Description: t.Description,
 			}
+			if len(query.GroupTags) > 0 {
+				key.Tag = extract(b.Tags, t.Tags)
+			}
+			if query.Filter != nil && !query.Filter(expr.Posting{Key: key, Amount: amount}) {
+				return nil
+			}
 			if query.Where(key) {
 				c.Insert(query.Select(key), amount)
 			}
@@ -0,0 +1,345 @@
+package journal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/common/mapper"
+	"github.com/sboehler/knut/lib/common/regex"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/price"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+// priceJournal creates a journal with a day for every day of the given
+// number of years and weekly prices for a few commodities.
+func priceJournal(reg *model.Registry, years int) *Builder {
+	chf := reg.Commodities().MustGet("CHF")
+	var commodities []*model.Commodity
+	for i := 0; i < 5; i++ {
+		commodities = append(commodities, reg.Commodities().MustGet(fmt.Sprintf("COM%d", i)))
+	}
+	j := New()
+	start := date.Date(2015, 1, 1)
+	for d := start; d.Before(start.AddDate(years, 0, 0)); d = d.AddDate(0, 0, 1) {
+		j.Day(d)
+		if d.Weekday() != 5 {
+			continue
+		}
+		for i, c := range commodities {
+			j.Add(&model.Price{
+				Date:      d,
+				Commodity: c,
+				Target:    chf,
+				Price:     decimal.NewFromInt(int64(d.YearDay() + i)),
+			})
+		}
+	}
+	return j
+}
+
+// normalizeDaily computes the normalized prices on every day, without
+// reusing the prices of the previous day.
+func normalizeDaily(v *model.Commodity, f func(*Day, price.NormalizedPrices)) *Processor {
+	prc := make(price.Prices)
+	return &Processor{
+		Price: func(p *model.Price) error {
+			prc.Insert(p.Commodity, p.Price, p.Target)
+			return nil
+		},
+		DayEnd: func(d *Day) error {
+			f(d, prc.Normalize(v))
+			return nil
+		},
+	}
+}
+
+func TestComputePrices(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	j := priceJournal(reg, 2)
+	var days, priced int
+
+	err := j.Build().Process(
+		ComputePrices(chf),
+		normalizeDaily(chf, func(d *Day, want price.NormalizedPrices) {
+			days++
+			priced += len(d.Prices)
+			if priced == 0 {
+				// ComputePrices leaves the prices empty until the first
+				// price directive.
+				return
+			}
+			if diff := cmp.Diff(want, d.Normalized); diff != "" {
+				t.Errorf("%s: ComputePrices() returned unexpected diff (-want/+got):\n%s\n", d.Date.Format("2006-01-02"), diff)
+			}
+		}),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if days != 731 {
+		t.Errorf("processed %d days, want 731", days)
+	}
+}
+
+func BenchmarkComputePrices(b *testing.B) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	j := priceJournal(reg, 10).Build()
+	b.Run("reuse", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := j.Process(ComputePrices(chf)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("daily", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := j.Process(normalizeDaily(chf, func(d *Day, np price.NormalizedPrices) { d.Normalized = np })); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestGroupPostings(t *testing.T) {
+	reg := registry.New()
+	chf, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD")
+	bank, food, rent := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Expenses:Food"), reg.Accounts().MustGet("Expenses:Rent")
+	trx := transaction.Builder{
+		Date: date.Date(2020, 1, 1),
+		Postings: posting.Builders{
+			{Credit: bank, Debit: food, Commodity: chf, Quantity: decimal.NewFromInt(10), Tags: []model.Tag{"a"}},
+			{Credit: bank, Debit: rent, Commodity: chf, Quantity: decimal.NewFromInt(7)},
+			{Credit: bank, Debit: food, Commodity: usd, Quantity: decimal.NewFromInt(10)},
+			{Credit: bank, Debit: food, Commodity: chf, Quantity: decimal.NewFromInt(5), Tags: []model.Tag{"b", "a"}},
+			{Credit: food, Debit: bank, Commodity: chf, Quantity: decimal.NewFromInt(3)},
+			{Credit: rent, Debit: bank, Commodity: chf, Quantity: decimal.NewFromInt(7)},
+		}.Build(),
+	}.Build()
+
+	if err := GroupPostings(true).Transaction(trx); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range trx.Postings {
+		got = append(got, fmt.Sprintf("%s %s %s %s %v", p.Account, p.Other, p.Quantity, p.Commodity.Name(), p.Tags))
+	}
+	want := []string{
+		"Assets:Bank Expenses:Food -12 CHF [a b]",
+		"Expenses:Food Assets:Bank 12 CHF [a b]",
+		"Assets:Bank Expenses:Food -10 USD []",
+		"Expenses:Food Assets:Bank 10 USD []",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GroupPostings() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if GroupPostings(false) != nil {
+		t.Errorf("GroupPostings(false) returned a processor, want nil")
+	}
+}
+
+func TestImplyPrices(t *testing.T) {
+	reg := registry.New()
+	chf, eur, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("EUR"), reg.Commodities().MustGet("USD")
+	bank, equity := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().EquityAccount()
+	exchange := func(qs map[*model.Commodity]int64) *model.Transaction {
+		var ps posting.Builders
+		for _, c := range []*model.Commodity{chf, eur, usd} {
+			if q, ok := qs[c]; ok {
+				ps = append(ps, posting.Builder{Credit: equity, Debit: bank, Commodity: c, Quantity: decimal.NewFromInt(q)})
+			}
+		}
+		return transaction.Builder{Date: date.Date(2020, 1, 1), Postings: ps.Build()}.Build()
+	}
+	tests := []struct {
+		desc   string
+		trx    []*model.Transaction
+		prices []*model.Price
+		want   []string
+	}{
+		{
+			desc: "exchange",
+			trx:  []*model.Transaction{exchange(map[*model.Commodity]int64{chf: -200, usd: 100})},
+			want: []string{"2020-01-01 CHF 0.5 USD"},
+		},
+		{
+			desc: "several exchanges",
+			trx: []*model.Transaction{
+				exchange(map[*model.Commodity]int64{chf: -200, usd: 100}),
+				exchange(map[*model.Commodity]int64{chf: 200, usd: -300}),
+			},
+			want: []string{"2020-01-01 CHF 1 USD"},
+		},
+		{
+			desc:   "explicit price",
+			trx:    []*model.Transaction{exchange(map[*model.Commodity]int64{chf: -200, usd: 100})},
+			prices: []*model.Price{{Date: date.Date(2020, 1, 1), Commodity: usd, Target: chf, Price: decimal.RequireFromString("2.1")}},
+			want:   []string{"2020-01-01 USD 2.1 CHF"},
+		},
+		{
+			desc: "same sign",
+			trx:  []*model.Transaction{exchange(map[*model.Commodity]int64{chf: 200, usd: 100})},
+		},
+		{
+			desc: "three commodities",
+			trx:  []*model.Transaction{exchange(map[*model.Commodity]int64{chf: -200, eur: 50, usd: 50})},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			d := &Day{Date: date.Date(2020, 1, 1), Transactions: test.trx, Prices: test.prices}
+
+			if err := ImplyPrices(true).DayStart(d); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, p := range d.Prices {
+				got = append(got, fmt.Sprintf("%s %s %s %s", p.Date.Format("2006-01-02"), p.Commodity.Name(), p.Price, p.Target.Name()))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ImplyPrices() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+	if ImplyPrices(false) != nil {
+		t.Errorf("ImplyPrices(false) returned a processor, want nil")
+	}
+}
+
+func TestValuate(t *testing.T) {
+	for _, test := range []struct {
+		desc      string
+		precision int
+		want      []string
+	}{
+		{
+			desc:      "unrounded",
+			precision: -1,
+			want:      []string{"Assets:Depot 3.0153", "Equity:Equity -3.003", "Income:Depot -0.0123"},
+		},
+		{
+			desc:      "rounded",
+			precision: 2,
+			want:      []string{"Assets:Depot 3.02", "Equity:Equity -3", "Income -0.01", "Income:Depot -0.01"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			reg := registry.New()
+			chf, aapl := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("AAPL")
+			depot, equity := reg.Accounts().MustGet("Assets:Depot"), reg.Accounts().MustGet("Equity:Equity")
+			if test.precision >= 0 {
+				if err := reg.Commodities().SetPrecision("CHF", test.precision); err != nil {
+					t.Fatal(err)
+				}
+			}
+			j := New()
+			for i, p := range []string{"1.001", "1.0049", "1.0051"} {
+				j.Add(&model.Price{Date: date.Date(2020, 1, i+1), Commodity: aapl, Target: chf, Price: decimal.RequireFromString(p)})
+			}
+			j.Add(transaction.Builder{
+				Date: date.Date(2020, 1, 1),
+				Postings: posting.Builder{
+					Credit:    equity,
+					Debit:     depot,
+					Commodity: aapl,
+					Quantity:  decimal.NewFromInt(3),
+				}.Build(),
+			}.Build())
+			values := make(map[string]decimal.Decimal)
+
+			err := j.Build().Process(ComputePrices(chf), Valuate(reg, chf), &Processor{
+				Posting: func(_ *model.Transaction, p *model.Posting) error {
+					values[p.Account.Name()] = values[p.Account.Name()].Add(p.Value)
+					return nil
+				},
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, name := range dict.SortedKeys(values, compare.Ordered[string]) {
+				got = append(got, fmt.Sprintf("%s %s", name, values[name]))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Valuate() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+type collection amounts.Amounts
+
+func (c collection) Insert(k amounts.Key, v decimal.Decimal) {
+	amounts.Amounts(c).Add(k, v)
+}
+
+func TestCloseAccountsTags(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	bank, food := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Expenses:Food")
+	j := New()
+	for i, tags := range [][]model.Tag{{"rome"}, {"paris"}, nil} {
+		j.Add(transaction.Builder{
+			Date: date.Date(2020, 1, i+1),
+			Tags: tags,
+			Postings: posting.Builder{
+				Credit:    bank,
+				Debit:     food,
+				Commodity: chf,
+				Quantity:  decimal.NewFromInt(int64(10 * (i + 1))),
+			}.Build(),
+		}.Build())
+	}
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 2, 29)}, date.Monthly, 0)
+	j.Day(date.Date(2020, 2, 1))
+	res := make(collection)
+
+	err := j.Build().Process(
+		CloseAccounts(j, reg, true, partition),
+		Query{
+			Select:    amounts.KeyMapper{Account: mapper.Identity[*model.Account], Tag: mapper.Identity[model.Tag]}.Build(),
+			GroupTags: regex.Regexes{regexp.MustCompile(".")},
+		}.Into(res),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, k := range amounts.Amounts(res).Index(nil) {
+		got = append(got, fmt.Sprintf("%s %s %s", k.Account, k.Tag, res[k]))
+	}
+	sort.Strings(got)
+	// The closings carry the tags of the closed postings, so every tag
+	// nets to zero in the expense account.
+	want := []string{
+		"Assets:Bank paris -20",
+		"Assets:Bank rome -10",
+		"Assets:Bank untagged -30",
+		"Equity:Equity paris 20",
+		"Equity:Equity rome 10",
+		"Equity:Equity untagged 30",
+		"Expenses:Food paris 0",
+		"Expenses:Food rome 0",
+		"Expenses:Food untagged 0",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CloseAccounts() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
@@ -30,6 +30,37 @@ type Registry struct {
 	index    map[string]*Account
 	accounts *multimap.Node[*Account]
 	swaps    map[*Account]*Account
+
+	// The special accounts are created up front, such that looking them
+	// up cannot fail.
+	equity, tbd, valuation, rounding *Account
+}
+
+// SpecialAccounts holds the names of the accounts which knut books to by
+// itself.
+type SpecialAccounts struct {
+	// Equity receives opening balances and the closing of income and
+	// expense accounts.
+	Equity string
+
+	// TBD receives bookings whose account is yet to be determined.
+	TBD string
+
+	// Valuation is the parent of the accounts which receive valuation
+	// gains and losses. It must be an income or expense account.
+	Valuation string
+
+	// Rounding receives the residuals of rounding values to the precision
+	// of the valuation commodity. By default, it is the valuation account.
+	Rounding string
+}
+
+// DefaultSpecialAccounts are the special accounts of a new registry.
+var DefaultSpecialAccounts = SpecialAccounts{
+	Equity:    "Equity:Equity",
+	TBD:       "Expenses:TBD",
+	Valuation: "Income",
+	Rounding:  "Income",
 }
 
 // NewRegistry creates a new thread-safe collection of accounts.
@@ -42,7 +73,9 @@ func NewRegistry() *Registry {
 	for _, t := range types {
 		reg.Get(t.String())
 	}
-
+	if err := reg.SetSpecialAccounts(DefaultSpecialAccounts); err != nil {
+		panic(fmt.Sprintf("invalid default special accounts: %v", err))
+	}
 	return reg
 }
 
@@ -172,14 +205,71 @@ func (as *Registry) SwapType(a *Account) *Account {
 	return sw
 }
 
+// SetSpecialAccounts sets the special accounts. Empty names keep the
+// current accounts.
+func (as *Registry) SetSpecialAccounts(s SpecialAccounts) error {
+	as.mutex.RLock()
+	equity, tbd, valuation, rounding := as.equity, as.tbd, as.valuation, as.rounding
+	as.mutex.RUnlock()
+	var err error
+	if s.Equity != "" {
+		if equity, err = as.Get(s.Equity); err != nil {
+			return fmt.Errorf("invalid equity account: %w", err)
+		}
+	}
+	if s.TBD != "" {
+		if tbd, err = as.Get(s.TBD); err != nil {
+			return fmt.Errorf("invalid TBD account: %w", err)
+		}
+	}
+	if s.Valuation != "" {
+		if valuation, err = as.Get(s.Valuation); err != nil {
+			return fmt.Errorf("invalid valuation account: %w", err)
+		}
+		if !valuation.IsIE() {
+			return fmt.Errorf("invalid valuation account %s: must be an income or expense account", valuation.Name())
+		}
+	}
+	if s.Rounding != "" {
+		if rounding, err = as.Get(s.Rounding); err != nil {
+			return fmt.Errorf("invalid rounding account: %w", err)
+		}
+	}
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+	as.equity, as.tbd, as.valuation, as.rounding = equity, tbd, valuation, rounding
+	return nil
+}
+
 // TBDAccount returns the TBD account.
 func (as *Registry) TBDAccount() *Account {
-	return as.MustGet("Expenses:TBD")
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+	return as.tbd
+}
+
+// EquityAccount returns the equity account.
+func (as *Registry) EquityAccount() *Account {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+	return as.equity
+}
+
+// RoundingAccount returns the rounding account.
+func (as *Registry) RoundingAccount() *Account {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+	return as.rounding
 }
 
 // ValuationAccountFor returns the valuation account which corresponds to
 // the given Asset or Liability account.
-func (as *Registry) ValuationAccountFor(a *Account) *Account {
-	segments := append(as.MustGet("Income").Segments(), a.Segments()[1:]...)
-	return as.MustGet(strings.Join(segments, ":"))
+func (as *Registry) ValuationAccountFor(a *Account) (*Account, error) {
+	if !a.IsAL() {
+		return nil, fmt.Errorf("account %s has no valuation account, as it is neither an asset nor a liability", a.Name())
+	}
+	as.mutex.RLock()
+	valuation := as.valuation
+	as.mutex.RUnlock()
+	return as.GetPath(append(valuation.Segments(), a.Segments()[1:]...))
 }
@@ -0,0 +1,93 @@
+package account
+
+import "testing"
+
+func TestSetSpecialAccounts(t *testing.T) {
+	reg := NewRegistry()
+
+	if err := reg.SetSpecialAccounts(SpecialAccounts{Equity: "Equity:Eigenkapital", Valuation: "Income:Bewertung"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := reg.EquityAccount().Name(); got != "Equity:Eigenkapital" {
+		t.Errorf("EquityAccount() = %s, want Equity:Eigenkapital", got)
+	}
+	if got := reg.TBDAccount().Name(); got != DefaultSpecialAccounts.TBD {
+		t.Errorf("TBDAccount() = %s, want %s", got, DefaultSpecialAccounts.TBD)
+	}
+	if got, err := reg.ValuationAccountFor(reg.MustGet("Assets:Depot")); err != nil || got.Name() != "Income:Bewertung:Depot" {
+		t.Errorf("ValuationAccountFor() = %v, %v, want Income:Bewertung:Depot", got, err)
+	}
+	if _, err := reg.ValuationAccountFor(reg.MustGet("Expenses:Food")); err == nil {
+		t.Errorf("ValuationAccountFor(Expenses:Food) succeeded, want an error")
+	}
+	for _, s := range []SpecialAccounts{
+		{Equity: "Eigenkapital"},
+		{Valuation: "Assets:Valuation"},
+	} {
+		if err := reg.SetSpecialAccounts(s); err == nil {
+			t.Errorf("SetSpecialAccounts(%v) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestRegistryInterns(t *testing.T) {
+	reg := NewRegistry()
+
+	a := reg.MustGet("Assets:Bank:Checking")
+
+	if got := reg.MustGet("Assets:Bank:Checking"); got != a {
+		t.Errorf("Get() returned a different pointer for the same name")
+	}
+	if got := reg.MustGetPath([]string{"Assets", "Bank", "Checking"}); got != a {
+		t.Errorf("GetPath() returned a different pointer for the same name")
+	}
+	if got := reg.MustGet("Assets:Bank"); got != reg.MustGetPath([]string{"Assets", "Bank"}) {
+		t.Errorf("Get() and GetPath() returned different pointers for a parent account")
+	}
+}
+
+var names = []string{
+	"Assets:Bank:Checking",
+	"Assets:Bank:Savings",
+	"Liabilities:CreditCard",
+	"Expenses:Groceries",
+	"Expenses:Rent",
+	"Income:Salary",
+}
+
+func BenchmarkRegistryGet(b *testing.B) {
+	reg := NewRegistry()
+	for _, n := range names {
+		reg.MustGet(n)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			reg.MustGet(names[i%len(names)])
+		}
+	})
+}
+
+func BenchmarkMapKey(b *testing.B) {
+	reg := NewRegistry()
+	accounts := make([]*Account, 0, len(names))
+	for _, n := range names {
+		accounts = append(accounts, reg.MustGet(n))
+	}
+	b.Run("pointer", func(b *testing.B) {
+		m := make(map[*Account]int)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m[accounts[i%len(accounts)]]++
+		}
+	})
+	b.Run("name", func(b *testing.B) {
+		m := make(map[string]int)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m[accounts[i%len(accounts)].Name()]++
+		}
+	})
+}
@@ -13,6 +13,7 @@ type Close struct {
 	Src     *syntax.Close
 	Date    time.Time
 	Account *account.Account
+	Note    string
 }
 
 func Create(reg *registry.Registry, c *syntax.Close) (*Close, error) {
@@ -28,5 +29,6 @@ func Create(reg *registry.Registry, c *syntax.Close) (*Close, error) {
 		Src:     c,
 		Date:    date,
 		Account: account,
+		Note:    c.Note.Content.Extract(),
 	}, nil
 }
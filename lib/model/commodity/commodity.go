@@ -1,15 +1,47 @@
 package commodity
 
+import "sync/atomic"
+
 // Commodity represents a currency or security.
 type Commodity struct {
 	name       string
 	IsCurrency bool
+
+	// symbol is displayed instead of the name, before the quantity or,
+	// if symbolSuffix is set, after it.
+	symbol       string
+	symbolSuffix bool
+
+	// precision holds the number of decimal places plus one, or zero if
+	// values are not rounded. It is accessed atomically, as journal files
+	// are parsed in parallel.
+	precision atomic.Int32
 }
 
-func (c Commodity) Name() string {
+func (c *Commodity) Name() string {
 	return c.name
 }
 
-func (c Commodity) String() string {
+func (c *Commodity) String() string {
 	return c.name
 }
+
+// Precision returns the number of decimal places to which values in the
+// commodity are rounded, and whether they are rounded at all.
+func (c *Commodity) Precision() (int32, bool) {
+	p := c.precision.Load()
+	return p - 1, p > 0
+}
+
+// Affixes returns the prefix and the suffix with which quantities of the
+// commodity are displayed, such as "$" for "$1,000.00" or " €" for
+// "1,000.00 €". Both are empty if the commodity has no symbol.
+func (c *Commodity) Affixes() (prefix, suffix string) {
+	switch {
+	case c.symbol == "":
+		return "", ""
+	case c.symbolSuffix:
+		return "", " " + c.symbol
+	}
+	return c.symbol, ""
+}
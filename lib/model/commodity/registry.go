@@ -16,6 +16,7 @@ package commodity
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"unicode"
 
@@ -54,7 +55,9 @@ func (cs *Registry) Get(name string) (*Commodity, error) {
 	if !isValidCommodity(name) {
 		return nil, fmt.Errorf("invalid commodity name %q", name)
 	}
-	res = &Commodity{name: name}
+	// The name is usually a slice of the text of a journal file, which
+	// must not be kept alive by the commodity.
+	res = &Commodity{name: strings.Clone(name)}
 	cs.insert(res)
 
 	return res, nil
@@ -88,6 +91,34 @@ func (cs *Registry) TagCurrency(name string) error {
 	return nil
 }
 
+// SetSymbol sets the symbol which is displayed with quantities of the
+// commodity, before them or, if suffix is set, after them.
+func (cs *Registry) SetSymbol(name, symbol string, suffix bool) error {
+	commodity, err := cs.Get(name)
+	if err != nil {
+		return err
+	}
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	commodity.symbol = symbol
+	commodity.symbolSuffix = suffix
+	return nil
+}
+
+// SetPrecision sets the number of decimal places to which values in the
+// commodity are rounded.
+func (cs *Registry) SetPrecision(name string, places int) error {
+	if places < 0 {
+		return fmt.Errorf("invalid precision %d for %s: must not be negative", places, name)
+	}
+	commodity, err := cs.Get(name)
+	if err != nil {
+		return err
+	}
+	commodity.precision.Store(int32(places) + 1)
+	return nil
+}
+
 func isValidCommodity(s string) bool {
 	if len(s) == 0 {
 		return false
@@ -0,0 +1,63 @@
+package commodity
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestRegistryGet(t *testing.T) {
+	reg := NewCommodities()
+	text := "2020-01-01 price USD 1.1 CHF"
+
+	c := reg.MustGet(text[17:20])
+
+	if got := reg.MustGet("USD"); got != c {
+		t.Errorf("Get() returned a different pointer for the same name")
+	}
+	if unsafe.StringData(c.Name()) == unsafe.StringData(text[17:20]) {
+		t.Errorf("Get() retained the text of the name")
+	}
+	if _, err := reg.Get("US$"); err == nil {
+		t.Errorf("Get(US$) succeeded, want an error")
+	}
+}
+
+func BenchmarkRegistryGet(b *testing.B) {
+	reg := NewCommodities()
+	names := []string{"USD", "CHF", "EUR", "AAPL", "VT", "BTC"}
+	for _, n := range names {
+		reg.MustGet(n)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			reg.MustGet(names[i%len(names)])
+		}
+	})
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	reg := NewCommodities()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				c := reg.MustGet(fmt.Sprintf("COM%d", k%10))
+				if err := reg.SetPrecision(c.Name(), 2); err != nil {
+					t.Error(err)
+				}
+				_ = c.Name()
+				c.Precision()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if prec, ok := reg.MustGet("COM0").Precision(); !ok || prec != 2 {
+		t.Errorf("got precision %d, %t, want 2, true", prec, ok)
+	}
+}
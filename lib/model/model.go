@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/sboehler/knut/lib/common/cpr"
 	"github.com/sboehler/knut/lib/model/account"
@@ -13,6 +14,8 @@ import (
 	"github.com/sboehler/knut/lib/model/posting"
 	"github.com/sboehler/knut/lib/model/price"
 	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/tag"
+	"github.com/sboehler/knut/lib/model/template"
 	"github.com/sboehler/knut/lib/model/transaction"
 	"github.com/sboehler/knut/lib/syntax"
 	"github.com/sourcegraph/conc/pool"
@@ -22,6 +25,7 @@ type Commodity = commodity.Commodity
 type AccountType = account.Type
 type Account = account.Account
 type Posting = posting.Posting
+type Tag = tag.Tag
 type Transaction = transaction.Transaction
 type Open = open.Open
 type Close = cls.Close
@@ -31,6 +35,41 @@ type Balance = assertion.Balance
 
 type Registry = registry.Registry
 
+type Templates = template.Templates
+
+// Scope is the state which the directives of a file share: the templates
+// defined in the file and the tags of the enclosing tag blocks.
+type Scope struct {
+	Templates Templates
+
+	tags [][]syntax.Tag
+}
+
+// NewScope creates the scope for the given directives of a file. An error
+// in the definitions of the templates is returned along with the scope.
+func NewScope(ds []syntax.Directive) (*Scope, error) {
+	templates, err := template.Collect(ds)
+	return &Scope{Templates: templates}, err
+}
+
+// applyTags adds the tags of the enclosing tag blocks to the transactions.
+func (s *Scope) applyTags(ts []*transaction.Transaction) {
+	if len(s.tags) == 0 {
+		return
+	}
+	for _, t := range ts {
+		tags := slices.Clone(t.Tags)
+		for _, block := range s.tags {
+			for _, tg := range tag.Create(block) {
+				if !slices.Contains(tags, tg) {
+					tags = append(tags, tg)
+				}
+			}
+		}
+		t.Tags = tags
+	}
+}
+
 type Directive any
 
 var (
@@ -46,14 +85,21 @@ type Result struct {
 	Directives []any
 }
 
-func FromStream(reg *registry.Registry, inCh <-chan syntax.File) (<-chan []Directive, func(context.Context) error) {
+// FromStream creates the model directives for the files from the given
+// channel, processing up to parallelism files concurrently. Zero means
+// runtime.GOMAXPROCS(0).
+func FromStream(reg *registry.Registry, inCh <-chan syntax.File, parallelism int) (<-chan []Directive, func(context.Context) error) {
 	return cpr.Produce(func(ctx context.Context, ch chan<- []Directive) error {
-		wg := pool.New().WithContext(ctx).WithCancelOnError().WithFirstError()
+		wg := pool.New().WithMaxGoroutines(syntax.Workers(parallelism)).WithContext(ctx).WithCancelOnError().WithFirstError()
 		cpr.ForEach(ctx, inCh, func(input syntax.File) error {
 			wg.Go(func(ctx context.Context) error {
+				scope, err := NewScope(input.Directives)
+				if err != nil {
+					return err
+				}
 				var ds []Directive
 				for _, d := range input.Directives {
-					m, err := ParseDirective(reg, d)
+					m, err := ParseDirective(reg, scope, d)
 					if err != nil {
 						return err
 					}
@@ -67,13 +113,17 @@ func FromStream(reg *registry.Registry, inCh <-chan syntax.File) (<-chan []Direc
 	})
 }
 
-func ParseDirective(reg *registry.Registry, w syntax.Directive) ([]Directive, error) {
+// ParseDirective creates the model directives for the given directive.
+// The directives of a file must be passed in order and share a scope,
+// which holds the templates and the tags of the enclosing tag blocks.
+func ParseDirective(reg *registry.Registry, scope *Scope, w syntax.Directive) ([]Directive, error) {
 	switch d := w.Directive.(type) {
 	case syntax.Transaction:
 		ts, err := transaction.Create(reg, &d)
 		if err != nil {
 			return nil, err
 		}
+		scope.applyTags(ts)
 		var res []Directive
 		for _, t := range ts {
 			res = append(res, t)
@@ -97,13 +147,44 @@ func ParseDirective(reg *registry.Registry, w syntax.Directive) ([]Directive, er
 			return nil, err
 		}
 		return []Directive{o}, nil
+	case syntax.Opening:
+		ts, err := transaction.CreateOpening(reg, &d)
+		if err != nil {
+			return nil, err
+		}
+		scope.applyTags(ts)
+		var res []Directive
+		for _, t := range ts {
+			res = append(res, t)
+		}
+		return res, nil
 	case syntax.Price:
 		o, err := price.Create(reg, &d)
 		if err != nil {
 			return nil, err
 		}
 		return []Directive{o}, nil
-	case syntax.Include:
+	case syntax.Apply:
+		ts, err := template.Create(reg, scope.Templates, &d)
+		if err != nil {
+			return nil, err
+		}
+		scope.applyTags(ts)
+		var res []Directive
+		for _, t := range ts {
+			res = append(res, t)
+		}
+		return res, nil
+	case syntax.TagBlock:
+		scope.tags = append(scope.tags, d.Tags)
+		return nil, nil
+	case syntax.EndTagBlock:
+		if len(scope.tags) == 0 {
+			return nil, syntax.Error{Range: d.Range, Message: "`end tag` without matching `tag`"}
+		}
+		scope.tags = scope.tags[:len(scope.tags)-1]
+		return nil, nil
+	case syntax.Include, syntax.Template:
 		return nil, nil
 	}
 	return nil, fmt.Errorf("unknown directive: %T", w)
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/syntax/parser"
+)
+
+func TestParseDirectiveTagBlocks(t *testing.T) {
+	text := strings.Join([]string{
+		`2020-01-01 "before"`,
+		`Assets:Bank Expenses:Food 1 USD`,
+		``,
+		`tag #travel`,
+		`2020-01-02 "outer" #travel #food`,
+		`Assets:Bank Expenses:Food 1 USD`,
+		``,
+		`tag #work`,
+		`2020-01-03 "inner"`,
+		`Assets:Bank Expenses:Food 1 USD`,
+		``,
+		`end tag`,
+		`2020-01-04 "outer again"`,
+		`Assets:Bank Expenses:Food 1 USD`,
+		``,
+		`end tag`,
+		`2020-01-05 "after"`,
+		`Assets:Bank Expenses:Food 1 USD`,
+		``,
+	}, "\n")
+	p := parser.New(text, "")
+	if err := p.Advance(); err != nil {
+		t.Fatalf("p.Advance() returned unexpected error: %v", err)
+	}
+	f, err := p.ParseFile()
+	if err != nil {
+		t.Fatalf("p.ParseFile() returned unexpected error: %v", err)
+	}
+	reg := registry.New()
+	scope, err := NewScope(f.Directives)
+	if err != nil {
+		t.Fatalf("NewScope() returned unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, d := range f.Directives {
+		ds, err := ParseDirective(reg, scope, d)
+		if err != nil {
+			t.Fatalf("ParseDirective() returned unexpected error: %v", err)
+		}
+		for _, d := range ds {
+			trx := d.(*Transaction)
+			got = append(got, fmt.Sprintf("%s %v", trx.Description, trx.Tags))
+		}
+	}
+
+	want := []string{
+		"before []",
+		"outer [travel food]",
+		"inner [travel work]",
+		"outer again [travel]",
+		"after []",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseDirective() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestParseDirectiveUnmatchedEndTag(t *testing.T) {
+	p := parser.New("end tag\n", "")
+	if err := p.Advance(); err != nil {
+		t.Fatalf("p.Advance() returned unexpected error: %v", err)
+	}
+	f, err := p.ParseFile()
+	if err != nil {
+		t.Fatalf("p.ParseFile() returned unexpected error: %v", err)
+	}
+	scope, err := NewScope(f.Directives)
+	if err != nil {
+		t.Fatalf("NewScope() returned unexpected error: %v", err)
+	}
+
+	_, err = ParseDirective(registry.New(), scope, f.Directives[0])
+
+	if err == nil {
+		t.Fatalf("ParseDirective() returned nil error, want an error")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/sboehler/knut/lib/model/account"
+	"github.com/sboehler/knut/lib/model/commodity"
 	"github.com/sboehler/knut/lib/model/registry"
 	"github.com/sboehler/knut/lib/syntax"
 )
@@ -13,6 +14,12 @@ type Open struct {
 	Src     *syntax.Open
 	Date    time.Time
 	Account *account.Account
+
+	// Commodities restricts the account to the given commodities, if it
+	// is not empty.
+	Commodities []*commodity.Commodity
+
+	Note string
 }
 
 func Create(reg *registry.Registry, o *syntax.Open) (*Open, error) {
@@ -24,9 +31,19 @@ func Create(reg *registry.Registry, o *syntax.Open) (*Open, error) {
 	if err != nil {
 		return nil, err
 	}
+	var commodities []*commodity.Commodity
+	for _, c := range o.Commodities {
+		com, err := reg.Commodities().Create(c)
+		if err != nil {
+			return nil, err
+		}
+		commodities = append(commodities, com)
+	}
 	return &Open{
-		Src:     o,
-		Date:    date,
-		Account: account,
+		Src:         o,
+		Date:        date,
+		Account:     account,
+		Commodities: commodities,
+		Note:        o.Note.Content.Extract(),
 	}, nil
 }
@@ -5,23 +5,63 @@ import (
 	"github.com/sboehler/knut/lib/model/account"
 	"github.com/sboehler/knut/lib/model/commodity"
 	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/tag"
 	"github.com/sboehler/knut/lib/syntax"
 	"github.com/shopspring/decimal"
 )
 
+// State is the clearing state of a posting.
+type State int
+
+const (
+	Unmarked State = iota
+	Pending
+	Cleared
+)
+
+// ParseState parses a state from its syntax.
+func ParseState(s syntax.State) State {
+	switch s.Extract() {
+	case "!":
+		return Pending
+	case "*":
+		return Cleared
+	}
+	return Unmarked
+}
+
+func (s State) String() string {
+	switch s {
+	case Pending:
+		return "!"
+	case Cleared:
+		return "*"
+	}
+	return ""
+}
+
 // Posting represents a posting.
 type Posting struct {
 	Src             *syntax.Booking
+	State           State
 	Quantity, Value decimal.Decimal
 	Account, Other  *account.Account
 	Commodity       *commodity.Commodity
+	Tags            []tag.Tag
+
+	// Virtual postings track amounts alongside the real ones, for example
+	// budget envelopes. Reports exclude them unless asked otherwise.
+	Virtual bool
 }
 
 type Builder struct {
 	Src             *syntax.Booking
+	State           State
 	Quantity, Value decimal.Decimal
 	Credit, Debit   *account.Account
 	Commodity       *commodity.Commodity
+	Tags            []tag.Tag
+	Virtual         bool
 }
 
 func (pb Builder) Build() []*Posting {
@@ -31,19 +71,25 @@ func (pb Builder) Build() []*Posting {
 	return []*Posting{
 		{
 			Src:       pb.Src,
+			State:     pb.State,
 			Account:   pb.Credit,
 			Other:     pb.Debit,
 			Commodity: pb.Commodity,
 			Quantity:  pb.Quantity.Neg(),
 			Value:     pb.Value.Neg(),
+			Tags:      pb.Tags,
+			Virtual:   pb.Virtual,
 		},
 		{
 			Src:       pb.Src,
+			State:     pb.State,
 			Account:   pb.Debit,
 			Other:     pb.Credit,
 			Commodity: pb.Commodity,
 			Quantity:  pb.Quantity,
 			Value:     pb.Value,
+			Tags:      pb.Tags,
+			Virtual:   pb.Virtual,
 		},
 	}
 }
@@ -95,10 +141,13 @@ func Create(reg *registry.Registry, bs []syntax.Booking) ([]*Posting, error) {
 		}
 		builder = append(builder, Builder{
 			Src:       &bs[i],
+			State:     ParseState(b.State),
 			Credit:    credit,
 			Debit:     debit,
 			Quantity:  amount,
 			Commodity: commodity,
+			Tags:      tag.Create(b.Tags),
+			Virtual:   b.Virtual,
 		})
 	}
 	return builder.Build(), nil
@@ -33,29 +33,69 @@ var one = decimal.NewFromInt(1)
 // Insert inserts a new price.
 func (ps Prices) Insert(commodity *commodity.Commodity, price decimal.Decimal, target *commodity.Commodity) {
 	ps.addPrice(target, commodity, price)
-	ps.addPrice(commodity, target, one.Div(price).Truncate(8))
+	ps.addPrice(commodity, target, Inverse(price))
+}
+
+// Inverse returns the price of the target in the commodity, given the
+// price of the commodity in the target, as Insert stores it.
+func Inverse(price decimal.Decimal) decimal.Decimal {
+	return one.Div(price).Truncate(8)
 }
 
 func (ps Prices) addPrice(target, commodity *commodity.Commodity, price decimal.Decimal) {
 	dict.GetDefault(ps, target, newNormalizedPrices)[commodity] = price
 }
 
-// Normalize creates a normalized price map for the given commodity.
+// Normalize creates a normalized price map for the given commodity. The
+// price of every commodity is derived along the chain of prices returned
+// by Path.
 func (ps Prices) Normalize(t *commodity.Commodity) NormalizedPrices {
 	res := NormalizedPrices{t: one}
-	ps.normalize(t, res)
+	ps.walk(t, func(c, via *commodity.Commodity) {
+		res[c] = Multiply(ps[via][c], res[via])
+	})
+	return res
+}
+
+// Path returns the chain of commodities along which Normalize derives the
+// price of c in t, starting with c and ending with t. It returns nil if
+// there is no such chain.
+func (ps Prices) Path(t, c *commodity.Commodity) []*commodity.Commodity {
+	vias := make(map[*commodity.Commodity]*commodity.Commodity)
+	ps.walk(t, func(c, via *commodity.Commodity) {
+		vias[c] = via
+	})
+	res := []*commodity.Commodity{c}
+	for c != t {
+		via, ok := vias[c]
+		if !ok {
+			return nil
+		}
+		res = append(res, via)
+		c = via
+	}
 	return res
 }
 
-// normalize recursively computes prices by traversing the price graph.
-// res must already contain a price for c.
-func (ps Prices) normalize(c *commodity.Commodity, res NormalizedPrices) {
-	for neighbor, price := range ps[c] {
-		if _, done := res[neighbor]; done {
-			continue
+// walk traverses the price graph breadth-first, starting at t, such that
+// prices are derived along the shortest chains, and calls f for every
+// commodity which it reaches, along with the commodity it is reached
+// from. Neighbors are visited in the order of their names, to derive the
+// same prices every time.
+func (ps Prices) walk(t *commodity.Commodity, f func(c, via *commodity.Commodity)) {
+	done := map[*commodity.Commodity]bool{t: true}
+	queue := []*commodity.Commodity{t}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, neighbor := range dict.SortedKeys(ps[c], commodity.Compare) {
+			if done[neighbor] {
+				continue
+			}
+			done[neighbor] = true
+			f(neighbor, c)
+			queue = append(queue, neighbor)
 		}
-		res[neighbor] = Multiply(price, res[c])
-		ps.normalize(neighbor, res)
 	}
 }
 
@@ -134,3 +134,39 @@ func TestNormalize(t *testing.T) {
 		})
 	}
 }
+
+func TestPath(t *testing.T) {
+	reg := registry.New()
+	aapl := reg.Commodities().MustGet("AAPL")
+	chf := reg.Commodities().MustGet("CHF")
+	eur := reg.Commodities().MustGet("EUR")
+	usd := reg.Commodities().MustGet("USD")
+	gbp := reg.Commodities().MustGet("GBP")
+	pr := make(Prices)
+	pr.Insert(aapl, decimal.RequireFromString("100"), usd)
+	pr.Insert(usd, decimal.RequireFromString("0.9"), eur)
+	pr.Insert(eur, decimal.RequireFromString("1.1"), chf)
+	// A direct price is preferred over a chain of prices.
+	pr.Insert(usd, decimal.RequireFromString("0.95"), chf)
+
+	for _, test := range []struct {
+		commodity *commodity.Commodity
+		want      []string
+	}{
+		{chf, []string{"CHF"}},
+		{usd, []string{"USD", "CHF"}},
+		{aapl, []string{"AAPL", "USD", "CHF"}},
+		{gbp, nil},
+	} {
+		t.Run(test.commodity.Name(), func(t *testing.T) {
+			var got []string
+			for _, c := range pr.Path(chf, test.commodity) {
+				got = append(got, c.Name())
+			}
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Fatalf("unexpected diff (-want/+got):\n%s", diff)
+			}
+		})
+	}
+}
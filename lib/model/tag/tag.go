@@ -0,0 +1,80 @@
+package tag
+
+import (
+	"slices"
+
+	"github.com/sboehler/knut/lib/common/predicate"
+	"github.com/sboehler/knut/lib/common/regex"
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// Tag represents a tag on a transaction or a posting.
+type Tag string
+
+// Name returns the name of the tag.
+func (t Tag) Name() string {
+	return string(t)
+}
+
+// Untagged is the tag of postings without a matching tag.
+const Untagged Tag = "untagged"
+
+// Create creates tags from their syntax.
+func Create(ts []syntax.Tag) []Tag {
+	if len(ts) == 0 {
+		return nil
+	}
+	res := make([]Tag, 0, len(ts))
+	for _, t := range ts {
+		res = append(res, Tag(t.Name()))
+	}
+	return res
+}
+
+// Merge returns the tags of the given lists, without duplicates, in the
+// order of their first occurrence.
+func Merge(tss ...[]Tag) []Tag {
+	var res []Tag
+	for _, ts := range tss {
+		for _, t := range ts {
+			if !slices.Contains(res, t) {
+				res = append(res, t)
+			}
+		}
+	}
+	return res
+}
+
+// Matches returns a predicate which is true if any of the given
+// tags matches any of the regexes.
+func Matches(rxs regex.Regexes) predicate.Predicate[[]Tag] {
+	if len(rxs) == 0 {
+		return predicate.True[[]Tag]
+	}
+	pred := predicate.ByName[Tag](rxs)
+	return func(ts []Tag) bool {
+		for _, t := range ts {
+			if pred(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Extract returns a function which extracts the first tag matching any
+// of the given regexes from the given lists of tags, or Untagged if no
+// tag matches.
+func Extract(rxs regex.Regexes) func(...[]Tag) Tag {
+	pred := predicate.ByName[Tag](rxs)
+	return func(tss ...[]Tag) Tag {
+		for _, ts := range tss {
+			for _, t := range ts {
+				if pred(t) {
+					return t
+				}
+			}
+		}
+		return Untagged
+	}
+}
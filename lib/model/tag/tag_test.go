@@ -0,0 +1,41 @@
+package tag
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/regex"
+)
+
+func TestExtract(t *testing.T) {
+	extract := Extract(regex.Regexes{regexp.MustCompile("^trip")})
+	for _, test := range []struct {
+		desc string
+		tss  [][]Tag
+		want Tag
+	}{
+		{desc: "no tags", want: Untagged},
+		{desc: "no match", tss: [][]Tag{{"food"}, {"work"}}, want: Untagged},
+		{desc: "posting tag", tss: [][]Tag{{"food", "trip-rome"}, nil}, want: "trip-rome"},
+		{desc: "transaction tag", tss: [][]Tag{{"food"}, {"trip-paris"}}, want: "trip-paris"},
+		{desc: "posting tag first", tss: [][]Tag{{"trip-rome"}, {"trip-paris"}}, want: "trip-rome"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := extract(test.tss...); got != test.want {
+				t.Errorf("extract() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	got := Merge([]Tag{"b", "a"}, nil, []Tag{"a", "c"})
+
+	if diff := cmp.Diff([]Tag{"b", "a", "c"}, got); diff != "" {
+		t.Errorf("Merge() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if got := Merge(nil, nil); got != nil {
+		t.Errorf("Merge(nil, nil) = %v, want nil", got)
+	}
+}
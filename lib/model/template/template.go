@@ -0,0 +1,115 @@
+// Package template expands apply directives into transactions.
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// Templates holds templates by name. Templates are scoped to the file
+// which defines them.
+type Templates map[string]*syntax.Template
+
+// Collect returns the templates defined by the given directives. If a
+// name is defined more than once, the first definition is kept and an
+// error is returned along with the templates.
+func Collect(ds []syntax.Directive) (Templates, error) {
+	var (
+		res = make(Templates)
+		err error
+	)
+	for _, d := range ds {
+		t, ok := d.Directive.(syntax.Template)
+		if !ok {
+			continue
+		}
+		name := t.Name.Extract()
+		if _, ok := res[name]; ok {
+			if err == nil {
+				err = syntax.Error{Range: t.Name, Message: fmt.Sprintf("duplicate template `%s`", name)}
+			}
+			continue
+		}
+		res[name] = &t
+	}
+	return res, err
+}
+
+// Expand instantiates the template referenced by the apply directive,
+// substituting the arguments for the parameters. The ranges of the
+// substituted accounts, quantities and commodities point to the arguments.
+func (ts Templates) Expand(a *syntax.Apply) (*syntax.Transaction, error) {
+	name := a.Name.Extract()
+	t, ok := ts[name]
+	if !ok {
+		return nil, syntax.Error{Range: a.Name, Message: fmt.Sprintf("unknown template `%s` (templates are only visible in the file which defines them)", name)}
+	}
+	if len(a.Args) != len(t.Params) {
+		return nil, syntax.Error{
+			Range:   a.Range,
+			Message: fmt.Sprintf("template `%s` takes %d arguments, got %d", name, len(t.Params), len(a.Args)),
+		}
+	}
+	args := make(map[string]syntax.Range)
+	for i, p := range t.Params {
+		args[p.Extract()] = a.Args[i]
+	}
+	substitute := func(r *syntax.Range) (bool, error) {
+		param, ok := strings.CutPrefix(r.Extract(), "$")
+		if !ok {
+			return false, nil
+		}
+		arg, ok := args[param]
+		if !ok {
+			return false, syntax.Error{Range: *r, Message: fmt.Sprintf("unknown parameter `%s` in template `%s`", param, name)}
+		}
+		*r = arg
+		return true, nil
+	}
+	trx := &syntax.Transaction{
+		Range:       a.Range,
+		Date:        a.Date,
+		Description: t.Description,
+		Tags:        t.Tags,
+	}
+	for _, b := range t.Bookings {
+		for _, acc := range []*syntax.Account{&b.Credit, &b.Debit} {
+			ok, err := substitute(&acc.Range)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				acc.Macro = false
+			}
+		}
+		for _, r := range []*syntax.Range{&b.Quantity.Range, &b.Commodity.Range} {
+			if _, err := substitute(r); err != nil {
+				return nil, err
+			}
+		}
+		trx.Bookings = append(trx.Bookings, b)
+	}
+	return trx, nil
+}
+
+// Create expands the apply directive and creates the resulting
+// transactions. Errors refer to the position of the apply directive.
+func Create(reg *registry.Registry, ts Templates, a *syntax.Apply) ([]*transaction.Transaction, error) {
+	trx, err := ts.Expand(a)
+	if err != nil {
+		return nil, err
+	}
+	res, err := transaction.Create(reg, trx)
+	if err != nil {
+		return nil, syntax.Error{
+			Range:   a.Range,
+			Message: fmt.Sprintf("while expanding template `%s`", a.Name.Extract()),
+			Wrapped: err,
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,123 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/sboehler/knut/lib/syntax/parser"
+)
+
+const split = "template split total share commodity account \"Split bill\" #dinner\n" +
+	"Assets:Bank $account $total $commodity\n" +
+	"$account Assets:Receivables $share $commodity\n" +
+	"\n"
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		desc    string
+		text    string
+		want    []string
+		wantErr string
+	}{
+		{
+			desc: "expand template",
+			text: split + "2020-01-10 apply split 100 50 USD Expenses:Dinner\n",
+			want: []string{
+				"2020-01-10 Split bill [dinner] Assets:Bank -> Expenses:Dinner 100 USD",
+				"2020-01-10 Split bill [dinner] Expenses:Dinner -> Assets:Receivables 50 USD",
+			},
+		},
+		{
+			desc:    "unknown template",
+			text:    split + "2020-01-10 apply splat 100 50 USD Expenses:Dinner\n",
+			wantErr: "unknown template `splat` (templates are only visible in the file which defines them)",
+		},
+		{
+			desc:    "wrong number of arguments",
+			text:    split + "2020-01-10 apply split 100 USD Expenses:Dinner\n",
+			wantErr: "template `split` takes 4 arguments, got 3",
+		},
+		{
+			desc:    "invalid argument",
+			text:    split + "2020-01-10 apply split 100 fifty USD Expenses:Dinner\n",
+			wantErr: "while expanding template `split`",
+		},
+		{
+			desc: "unknown parameter",
+			text: "template split amount \"Split bill\"\n" +
+				"Assets:Bank Expenses:Dinner $amount $commodity\n" +
+				"\n" +
+				"2020-01-10 apply split 100\n",
+			wantErr: "unknown parameter `commodity` in template `split`",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			reg := registry.New()
+			p := parser.New(test.text, "")
+			if err := p.Advance(); err != nil {
+				t.Fatalf("p.Advance() returned unexpected error: %v", err)
+			}
+			f, err := p.ParseFile()
+			if err != nil {
+				t.Fatalf("p.ParseFile() returned unexpected error: %v", err)
+			}
+			ts, err := Collect(f.Directives)
+			if err != nil {
+				t.Fatalf("Collect() returned unexpected error: %v", err)
+			}
+			a := f.Directives[1].Directive.(syntax.Apply)
+
+			trx, err := Create(reg, ts, &a)
+
+			var gotErr string
+			if serr := (syntax.Error{}); errors.As(err, &serr) {
+				gotErr = serr.Message
+			}
+			if diff := cmp.Diff(test.wantErr, gotErr); diff != "" {
+				t.Fatalf("Create() returned unexpected diff in err (-want/+got):\n%s\n", diff)
+			}
+			var got []string
+			for _, t := range trx {
+				for _, pst := range t.Postings {
+					if pst.Quantity.IsNegative() {
+						continue
+					}
+					got = append(got, fmt.Sprintf("%s %s %v %s -> %s %s %s", t.Date.Format("2006-01-02"), t.Description, t.Tags, pst.Other.Name(), pst.Account.Name(), pst.Quantity, pst.Commodity.Name()))
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Create() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestCollectDuplicate(t *testing.T) {
+	text := split + split
+	p := parser.New(text, "")
+	if err := p.Advance(); err != nil {
+		t.Fatalf("p.Advance() returned unexpected error: %v", err)
+	}
+	f, err := p.ParseFile()
+	if err != nil {
+		t.Fatalf("p.ParseFile() returned unexpected error: %v", err)
+	}
+
+	ts, err := Collect(f.Directives)
+
+	want := syntax.Error{
+		Range:   f.Directives[1].Directive.(syntax.Template).Name,
+		Message: "duplicate template `split`",
+	}
+	if diff := cmp.Diff(want, err); diff != "" {
+		t.Errorf("Collect() returned unexpected diff in err (-want/+got):\n%s\n", diff)
+	}
+	if got := ts["split"]; got == nil || got.Start != 0 {
+		t.Errorf("Collect() kept %v, want the first definition", got)
+	}
+}
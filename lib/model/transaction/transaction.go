@@ -9,15 +9,27 @@ import (
 	"github.com/sboehler/knut/lib/model/commodity"
 	"github.com/sboehler/knut/lib/model/posting"
 	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/tag"
 	"github.com/sboehler/knut/lib/syntax"
 	"github.com/shopspring/decimal"
 )
 
 // Transaction represents a transaction.
 type Transaction struct {
-	Src         *syntax.Transaction
-	Date        time.Time
+	Src  *syntax.Transaction
+	Date time.Time
+
+	// Effective is the date on which the transaction takes effect, for
+	// example when an expense is recognized. It is zero if the
+	// transaction has no effective date.
+	Effective time.Time
+
+	// Time is the time of day as an offset from the start of Date. It is
+	// zero for transactions without a time.
+	Time time.Duration
+
 	Description string
+	Tags        []tag.Tag
 	Postings    []*posting.Posting
 	Targets     []*commodity.Commodity
 }
@@ -27,6 +39,9 @@ func Compare(t *Transaction, t2 *Transaction) compare.Order {
 	if o := compare.Time(t.Date, t2.Date); o != compare.Equal {
 		return o
 	}
+	if o := compare.Ordered(t.Time, t2.Time); o != compare.Equal {
+		return o
+	}
 	if o := compare.Ordered(t.Description, t2.Description); o != compare.Equal {
 		return o
 	}
@@ -42,7 +57,10 @@ func Compare(t *Transaction, t2 *Transaction) compare.Order {
 type Builder struct {
 	Src         *syntax.Transaction
 	Date        time.Time
+	Effective   time.Time
+	Time        time.Duration
 	Description string
+	Tags        []tag.Tag
 	Postings    []*posting.Posting
 	Targets     []*commodity.Commodity
 }
@@ -52,7 +70,10 @@ func (tb Builder) Build() *Transaction {
 	return &Transaction{
 		Src:         tb.Src,
 		Date:        tb.Date,
+		Effective:   tb.Effective,
+		Time:        tb.Time,
 		Description: tb.Description,
+		Tags:        tb.Tags,
 		Postings:    tb.Postings,
 		Targets:     tb.Targets,
 	}
@@ -63,6 +84,18 @@ func Create(reg *registry.Registry, t *syntax.Transaction) ([]*Transaction, erro
 	if err != nil {
 		return nil, err
 	}
+	var effective time.Time
+	if !t.Effective.Empty() {
+		if effective, err = t.Effective.Parse(); err != nil {
+			return nil, err
+		}
+	}
+	var tm time.Duration
+	if !t.Time.Empty() {
+		if tm, err = t.Time.Parse(); err != nil {
+			return nil, err
+		}
+	}
 	desc := t.Description.Content.Extract()
 	postings, err := posting.Create(reg, t.Bookings)
 	if err != nil {
@@ -82,7 +115,10 @@ func Create(reg *registry.Registry, t *syntax.Transaction) ([]*Transaction, erro
 	res := Builder{
 		Src:         t,
 		Date:        date,
+		Effective:   effective,
+		Time:        tm,
 		Description: desc,
+		Tags:        tag.Create(t.Tags),
 		Postings:    postings,
 		Targets:     targets,
 	}.Build()
@@ -93,6 +129,43 @@ func Create(reg *registry.Registry, t *syntax.Transaction) ([]*Transaction, erro
 
 }
 
+// CreateOpening expands an opening balance directive into a transaction which
+// books the given balances against the equity account.
+func CreateOpening(reg *registry.Registry, o *syntax.Opening) ([]*Transaction, error) {
+	date, err := o.Date.Parse()
+	if err != nil {
+		return nil, err
+	}
+	var builders posting.Builders
+	for _, bal := range o.Balances {
+		account, err := reg.Accounts().Create(bal.Account)
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := bal.Quantity.Parse()
+		if err != nil {
+			return nil, err
+		}
+		commodity, err := reg.Commodities().Create(bal.Commodity)
+		if err != nil {
+			return nil, err
+		}
+		builders = append(builders, posting.Builder{
+			Credit:    reg.Accounts().EquityAccount(),
+			Debit:     account,
+			Commodity: commodity,
+			Quantity:  quantity,
+		})
+	}
+	return []*Transaction{
+		Builder{
+			Date:        date,
+			Description: "Opening balance",
+			Postings:    builders.Build(),
+		}.Build(),
+	}, nil
+}
+
 // Expand expands an accrual transaction.
 func expand(reg *registry.Registry, t *Transaction, accrual *syntax.Accrual) ([]*Transaction, error) {
 	account, err := reg.Accounts().Create(accrual.Account)
@@ -121,33 +194,52 @@ func expand(reg *registry.Registry, t *Transaction, accrual *syntax.Accrual) ([]
 			result = append(result, Builder{
 				Src:         t.Src,
 				Date:        t.Date,
+				Time:        t.Time,
 				Description: t.Description,
+				Tags:        t.Tags,
 				Postings: posting.Builder{
 					Credit:    account,
 					Debit:     p.Account,
 					Commodity: p.Commodity,
 					Quantity:  p.Quantity,
+					Tags:      p.Tags,
+					State:     p.State,
+					Virtual:   p.Virtual,
 				}.Build(),
 				Targets: t.Targets,
 			}.Build())
 		}
 		if p.Account.IsIE() {
 			partition := date.NewPartition(date.Period{Start: start, End: end}, interval, 0)
+			dates := partition.EndDates()
+			if accrual.Boundary.Extract() == "start" {
+				dates = partition.StartDates()
+			}
+			// The remainder is booked in the first period, unless
+			// specified otherwise.
 			amount, rem := p.Quantity.QuoRem(decimal.NewFromInt(int64(partition.Size())), 1)
-			for i, dt := range partition.EndDates() {
+			remIndex := 0
+			if accrual.Remainder.Extract() == "last" {
+				remIndex = len(dates) - 1
+			}
+			for i, dt := range dates {
 				a := amount
-				if i == 0 {
+				if i == remIndex {
 					a = a.Add(rem)
 				}
 				result = append(result, Builder{
 					Src:         t.Src,
 					Date:        dt,
 					Description: fmt.Sprintf("%s (accrual %d/%d)", t.Description, i+1, partition.Size()),
+					Tags:        t.Tags,
 					Postings: posting.Builder{
 						Credit:    account,
 						Debit:     p.Account,
 						Commodity: p.Commodity,
 						Quantity:  a,
+						Tags:      p.Tags,
+						State:     p.State,
+						Virtual:   p.Virtual,
 					}.Build(),
 					Targets: t.Targets,
 				}.Build())
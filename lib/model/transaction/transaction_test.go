@@ -0,0 +1,91 @@
+package transaction
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/sboehler/knut/lib/syntax/parser"
+)
+
+func TestCreateAccrual(t *testing.T) {
+	tests := []struct {
+		desc string
+		text string
+		want []string
+	}{
+		{
+			desc: "book at the end of the periods",
+			text: "@accrue monthly 2020-01-15 2020-03-31 Assets:Prepaid\n2020-01-10 \"T\"\nAssets:Bank Expenses:Tax 100 USD\n",
+			want: []string{
+				"2020-01-10 Assets:Bank -> Assets:Prepaid 100",
+				"2020-01-31 Assets:Prepaid -> Expenses:Tax 33.4",
+				"2020-02-29 Assets:Prepaid -> Expenses:Tax 33.3",
+				"2020-03-31 Assets:Prepaid -> Expenses:Tax 33.3",
+			},
+		},
+		{
+			desc: "book at the start of the periods",
+			text: "@accrue monthly 2020-01-15 2020-03-31 Assets:Prepaid start\n2020-01-10 \"T\"\nAssets:Bank Expenses:Tax 100 USD\n",
+			want: []string{
+				"2020-01-10 Assets:Bank -> Assets:Prepaid 100",
+				"2020-01-15 Assets:Prepaid -> Expenses:Tax 33.4",
+				"2020-02-01 Assets:Prepaid -> Expenses:Tax 33.3",
+				"2020-03-01 Assets:Prepaid -> Expenses:Tax 33.3",
+			},
+		},
+		{
+			desc: "book the remainder in the last period",
+			text: "@accrue monthly 2020-01-01 2020-03-31 Assets:Prepaid last\n2020-01-10 \"T\"\nAssets:Bank Expenses:Tax 100 USD\n",
+			want: []string{
+				"2020-01-10 Assets:Bank -> Assets:Prepaid 100",
+				"2020-01-31 Assets:Prepaid -> Expenses:Tax 33.3",
+				"2020-02-29 Assets:Prepaid -> Expenses:Tax 33.3",
+				"2020-03-31 Assets:Prepaid -> Expenses:Tax 33.4",
+			},
+		},
+		{
+			desc: "accrue every posting",
+			text: "@accrue monthly 2020-01-01 2020-02-29 Assets:Prepaid\n2020-01-10 \"T\"\nAssets:Bank Expenses:Tax 100 USD\nAssets:Bank Expenses:Fees 10 USD\n",
+			want: []string{
+				"2020-01-10 Assets:Bank -> Assets:Prepaid 100",
+				"2020-01-31 Assets:Prepaid -> Expenses:Tax 50",
+				"2020-02-29 Assets:Prepaid -> Expenses:Tax 50",
+				"2020-01-10 Assets:Bank -> Assets:Prepaid 10",
+				"2020-01-31 Assets:Prepaid -> Expenses:Fees 5",
+				"2020-02-29 Assets:Prepaid -> Expenses:Fees 5",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			reg := registry.New()
+			p := parser.New(test.text, "")
+			if err := p.Advance(); err != nil {
+				t.Fatalf("p.Advance() returned unexpected error: %v", err)
+			}
+			f, err := p.ParseFile()
+			if err != nil {
+				t.Fatalf("p.ParseFile() returned unexpected error: %v", err)
+			}
+			st := f.Directives[0].Directive.(syntax.Transaction)
+			trx, err := Create(reg, &st)
+			if err != nil {
+				t.Fatalf("Create() returned unexpected error: %v", err)
+			}
+			var got []string
+			for _, t := range trx {
+				pst := t.Postings[0]
+				if pst.Quantity.IsNegative() {
+					pst = t.Postings[1]
+				}
+				got = append(got, fmt.Sprintf("%s %s -> %s %s", t.Date.Format("2006-01-02"), pst.Other.Name(), pst.Account.Name(), pst.Quantity))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Create() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Package allocation computes the allocation of a portfolio to classes of
+// commodities, such as equities, bonds, cash or crypto.
+package allocation
+
+import (
+	"time"
+
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/performance"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/maps"
+)
+
+// Report holds the value of the portfolio in each class on a given date.
+// The processor returned by Process must run after the processor returned
+// by performance.Calculator.ComputeValues.
+type Report struct {
+	Universe performance.Universe
+	Date     time.Time
+
+	values map[*model.Commodity]decimal.Decimal
+}
+
+// NewReport creates a new report. Commodities which are not classified in
+// the universe are assigned to the class "Other".
+func NewReport(universe performance.Universe, date time.Time) *Report {
+	return &Report{
+		Universe: universe,
+		Date:     date,
+	}
+}
+
+// Process returns a processor which fills the report.
+func (r *Report) Process() *journal.Processor {
+	return &journal.Processor{
+		DayEnd: func(d *journal.Day) error {
+			if !d.Date.After(r.Date) && d.Performance != nil {
+				r.values = d.Performance.V1
+			}
+			return nil
+		},
+	}
+}
+
+// Entry is the value of a class and its share of the total value.
+type Entry struct {
+	Class string
+	Value decimal.Decimal
+	Share float64
+}
+
+// Entries returns an entry for each class with a non-zero value, sorted by
+// decreasing value.
+func (r *Report) Entries() []Entry {
+	values := make(map[string]decimal.Decimal)
+	for c, v := range r.values {
+		class := r.Universe.Class(c)
+		values[class] = values[class].Add(v)
+	}
+	total := r.Total()
+	var res []Entry
+	for class, v := range values {
+		if v.IsZero() {
+			continue
+		}
+		e := Entry{Class: class, Value: v}
+		if !total.IsZero() {
+			e.Share, _ = v.Div(total).Float64()
+		}
+		res = append(res, e)
+	}
+	compare.Sort(res, func(e1, e2 Entry) compare.Order {
+		if o := compare.Decimal(e2.Value, e1.Value); o != compare.Equal {
+			return o
+		}
+		return compare.Ordered(e1.Class, e2.Class)
+	})
+	return res
+}
+
+// Total returns the total value of the portfolio.
+func (r *Report) Total() decimal.Decimal {
+	return decimal.Sum(decimal.Zero, maps.Values(r.values)...)
+}
+
+// Renderer renders a report.
+type Renderer struct{}
+
+// Render renders a report, with a row for each class.
+func (rn Renderer) Render(r *Report) *table.Table {
+	tbl := table.New(1, 1, 1)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().
+		AddText("Class", table.Center).
+		AddText(r.Date.Format("2006-01-02"), table.Center).
+		AddText("Share", table.Center)
+	tbl.AddSeparatorRow()
+	for _, e := range r.Entries() {
+		tbl.AddRow().
+			AddText(e.Class, table.Left).
+			AddDecimal(e.Value).
+			AddPercent(e.Share)
+	}
+	tbl.AddSeparatorRow()
+	row := tbl.AddRow().
+		AddText("Total", table.Left).
+		AddDecimal(r.Total())
+	if r.Total().IsZero() {
+		row.AddEmpty()
+	} else {
+		row.AddPercent(1)
+	}
+	tbl.AddSeparatorRow()
+	return tbl
+}
@@ -0,0 +1,73 @@
+package allocation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/predicate"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/performance"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestEntries(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	portfolio, equity := reg.Accounts().MustGet("Assets:Portfolio"), reg.Accounts().MustGet("Equity:Equity")
+	universe, err := performance.LoadUniverse(reg.Commodities(), strings.NewReader("Equity: [AAPL]\nCash: [CHF]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := journal.New()
+	j.Add(&model.Open{Date: date.Date(2020, 1, 1), Account: portfolio})
+	j.Add(&model.Open{Date: date.Date(2020, 1, 1), Account: equity})
+	for name, p := range map[string]int64{"AAPL": 100, "BTC": 1000} {
+		j.Add(&model.Price{Date: date.Date(2020, 1, 1), Commodity: reg.Commodities().MustGet(name), Target: chf, Price: decimal.NewFromInt(p)})
+	}
+	for name, qty := range map[string]int64{"AAPL": 6, "BTC": 1, "CHF": 1400} {
+		j.Add(transaction.Builder{
+			Date: date.Date(2020, 1, 1),
+			Postings: posting.Builder{
+				Credit:    equity,
+				Debit:     portfolio,
+				Commodity: reg.Commodities().MustGet(name),
+				Quantity:  decimal.NewFromInt(qty),
+			}.Build(),
+		}.Build())
+	}
+	calculator := &performance.Calculator{
+		Context:         reg,
+		Valuation:       chf,
+		AccountFilter:   predicate.True[*model.Account],
+		CommodityFilter: predicate.True[*model.Commodity],
+	}
+	rep := NewReport(universe, date.Date(2020, 1, 31))
+
+	err = j.Build().Process(
+		journal.ComputePrices(chf),
+		journal.Valuate(reg, chf),
+		calculator.ComputeValues(),
+		rep.Process(),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Class: "Cash", Value: decimal.NewFromInt(1400), Share: 1400.0 / 3000},
+		{Class: "Other", Value: decimal.NewFromInt(1000), Share: 1000.0 / 3000},
+		{Class: "Equity", Value: decimal.NewFromInt(600), Share: 0.2},
+	}
+	if diff := cmp.Diff(want, rep.Entries()); diff != "" {
+		t.Errorf("Entries() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if got := rep.Total(); !got.Equal(decimal.NewFromInt(3000)) {
+		t.Errorf("Total() = %s, want 3000", got)
+	}
+}
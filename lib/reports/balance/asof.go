@@ -0,0 +1,89 @@
+package balance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/mapper"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/check"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/shopspring/decimal"
+)
+
+type positions amounts.Amounts
+
+func (ps positions) Insert(k amounts.Key, v decimal.Decimal) {
+	amounts.Amounts(ps).Add(k, v)
+}
+
+// AsOf returns the positions of all accounts at the end of the given date,
+// using the same processing pipeline as the balance command, including
+// its checks, without closing income and expense accounts. It processes a
+// copy of the journal, so the journal is not modified.
+//
+// The keys of the result are amounts.AccountCommodityKey(account,
+// commodity): only the Account and Commodity fields are set. If valuation
+// is nil, the amounts are the quantities of the commodity held in the
+// account. Otherwise, they are the values of these positions in the
+// valuation commodity as of the given date, including the valuation gains
+// and losses booked up to then. Virtual postings are ignored, and
+// positions which are zero are omitted.
+func AsOf(j *journal.Builder, reg *model.Registry, d time.Time, valuation *model.Commodity) (amounts.Amounts, error) {
+	res := make(amounts.Amounts)
+	period := j.Period()
+	if d.Before(period.Start) {
+		return res, nil
+	}
+	j = j.Clone()
+	partition := date.NewPartition(date.Period{Start: period.Start, End: d}, date.Once, 0)
+	j.Days(partition.EndDates())
+	err := j.Build().Process(
+		journal.ComputePrices(valuation),
+		check.Check(),
+		journal.Valuate(reg, valuation),
+		journal.Filter(partition),
+		journal.Query{
+			Select: amounts.KeyMapper{
+				Account:   mapper.Identity[*model.Account],
+				Commodity: mapper.Identity[*model.Commodity],
+			}.Build(),
+			Valuation: valuation,
+		}.Into(positions(res)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range res {
+		if v.IsZero() {
+			delete(res, k)
+		}
+	}
+	return res, nil
+}
+
+// TotalByType returns the values of all accounts at the end of the given
+// date in the valuation commodity, as computed by AsOf, aggregated by
+// account type. As the valuation gains and losses are booked to the
+// valuation accounts, the totals of all account types sum up to zero.
+func TotalByType(j *journal.Builder, reg *model.Registry, d time.Time, valuation *model.Commodity) (map[model.AccountType]decimal.Decimal, error) {
+	if valuation == nil {
+		return nil, fmt.Errorf("missing valuation commodity")
+	}
+	values, err := AsOf(j, reg, d, valuation)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[model.AccountType]decimal.Decimal)
+	for k, v := range values {
+		res[k.Account.Type()] = res[k.Account.Type()].Add(v)
+	}
+	for t, v := range res {
+		if v.IsZero() {
+			delete(res, t)
+		}
+	}
+	return res, nil
+}
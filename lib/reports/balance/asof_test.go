@@ -0,0 +1,146 @@
+package balance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/account"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestAsOf(t *testing.T) {
+	reg := registry.New()
+	chf, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD")
+	bank, salary := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Income:Salary")
+	build := func() *journal.Builder {
+		j := journal.New()
+		for _, d := range []*model.Open{
+			{Date: date.Date(2020, 1, 1), Account: bank},
+			{Date: date.Date(2020, 1, 1), Account: salary},
+		} {
+			j.Add(d)
+		}
+		j.Add(&model.Price{Date: date.Date(2020, 1, 1), Commodity: usd, Target: chf, Price: decimal.RequireFromString("0.9")})
+		j.Add(&model.Price{Date: date.Date(2020, 1, 15), Commodity: usd, Target: chf, Price: decimal.RequireFromString("0.8")})
+		j.Add(&model.Price{Date: date.Date(2020, 2, 15), Commodity: usd, Target: chf, Price: decimal.RequireFromString("0.7")})
+		for _, d := range []time.Time{date.Date(2020, 1, 1), date.Date(2020, 2, 1)} {
+			j.Add(transaction.Builder{
+				Date: d,
+				Postings: posting.Builder{
+					Credit:    salary,
+					Debit:     bank,
+					Commodity: usd,
+					Quantity:  decimal.NewFromInt(100),
+				}.Build(),
+			}.Build())
+		}
+		return j
+	}
+
+	for _, test := range []struct {
+		desc      string
+		valuation *model.Commodity
+		want      amounts.Amounts
+	}{
+		{
+			desc: "quantities",
+			want: amounts.Amounts{
+				amounts.AccountCommodityKey(bank, usd):   decimal.NewFromInt(200),
+				amounts.AccountCommodityKey(salary, usd): decimal.NewFromInt(-200),
+			},
+		},
+		{
+			desc:      "values",
+			valuation: chf,
+			want: amounts.Amounts{
+				amounts.AccountCommodityKey(bank, usd):                                  decimal.NewFromInt(160),
+				amounts.AccountCommodityKey(salary, usd):                                decimal.NewFromInt(-170),
+				amounts.AccountCommodityKey(reg.Accounts().MustGet("Income:Bank"), usd): decimal.NewFromInt(10),
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			j := build()
+
+			// The journal is not modified, so it can be processed again.
+			for i := 0; i < 2; i++ {
+				got, err := AsOf(j, reg, date.Date(2020, 2, 10), test.valuation)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(test.want, got); diff != "" {
+					t.Errorf("AsOf() returned unexpected diff (-want/+got):\n%s\n", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestTotalByType(t *testing.T) {
+	reg := registry.New()
+	chf, usd := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD")
+	bank, salary, food := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Income:Salary"), reg.Accounts().MustGet("Expenses:Food")
+	build := func(accounts ...*model.Account) *journal.Builder {
+		j := journal.New()
+		for _, a := range accounts {
+			j.Add(&model.Open{Date: date.Date(2020, 1, 1), Account: a})
+		}
+		j.Add(&model.Price{Date: date.Date(2020, 1, 1), Commodity: usd, Target: chf, Price: decimal.RequireFromString("0.9")})
+		j.Add(&model.Price{Date: date.Date(2020, 2, 1), Commodity: usd, Target: chf, Price: decimal.RequireFromString("0.8")})
+		j.Add(transaction.Builder{
+			Date: date.Date(2020, 1, 1),
+			Postings: posting.Builder{
+				Credit:    salary,
+				Debit:     bank,
+				Commodity: usd,
+				Quantity:  decimal.NewFromInt(100),
+			}.Build(),
+		}.Build())
+		j.Add(transaction.Builder{
+			Date: date.Date(2020, 1, 2),
+			Postings: posting.Builder{
+				Credit:    bank,
+				Debit:     food,
+				Commodity: chf,
+				Quantity:  decimal.NewFromInt(9),
+			}.Build(),
+		}.Build())
+		return j
+	}
+
+	t.Run("totals", func(t *testing.T) {
+		// The income is valued at the price of the day it is booked, and
+		// the loss of value of the USD position is booked to the valuation
+		// account Income:Bank.
+		got, err := TotalByType(build(bank, salary, food), reg, date.Date(2020, 2, 1), chf)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[model.AccountType]decimal.Decimal{
+			account.ASSETS:   decimal.NewFromInt(71),
+			account.INCOME:   decimal.NewFromInt(-80),
+			account.EXPENSES: decimal.NewFromInt(9),
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("TotalByType() returned unexpected diff (-want/+got):\n%s\n", diff)
+		}
+	})
+
+	t.Run("check", func(t *testing.T) {
+		_, err := TotalByType(build(bank, salary), reg, date.Date(2020, 2, 1), chf)
+
+		if err == nil {
+			t.Errorf("TotalByType() returned no error for a booking to an account which is not open")
+		}
+	})
+}
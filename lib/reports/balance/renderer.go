@@ -19,12 +19,15 @@ import (
 
 	"github.com/sboehler/knut/lib/amounts"
 	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/dict"
 	"github.com/sboehler/knut/lib/common/mapper"
 	"github.com/sboehler/knut/lib/common/regex"
 	"github.com/sboehler/knut/lib/common/table"
 	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/account"
 	"github.com/sboehler/knut/lib/model/commodity"
 	"github.com/shopspring/decimal"
+	"golang.org/x/exp/slices"
 )
 
 // Renderer renders a report.
@@ -32,93 +35,370 @@ type Renderer struct {
 	Valuation          *model.Commodity
 	CommodityDetails   regex.Regexes
 	SortAlphabetically bool
-	Diff               bool
+
+	// Diff renders the change of every row within each period instead of
+	// the cumulative balance at the end of the period.
+	Diff bool
+
+	// Flows renders the rows of the income and expense accounts, and the
+	// result, as the change within each period, while the other rows stay
+	// cumulative.
+	Flows bool
+
+	// Subtotals renders a row with the total of each top-level account,
+	// such as Assets or Expenses, after its accounts.
+	Subtotals bool
+
+	// CommodityTotals renders the total value of the A+L accounts for
+	// each commodity, after the total of the A+L accounts. It has no
+	// effect without a valuation or in pivot mode, where the total is
+	// split by commodity already.
+	CommodityTotals bool
+
+	// OnlyTotals renders a single row with the total of each account
+	// type, such as Assets or Income, instead of the accounts, followed
+	// by the grand totals.
+	OnlyTotals bool
+
+	// Flat renders one row per account with the full account name,
+	// without the hierarchy of segments.
+	Flat bool
+
+	// Notes maps accounts to their notes. If it is not nil, a column
+	// with the notes is rendered.
+	Notes map[*model.Account]string
+
+	// Tags renders a row for each tag of an account, with a column
+	// for the tag.
+	Tags bool
+
+	// Pivot renders a column for each commodity instead of a column for
+	// each period, with the amounts of the last period. Tags are not
+	// rendered in pivot mode.
+	Pivot bool
+
+	// CollapseZero hides the rows of accounts which are zero in all
+	// periods, and accounts all of whose subaccounts are hidden.
+	CollapseZero bool
+
+	// PositiveOnly and NegativeOnly hide the rows whose amount in the last
+	// period, as rendered, is not positive or not negative, respectively,
+	// and accounts all of whose subaccounts are hidden. The totals still
+	// include the hidden rows.
+	PositiveOnly, NegativeOnly bool
+
+	// Reverse renders the periods from the newest to the oldest. The
+	// amounts are computed in chronological order regardless, so a diff
+	// is always the change from the preceding period.
+	Reverse bool
+
+	// Transpose renders a row for each column and a column for each row,
+	// for example a row for each period and a column for each account.
+	// Separators and empty rows are dropped.
+	Transpose bool
+
+	// Annualize scales the rows of the income and expense accounts and
+	// the result by the ratio of a year to the length of each period.
+	// The rows must hold the flows within each period, as they do if the
+	// income and expenses are closed at the start of each period, with
+	// Diff if they are not closed, or with Flows.
+	Annualize bool
 
 	drawCommsColumn bool
 	partition       date.Partition
+	commodities     []*model.Commodity
+	factors         []decimal.Decimal
 }
 
 // Render renders a report.
 func (rn *Renderer) Render(r *Report) *table.Table {
-	rn.drawCommsColumn = rn.Valuation == nil || len(rn.CommodityDetails) > 0
+	rn.drawCommsColumn = !rn.Pivot && (rn.Valuation == nil || len(rn.CommodityDetails) > 0 || rn.CommodityTotals)
 	rn.partition = r.partition
+	rn.factors = nil
+	if rn.Annualize {
+		ends := rn.partition.EndDates()
+		for i, start := range rn.partition.StartDates() {
+			rn.factors = append(rn.factors, annualFactor(start, ends[i]))
+		}
+	}
+	if rn.Pivot {
+		rn.Tags = false
+		rn.commodities = r.Commodities()
+	}
 	r.SetAccounts()
 	if rn.SortAlphabetically {
 		r.SortAlpha()
 	} else {
 		r.SortWeighted()
 	}
-	var tbl *table.Table
+	groups := []int{1}
+	if rn.Notes != nil {
+		groups = append(groups, 1)
+	}
+	if rn.Tags {
+		groups = append(groups, 1)
+	}
 	if rn.drawCommsColumn {
-		tbl = table.New(1, 1, rn.partition.Size())
+		groups = append(groups, 1)
+	}
+	if rn.Pivot {
+		groups = append(groups, len(rn.commodities))
 	} else {
-		tbl = table.New(1, rn.partition.Size())
+		groups = append(groups, rn.partition.Size())
 	}
+	tbl := table.New(groups...)
 	tbl.AddSeparatorRow()
 	header := tbl.AddRow().AddText("Account", table.Center)
+	if rn.Notes != nil {
+		header.AddText("Note", table.Center)
+	}
+	if rn.Tags {
+		header.AddText("Tag", table.Center)
+	}
 	if rn.drawCommsColumn {
 		header.AddText("Comm", table.Center)
 	}
-	for _, d := range rn.partition.EndDates() {
-		header.AddText(d.Format("2006-01-02"), table.Center)
+	if rn.Pivot {
+		for _, c := range rn.commodities {
+			header.AddText(c.Name(), table.Center)
+		}
+	} else {
+		for _, d := range rn.dates() {
+			header.AddText(d.Format("2006-01-02"), table.Center)
+		}
 	}
 	tbl.AddSeparatorRow()
 
-	totalAL, totalResult, totalEIE := r.Totals(amounts.KeyMapper{
+	totalsMapper := amounts.KeyMapper{
 		Date:      mapper.Identity[time.Time],
-		Commodity: commodity.IdentityIf(rn.Valuation == nil),
-	}.Build())
+		Commodity: commodity.IdentityIf(rn.Valuation == nil || rn.Pivot),
+	}.Build()
+	totalAL, totalResult, totalEIE := r.Totals(totalsMapper)
 
+	if rn.OnlyTotals {
+		rn.renderTotals(tbl, false, r.AL.Sorted, totalsMapper)
+	}
 	for _, n := range r.AL.Sorted {
+		if rn.hidden(n) || rn.OnlyTotals {
+			continue
+		}
 		rn.renderNode(tbl, 0, false, n)
+		if rn.Subtotals {
+			rn.render(tbl, 0, nil, "Total "+n.Segment, false, false, Subtotal(n, totalsMapper))
+		}
 		tbl.AddEmptyRow()
 	}
 
-	rn.render(tbl, 0, "Total (A+L)", false, totalAL)
+	rn.render(tbl, 0, nil, "Total (A+L)", false, false, totalAL)
+	if rn.CommodityTotals && rn.Valuation != nil && !rn.Pivot {
+		commodityAL, _, _ := r.Totals(amounts.KeyMapper{
+			Date:      mapper.Identity[time.Time],
+			Commodity: mapper.Identity[*model.Commodity],
+		}.Build())
+		tbl.AddEmptyRow()
+		rn.render(tbl, 0, nil, "By commodity (A+L)", false, false, commodityAL)
+	}
 	tbl.AddSeparatorRow()
+	if rn.Annualize {
+		tbl.AddRow().AddText("Income and expenses annualized", table.Left).FillEmpty()
+		tbl.AddEmptyRow()
+	}
+	if rn.OnlyTotals {
+		rn.renderTotals(tbl, true, r.EIE.Sorted, totalsMapper)
+	}
 	for _, n := range r.EIE.Sorted {
+		if rn.hidden(n) || rn.OnlyTotals {
+			continue
+		}
 		rn.renderNode(tbl, 0, true, n)
+		if rn.Subtotals {
+			rn.render(tbl, 0, nil, "Total "+n.Segment, true, rn.isFlow(n), Subtotal(n, totalsMapper))
+		}
 		tbl.AddEmptyRow()
 	}
-	rn.render(tbl, 0, "Result (I+E)", true, totalResult)
+	result := "Result (I+E)"
+	if rn.Annualize {
+		result += " p.a."
+	}
+	rn.render(tbl, 0, nil, result, true, true, totalResult)
 	tbl.AddEmptyRow()
-	rn.render(tbl, 0, "Total (E+I+E)", true, totalEIE)
+	rn.render(tbl, 0, nil, "Total (E+I+E)", true, false, totalEIE)
 	tbl.AddSeparatorRow()
 	totalAL.Plus(totalEIE)
-	rn.render(tbl, 0, "Delta", false, totalAL)
+	rn.render(tbl, 0, nil, "Delta", false, false, totalAL)
 	tbl.AddSeparatorRow()
 
+	if rn.Transpose {
+		return tbl.Transpose()
+	}
 	return tbl
 }
 
+// renderTotals renders a row with the total of each of the given
+// top-level nodes, followed by an empty row.
+func (rn *Renderer) renderTotals(t *table.Table, neg bool, ns []*Node, m mapper.Mapper[amounts.Key]) {
+	var rendered bool
+	for _, n := range ns {
+		if rn.hidden(n) {
+			continue
+		}
+		vals := Subtotal(n, m)
+		if rn.PositiveOnly || rn.NegativeOnly {
+			rn.filterSign(vals, neg, rn.isFlow(n))
+			if len(vals) == 0 {
+				continue
+			}
+		}
+		rn.render(t, 0, nil, n.Segment, neg, rn.isFlow(n), vals)
+		rendered = true
+	}
+	if rendered {
+		t.AddEmptyRow()
+	}
+}
+
 func (rn *Renderer) renderNode(t *table.Table, indent int, neg bool, n *Node) {
-	var vals amounts.Amounts
-	if n.Value.Account != nil {
-		showCommodities := rn.Valuation == nil || rn.CommodityDetails.MatchString(n.Value.Account.Name())
-		vals = n.Value.Amounts.SumBy(nil, amounts.KeyMapper{
-			Date:      mapper.Identity[time.Time],
-			Commodity: commodity.IdentityIf(showCommodities),
-		}.Build())
+	if rn.hidden(n) {
+		return
+	}
+	vals := rn.values(n)
+	if rn.Flat {
+		if len(vals) > 0 {
+			rn.render(t, 0, n.Value.Account, n.Value.Account.Name(), neg, rn.isFlow(n), vals)
+		}
+		for _, ch := range n.Sorted {
+			rn.renderNode(t, 0, neg, ch)
+		}
+		return
 	}
 	if n.Segment != "" {
-		rn.render(t, indent, n.Segment, neg, vals)
+		rn.render(t, indent, n.Value.Account, n.Segment, neg, rn.isFlow(n), vals)
 	}
 	for _, ch := range n.Sorted {
 		rn.renderNode(t, indent+2, neg, ch)
 	}
 }
 
-func (rn *Renderer) render(t *table.Table, indent int, name string, neg bool, vals amounts.Amounts) {
+// values returns the amounts of the account of the node, aggregated into
+// the keys of the rendered rows.
+func (rn *Renderer) values(n *Node) amounts.Amounts {
+	if n.Value.Account == nil {
+		return nil
+	}
+	showCommodities := rn.Valuation == nil || rn.Pivot || rn.CommodityDetails.MatchString(n.Value.Account.Name())
+	vals := n.Value.Amounts.SumBy(nil, amounts.KeyMapper{
+		Date:      mapper.Identity[time.Time],
+		Commodity: commodity.IdentityIf(showCommodities),
+		Tag:       mapper.IdentityIf[model.Tag](rn.Tags),
+	}.Build())
+	if rn.CollapseZero {
+		// A row is zero in all periods, cumulative or not, if and only if
+		// it has no change in any period.
+		for k, v := range vals {
+			if v.IsZero() {
+				delete(vals, k)
+			}
+		}
+	}
+	if rn.PositiveOnly || rn.NegativeOnly {
+		rn.filterSign(vals, !n.Value.Account.IsAL(), rn.isFlow(n))
+	}
+	return vals
+}
+
+// filterSign removes the rows whose amount in the last period has the
+// wrong sign, the way the row is rendered.
+func (rn *Renderer) filterSign(vals amounts.Amounts, neg, flow bool) {
+	diff := rn.Diff || rn.Flows && flow
+	dates := rn.partition.EndDates()
+	last := dates[len(dates)-1]
+	row := func(k amounts.Key) amounts.Key {
+		return amounts.Key{Commodity: k.Commodity, Tag: k.Tag}
+	}
+	totals := make(map[amounts.Key]decimal.Decimal)
+	for k, v := range vals {
+		if diff && !k.Date.Equal(last) {
+			continue
+		}
+		totals[row(k)] = totals[row(k)].Add(v)
+	}
+	for k := range vals {
+		total := totals[row(k)]
+		if neg {
+			total = total.Neg()
+		}
+		if rn.PositiveOnly && !total.IsPositive() || rn.NegativeOnly && !total.IsNegative() {
+			delete(vals, k)
+		}
+	}
+}
+
+// isFlow returns whether the node is an income or expense account, whose
+// rows are flows with Flows and are annualized with Annualize.
+func (rn *Renderer) isFlow(n *Node) bool {
+	if n.Value.Account == nil {
+		return false
+	}
+	t := n.Value.Account.Type()
+	return t == account.INCOME || t == account.EXPENSES
+}
+
+// annualFactor returns the ratio of the length of a year to the length of
+// the period with the given first and last day.
+func annualFactor(start, end time.Time) decimal.Decimal {
+	days := int64(end.Sub(start)/(24*time.Hour)) + 1
+	year := int64(start.AddDate(1, 0, 0).Sub(start) / (24 * time.Hour))
+	return decimal.NewFromInt(year).Div(decimal.NewFromInt(days))
+}
+
+// hidden returns whether the node and all its descendants have no rows
+// to render, because they are zero in all periods or have the wrong sign,
+// and are therefore hidden.
+func (rn *Renderer) hidden(n *Node) bool {
+	if !rn.CollapseZero && !rn.PositiveOnly && !rn.NegativeOnly || len(rn.values(n)) > 0 {
+		return false
+	}
+	for _, ch := range n.Sorted {
+		if !rn.hidden(ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// render renders the rows of an account or a total. The rows of the E+I+E
+// section are rendered negated, and those of income and expense accounts
+// are flows.
+func (rn *Renderer) render(t *table.Table, indent int, account *model.Account, name string, neg, flow bool, vals amounts.Amounts) {
+	if rn.Pivot {
+		rn.renderPivot(t, indent, account, name, neg, flow, vals)
+		return
+	}
 	if len(vals) == 0 {
-		t.AddRow().AddIndented(name, indent).FillEmpty()
+		row := t.AddRow().AddIndented(name, indent)
+		rn.renderNote(row, account)
+		row.FillEmpty()
 		return
 	}
-	for i, commodity := range vals.CommoditiesSorted() {
+	for i, key := range rows(vals) {
+		commodity := key.Commodity
 		row := t.AddRow()
-		if i == 0 {
+		// In flat mode, the name is repeated for easier post-processing.
+		if i == 0 || rn.Flat {
 			row.AddIndented(name, indent)
+			rn.renderNote(row, account)
 		} else {
 			row.AddEmpty()
+			if rn.Notes != nil {
+				row.AddEmpty()
+			}
+		}
+		if rn.Tags {
+			if key.Tag != "" {
+				row.AddText(key.Tag.Name(), table.Left)
+			} else {
+				row.AddEmpty()
+			}
 		}
 		if rn.drawCommsColumn {
 			if commodity != nil {
@@ -129,17 +409,108 @@ func (rn *Renderer) render(t *table.Table, indent int, name string, neg bool, va
 				row.AddEmpty()
 			}
 		}
+		diff := rn.Diff || rn.Flows && flow
 		var total decimal.Decimal
-		for _, date := range rn.partition.EndDates() {
-			v := vals[amounts.DateCommodityKey(date, commodity)]
-			if !rn.Diff {
+		values := make([]decimal.Decimal, 0, rn.partition.Size())
+		for i, date := range rn.partition.EndDates() {
+			v := vals[amounts.Key{Date: date, Commodity: commodity, Tag: key.Tag}]
+			if !diff {
 				total = total.Add(v)
 				v = total
 			}
 			if neg {
 				v = v.Neg()
 			}
-			row.AddDecimal(v)
+			if rn.Annualize && flow {
+				v = v.Mul(rn.factors[i])
+			}
+			values = append(values, v)
+		}
+		if rn.Reverse {
+			slices.Reverse(values)
+		}
+		unit := commodity
+		if unit == nil {
+			unit = rn.Valuation
+		}
+		for _, v := range values {
+			addAmount(row, v, unit)
+		}
+	}
+}
+
+// dates returns the end dates of the periods in the order in which they
+// are rendered.
+func (rn *Renderer) dates() []time.Time {
+	dates := rn.partition.EndDates()
+	if rn.Reverse {
+		dates = slices.Clone(dates)
+		slices.Reverse(dates)
+	}
+	return dates
+}
+
+func (rn *Renderer) renderNote(row *table.Row, account *model.Account) {
+	if rn.Notes == nil {
+		return
+	}
+	if note, ok := rn.Notes[account]; ok {
+		row.AddText(note, table.Left)
+	} else {
+		row.AddEmpty()
+	}
+}
+
+func (rn *Renderer) renderPivot(t *table.Table, indent int, account *model.Account, name string, neg, flow bool, vals amounts.Amounts) {
+	row := t.AddRow().AddIndented(name, indent)
+	rn.renderNote(row, account)
+	if len(vals) == 0 {
+		row.FillEmpty()
+		return
+	}
+	diff := rn.Diff || rn.Flows && flow
+	dates := rn.partition.EndDates()
+	last := dates[len(dates)-1]
+	for _, c := range rn.commodities {
+		var total decimal.Decimal
+		for k, v := range vals {
+			if k.Commodity != c || diff && !k.Date.Equal(last) {
+				continue
+			}
+			total = total.Add(v)
+		}
+		if neg {
+			total = total.Neg()
+		}
+		if rn.Annualize && flow {
+			total = total.Mul(rn.factors[len(rn.factors)-1])
+		}
+		addAmount(row, total, c)
+	}
+}
+
+// addAmount adds a number cell which is displayed with the symbol of the
+// given commodity, if it has one.
+func addAmount(row *table.Row, v decimal.Decimal, c *model.Commodity) {
+	var prefix, suffix string
+	if c != nil {
+		prefix, suffix = c.Affixes()
+	}
+	row.AddAmount(v, prefix, suffix)
+}
+
+// rows returns the tag and commodity of each row, sorted by tag and
+// commodity.
+func rows(vals amounts.Amounts) []amounts.Key {
+	byTag := make(map[model.Tag]amounts.Amounts)
+	for k, v := range vals {
+		dict.GetDefault(byTag, k.Tag, func() amounts.Amounts { return make(amounts.Amounts) })[k] = v
+	}
+	var res []amounts.Key
+	for _, tag := range vals.TagsSorted() {
+		for _, commodity := range byTag[tag].CommoditiesSorted() {
+			res = append(res, amounts.Key{Tag: tag, Commodity: commodity})
 		}
 	}
+	return res
 }
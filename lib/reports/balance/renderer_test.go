@@ -0,0 +1,496 @@
+package balance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/tag"
+	"github.com/shopspring/decimal"
+)
+
+func TestRenderReverse(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	bank := reg.Accounts().MustGet("Assets:Bank")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 3, 31)}, date.Monthly, 0)
+
+	for _, test := range []struct {
+		desc          string
+		diff, reverse bool
+		want          []string
+	}{
+		{
+			desc: "balances",
+			want: []string{"Bank", "CHF", "100", "150", "120"},
+		},
+		{
+			desc:    "reversed balances",
+			reverse: true,
+			want:    []string{"Bank", "CHF", "120", "150", "100"},
+		},
+		{
+			desc: "diffs",
+			diff: true,
+			want: []string{"Bank", "CHF", "100", "50", "-30"},
+		},
+		{
+			desc:    "reversed diffs",
+			diff:    true,
+			reverse: true,
+			want:    []string{"Bank", "CHF", "-30", "50", "100"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			r := NewReport(reg, partition)
+			for i, v := range []int64{100, 50, -30} {
+				r.Insert(amounts.Key{Date: partition.EndDates()[i], Account: bank, Commodity: chf}, decimal.NewFromInt(v))
+			}
+			rn := Renderer{Diff: test.diff, Reverse: test.reverse}
+			var buf bytes.Buffer
+			if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+				t.Fatal(err)
+			}
+			recs, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rec := range recs {
+				if rec[0] == "Bank" {
+					got = rec
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestRenderCollapseZero(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 3, 31)}, date.Monthly, 0)
+
+	for _, test := range []struct {
+		desc         string
+		collapseZero bool
+		want         []string
+	}{
+		{
+			desc: "all accounts",
+			want: []string{"Assets", "Bank", "Old", "Cash", "Savings", "Expenses", "Food"},
+		},
+		{
+			desc:         "collapse zero",
+			collapseZero: true,
+			want:         []string{"Assets", "Bank", "Savings", "Expenses", "Food"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			r := NewReport(reg, partition)
+			dates := partition.EndDates()
+			for _, e := range []struct {
+				account string
+				date    int
+				value   int64
+			}{
+				{"Assets:Bank", 0, 100},
+				{"Assets:Old:Cash", 0, 0},
+				{"Assets:Old:Cash", 2, 0},
+				{"Assets:Savings", 1, 10},
+				{"Assets:Savings", 2, -10},
+				{"Expenses:Food", 1, 20},
+			} {
+				r.Insert(amounts.Key{Date: dates[e.date], Account: reg.Accounts().MustGet(e.account), Commodity: chf}, decimal.NewFromInt(e.value))
+			}
+			rn := Renderer{Diff: true, CollapseZero: test.collapseZero}
+			var buf bytes.Buffer
+			if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+				t.Fatal(err)
+			}
+			recs, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rec := range recs[1:] {
+				if name := strings.TrimSpace(rec[0]); name != "" && !strings.Contains(name, "(") && name != "Delta" {
+					got = append(got, name)
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestRenderOnlyTotals(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 1, 31)}, date.Monthly, 0)
+	r := NewReport(reg, partition)
+	for _, e := range []struct {
+		account string
+		value   int64
+	}{
+		{"Assets:Bank", 100},
+		{"Assets:Cash", 20},
+		{"Liabilities:Card", -30},
+		{"Expenses:Food", 40},
+		{"Income:Salary", -130},
+	} {
+		r.Insert(amounts.Key{Date: partition.EndDates()[0], Account: reg.Accounts().MustGet(e.account), Commodity: chf}, decimal.NewFromInt(e.value))
+	}
+	rn := Renderer{OnlyTotals: true, SortAlphabetically: true}
+	var buf bytes.Buffer
+
+	if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, rec := range recs[1:] {
+		if rec[0] != "" {
+			got = append(got, []string{rec[0], rec[len(rec)-1]})
+		}
+	}
+	want := [][]string{
+		{"Assets", "120"},
+		{"Liabilities", "-30"},
+		{"Total (A+L)", "90"},
+		{"Income", "130"},
+		{"Expenses", "-40"},
+		{"Result (I+E)", "90"},
+		{"Total (E+I+E)", "90"},
+		{"Delta", "0"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestRenderCommodityTotals(t *testing.T) {
+	reg := registry.New()
+	chf, aapl := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("AAPL")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 1, 31)}, date.Monthly, 0)
+	r := NewReport(reg, partition)
+	for _, e := range []struct {
+		account   string
+		commodity *model.Commodity
+		value     int64
+	}{
+		{"Assets:Bank", chf, 100},
+		{"Assets:Broker", chf, 50},
+		{"Assets:Broker", aapl, 20},
+		{"Assets:Pension", aapl, 10},
+		{"Equity:Equity", chf, -180},
+	} {
+		r.Insert(amounts.Key{Date: partition.EndDates()[0], Account: reg.Accounts().MustGet(e.account), Commodity: e.commodity}, decimal.NewFromInt(e.value))
+	}
+	rn := Renderer{Valuation: chf, CommodityTotals: true, SortAlphabetically: true}
+	var buf bytes.Buffer
+
+	if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for i, rec := range recs {
+		if strings.HasPrefix(rec[0], "By commodity") {
+			got = append(got, recs[i][1:], recs[i+1][1:])
+		}
+	}
+	want := [][]string{
+		{"AAPL", "30"},
+		{"CHF", "150"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestRenderAnnualize(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	// The period has 73 days, a fifth of the year.
+	partition := date.NewPartition(date.Period{Start: date.Date(2021, 1, 1), End: date.Date(2021, 3, 14)}, date.Once, 0)
+	r := NewReport(reg, partition)
+	for _, e := range []struct {
+		account string
+		value   int64
+	}{
+		{"Assets:Bank", 100},
+		{"Equity:Equity", -10},
+		{"Expenses:Food", 40},
+		{"Income:Salary", -130},
+	} {
+		r.Insert(amounts.Key{Date: partition.EndDates()[0], Account: reg.Accounts().MustGet(e.account), Commodity: chf}, decimal.NewFromInt(e.value))
+	}
+	rn := Renderer{Annualize: true, Flat: true, SortAlphabetically: true}
+	var buf bytes.Buffer
+
+	if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, rec := range recs[1:] {
+		if rec[0] != "" {
+			got = append(got, []string{rec[0], rec[len(rec)-1]})
+		}
+	}
+	want := [][]string{
+		{"Assets:Bank", "100"},
+		{"Total (A+L)", "100"},
+		{"Income and expenses annualized", ""},
+		{"Equity:Equity", "10"},
+		{"Income:Salary", "650"},
+		{"Expenses:Food", "-200"},
+		{"Result (I+E) p.a.", "450"},
+		{"Total (E+I+E)", "100"},
+		{"Delta", "0"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestRenderSign(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 2, 29)}, date.Monthly, 0)
+	r := NewReport(reg, partition)
+	jan, feb := partition.EndDates()[0], partition.EndDates()[1]
+	for _, e := range []struct {
+		date    time.Time
+		account string
+		value   int64
+	}{
+		{jan, "Assets:Bank", -140},
+		{feb, "Assets:Bank", 10},
+		{jan, "Expenses:Food", 40},
+		{feb, "Expenses:Food", 10},
+		{jan, "Expenses:Rent", 100},
+		{feb, "Expenses:Rent", -20},
+	} {
+		r.Insert(amounts.Key{Date: e.date, Account: reg.Accounts().MustGet(e.account), Commodity: chf}, decimal.NewFromInt(e.value))
+	}
+	for _, test := range []struct {
+		desc string
+		rn   Renderer
+		want []string
+	}{
+		{
+			desc: "positive",
+			rn:   Renderer{Diff: true, PositiveOnly: true},
+			want: []string{"Assets", "Bank", "Expenses", "Rent"},
+		},
+		{
+			desc: "negative",
+			rn:   Renderer{Diff: true, NegativeOnly: true},
+			want: []string{"Expenses", "Food"},
+		},
+		{
+			desc: "negative cumulative",
+			rn:   Renderer{NegativeOnly: true},
+			want: []string{"Assets", "Bank", "Expenses", "Food", "Rent"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			test.rn.SortAlphabetically = true
+
+			if err := new(table.CSVRenderer).Render(test.rn.Render(r), &buf); err != nil {
+				t.Fatal(err)
+			}
+
+			recs, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, rec := range recs[1:] {
+				if rec[0] != "" && !strings.HasPrefix(rec[0], "Total") && rec[0] != "Result (I+E)" && rec[0] != "Delta" {
+					got = append(got, rec[0])
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestRenderFlows(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 2, 29)}, date.Monthly, 0)
+	r := NewReport(reg, partition)
+	jan, feb := partition.EndDates()[0], partition.EndDates()[1]
+	for _, e := range []struct {
+		date    time.Time
+		account string
+		value   int64
+	}{
+		{jan, "Assets:Bank", 150},
+		{feb, "Assets:Bank", 50},
+		{jan, "Equity:Equity", -100},
+		{jan, "Income:Salary", -50},
+		{feb, "Income:Salary", -50},
+	} {
+		r.Insert(amounts.Key{Date: e.date, Account: reg.Accounts().MustGet(e.account), Commodity: chf}, decimal.NewFromInt(e.value))
+	}
+	rn := Renderer{Flows: true, Flat: true, SortAlphabetically: true}
+	var buf bytes.Buffer
+
+	if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, rec := range recs[1:] {
+		if rec[0] != "" {
+			got = append(got, []string{rec[0], rec[len(rec)-2], rec[len(rec)-1]})
+		}
+	}
+	// Equity stays cumulative, only income and expenses are flows.
+	want := [][]string{
+		{"Assets:Bank", "150", "200"},
+		{"Total (A+L)", "150", "200"},
+		{"Equity:Equity", "100", "100"},
+		{"Income:Salary", "50", "50"},
+		{"Result (I+E)", "50", "50"},
+		{"Total (E+I+E)", "150", "200"},
+		{"Delta", "0", "0"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestRenderTags(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 1, 31)}, date.Once, 0)
+	r := NewReport(reg, partition)
+	for _, e := range []struct {
+		account string
+		tag     model.Tag
+		value   int64
+	}{
+		{"Assets:Bank", tag.Untagged, -30},
+		{"Expenses:Food", "rome", 10},
+		{"Expenses:Food", "paris", 20},
+	} {
+		r.Insert(amounts.Key{Date: partition.EndDates()[0], Account: reg.Accounts().MustGet(e.account), Commodity: chf, Tag: e.tag}, decimal.NewFromInt(e.value))
+	}
+	rn := Renderer{Tags: true, Flat: true}
+	var buf bytes.Buffer
+
+	if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, rec := range recs {
+		if strings.Contains(rec[0], ":") {
+			got = append(got, rec)
+		}
+	}
+	want := [][]string{
+		{"Assets:Bank", "untagged", "CHF", "-30"},
+		{"Expenses:Food", "paris", "CHF", "-20"},
+		{"Expenses:Food", "rome", "CHF", "-10"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestRenderSubtotals(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 1, 31)}, date.Once, 0)
+
+	for _, test := range []struct {
+		desc      string
+		subtotals bool
+		want      [][]string
+	}{
+		{
+			desc: "no subtotals",
+			want: [][]string{
+				{"Assets", "", ""}, {"Bank", "CHF", "100"}, {"Cash", "CHF", "20"},
+				{"Expenses", "", ""}, {"Food", "CHF", "-30"},
+			},
+		},
+		{
+			desc:      "subtotals",
+			subtotals: true,
+			want: [][]string{
+				{"Assets", "", ""}, {"Bank", "CHF", "100"}, {"Cash", "CHF", "20"}, {"Total Assets", "CHF", "120"},
+				{"Expenses", "", ""}, {"Food", "CHF", "-30"}, {"Total Expenses", "CHF", "-30"},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			r := NewReport(reg, partition)
+			for account, value := range map[string]int64{"Assets:Bank": 100, "Assets:Cash": 20, "Expenses:Food": 30} {
+				r.Insert(amounts.Key{Date: partition.EndDates()[0], Account: reg.Accounts().MustGet(account), Commodity: chf}, decimal.NewFromInt(value))
+			}
+			rn := Renderer{Subtotals: test.subtotals}
+			var buf bytes.Buffer
+			if err := new(table.CSVRenderer).Render(rn.Render(r), &buf); err != nil {
+				t.Fatal(err)
+			}
+			recs, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got [][]string
+			for _, rec := range recs {
+				switch rec[0] {
+				case "Assets", "Bank", "Cash", "Total Assets", "Expenses", "Food", "Total Expenses":
+					got = append(got, rec)
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
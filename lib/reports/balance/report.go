@@ -6,8 +6,10 @@ import (
 	"github.com/sboehler/knut/lib/common/date"
 	"github.com/sboehler/knut/lib/common/mapper"
 	"github.com/sboehler/knut/lib/common/multimap"
+	"github.com/sboehler/knut/lib/common/set"
 	"github.com/sboehler/knut/lib/model"
 	"github.com/sboehler/knut/lib/model/account"
+	"github.com/sboehler/knut/lib/model/commodity"
 	"github.com/shopspring/decimal"
 )
 
@@ -78,12 +80,30 @@ func (r *Report) SortWeighted() {
 		if n1.Value.Account.Level() == 1 && n2.Value.Account.Level() == 1 {
 			return compare.Ordered(n1.Value.Account.Type(), n2.Value.Account.Type())
 		}
-		return compare.Decimal(n1.Value.Weight, n2.Value.Weight)
+		if o := compare.Decimal(n1.Value.Weight, n2.Value.Weight); o != compare.Equal {
+			return o
+		}
+		return account.Compare(n1.Value.Account, n2.Value.Account)
 	}
 	r.AL.Sort(f)
 	r.EIE.Sort(f)
 }
 
+// Commodities returns the commodities of the report, sorted by name.
+func (r *Report) Commodities() []*model.Commodity {
+	res := set.New[*model.Commodity]()
+	collect := func(n *Node) {
+		for c := range n.Value.Amounts.Commodities() {
+			if c != nil {
+				res.Add(c)
+			}
+		}
+	}
+	r.AL.PostOrder(collect)
+	r.EIE.PostOrder(collect)
+	return res.Sorted(commodity.Compare)
+}
+
 func (r *Report) SetAccounts() {
 	setAccounts(r.Registry.Accounts(), r.AL)
 	setAccounts(r.Registry.Accounts(), r.EIE)
@@ -124,3 +144,12 @@ func (r *Report) Totals(m mapper.Mapper[amounts.Key]) (amounts.Amounts, amounts.
 	})
 	return al, result, eie
 }
+
+// Subtotal sums the amounts of the given node and all its descendants.
+func Subtotal(n *Node, m mapper.Mapper[amounts.Key]) amounts.Amounts {
+	res := make(amounts.Amounts)
+	n.PostOrder(func(n *Node) {
+		n.Value.Amounts.SumIntoBy(res, nil, m)
+	})
+	return res
+}
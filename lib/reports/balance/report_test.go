@@ -0,0 +1,52 @@
+package balance
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
+)
+
+func TestSortWeighted(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	partition := date.NewPartition(date.Period{Start: date.Date(2020, 1, 1), End: date.Date(2020, 1, 31)}, date.Once, 0)
+	want := []string{
+		"Assets:Depot", "Assets:Bank", "Assets:Cash", "Assets:Wallet",
+		"Expenses:Rent", "Expenses:Food", "Expenses:Travel",
+	}
+	// Accounts with equal weights are sorted by name, whatever the order
+	// of the map iteration.
+	for i := 0; i < 20; i++ {
+		r := NewReport(reg, partition)
+		for _, e := range []struct {
+			account string
+			value   int64
+		}{
+			{"Assets:Wallet", 10},
+			{"Assets:Cash", -50},
+			{"Assets:Bank", 50},
+			{"Assets:Depot", 100},
+			{"Expenses:Travel", 20},
+			{"Expenses:Food", 20},
+			{"Expenses:Rent", 30},
+		} {
+			r.Insert(amounts.Key{Date: partition.EndDates()[0], Account: reg.Accounts().MustGet(e.account), Commodity: chf, Valuation: chf}, decimal.NewFromInt(e.value))
+		}
+
+		r.SortWeighted()
+
+		var got []string
+		for _, n := range append(r.AL.Sorted, r.EIE.Sorted...) {
+			for _, ch := range n.Sorted {
+				got = append(got, ch.Value.Account.Name())
+			}
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("SortWeighted() returned unexpected diff (-want/+got):\n%s\n", diff)
+		}
+	}
+}
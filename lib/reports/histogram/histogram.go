@@ -0,0 +1,144 @@
+// Package histogram buckets the expenses of transactions by amount.
+package histogram
+
+import (
+	"fmt"
+
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/common/regex"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/account"
+	"github.com/sboehler/knut/lib/model/commodity"
+	"github.com/shopspring/decimal"
+)
+
+// Report counts and sums the expenses of transactions in buckets by
+// amount. The expenses of a transaction are the sum of its postings on
+// expense accounts, by commodity or, with a valuation, in the valuation
+// commodity. Transactions with zero or negative expenses, such as
+// refunds, are left out.
+type Report struct {
+	// Edges are the bounds between the buckets, in ascending order. The
+	// first bucket ranges from zero to the first edge, the last one from
+	// the last edge upwards.
+	Edges []decimal.Decimal
+
+	Valuation *model.Commodity
+
+	// Accounts restricts the expense accounts, if it is not empty.
+	Accounts regex.Regexes
+
+	buckets map[*model.Commodity][]Bucket
+}
+
+// Bucket holds the number and the total of the expenses in a range.
+type Bucket struct {
+	Count int
+	Total decimal.Decimal
+}
+
+// Process returns a processor which fills the report.
+func (r *Report) Process() *journal.Processor {
+	r.buckets = make(map[*model.Commodity][]Bucket)
+	return &journal.Processor{
+		Transaction: func(t *model.Transaction) error {
+			expenses := make(map[*model.Commodity]decimal.Decimal)
+			for _, p := range t.Postings {
+				if p.Virtual || p.Account.Type() != account.EXPENSES {
+					continue
+				}
+				if len(r.Accounts) > 0 && !r.Accounts.MatchString(p.Account.Name()) {
+					continue
+				}
+				if r.Valuation != nil {
+					expenses[r.Valuation] = expenses[r.Valuation].Add(p.Value)
+				} else {
+					expenses[p.Commodity] = expenses[p.Commodity].Add(p.Quantity)
+				}
+			}
+			for c, amount := range expenses {
+				if amount.IsPositive() {
+					r.insert(c, amount)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func (r *Report) insert(c *model.Commodity, amount decimal.Decimal) {
+	bs := dict.GetDefault(r.buckets, c, func() []Bucket { return make([]Bucket, len(r.Edges)+1) })
+	i := 0
+	for i < len(r.Edges) && amount.GreaterThanOrEqual(r.Edges[i]) {
+		i++
+	}
+	bs[i].Count++
+	bs[i].Total = bs[i].Total.Add(amount)
+}
+
+// Buckets returns the buckets of the given commodity.
+func (r *Report) Buckets(c *model.Commodity) []Bucket {
+	return r.buckets[c]
+}
+
+// Label returns the range of the bucket with the given index.
+func (r *Report) Label(i int) string {
+	switch {
+	case len(r.Edges) == 0:
+		return "all"
+	case i == len(r.Edges):
+		return r.Edges[i-1].String() + "+"
+	case i == 0:
+		return "0 - " + r.Edges[0].String()
+	}
+	return r.Edges[i-1].String() + " - " + r.Edges[i].String()
+}
+
+// Renderer renders a report.
+type Renderer struct{}
+
+// Render renders a report.
+func (rn Renderer) Render(r *Report) *table.Table {
+	tbl := table.New(1, 1, 1, 1, 1)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().
+		AddText("Comm", table.Center).
+		AddText("Amount", table.Center).
+		AddText("Count", table.Center).
+		AddText("Total", table.Center).
+		AddText("Share", table.Center)
+	tbl.AddSeparatorRow()
+	for _, c := range dict.SortedKeys(r.buckets, commodity.Compare) {
+		bs := r.buckets[c]
+		var total decimal.Decimal
+		var count int
+		for _, b := range bs {
+			total = total.Add(b.Total)
+			count += b.Count
+		}
+		for i, b := range bs {
+			row := tbl.AddRow()
+			if i == 0 {
+				row.AddText(c.Name(), table.Left)
+			} else {
+				row.AddEmpty()
+			}
+			row.AddText(r.Label(i), table.Left).
+				AddText(fmt.Sprint(b.Count), table.Right).
+				AddDecimal(b.Total)
+			share, _ := b.Total.Div(total).Float64()
+			row.AddPercent(share)
+		}
+		tbl.AddSeparatorRow()
+		tbl.AddRow().
+			AddEmpty().
+			AddText("Total", table.Left).
+			AddText(fmt.Sprint(count), table.Right).
+			AddDecimal(total).
+			AddPercent(1)
+		tbl.AddSeparatorRow()
+	}
+	return tbl
+}
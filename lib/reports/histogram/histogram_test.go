@@ -0,0 +1,56 @@
+package histogram
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestReport(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	bank := reg.Accounts().MustGet("Assets:Bank")
+	food := reg.Accounts().MustGet("Expenses:Food")
+	rent := reg.Accounts().MustGet("Expenses:Rent")
+	r := &Report{Edges: []decimal.Decimal{decimal.NewFromInt(10), decimal.NewFromInt(100)}}
+	proc := r.Process()
+	for _, bookings := range [][]posting.Builder{
+		{{Credit: bank, Debit: food, Commodity: chf, Quantity: decimal.NewFromInt(5)}},
+		{{Credit: bank, Debit: food, Commodity: chf, Quantity: decimal.NewFromInt(10)}},
+		// The expenses of a transaction are summed.
+		{
+			{Credit: bank, Debit: food, Commodity: chf, Quantity: decimal.NewFromInt(60)},
+			{Credit: bank, Debit: food, Commodity: chf, Quantity: decimal.NewFromInt(50)},
+		},
+		{{Credit: bank, Debit: rent, Commodity: chf, Quantity: decimal.NewFromInt(1000)}},
+		// Refunds are left out.
+		{{Credit: food, Debit: bank, Commodity: chf, Quantity: decimal.NewFromInt(20)}},
+	} {
+		trx := transaction.Builder{Postings: posting.Builders(bookings).Build()}.Build()
+		if err := proc.Transaction(trx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := r.Buckets(chf)
+
+	want := []Bucket{
+		{Count: 1, Total: decimal.NewFromInt(5)},
+		{Count: 1, Total: decimal.NewFromInt(10)},
+		{Count: 2, Total: decimal.NewFromInt(1110)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Buckets() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	var labels []string
+	for i := range got {
+		labels = append(labels, r.Label(i))
+	}
+	if diff := cmp.Diff([]string{"0 - 10", "10 - 100", "100+"}, labels); diff != "" {
+		t.Errorf("Label() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
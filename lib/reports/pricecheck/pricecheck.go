@@ -0,0 +1,192 @@
+package pricecheck
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/commodity"
+	"github.com/sboehler/knut/lib/model/price"
+	"github.com/shopspring/decimal"
+)
+
+// Status is the status of the price of a commodity.
+type Status int
+
+const (
+	OK Status = iota
+	Stale
+	Missing
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Stale:
+		return "stale"
+	case Missing:
+		return "missing"
+	}
+	return fmt.Sprintf("unknown status %d", int(s))
+}
+
+// Report checks the prices of the commodities held in asset and liability
+// accounts on a given date. The price of a commodity may be derived from a
+// chain of prices, for example from a stock price in USD and an exchange
+// rate from USD to the valuation commodity, and every price on the chain
+// must be recent. The processor returned by Process must run after
+// journal.ComputePrices with the same valuation.
+type Report struct {
+	Valuation *model.Commodity
+	Date      time.Time
+
+	// MaxAge is the maximum age of a price in days.
+	MaxAge int
+
+	quantities map[*model.Commodity]decimal.Decimal
+	prices     price.Prices
+	latest     map[pair]time.Time
+	normalized price.NormalizedPrices
+}
+
+// pair is a commodity and a target commodity, in either order.
+type pair struct {
+	commodity, target *model.Commodity
+}
+
+// Entry is the price status of a commodity.
+type Entry struct {
+	Commodity *model.Commodity
+	Quantity  decimal.Decimal
+
+	// Price is the normalized price in the valuation commodity. Latest is
+	// the date of the oldest of the latest prices on the chain of prices
+	// from which the price is derived. Both are zero if the price is
+	// missing.
+	Price  decimal.Decimal
+	Latest time.Time
+	Age    int
+	Status Status
+}
+
+// NewReport creates a new report.
+func NewReport(valuation *model.Commodity, date time.Time, maxAge int) *Report {
+	return &Report{
+		Valuation:  valuation,
+		Date:       date,
+		MaxAge:     maxAge,
+		quantities: make(map[*model.Commodity]decimal.Decimal),
+		prices:     make(price.Prices),
+		latest:     make(map[pair]time.Time),
+	}
+}
+
+// Process returns a processor which fills the report.
+func (r *Report) Process() *journal.Processor {
+	return &journal.Processor{
+		Price: func(p *model.Price) error {
+			if p.Date.After(r.Date) {
+				return nil
+			}
+			r.prices.Insert(p.Commodity, p.Price, p.Target)
+			// The price is used inversely for the target.
+			r.latest[pair{p.Commodity, p.Target}] = p.Date
+			r.latest[pair{p.Target, p.Commodity}] = p.Date
+			return nil
+		},
+		Posting: func(t *model.Transaction, p *model.Posting) error {
+			if t.Date.After(r.Date) || !p.Account.IsAL() {
+				return nil
+			}
+			r.quantities[p.Commodity] = r.quantities[p.Commodity].Add(p.Quantity)
+			return nil
+		},
+		DayEnd: func(d *journal.Day) error {
+			if !d.Date.After(r.Date) {
+				r.normalized = d.Normalized
+			}
+			return nil
+		},
+	}
+}
+
+// Entries returns the entries for all commodities held on the report
+// date, except the valuation commodity, sorted by commodity.
+func (r *Report) Entries() []Entry {
+	var res []Entry
+	for _, c := range dict.SortedKeys(r.quantities, commodity.Compare) {
+		qty := r.quantities[c]
+		if qty.IsZero() || c == r.Valuation {
+			continue
+		}
+		e := Entry{Commodity: c, Quantity: qty}
+		prc, err := r.normalized.Price(c)
+		latest, ok := r.oldest(c)
+		switch {
+		case err != nil || !ok:
+			e.Status = Missing
+		default:
+			e.Price = prc
+			e.Latest = latest
+			e.Age = int(r.Date.Sub(latest).Hours() / 24)
+			if e.Age > r.MaxAge {
+				e.Status = Stale
+			}
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// oldest returns the date of the oldest price on the chain of prices from
+// which the price of the commodity in the valuation commodity is derived.
+func (r *Report) oldest(c *model.Commodity) (time.Time, bool) {
+	path := r.prices.Path(r.Valuation, c)
+	if len(path) < 2 {
+		return time.Time{}, false
+	}
+	res := r.latest[pair{path[0], path[1]}]
+	for i := 2; i < len(path); i++ {
+		if d := r.latest[pair{path[i-1], path[i]}]; d.Before(res) {
+			res = d
+		}
+	}
+	return res, true
+}
+
+// Renderer renders a report.
+type Renderer struct{}
+
+// Render renders a report.
+func (rn Renderer) Render(r *Report) *table.Table {
+	tbl := table.New(1, 1, 1, 1, 1, 1)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().
+		AddText("Comm", table.Center).
+		AddText("Quantity", table.Center).
+		AddText("Price", table.Center).
+		AddText("Latest", table.Center).
+		AddText("Age", table.Center).
+		AddText("Status", table.Center)
+	tbl.AddSeparatorRow()
+	for _, e := range r.Entries() {
+		row := tbl.AddRow().
+			AddText(e.Commodity.Name(), table.Left).
+			AddDecimal(e.Quantity)
+		if e.Status == Missing {
+			row.AddEmpty().AddEmpty().AddEmpty()
+		} else {
+			row.AddText(e.Price.String(), table.Right).
+				AddText(e.Latest.Format("2006-01-02"), table.Left).
+				AddText(strconv.Itoa(e.Age), table.Right)
+		}
+		row.AddText(e.Status.String(), table.Left)
+	}
+	tbl.AddSeparatorRow()
+	return tbl
+}
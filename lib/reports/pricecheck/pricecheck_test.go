@@ -0,0 +1,127 @@
+package pricecheck
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+type testPrice struct {
+	day               int
+	commodity, target string
+	price             int64
+}
+
+func TestEntries(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		prices []testPrice
+		want   []string
+	}{
+		{
+			desc: "clean series",
+			prices: []testPrice{
+				{1, "AAPL", "CHF", 100}, {15, "AAPL", "CHF", 110}, {29, "AAPL", "CHF", 120},
+				{1, "USD", "CHF", 1}, {25, "USD", "CHF", 1},
+			},
+			want: []string{"AAPL 10 120 2020-01-29 2 ok", "USD 50 1 2020-01-25 6 ok"},
+		},
+		{
+			desc: "stale price",
+			prices: []testPrice{
+				{1, "AAPL", "CHF", 100}, {15, "AAPL", "CHF", 110}, {29, "AAPL", "CHF", 120},
+				{1, "USD", "CHF", 1}, {10, "USD", "CHF", 1},
+			},
+			want: []string{"AAPL 10 120 2020-01-29 2 ok", "USD 50 1 2020-01-10 21 stale"},
+		},
+		{
+			desc: "missing price",
+			prices: []testPrice{
+				{1, "AAPL", "CHF", 100},
+			},
+			want: []string{"AAPL 10 100 2020-01-01 30 stale", "USD 50 0 0001-01-01 0 missing"},
+		},
+		{
+			desc: "inverse price",
+			prices: []testPrice{
+				{29, "AAPL", "CHF", 120},
+				{30, "CHF", "USD", 1},
+			},
+			want: []string{"AAPL 10 120 2020-01-29 2 ok", "USD 50 1 2020-01-30 1 ok"},
+		},
+		{
+			desc: "stale intermediate price",
+			prices: []testPrice{
+				{29, "AAPL", "USD", 120},
+				{5, "USD", "CHF", 1},
+			},
+			want: []string{"AAPL 10 120 2020-01-05 26 stale", "USD 50 1 2020-01-05 26 stale"},
+		},
+		{
+			desc: "recent intermediate price",
+			prices: []testPrice{
+				{5, "AAPL", "USD", 120},
+				{29, "CHF", "USD", 1},
+			},
+			want: []string{"AAPL 10 120 2020-01-05 26 stale", "USD 50 1 2020-01-29 2 ok"},
+		},
+		{
+			desc: "price after the report date",
+			prices: []testPrice{
+				{29, "AAPL", "CHF", 120}, {40, "AAPL", "CHF", 130},
+				{1, "USD", "CHF", 1}, {40, "USD", "CHF", 1},
+			},
+			want: []string{"AAPL 10 120 2020-01-29 2 ok", "USD 50 1 2020-01-01 30 stale"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			reg := registry.New()
+			chf := reg.Commodities().MustGet("CHF")
+			portfolio, equity := reg.Accounts().MustGet("Assets:Portfolio"), reg.Accounts().MustGet("Equity:Equity")
+			j := journal.New()
+			j.Add(&model.Open{Date: date.Date(2020, 1, 1), Account: portfolio})
+			j.Add(&model.Open{Date: date.Date(2020, 1, 1), Account: equity})
+			for _, p := range test.prices {
+				j.Add(&model.Price{
+					Date:      date.Date(2020, 1, p.day),
+					Commodity: reg.Commodities().MustGet(p.commodity),
+					Target:    reg.Commodities().MustGet(p.target),
+					Price:     decimal.NewFromInt(p.price),
+				})
+			}
+			for name, qty := range map[string]int64{"AAPL": 10, "USD": 50, "CHF": 1000} {
+				j.Add(transaction.Builder{
+					Date: date.Date(2020, 1, 1),
+					Postings: posting.Builder{
+						Credit:    equity,
+						Debit:     portfolio,
+						Commodity: reg.Commodities().MustGet(name),
+						Quantity:  decimal.NewFromInt(qty),
+					}.Build(),
+				}.Build())
+			}
+			rep := NewReport(chf, date.Date(2020, 1, 31), 14)
+
+			err := j.Build().Process(journal.ComputePrices(chf), rep.Process())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range rep.Entries() {
+				got = append(got, fmt.Sprintf("%s %s %s %s %d %s", e.Commodity.Name(), e.Quantity, e.Price, e.Latest.Format("2006-01-02"), e.Age, e.Status))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Entries() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
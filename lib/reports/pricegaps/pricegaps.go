@@ -0,0 +1,150 @@
+// Package pricegaps finds the largest gaps between the price directives
+// of commodity pairs.
+package pricegaps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/regex"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/commodity"
+)
+
+// Pair is a commodity and the target commodity of its prices.
+type Pair struct {
+	Commodity, Target *model.Commodity
+}
+
+// Gap is the time between two consecutive prices of a pair, or between
+// the last price of a pair and the end of the report, during which
+// valuations carry the earlier price forward.
+type Gap struct {
+	Pair
+	From, To time.Time
+}
+
+// Days returns the length of the gap in days.
+func (g Gap) Days() int {
+	return int(g.To.Sub(g.From).Hours() / 24)
+}
+
+// Report collects the gaps between the dates of consecutive price
+// directives of each pair, as declared in the journal, and the trailing
+// gap between the last price of each pair and the end of the report.
+type Report struct {
+	// Commodities restricts the pairs to those whose commodity or target
+	// matches, if it is not empty.
+	Commodities regex.Regexes
+
+	// End is the end of the report. Later prices are ignored. If it is
+	// zero, the report ends on the last day of the journal.
+	End time.Time
+
+	latest  map[Pair]time.Time
+	gaps    map[Pair][]Gap
+	lastDay time.Time
+}
+
+// Process returns a processor which fills the report.
+func (r *Report) Process() *journal.Processor {
+	r.latest = make(map[Pair]time.Time)
+	r.gaps = make(map[Pair][]Gap)
+	return &journal.Processor{
+		Price: func(p *model.Price) error {
+			if !r.End.IsZero() && p.Date.After(r.End) {
+				return nil
+			}
+			if len(r.Commodities) > 0 && !r.Commodities.MatchString(p.Commodity.Name()) && !r.Commodities.MatchString(p.Target.Name()) {
+				return nil
+			}
+			k := Pair{Commodity: p.Commodity, Target: p.Target}
+			if prev, ok := r.latest[k]; ok && p.Date.After(prev) {
+				r.gaps[k] = append(r.gaps[k], Gap{Pair: k, From: prev, To: p.Date})
+			}
+			r.latest[k] = p.Date
+			return nil
+		},
+		DayEnd: func(d *journal.Day) error {
+			if d.Date.After(r.lastDay) {
+				r.lastDay = d.Date
+			}
+			return nil
+		},
+	}
+}
+
+// end returns the end of the report.
+func (r *Report) end() time.Time {
+	if !r.End.IsZero() {
+		return r.End
+	}
+	return r.lastDay
+}
+
+// Gaps returns the n largest gaps of each pair, or all gaps if n is not
+// positive, sorted by length in descending order.
+func (r *Report) Gaps(n int) []Gap {
+	var res []Gap
+	end := r.end()
+	for k, latest := range r.latest {
+		gs := append([]Gap(nil), r.gaps[k]...)
+		if end.After(latest) {
+			gs = append(gs, Gap{Pair: k, From: latest, To: end})
+		}
+		compare.Sort(gs, compareGaps)
+		if n > 0 && len(gs) > n {
+			gs = gs[:n]
+		}
+		res = append(res, gs...)
+	}
+	compare.Sort(res, compareGaps)
+	return res
+}
+
+// compareGaps orders gaps by length in descending order, then by pair and
+// date.
+func compareGaps(g1, g2 Gap) compare.Order {
+	if o := compare.Ordered(g2.Days(), g1.Days()); o != compare.Equal {
+		return o
+	}
+	if o := commodity.Compare(g1.Commodity, g2.Commodity); o != compare.Equal {
+		return o
+	}
+	if o := commodity.Compare(g1.Target, g2.Target); o != compare.Equal {
+		return o
+	}
+	return compare.Time(g1.From, g2.From)
+}
+
+// Renderer renders a report.
+type Renderer struct {
+	// PerPair limits the gaps of each pair, if it is positive.
+	PerPair int
+}
+
+// Render renders a report.
+func (rn Renderer) Render(r *Report) *table.Table {
+	tbl := table.New(1, 1, 1, 1, 1)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().
+		AddText("Comm", table.Center).
+		AddText("Target", table.Center).
+		AddText("From", table.Center).
+		AddText("To", table.Center).
+		AddText("Days", table.Center)
+	tbl.AddSeparatorRow()
+	for _, g := range r.Gaps(rn.PerPair) {
+		tbl.AddRow().
+			AddText(g.Commodity.Name(), table.Left).
+			AddText(g.Target.Name(), table.Left).
+			AddText(g.From.Format("2006-01-02"), table.Left).
+			AddText(g.To.Format("2006-01-02"), table.Left).
+			AddText(fmt.Sprint(g.Days()), table.Right)
+	}
+	tbl.AddSeparatorRow()
+	return tbl
+}
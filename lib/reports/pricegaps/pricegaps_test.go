@@ -0,0 +1,125 @@
+package pricegaps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
+)
+
+func TestReport(t *testing.T) {
+	reg := registry.New()
+	chf, usd, aapl := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD"), reg.Commodities().MustGet("AAPL")
+	r := new(Report)
+	proc := r.Process()
+	for _, p := range []struct {
+		day               int
+		commodity, target *model.Commodity
+	}{
+		{1, usd, chf},
+		{1, aapl, usd},
+		{11, usd, chf},
+		// A second price on the same day is no gap.
+		{11, usd, chf},
+		{61, usd, chf},
+		{62, usd, chf},
+		{92, aapl, usd},
+	} {
+		pr := &model.Price{Date: date.Date(2020, 1, p.day), Commodity: p.commodity, Target: p.target, Price: decimal.NewFromInt(1)}
+		if err := proc.Price(pr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary := func(gaps []Gap) []string {
+		var res []string
+		for _, g := range gaps {
+			res = append(res, fmt.Sprintf("%s/%s %s %d", g.Commodity.Name(), g.Target.Name(), g.From.Format("2006-01-02"), g.Days()))
+		}
+		return res
+	}
+	for _, test := range []struct {
+		n    int
+		want []string
+	}{
+		{
+			n:    0,
+			want: []string{"AAPL/USD 2020-01-01 91", "USD/CHF 2020-01-11 50", "USD/CHF 2020-01-01 10", "USD/CHF 2020-03-01 1"},
+		},
+		{
+			n:    1,
+			want: []string{"AAPL/USD 2020-01-01 91", "USD/CHF 2020-01-11 50"},
+		},
+	} {
+		t.Run(fmt.Sprint(test.n), func(t *testing.T) {
+			if diff := cmp.Diff(test.want, summary(r.Gaps(test.n))); diff != "" {
+				t.Errorf("Gaps() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func TestReportTrailingGaps(t *testing.T) {
+	reg := registry.New()
+	chf, usd, eur := reg.Commodities().MustGet("CHF"), reg.Commodities().MustGet("USD"), reg.Commodities().MustGet("EUR")
+	prices := []struct {
+		day               int
+		commodity, target *model.Commodity
+	}{
+		{1, usd, chf},
+		{1, eur, chf},
+		{11, usd, chf},
+		{31, usd, chf},
+	}
+	summary := func(gaps []Gap) []string {
+		var res []string
+		for _, g := range gaps {
+			res = append(res, fmt.Sprintf("%s/%s %s %s", g.Commodity.Name(), g.Target.Name(), g.From.Format("2006-01-02"), g.To.Format("2006-01-02")))
+		}
+		return res
+	}
+	for _, test := range []struct {
+		desc string
+		end  time.Time
+		want []string
+	}{
+		{
+			desc: "journal end",
+			want: []string{"EUR/CHF 2020-01-01 2020-02-05", "USD/CHF 2020-01-11 2020-01-31", "USD/CHF 2020-01-01 2020-01-11", "USD/CHF 2020-01-31 2020-02-05"},
+		},
+		{
+			desc: "end date",
+			end:  date.Date(2020, 1, 21),
+			want: []string{"EUR/CHF 2020-01-01 2020-01-21", "USD/CHF 2020-01-01 2020-01-11", "USD/CHF 2020-01-11 2020-01-21"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			r := &Report{End: test.end}
+			proc := r.Process()
+			for _, p := range prices {
+				d := date.Date(2020, 1, p.day)
+				pr := &model.Price{Date: d, Commodity: p.commodity, Target: p.target, Price: decimal.NewFromInt(1)}
+				if err := proc.Price(pr); err != nil {
+					t.Fatal(err)
+				}
+				if err := proc.DayEnd(&journal.Day{Date: d}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// The journal ends after the last price.
+			if err := proc.DayEnd(&journal.Day{Date: date.Date(2020, 2, 5)}); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(test.want, summary(r.Gaps(0))); diff != "" {
+				t.Errorf("Gaps() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package prices
+
+import (
+	"time"
+
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/set"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/commodity"
+	"github.com/sboehler/knut/lib/model/price"
+	"github.com/shopspring/decimal"
+)
+
+// Report holds the normalized prices at the end of each period of a
+// partition. The processor returned by Process must run after
+// journal.ComputePrices with the same valuation, and the journal must
+// contain a day for each end date of the partition.
+type Report struct {
+	Valuation *model.Commodity
+	Partition date.Partition
+
+	prices map[time.Time]price.NormalizedPrices
+}
+
+// NewReport creates a new report.
+func NewReport(valuation *model.Commodity, partition date.Partition) *Report {
+	return &Report{
+		Valuation: valuation,
+		Partition: partition,
+		prices:    make(map[time.Time]price.NormalizedPrices),
+	}
+}
+
+// Process returns a processor which fills the report.
+func (r *Report) Process() *journal.Processor {
+	dates := set.FromSlice(r.Partition.EndDates())
+	return &journal.Processor{
+		DayEnd: func(d *journal.Day) error {
+			if dates.Has(d.Date) {
+				r.prices[d.Date] = d.Normalized
+			}
+			return nil
+		},
+	}
+}
+
+// Commodities returns the commodities which have a price in at least one
+// period, except the valuation commodity, sorted by name.
+func (r *Report) Commodities() []*model.Commodity {
+	cs := set.New[*model.Commodity]()
+	for _, np := range r.prices {
+		for c := range np {
+			if c != r.Valuation {
+				cs.Add(c)
+			}
+		}
+	}
+	return cs.Sorted(commodity.Compare)
+}
+
+// Price returns the price of the commodity at the given end date of a
+// period. It returns false if there is no price.
+func (r *Report) Price(c *model.Commodity, d time.Time) (decimal.Decimal, bool) {
+	prc, ok := r.prices[d][c]
+	return prc, ok
+}
+
+// Renderer renders a report.
+type Renderer struct {
+	// Significant is the number of significant digits to which prices
+	// are rounded for display. Prices are shown in full precision if it
+	// is zero.
+	Significant int32
+}
+
+// Render renders a report, with a row for each commodity and a column for
+// each period. Missing prices are left blank.
+func (rn Renderer) Render(r *Report) *table.Table {
+	dates := r.Partition.EndDates()
+	tbl := table.New(1, len(dates))
+	tbl.AddSeparatorRow()
+	header := tbl.AddRow().AddText("Comm", table.Center)
+	for _, d := range dates {
+		header.AddText(d.Format("2006-01-02"), table.Center)
+	}
+	tbl.AddSeparatorRow()
+	for _, c := range r.Commodities() {
+		row := tbl.AddRow().AddText(c.Name(), table.Left)
+		for _, d := range dates {
+			if prc, ok := r.Price(c, d); ok {
+				row.AddText(rn.format(prc), table.Right)
+			} else {
+				row.AddEmpty()
+			}
+		}
+	}
+	tbl.AddSeparatorRow()
+	return tbl
+}
+
+func (rn Renderer) format(d decimal.Decimal) string {
+	if rn.Significant <= 0 || d.IsZero() {
+		return d.String()
+	}
+	// The number of digits before the decimal point, which is negative
+	// for leading zeros after the decimal point.
+	magnitude := int32(d.NumDigits()) + d.Exponent()
+	places := rn.Significant - magnitude
+	d = d.Round(places)
+	if places < 0 {
+		places = 0
+	}
+	return d.StringFixed(places)
+}
@@ -0,0 +1,30 @@
+package prices
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		significant int32
+		price       string
+		want        string
+	}{
+		{0, "1.23456789", "1.23456789"},
+		{4, "1.23456789", "1.235"},
+		{4, "116.6005", "116.6"},
+		{4, "0.0123456", "0.01235"},
+		{2, "1234.5", "1200"},
+		{4, "0", "0"},
+	}
+	for _, test := range tests {
+		t.Run(test.price, func(t *testing.T) {
+			got := Renderer{Significant: test.significant}.format(decimal.RequireFromString(test.price))
+			if got != test.want {
+				t.Errorf("format(%s) = %s, want %s", test.price, got, test.want)
+			}
+		})
+	}
+}
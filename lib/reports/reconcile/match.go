@@ -0,0 +1,262 @@
+package reconcile
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/commodity"
+	"github.com/shopspring/decimal"
+)
+
+// Item is a posting of an account, either on a statement or in the
+// journal.
+type Item struct {
+	Date        time.Time
+	Description string
+	Commodity   *model.Commodity
+	Quantity    decimal.Decimal
+}
+
+// Items collects the postings of an account.
+type Items struct {
+	Account *model.Account
+	Items   []Item
+}
+
+// Process returns a processor which collects the postings of the account.
+// Virtual postings are not on any statement, so they are skipped.
+func (is *Items) Process() *journal.Processor {
+	return &journal.Processor{
+		Posting: func(t *model.Transaction, p *model.Posting) error {
+			if p.Account == is.Account && !p.Virtual {
+				is.Items = append(is.Items, Item{
+					Date:        t.Date,
+					Description: t.Description,
+					Commodity:   p.Commodity,
+					Quantity:    p.Quantity,
+				})
+			}
+			return nil
+		},
+	}
+}
+
+// Matching is the result of matching the items of a statement with the
+// items of the journal.
+type Matching struct {
+	// Matched is the number of matched items.
+	Matched int
+
+	// Statement are the items of the statement which are missing in the
+	// journal, Journal the items of the journal which are missing on the
+	// statement.
+	Statement, Journal []Item
+}
+
+// Match matches the items of the statement with the items of the journal
+// with the same commodity and quantity, whose date differs by at most
+// window days. Every item is matched at most once. Match matches as many
+// items as possible, and among those matchings it chooses one where the
+// dates differ the least in total. Items of the journal outside of the
+// period of the statement, extended by the window, are ignored.
+func Match(statement, journal []Item, window int) *Matching {
+	res := new(Matching)
+	if len(statement) == 0 {
+		return res
+	}
+	statement = sortItems(statement)
+	journal = sortItems(journal)
+	from := statement[0].Date.AddDate(0, 0, -window)
+	to := statement[len(statement)-1].Date.AddDate(0, 0, window)
+	var candidates []Item
+	for _, it := range journal {
+		if !it.Date.Before(from) && !it.Date.After(to) {
+			candidates = append(candidates, it)
+		}
+	}
+	// Only items with the same commodity and quantity can match, so the
+	// items are matched in groups.
+	type key struct {
+		commodity *model.Commodity
+		quantity  string
+	}
+	groups := make(map[key]*group)
+	get := func(it Item) *group {
+		k := key{it.Commodity, it.Quantity.String()}
+		return dict.GetDefault(groups, k, func() *group { return new(group) })
+	}
+	for i, it := range statement {
+		g := get(it)
+		g.statement = append(g.statement, i)
+	}
+	for i, it := range candidates {
+		g := get(it)
+		g.journal = append(g.journal, i)
+	}
+	matchedStatement := make([]bool, len(statement))
+	matchedJournal := make([]bool, len(candidates))
+	for _, g := range groups {
+		match := assign(len(g.statement), len(g.journal), func(i, j int) (int, bool) {
+			days := absDays(candidates[g.journal[j]].Date.Sub(statement[g.statement[i]].Date))
+			return days, days <= window
+		})
+		for i, j := range match {
+			if j >= 0 {
+				matchedStatement[g.statement[i]] = true
+				matchedJournal[g.journal[j]] = true
+				res.Matched++
+			}
+		}
+	}
+	for i, s := range statement {
+		if !matchedStatement[i] {
+			res.Statement = append(res.Statement, s)
+		}
+	}
+	for i, c := range candidates {
+		if !matchedJournal[i] {
+			res.Journal = append(res.Journal, c)
+		}
+	}
+	return res
+}
+
+// group are the indexes of the items of the statement and of the journal
+// with the same commodity and quantity.
+type group struct {
+	statement, journal []int
+}
+
+// assign matches n items with m items, where cost returns the cost of
+// matching the i-th with the j-th item and whether they can be matched at
+// all. It returns for every one of the n items the index of the matched
+// item, or -1. The matching has the most pairs and, among those, the
+// least total cost. It is built with successive shortest augmenting paths:
+// every step rematches items along the cheapest path which adds a pair.
+func assign(n, m int, cost func(i, j int) (int, bool)) []int {
+	const inf = math.MaxInt
+	matchN, matchM := make([]int, n), make([]int, m)
+	for i := range matchN {
+		matchN[i] = -1
+	}
+	for j := range matchM {
+		matchM[j] = -1
+	}
+	distN, distM, prev := make([]int, n), make([]int, m), make([]int, m)
+	for {
+		// Find the cheapest paths from the unmatched items on the left,
+		// which alternate between unmatched and matched pairs, with the
+		// Bellman-Ford algorithm. Matched pairs are traversed backwards,
+		// at negative cost.
+		for i := range distN {
+			distN[i] = inf
+			if matchN[i] < 0 {
+				distN[i] = 0
+			}
+		}
+		for j := range distM {
+			distM[j] = inf
+		}
+		for changed := true; changed; {
+			changed = false
+			for i := range distN {
+				if distN[i] == inf {
+					continue
+				}
+				for j := range distM {
+					c, ok := cost(i, j)
+					if !ok || matchN[i] == j || distN[i]+c >= distM[j] {
+						continue
+					}
+					distM[j], prev[j] = distN[i]+c, i
+					changed = true
+					if k := matchM[j]; k >= 0 {
+						ck, _ := cost(k, j)
+						if distM[j]-ck < distN[k] {
+							distN[k] = distM[j] - ck
+						}
+					}
+				}
+			}
+		}
+		best := -1
+		for j := range distM {
+			if matchM[j] < 0 && distM[j] < inf && (best < 0 || distM[j] < distM[best]) {
+				best = j
+			}
+		}
+		if best < 0 {
+			return matchN
+		}
+		for j := best; j >= 0; {
+			i := prev[j]
+			next := matchN[i]
+			matchN[i], matchM[j] = j, i
+			j = next
+		}
+	}
+}
+
+func sortItems(items []Item) []Item {
+	res := append([]Item(nil), items...)
+	compare.Sort(res, compare.Combine(
+		func(i1, i2 Item) compare.Order { return compare.Time(i1.Date, i2.Date) },
+		func(i1, i2 Item) compare.Order { return commodity.Compare(i1.Commodity, i2.Commodity) },
+		func(i1, i2 Item) compare.Order { return compare.Decimal(i1.Quantity, i2.Quantity) },
+	))
+	return res
+}
+
+func absDays(d time.Duration) int {
+	days := int(d.Hours() / 24)
+	if days < 0 {
+		return -days
+	}
+	return days
+}
+
+// MatchRenderer renders a matching.
+type MatchRenderer struct{}
+
+// Render renders a matching.
+func (rn MatchRenderer) Render(m *Matching) *table.Table {
+	tbl := table.New(1, 1, 1, 1)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().
+		AddText("Date", table.Center).
+		AddText("Description", table.Center).
+		AddText("Comm", table.Center).
+		AddText("Amount", table.Center)
+	tbl.AddSeparatorRow()
+	rn.renderItems(tbl, "Missing in the journal", m.Statement)
+	rn.renderItems(tbl, "Missing on the statement", m.Journal)
+	tbl.AddRow().
+		AddEmpty().
+		AddText(fmt.Sprintf("%d items matched", m.Matched), table.Left).
+		AddEmpty().
+		AddEmpty()
+	tbl.AddSeparatorRow()
+	return tbl
+}
+
+func (rn MatchRenderer) renderItems(tbl *table.Table, title string, items []Item) {
+	tbl.AddRow().
+		AddEmpty().
+		AddText(fmt.Sprintf("%s (%d)", title, len(items)), table.Left).
+		AddEmpty().
+		AddEmpty()
+	for _, it := range items {
+		tbl.AddRow().
+			AddText(it.Date.Format("2006-01-02"), table.Left).
+			AddText(truncate(it.Description, 60), table.Left).
+			AddText(it.Commodity.Name(), table.Left).
+			AddDecimal(it.Quantity)
+	}
+	tbl.AddSeparatorRow()
+}
@@ -0,0 +1,143 @@
+package reconcile
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
+)
+
+func TestMatch(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	item := func(day int, quantity int64) Item {
+		return Item{Date: date.Date(2020, 1, day), Commodity: chf, Quantity: decimal.NewFromInt(quantity)}
+	}
+	statement := []Item{item(10, -50), item(12, -50), item(15, 100), item(20, -7)}
+	journal := []Item{
+		item(1, -99),  // before the statement
+		item(11, -50), // matches the 10th and the 12th, but only once
+		item(14, 100), // within the window
+		item(20, -8),  // wrong amount
+		item(28, -30), // after the statement
+	}
+
+	got := Match(statement, journal, 2)
+
+	summary := func(items []Item) []string {
+		var res []string
+		for _, it := range items {
+			res = append(res, it.Date.Format("2006-01-02")+" "+it.Quantity.String())
+		}
+		return res
+	}
+	if got.Matched != 2 {
+		t.Errorf("Match() matched %d items, want 2", got.Matched)
+	}
+	if diff := cmp.Diff([]string{"2020-01-12 -50", "2020-01-20 -7"}, summary(got.Statement)); diff != "" {
+		t.Errorf("Match() returned unexpected statement items (-want/+got):\n%s\n", diff)
+	}
+	if diff := cmp.Diff([]string{"2020-01-20 -8"}, summary(got.Journal)); diff != "" {
+		t.Errorf("Match() returned unexpected journal items (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestMatchAssignment(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	item := func(day int) Item {
+		return Item{Date: date.Date(2020, 1, day), Commodity: chf, Quantity: decimal.NewFromInt(-50)}
+	}
+	for _, test := range []struct {
+		desc               string
+		statement, journal []Item
+		want               int
+	}{
+		{
+			desc: "closest date is taken",
+			// Matching the 4th with the 3rd leaves the 2nd with the 1st.
+			statement: []Item{item(3), item(5)},
+			journal:   []Item{item(1), item(4)},
+			want:      2,
+		},
+		{
+			desc:      "closest date is taken by the other item",
+			statement: []Item{item(2), item(4)},
+			journal:   []Item{item(0), item(3)},
+			want:      2,
+		},
+		{
+			desc:      "chain",
+			statement: []Item{item(2), item(4), item(6)},
+			journal:   []Item{item(3), item(5), item(8)},
+			want:      3,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := Match(test.statement, test.journal, 2)
+
+			if got.Matched != test.want || len(got.Statement) != 0 || len(got.Journal) != 0 {
+				t.Errorf("Match() matched %d items, left %v and %v, want %d items matched", got.Matched, got.Statement, got.Journal, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchPrefersCloseDates(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	item := func(day int) Item {
+		return Item{Date: date.Date(2020, 1, day), Commodity: chf, Quantity: decimal.NewFromInt(-50)}
+	}
+
+	got := Match([]Item{item(10)}, []Item{item(8), item(10), item(11)}, 2)
+
+	var days []int
+	for _, it := range got.Journal {
+		days = append(days, it.Date.Day())
+	}
+	if diff := cmp.Diff([]int{8, 11}, days); diff != "" {
+		t.Errorf("Match() returned unexpected journal items (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestItemsSkipVirtualPostings(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	bank, other := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Expenses:Other")
+	is := &Items{Account: bank}
+	proc := is.Process()
+	trx := &model.Transaction{Date: date.Date(2020, 1, 1), Description: "shopping"}
+	for _, p := range []*model.Posting{
+		{Account: bank, Other: other, Commodity: chf, Quantity: decimal.NewFromInt(-10)},
+		{Account: bank, Other: other, Commodity: chf, Quantity: decimal.NewFromInt(-5), Virtual: true},
+	} {
+		if err := proc.Posting(trx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(is.Items) != 1 || !is.Items[0].Quantity.Equal(decimal.NewFromInt(-10)) {
+		t.Errorf("Process() collected %v, want the posting of -10 CHF only", is.Items)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"Migros", 10, "Migros"},
+		{"Migros", 3, "Mig"},
+		{"Zürich", 2, "Zü"},
+		{"Zürich", 0, ""},
+	} {
+		if got := truncate(test.s, test.n); got != test.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", test.s, test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package reconcile
+
+import (
+	"time"
+
+	"github.com/sboehler/knut/lib/common/dict"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/commodity"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/shopspring/decimal"
+	"golang.org/x/exp/slices"
+)
+
+// Report contains the postings of an account since the last passing
+// assertion of the account, by commodity.
+type Report struct {
+	Account *model.Account
+
+	// States restricts the listed postings to the given states. The
+	// balances always include all postings.
+	States []posting.State
+
+	positions map[*model.Commodity]*Position
+}
+
+// Position is the reconciliation state of a commodity.
+type Position struct {
+	Commodity *model.Commodity
+
+	// Asserted is the quantity of the last passing assertion,
+	// AssertedOn its date. AssertedOn is zero if there is none.
+	Asserted   decimal.Decimal
+	AssertedOn time.Time
+
+	// Quantity is the current computed quantity.
+	Quantity decimal.Decimal
+
+	Entries []Entry
+}
+
+// Entry is a posting with the running balance.
+type Entry struct {
+	Date              time.Time
+	State             posting.State
+	Description       string
+	Other             *model.Account
+	Quantity, Balance decimal.Decimal
+}
+
+// NewReport creates a new report for the given account.
+func NewReport(a *model.Account) *Report {
+	return &Report{
+		Account:   a,
+		positions: make(map[*model.Commodity]*Position),
+	}
+}
+
+func (r *Report) position(c *model.Commodity) *Position {
+	return dict.GetDefault(r.positions, c, func() *Position { return &Position{Commodity: c} })
+}
+
+// Process returns a processor which fills the report.
+func (r *Report) Process() *journal.Processor {
+	return &journal.Processor{
+		Posting: func(t *model.Transaction, p *model.Posting) error {
+			if p.Account != r.Account {
+				return nil
+			}
+			pos := r.position(p.Commodity)
+			pos.Quantity = pos.Quantity.Add(p.Quantity)
+			if len(r.States) > 0 && !slices.Contains(r.States, p.State) {
+				return nil
+			}
+			pos.Entries = append(pos.Entries, Entry{
+				Date:        t.Date,
+				State:       p.State,
+				Description: t.Description,
+				Other:       p.Other,
+				Quantity:    p.Quantity,
+				Balance:     pos.Quantity,
+			})
+			return nil
+		},
+		Balance: func(a *model.Assertion, bal *model.Balance) error {
+			if bal.Account != r.Account {
+				return nil
+			}
+			pos := r.position(bal.Commodity)
+			if !pos.Quantity.Equal(bal.Quantity) {
+				return nil
+			}
+			pos.Asserted = bal.Quantity
+			pos.AssertedOn = a.Date
+			pos.Entries = nil
+			return nil
+		},
+	}
+}
+
+// Positions returns the positions, sorted by commodity.
+func (r *Report) Positions() []*Position {
+	var res []*Position
+	for _, c := range dict.SortedKeys(r.positions, commodity.Compare) {
+		res = append(res, r.positions[c])
+	}
+	return res
+}
+
+// Renderer renders a report.
+type Renderer struct{}
+
+// Render renders a report.
+func (rn Renderer) Render(r *Report) *table.Table {
+	tbl := table.New(1, 1, 1, 1, 1, 1)
+	tbl.AddSeparatorRow()
+	tbl.AddRow().
+		AddText("Date", table.Center).
+		AddText("Description", table.Center).
+		AddText("Account", table.Center).
+		AddText("Comm", table.Center).
+		AddText("Amount", table.Center).
+		AddText("Balance", table.Center)
+	tbl.AddSeparatorRow()
+	for _, pos := range r.Positions() {
+		rn.renderPosition(tbl, pos)
+		tbl.AddSeparatorRow()
+	}
+	return tbl
+}
+
+func (rn Renderer) renderPosition(tbl *table.Table, pos *Position) {
+	row := tbl.AddRow()
+	if pos.AssertedOn.IsZero() {
+		row.AddEmpty().AddText("No passing assertion", table.Left)
+	} else {
+		row.AddText(pos.AssertedOn.Format("2006-01-02"), table.Left).AddText("Last passing assertion", table.Left)
+	}
+	row.AddEmpty().AddText(pos.Commodity.Name(), table.Left).AddEmpty().AddDecimal(pos.Asserted)
+	for _, e := range pos.Entries {
+		desc := truncate(e.Description, 60)
+		if e.State != posting.Unmarked {
+			desc = e.State.String() + " " + desc
+		}
+		tbl.AddRow().
+			AddText(e.Date.Format("2006-01-02"), table.Left).
+			AddText(desc, table.Left).
+			AddText(e.Other.Name(), table.Left).
+			AddText(pos.Commodity.Name(), table.Left).
+			AddDecimal(e.Quantity).
+			AddDecimal(e.Balance)
+	}
+	tbl.AddSeparatorRow()
+	tbl.AddRow().
+		AddEmpty().
+		AddText("Computed balance", table.Left).
+		AddEmpty().
+		AddText(pos.Commodity.Name(), table.Left).
+		AddEmpty().
+		AddDecimal(pos.Quantity)
+	tbl.AddRow().
+		AddEmpty().
+		AddText("Change since last assertion", table.Left).
+		AddEmpty().
+		AddText(pos.Commodity.Name(), table.Left).
+		AddEmpty().
+		AddDecimal(pos.Quantity.Sub(pos.Asserted))
+}
+
+// truncate shortens the string to at most n runes.
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/sboehler/knut/lib/model/commodity"
 	"github.com/sboehler/knut/lib/model/registry"
 	"github.com/shopspring/decimal"
+	"golang.org/x/exp/slices"
 )
 
 type Report struct {
@@ -43,10 +44,23 @@ func (r *Report) Insert(k amounts.Key, v decimal.Decimal) {
 }
 
 type Renderer struct {
+	// Valuation is the commodity of amounts without a commodity. It is
+	// only used to display its symbol.
+	Valuation *commodity.Commodity
+
 	ShowCommodities    bool
 	ShowSource         bool
 	ShowDescriptions   bool
 	SortAlphabetically bool
+
+	// Reverse renders the most recent dates first.
+	Reverse bool
+
+	// Offset skips the given number of dates, and Limit renders at most
+	// the given number of dates if it is positive. Both count the
+	// sections of the register, that is, the dates, and apply after
+	// Reverse.
+	Offset, Limit int
 }
 
 func (rn *Renderer) Render(r *Report) *table.Table {
@@ -77,6 +91,13 @@ func (rn *Renderer) Render(r *Report) *table.Table {
 	tbl.AddSeparatorRow()
 
 	dates := dict.SortedKeys(r.nodes, compare.Time)
+	if rn.Reverse {
+		slices.Reverse(dates)
+	}
+	dates = dates[min(max(rn.Offset, 0), len(dates)):]
+	if rn.Limit > 0 && rn.Limit < len(dates) {
+		dates = dates[:rn.Limit]
+	}
 	for _, d := range dates {
 		n := r.nodes[d]
 		rn.renderNode(tbl, n)
@@ -103,7 +124,15 @@ func (rn *Renderer) renderNode(tbl *table.Table, n *Node) {
 			row.AddText(k.Account.Name(), table.Left)
 		}
 		row.AddText(k.Other.Name(), table.Left)
-		row.AddDecimal(n.Amounts[k].Neg())
+		unit := k.Commodity
+		if unit == nil {
+			unit = rn.Valuation
+		}
+		var prefix, suffix string
+		if unit != nil {
+			prefix, suffix = unit.Affixes()
+		}
+		row.AddAmount(n.Amounts[k].Neg(), prefix, suffix)
 		if rn.ShowCommodities {
 			row.AddText(k.Commodity.Name(), table.Left)
 		}
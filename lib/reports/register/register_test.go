@@ -0,0 +1,52 @@
+package register
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/table"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/shopspring/decimal"
+)
+
+func TestRenderPaging(t *testing.T) {
+	reg := registry.New()
+	bank, food := reg.Accounts().MustGet("Assets:Bank"), reg.Accounts().MustGet("Expenses:Food")
+	dates := []string{"2020-01-01", "2020-01-02", "2020-01-03", "2020-01-04"}
+
+	for _, test := range []struct {
+		desc     string
+		renderer Renderer
+		want     []string
+	}{
+		{"all", Renderer{}, dates},
+		{"reverse", Renderer{Reverse: true}, []string{"2020-01-04", "2020-01-03", "2020-01-02", "2020-01-01"}},
+		{"limit", Renderer{Limit: 2}, []string{"2020-01-01", "2020-01-02"}},
+		{"offset", Renderer{Offset: 3}, []string{"2020-01-04"}},
+		{"offset beyond end", Renderer{Offset: 5}, nil},
+		{"latest", Renderer{Reverse: true, Offset: 1, Limit: 2}, []string{"2020-01-03", "2020-01-02"}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			r := NewReport(reg)
+			for i := range dates {
+				r.Insert(amounts.Key{Date: date.Date(2020, 1, i+1), Account: bank, Other: food}, decimal.NewFromInt(1))
+			}
+			var buf strings.Builder
+			if err := new(table.TextRenderer).Render(test.renderer.Render(r), &buf); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if fields := strings.Fields(strings.Trim(line, "| ")); len(fields) > 0 && strings.HasPrefix(fields[0], "2020-") {
+					got = append(got, fields[0])
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Render() returned unexpected diff (-want/+got):\n%s\n", diff)
+			}
+		})
+	}
+}
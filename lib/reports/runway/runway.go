@@ -0,0 +1,123 @@
+// Package runway computes the burn rate and the runway of the cash.
+package runway
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/sboehler/knut/lib/common/regex"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/shopspring/decimal"
+)
+
+// Report computes the value of the cash accounts on a date, and the burn
+// rate as the average monthly net outflow from the cash accounts over a
+// number of months up to the date. Transfers between cash accounts and
+// changes of the value of the cash, which have no quantity, are not
+// flows.
+type Report struct {
+	Valuation *model.Commodity
+
+	// Cash selects the cash accounts among the assets and liabilities.
+	// All of them are cash accounts if it is empty.
+	Cash regex.Regexes
+
+	// Date is the date of the report, Months the length of the window
+	// for the burn rate.
+	Date   time.Time
+	Months int
+
+	// Balance is the value of the cash accounts on the date, Outflow the
+	// net outflow from the cash accounts within the window.
+	Balance, Outflow decimal.Decimal
+}
+
+func (r *Report) isCash(a *model.Account) bool {
+	return a.IsAL() && (len(r.Cash) == 0 || r.Cash.MatchString(a.Name()))
+}
+
+// Start returns the start of the window, exclusive.
+func (r *Report) Start() time.Time {
+	return r.Date.AddDate(0, -r.Months, 0)
+}
+
+// Process returns a processor which fills the report. It expects valuated
+// postings up to the date of the report.
+func (r *Report) Process() *journal.Processor {
+	return &journal.Processor{
+		Posting: func(t *model.Transaction, p *model.Posting) error {
+			if p.Virtual || !r.isCash(p.Account) || t.Date.After(r.Date) {
+				return nil
+			}
+			r.Balance = r.Balance.Add(p.Value)
+			if p.Quantity.IsZero() || r.isCash(p.Other) || !t.Date.After(r.Start()) {
+				return nil
+			}
+			r.Outflow = r.Outflow.Sub(p.Value)
+			return nil
+		},
+	}
+}
+
+// BurnRate returns the average net outflow per month. It is negative if
+// the cash grows.
+func (r *Report) BurnRate() decimal.Decimal {
+	return r.Outflow.Div(decimal.NewFromInt(int64(r.Months)))
+}
+
+// Runway returns the number of months until the cash is used up at the
+// current burn rate, and false if the cash does not decrease.
+func (r *Report) Runway() (float64, bool) {
+	burn := r.BurnRate()
+	if !burn.IsPositive() {
+		return math.Inf(1), false
+	}
+	months, _ := r.Balance.Div(burn).Float64()
+	return math.Max(months, 0), true
+}
+
+// ZeroDate returns the date on which the cash is used up at the current
+// burn rate, and false if the cash does not decrease.
+func (r *Report) ZeroDate() (time.Time, bool) {
+	if _, ok := r.Runway(); !ok {
+		return time.Time{}, false
+	}
+	days := r.Date.Sub(r.Start()).Hours() / 24
+	perDay := r.Outflow.Div(decimal.NewFromFloat(days))
+	left, _ := r.Balance.Div(perDay).Ceil().Float64()
+	return r.Date.AddDate(0, 0, int(math.Max(left, 0))), true
+}
+
+// Render writes the report as plain text, rounding amounts to the given
+// number of digits.
+func (r *Report) Render(w io.Writer, digits int32) error {
+	var runway, zero string
+	if months, ok := r.Runway(); ok {
+		runway = fmt.Sprintf("%.1f months", months)
+		d, _ := r.ZeroDate()
+		zero = d.Format("2006-01-02")
+	} else if r.BurnRate().IsNegative() {
+		runway = fmt.Sprintf("infinite, the cash grows by %s %s per month", r.BurnRate().Neg().StringFixed(digits), r.Valuation)
+		zero = "never"
+	} else {
+		runway, zero = "infinite", "never"
+	}
+	rows := []struct {
+		label, value string
+	}{
+		{"Date", r.Date.Format("2006-01-02")},
+		{"Cash", fmt.Sprintf("%s %s", r.Balance.StringFixed(digits), r.Valuation)},
+		{"Burn rate", fmt.Sprintf("%s %s per month, over %d months", r.BurnRate().StringFixed(digits), r.Valuation, r.Months)},
+		{"Runway", runway},
+		{"Zero cash", zero},
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "%-11s %s\n", row.label+":", row.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
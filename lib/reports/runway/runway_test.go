@@ -0,0 +1,74 @@
+package runway
+
+import (
+	"testing"
+
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestReport(t *testing.T) {
+	reg := registry.New()
+	chf := reg.Commodities().MustGet("CHF")
+	equity := reg.Accounts().EquityAccount()
+	bank := reg.Accounts().MustGet("Assets:Bank")
+	savings := reg.Accounts().MustGet("Assets:Savings")
+	rent := reg.Accounts().MustGet("Expenses:Rent")
+	valuation := reg.Accounts().MustGet("Income:Bank")
+	r := &Report{Valuation: chf, Date: date.Date(2020, 6, 30), Months: 3}
+	proc := r.Process()
+	for _, e := range []struct {
+		date  int
+		b     posting.Builder
+		noQty bool
+	}{
+		// Before the window: funding.
+		{date: 1, b: posting.Builder{Credit: equity, Debit: bank, Value: decimal.NewFromInt(10000)}},
+		// Within the window: three months of rent.
+		{date: 100, b: posting.Builder{Credit: bank, Debit: rent, Value: decimal.NewFromInt(1000)}},
+		{date: 130, b: posting.Builder{Credit: bank, Debit: rent, Value: decimal.NewFromInt(1000)}},
+		{date: 160, b: posting.Builder{Credit: bank, Debit: rent, Value: decimal.NewFromInt(1000)}},
+		// A transfer between cash accounts is no flow.
+		{date: 161, b: posting.Builder{Credit: bank, Debit: savings, Value: decimal.NewFromInt(500)}},
+		// A revaluation has no quantity and is no flow.
+		{date: 162, b: posting.Builder{Credit: valuation, Debit: bank, Value: decimal.NewFromInt(300)}, noQty: true},
+	} {
+		e.b.Commodity = chf
+		if !e.noQty {
+			e.b.Quantity = e.b.Value
+		}
+		trx := transaction.Builder{Date: date.Date(2020, 1, e.date), Postings: e.b.Build()}.Build()
+		for _, p := range trx.Postings {
+			if err := proc.Posting(trx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if want := decimal.NewFromInt(7300); !r.Balance.Equal(want) {
+		t.Errorf("Balance = %s, want %s", r.Balance, want)
+	}
+	if want := decimal.NewFromInt(1000); !r.BurnRate().Equal(want) {
+		t.Errorf("BurnRate() = %s, want %s", r.BurnRate(), want)
+	}
+	if got, ok := r.Runway(); !ok || got != 7.3 {
+		t.Errorf("Runway() = %v, %t, want 7.3, true", got, ok)
+	}
+	if got, ok := r.ZeroDate(); !ok || got.Before(date.Date(2021, 1, 30)) || got.After(date.Date(2021, 2, 10)) {
+		t.Errorf("ZeroDate() = %v, %t, want about 7.3 months after the date", got, ok)
+	}
+}
+
+func TestReportGrowing(t *testing.T) {
+	r := &Report{Date: date.Date(2020, 6, 30), Months: 6, Balance: decimal.NewFromInt(100), Outflow: decimal.NewFromInt(-60)}
+
+	if _, ok := r.Runway(); ok {
+		t.Errorf("Runway() is finite, want infinite")
+	}
+	if _, ok := r.ZeroDate(); ok {
+		t.Errorf("ZeroDate() exists, want none")
+	}
+}
@@ -0,0 +1,137 @@
+// Package stats summarizes a journal.
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/sboehler/knut/lib/common/set"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+)
+
+// Stats summarizes a journal.
+type Stats struct {
+	Files        int    `json:"files"`
+	Transactions int    `json:"transactions"`
+	Postings     int    `json:"postings"`
+	Prices       int    `json:"prices"`
+	Assertions   int    `json:"assertions"`
+	Accounts     int    `json:"accounts"`
+	Commodities  int    `json:"commodities"`
+	From         string `json:"from,omitempty"`
+	To           string `json:"to,omitempty"`
+
+	// TopAccounts and TopCommodities are the most used accounts and
+	// commodities, by number of postings.
+	TopAccounts    []Count `json:"topAccounts"`
+	TopCommodities []Count `json:"topCommodities"`
+}
+
+// Count is the number of postings of an account or a commodity.
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Compute computes the statistics of the journal, with up to top most
+// used accounts and commodities.
+func Compute(j *journal.Journal, top int) *Stats {
+	var (
+		s           Stats
+		accounts    = set.New[*model.Account]()
+		commodities = set.New[*model.Commodity]()
+		accountUse  = make(map[string]int)
+		commUse     = make(map[string]int)
+	)
+	for _, d := range j.Days {
+		if len(d.Transactions) > 0 {
+			if s.From == "" {
+				s.From = d.Date.Format("2006-01-02")
+			}
+			s.To = d.Date.Format("2006-01-02")
+		}
+		s.Prices += len(d.Prices)
+		s.Assertions += len(d.Assertions)
+		for _, o := range d.Openings {
+			accounts.Add(o.Account)
+		}
+		for _, p := range d.Prices {
+			commodities.Add(p.Commodity)
+			commodities.Add(p.Target)
+		}
+		for _, t := range d.Transactions {
+			s.Transactions++
+			for _, p := range t.Postings {
+				s.Postings++
+				accounts.Add(p.Account)
+				commodities.Add(p.Commodity)
+				accountUse[p.Account.Name()]++
+				commUse[p.Commodity.Name()]++
+			}
+		}
+	}
+	s.Accounts = len(accounts)
+	s.Commodities = len(commodities)
+	s.TopAccounts = topN(accountUse, top)
+	s.TopCommodities = topN(commUse, top)
+	return &s
+}
+
+func topN(m map[string]int, n int) []Count {
+	res := make([]Count, 0, len(m))
+	for name, count := range m {
+		res = append(res, Count{Name: name, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Name < res[j].Name
+	})
+	if len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
+
+// Render writes the statistics as plain text.
+func (s *Stats) Render(w io.Writer) error {
+	rows := []struct {
+		label string
+		value any
+	}{
+		{"Files", s.Files},
+		{"Transactions", s.Transactions},
+		{"Postings", s.Postings},
+		{"Prices", s.Prices},
+		{"Assertions", s.Assertions},
+		{"Accounts", s.Accounts},
+		{"Commodities", s.Commodities},
+		{"From", s.From},
+		{"To", s.To},
+	}
+	for _, r := range rows {
+		if _, err := fmt.Fprintf(w, "%-14s %v\n", r.label+":", r.value); err != nil {
+			return err
+		}
+	}
+	for _, section := range []struct {
+		title  string
+		counts []Count
+	}{
+		{"Top accounts", s.TopAccounts},
+		{"Top commodities", s.TopCommodities},
+	} {
+		if _, err := fmt.Fprintf(w, "\n%s:\n", section.title); err != nil {
+			return err
+		}
+		for _, c := range section.counts {
+			if _, err := fmt.Fprintf(w, "  %8d  %s\n", c.Count, c.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/posting"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/model/transaction"
+	"github.com/shopspring/decimal"
+)
+
+func TestCompute(t *testing.T) {
+	reg := registry.New()
+	bank := reg.Accounts().MustGet("Assets:Bank")
+	food := reg.Accounts().MustGet("Expenses:Food")
+	rent := reg.Accounts().MustGet("Expenses:Rent")
+	chf := reg.Commodities().MustGet("CHF")
+	j := journal.New()
+	j.Add(&model.Open{Date: date.Date(2020, 1, 1), Account: bank})
+	for i, a := range []*model.Account{food, food, rent} {
+		j.Add(transaction.Builder{
+			Date: date.Date(2020, 1, 2+i),
+			Postings: posting.Builder{
+				Credit:    bank,
+				Debit:     a,
+				Commodity: chf,
+				Quantity:  decimal.NewFromInt(10),
+			}.Build(),
+		}.Build())
+	}
+
+	got := Compute(j.Build(), 2)
+
+	want := &Stats{
+		Transactions:   3,
+		Postings:       6,
+		Accounts:       3,
+		Commodities:    1,
+		From:           "2020-01-02",
+		To:             "2020-01-04",
+		TopAccounts:    []Count{{"Assets:Bank", 3}, {"Expenses:Food", 2}},
+		TopCommodities: []Count{{"CHF", 6}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Compute() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
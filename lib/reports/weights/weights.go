@@ -12,6 +12,7 @@ import (
 	"github.com/sboehler/knut/lib/journal"
 	"github.com/sboehler/knut/lib/journal/performance"
 	"github.com/sboehler/knut/lib/model/account"
+	"github.com/shopspring/decimal"
 )
 
 type Query struct {
@@ -27,9 +28,12 @@ func (q Query) Execute(j *journal.Builder, r *Report) *journal.Processor {
 			if !days.Has(d) {
 				return nil
 			}
-			var total float64
+			var total decimal.Decimal
 			for _, v := range d.Performance.V1 {
-				total += v
+				total = total.Add(v)
+			}
+			if total.IsZero() {
+				return nil
 			}
 			for com, v := range d.Performance.V1 {
 				ss := q.Universe.Locate(com)
@@ -37,7 +41,8 @@ func (q Query) Execute(j *journal.Builder, r *Report) *journal.Processor {
 				if ok && level < len(ss)-suffix {
 					ss = append(ss[:level], ss[len(ss)-suffix:]...)
 				}
-				r.Add(ss, d.Date, v/total)
+				w, _ := v.Div(total).Float64()
+				r.Add(ss, d.Date, w)
 			}
 			return nil
 		},
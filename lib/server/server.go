@@ -0,0 +1,450 @@
+// Package server serves reports on a journal as JSON over HTTP.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sboehler/knut/lib/amounts"
+	"github.com/sboehler/knut/lib/amounts/expr"
+	"github.com/sboehler/knut/lib/common/compare"
+	"github.com/sboehler/knut/lib/common/date"
+	"github.com/sboehler/knut/lib/common/mapper"
+	"github.com/sboehler/knut/lib/common/predicate"
+	"github.com/sboehler/knut/lib/common/regex"
+	"github.com/sboehler/knut/lib/journal"
+	"github.com/sboehler/knut/lib/journal/check"
+	"github.com/sboehler/knut/lib/model"
+	"github.com/sboehler/knut/lib/model/commodity"
+	"github.com/sboehler/knut/lib/model/registry"
+	"github.com/sboehler/knut/lib/reports/prices"
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/shopspring/decimal"
+)
+
+// Server serves reports on the journal at Path. The server keeps the
+// parsed files of the journal and parses them again only if one of them
+// has been modified. As processing mutates the journal, every request
+// creates its own journal from the parsed files.
+type Server struct {
+	Path string
+
+	// NewRegistry, if not nil, creates the registry of the journal. By
+	// default, the registry is created by registry.New.
+	NewRegistry func() (*model.Registry, error)
+
+	// NewResolver, if not nil, creates the resolver for the includes of
+	// the journal. By default, the resolver is the zero value.
+	NewResolver func() *syntax.Resolver
+
+	// Prepare, if not nil, is called with every journal before it is
+	// processed, for example to resolve conflicting prices.
+	Prepare func(*journal.Builder)
+
+	mu    sync.Mutex
+	cache *parsed
+}
+
+// parsed are the parsed files of the journal, along with the modification
+// times of the files at the time they were parsed.
+type parsed struct {
+	reg      *model.Registry
+	resolver *syntax.Resolver
+	files    []syntax.File
+	modTimes map[string]time.Time
+}
+
+// current reports whether none of the files has been modified since they
+// were parsed.
+func (p *parsed) current() bool {
+	for path, t := range p.modTimes {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// parse returns the parsed files of the journal, parsing them again if
+// they have been modified.
+func (s *Server) parse(ctx context.Context) (*parsed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache != nil && s.cache.current() {
+		return s.cache, nil
+	}
+	reg := registry.New()
+	if s.NewRegistry != nil {
+		var err error
+		if reg, err = s.NewRegistry(); err != nil {
+			return nil, err
+		}
+	}
+	r := new(syntax.Resolver)
+	if s.NewResolver != nil {
+		r = s.NewResolver()
+	}
+	start := time.Now()
+	files, err := parseFiles(ctx, r, s.Path)
+	if err != nil {
+		return nil, err
+	}
+	res := &parsed{reg: reg, resolver: r, files: files, modTimes: make(map[string]time.Time)}
+	for _, f := range files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		res.modTimes[f.Path] = info.ModTime()
+		if !info.ModTime().Before(start) {
+			// The file may have been modified while it was parsed, so
+			// the files must be parsed again for the next request.
+			s.cache = nil
+			return res, nil
+		}
+	}
+	s.cache = res
+	return res, nil
+}
+
+// parseFiles parses the file and the files which it includes.
+func parseFiles(ctx context.Context, r *syntax.Resolver, path string) ([]syntax.File, error) {
+	if r.Sequential {
+		return r.ParseFiles(path)
+	}
+	ch, worker := r.ParseFileRecursively(path)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- worker(ctx)
+	}()
+	var files []syntax.File
+	for f := range ch {
+		files = append(files, f)
+	}
+	return files, <-errCh
+}
+
+// Handler returns the HTTP handler of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/balance", s.handle(s.balance))
+	mux.HandleFunc("/register", s.handle(s.register))
+	mux.HandleFunc("/prices", s.handle(s.prices))
+	return mux
+}
+
+// Entry is an amount in a report. Fields which are not part of the report
+// are empty.
+type Entry struct {
+	Date        string          `json:"date"`
+	Account     string          `json:"account,omitempty"`
+	Other       string          `json:"other,omitempty"`
+	Commodity   string          `json:"commodity,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Amount      decimal.Decimal `json:"amount"`
+}
+
+// Params are the parameters of a request, given as query parameters:
+//
+//	from, to     the period (YYYY-MM-DD)
+//	interval     once, daily, weekly, monthly, quarterly or yearly
+//	first        the number of first periods to show
+//	last         the number of last periods to show
+//	val          the valuation commodity
+//	account      a regex to filter accounts, may be repeated
+//	commodity    a regex to filter commodities, may be repeated
+//	where        a filter expression, see package expr
+type Params struct {
+	Period      date.Period
+	Interval    date.Interval
+	First, Last int
+	Valuation   string
+	Accounts    regex.Regexes
+	Commodities regex.Regexes
+	Filter      predicate.Predicate[expr.Posting]
+}
+
+// ParseParams parses the parameters of a request.
+func ParseParams(q url.Values) (Params, error) {
+	p := Params{
+		Period:    date.Period{End: date.Today()},
+		Interval:  date.Once,
+		Valuation: q.Get("val"),
+	}
+	var err error
+	if s := q.Get("from"); s != "" {
+		if p.Period.Start, err = time.Parse("2006-01-02", s); err != nil {
+			return p, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if s := q.Get("to"); s != "" {
+		if p.Period.End, err = time.Parse("2006-01-02", s); err != nil {
+			return p, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if s := q.Get("interval"); s != "" {
+		if p.Interval, err = date.ParseInterval(s); err != nil {
+			return p, err
+		}
+	}
+	if s := q.Get("first"); s != "" {
+		if p.First, err = strconv.Atoi(s); err != nil {
+			return p, fmt.Errorf("invalid first: %w", err)
+		}
+	}
+	if s := q.Get("last"); s != "" {
+		if p.Last, err = strconv.Atoi(s); err != nil {
+			return p, fmt.Errorf("invalid last: %w", err)
+		}
+	}
+	for _, s := range q["account"] {
+		rx, err := regexp.Compile(s)
+		if err != nil {
+			return p, err
+		}
+		p.Accounts.Add(rx)
+	}
+	for _, s := range q["commodity"] {
+		rx, err := regexp.Compile(s)
+		if err != nil {
+			return p, err
+		}
+		p.Commodities.Add(rx)
+	}
+	if s := q.Get("where"); s != "" {
+		if p.Filter, err = expr.Parse(s); err != nil {
+			return p, fmt.Errorf("invalid where: %w", err)
+		}
+	}
+	return p, nil
+}
+
+type handlerFunc func(context.Context, Params) ([]Entry, error)
+
+func (s *Server) handle(f handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		params, err := ParseParams(r.URL.Query())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		res, err := f(r.Context(), params)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if res == nil {
+			res = []Entry{}
+		}
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+type collection amounts.Amounts
+
+func (c collection) Insert(k amounts.Key, v decimal.Decimal) {
+	amounts.Amounts(c).Add(k, v)
+}
+
+// load reads the journal and returns it along with the partition and the
+// valuation commodity of the request.
+func (s *Server) load(ctx context.Context, p Params) (*journal.Builder, *model.Registry, date.Partition, *model.Commodity, error) {
+	c, err := s.parse(ctx)
+	if err != nil {
+		return nil, nil, date.Partition{}, nil, err
+	}
+	reg := c.reg
+	var valuation *model.Commodity
+	if p.Valuation != "" {
+		if valuation, err = reg.Commodities().Get(p.Valuation); err != nil {
+			return nil, nil, date.Partition{}, nil, err
+		}
+	}
+	j, err := journal.FromFiles(reg, c.files, c.resolver)
+	if err != nil {
+		return nil, nil, date.Partition{}, nil, err
+	}
+	if s.Prepare != nil {
+		s.Prepare(j)
+	}
+	partition := date.NewPartition(p.Period.Clip(j.Period()), p.Interval, p.Last).First(p.First)
+	return j, reg, partition, valuation, nil
+}
+
+// balance returns the balances at the end of each period.
+func (s *Server) balance(ctx context.Context, p Params) ([]Entry, error) {
+	j, reg, partition, valuation, err := s.load(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	res := make(collection)
+	err = j.Build().Process(
+		check.Check(),
+		journal.ComputePrices(valuation),
+		journal.Valuate(reg, valuation),
+		journal.Filter(partition),
+		journal.CloseAccounts(j, reg, true, partition),
+		journal.Query{
+			Select: amounts.KeyMapper{
+				Date:      partition.Align(),
+				Account:   mapper.Identity[*model.Account],
+				Commodity: mapper.Identity[*model.Commodity],
+			}.Build(),
+			Where: predicate.And(
+				amounts.AccountMatches(p.Accounts),
+				amounts.CommodityMatches(p.Commodities),
+			),
+			Valuation: valuation,
+			Filter:    p.Filter,
+		}.Into(res),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// Accumulate the flows of the periods into balances.
+	balances := make(amounts.Amounts)
+	var entries []Entry
+	for _, d := range partition.EndDates() {
+		for k, v := range res {
+			if k.Date.Equal(d) {
+				balances.Add(amounts.AccountCommodityKey(k.Account, k.Commodity), v)
+			}
+		}
+		for _, k := range balances.Index(compareAccountCommodity) {
+			if balances[k].IsZero() {
+				continue
+			}
+			entries = append(entries, Entry{
+				Date:      d.Format("2006-01-02"),
+				Account:   k.Account.Name(),
+				Commodity: k.Commodity.Name(),
+				Amount:    balances[k],
+			})
+		}
+	}
+	return entries, nil
+}
+
+// register returns the flows between accounts in each period.
+func (s *Server) register(ctx context.Context, p Params) ([]Entry, error) {
+	j, reg, partition, valuation, err := s.load(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	res := make(collection)
+	err = j.Build().Process(
+		journal.Sort(),
+		journal.ComputePrices(valuation),
+		check.Check(),
+		journal.Valuate(reg, valuation),
+		journal.Filter(partition),
+		journal.Query{
+			Select: amounts.KeyMapper{
+				Date:        partition.Align(),
+				Account:     mapper.Identity[*model.Account],
+				Other:       mapper.Identity[*model.Account],
+				Commodity:   mapper.Identity[*model.Commodity],
+				Description: mapper.Identity[string],
+			}.Build(),
+			Where: predicate.And(
+				amounts.AccountMatches(p.Accounts),
+				amounts.CommodityMatches(p.Commodities),
+			),
+			Valuation: valuation,
+			Filter:    p.Filter,
+		}.Into(res),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, k := range amounts.Amounts(res).Index(compareRegister) {
+		if res[k].IsZero() {
+			continue
+		}
+		entries = append(entries, Entry{
+			Date:        k.Date.Format("2006-01-02"),
+			Account:     k.Account.Name(),
+			Other:       k.Other.Name(),
+			Commodity:   k.Commodity.Name(),
+			Description: k.Description,
+			Amount:      res[k],
+		})
+	}
+	return entries, nil
+}
+
+// prices returns the prices of all commodities in the valuation
+// commodity at the end of each period.
+func (s *Server) prices(ctx context.Context, p Params) ([]Entry, error) {
+	if p.Valuation == "" {
+		return nil, fmt.Errorf("missing valuation commodity")
+	}
+	j, _, partition, valuation, err := s.load(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	j.Days(partition.EndDates())
+	rep := prices.NewReport(valuation, partition)
+	if err := j.Build().Process(journal.ComputePrices(valuation), rep.Process()); err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, c := range rep.Commodities() {
+		if !p.Commodities.MatchString(c.Name()) && len(p.Commodities) > 0 {
+			continue
+		}
+		for _, d := range partition.EndDates() {
+			if prc, ok := rep.Price(c, d); ok {
+				entries = append(entries, Entry{
+					Date:      d.Format("2006-01-02"),
+					Commodity: c.Name(),
+					Amount:    prc,
+				})
+			}
+		}
+	}
+	return entries, nil
+}
+
+func compareAccountCommodity(k1, k2 amounts.Key) compare.Order {
+	if o := compare.Ordered(k1.Account.Name(), k2.Account.Name()); o != compare.Equal {
+		return o
+	}
+	return commodity.Compare(k1.Commodity, k2.Commodity)
+}
+
+func compareRegister(k1, k2 amounts.Key) compare.Order {
+	if o := compare.Time(k1.Date, k2.Date); o != compare.Equal {
+		return o
+	}
+	if o := compareAccountCommodity(k1, k2); o != compare.Equal {
+		return o
+	}
+	if o := compare.Ordered(k1.Other.Name(), k2.Other.Name()); o != compare.Equal {
+		return o
+	}
+	return compare.Ordered(k1.Description, k2.Description)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shopspring/decimal"
+)
+
+const journalText = `2020-01-01 open Assets:Bank
+2020-01-01 open Income:Salary
+
+2020-01-25 "Salary"
+Income:Salary Assets:Bank 1000 USD
+
+2020-02-25 "Salary"
+Income:Salary Assets:Bank 1000 USD
+`
+
+func TestBalance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.knut")
+	if err := os.WriteFile(path, []byte(journalText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := Server{Path: path}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/balance?from=2020-01-01&to=2020-02-29&interval=monthly&account=Assets", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /balance returned status %d: %s", rec.Code, rec.Body)
+	}
+	var got []Entry
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal() returned unexpected error: %v", err)
+	}
+	want := []Entry{
+		{Date: "2020-01-31", Account: "Assets:Bank", Commodity: "USD", Amount: decimal.NewFromInt(1000)},
+		{Date: "2020-02-25", Account: "Assets:Bank", Commodity: "USD", Amount: decimal.NewFromInt(2000)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GET /balance returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	s := Server{Path: "does-not-matter.knut"}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/register?interval=hourly", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /register returned status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.knut")
+	if err := os.WriteFile(path, []byte(journalText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := Server{Path: path}
+	balance := func() []Entry {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/balance?from=2020-01-01&to=2020-02-29&account=Assets", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /balance returned status %d: %s", rec.Code, rec.Body)
+		}
+		var got []Entry
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("json.Unmarshal() returned unexpected error: %v", err)
+		}
+		return got
+	}
+	// Parse the journal and make sure that the server keeps it.
+	balance()
+	if got := balance(); len(got) != 1 || !got[0].Amount.Equal(decimal.NewFromInt(2000)) {
+		t.Fatalf("GET /balance returned %v, want a balance of 2000", got)
+	}
+	if s.cache == nil {
+		t.Fatalf("server did not keep the parsed journal")
+	}
+
+	text := journalText + "\n2020-02-26 \"Bonus\"\nIncome:Salary Assets:Bank 500 USD\n"
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{Date: "2020-02-26", Account: "Assets:Bank", Commodity: "USD", Amount: decimal.NewFromInt(2500)},
+	}
+	if diff := cmp.Diff(want, balance()); diff != "" {
+		t.Errorf("GET /balance returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}
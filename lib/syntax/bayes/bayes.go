@@ -16,13 +16,27 @@ package bayes
 
 import (
 	"math"
+	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/sboehler/knut/lib/common/dict"
 	"github.com/sboehler/knut/lib/common/set"
 	"github.com/sboehler/knut/lib/syntax"
+	"github.com/sboehler/knut/lib/syntax/infer"
 )
 
+func init() {
+	infer.Register("bayes", func(o infer.Options) infer.Model {
+		m := NewModel(o.Account)
+		m.Normalize = o.Normalize
+		m.MinConfidence = o.MinConfidence
+		return m
+	})
+}
+
+var _ infer.Model = (*Model)(nil)
+
 // Model implements a Bayes model for accounts and text tokens derived from transactions.
 type Model struct {
 	count                  int
@@ -30,6 +44,14 @@ type Model struct {
 	countByTokenAndAccount map[token]countByAccount
 
 	account string
+
+	// Normalize enables the normalization of descriptions before they are
+	// tokenized. See Normalize.
+	Normalize bool
+
+	// MinConfidence is the minimum confidence of a prediction. Accounts
+	// are left unchanged if the confidence is below.
+	MinConfidence float64
 }
 
 type token string
@@ -66,7 +88,7 @@ func (m *Model) Update(t *syntax.Transaction) {
 func (m *Model) update(t *syntax.Transaction, b *syntax.Booking, account, other string) {
 	m.count++
 	m.countByAccount[account]++
-	for token := range tokenize(t, b, other) {
+	for token := range m.tokenize(t, b, other) {
 		dict.GetDefault(m.countByTokenAndAccount, token, newCountByAccount)[account]++
 	}
 }
@@ -77,39 +99,80 @@ func newCountByAccount() countByAccount {
 	return make(map[string]int)
 }
 
-// Infer replaces the given account with an inferred account.
+// Infer replaces the given account with an inferred account and returns
+// the predictions.
 // P(A | T1 & T2 & ... & Tn) ~ P(A) * P(T1|A) * P(T2|A) * ... * P(Tn|A)
-func (m *Model) Infer(t *syntax.Transaction) {
+func (m *Model) Infer(t *syntax.Transaction) []infer.Prediction {
+	var res []infer.Prediction
 	for i := range t.Bookings {
-		credit := t.Bookings[i].Credit.Extract()
-		debit := t.Bookings[i].Debit.Extract()
+		b := &t.Bookings[i]
+		credit := b.Credit.Extract()
+		debit := b.Debit.Extract()
 		if credit == m.account {
-			t.Bookings[i].Credit = m.inferAccount(t, &t.Bookings[i], debit)
+			p := m.inferAccount(t, b, debit)
+			if p.Accepted {
+				b.Credit = account(p.Account)
+			}
+			res = append(res, p)
 		}
 		if debit == m.account {
-			t.Bookings[i].Debit = m.inferAccount(t, &t.Bookings[i], credit)
+			p := m.inferAccount(t, b, credit)
+			if p.Accepted {
+				b.Debit = account(p.Account)
+			}
+			res = append(res, p)
 		}
 	}
+	return res
 }
 
-func (m *Model) inferAccount(t *syntax.Transaction, b *syntax.Booking, other string) syntax.Account {
+func account(name string) syntax.Account {
+	return syntax.Account{
+		Range: syntax.Range{Start: 0, End: len(name), Text: name},
+	}
+}
+
+func (m *Model) inferAccount(t *syntax.Transaction, b *syntax.Booking, other string) infer.Prediction {
 	var (
-		tokens = tokenize(t, b, other)
-		max    = math.Inf(-1)
-		best   string
+		tokens     = m.tokenize(t, b, other)
+		max        = math.Inf(-1)
+		candidates []infer.Candidate
 	)
 	for candidate := range m.countByAccount {
 		if candidate == other {
 			continue // the other account of this booking is not a valid candidate
 		}
 		score := m.scoreCandidate(candidate, tokens)
+		candidates = append(candidates, infer.Candidate{Account: candidate, Confidence: score})
 		if score > max {
-			best = candidate
 			max = score
 		}
 	}
-	return syntax.Account{
-		Range: syntax.Range{Start: 0, End: len(best), Text: best},
+	if len(candidates) == 0 {
+		return infer.Prediction{Booking: b}
+	}
+	// The confidence of a candidate is its posterior probability, normalized
+	// over all candidates.
+	var sum float64
+	for i := range candidates {
+		candidates[i].Confidence = math.Exp(candidates[i].Confidence - max)
+		sum += candidates[i].Confidence
+	}
+	for i := range candidates {
+		candidates[i].Confidence /= sum
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
+		}
+		return candidates[i].Account < candidates[j].Account
+	})
+	return infer.Prediction{
+		Booking:    b,
+		Account:    candidates[0].Account,
+		Confidence: candidates[0].Confidence,
+		Accepted:   candidates[0].Confidence >= m.MinConfidence,
+		Candidates: candidates,
 	}
 }
 
@@ -126,11 +189,24 @@ func (m *Model) scoreCandidate(candidate string, tokens set.Set[token]) float64
 	return score
 }
 
-func tokenize(t *syntax.Transaction, b *syntax.Booking, other string) set.Set[token] {
-	tokens := append(strings.Fields(t.Description.Content.Extract()), b.Commodity.Extract(), b.Quantity.Extract(), other)
+func (m *Model) tokenize(t *syntax.Transaction, b *syntax.Booking, other string) set.Set[token] {
+	desc := t.Description.Content.Extract()
+	if m.Normalize {
+		desc = Normalize(desc)
+	}
+	tokens := append(strings.Fields(desc), b.Commodity.Extract(), b.Quantity.Extract(), other)
 	result := set.New[token]()
 	for _, t := range tokens {
 		result.Add(token(strings.ToLower(t)))
 	}
 	return result
 }
+
+// Normalize lowercases the given description and strips everything but
+// letters, such as dates, reference numbers and punctuation. This maps
+// slightly different descriptions of the same merchant to the same tokens.
+func Normalize(desc string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(desc), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}), " ")
+}
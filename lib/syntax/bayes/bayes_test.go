@@ -12,10 +12,12 @@ import (
 
 func TestPrintFile(t *testing.T) {
 	tests := []struct {
-		desc     string
-		training string
-		target   string
-		want     string
+		desc          string
+		normalize     bool
+		minConfidence float64
+		training      string
+		target        string
+		want          string
 	}{
 		{
 			desc: "print transaction",
@@ -51,6 +53,49 @@ func TestPrintFile(t *testing.T) {
 				`A D        400 CHF`,
 			),
 		},
+		{
+			desc:      "normalize descriptions",
+			normalize: true,
+			training: lines(
+				`2022-03-03 "COOP-1234/ZURICH 03.03.2022"`,
+				`A B 10 CHF`,
+				``,
+				`2022-03-03 "Migros Basel"`,
+				`A C 10 CHF`,
+				``,
+				`2022-03-04 "Migros Bern"`,
+				`A C 10 CHF`,
+				``,
+			),
+			target: lines(
+				`2022-03-05 "Coop Zurich"`,
+				`A TBD 10 CHF`,
+			),
+			want: lines(
+				`2022-03-05 "Coop Zurich"`,
+				`A B         10 CHF`,
+			),
+		},
+		{
+			desc:          "reject predictions with low confidence",
+			minConfidence: 0.9,
+			training: lines(
+				`2022-03-03 "Hello world"`,
+				`A B 400 CHF`,
+				``,
+				`2022-03-03 "Hello world"`,
+				`A C 400 CHF`,
+				``,
+			),
+			target: lines(
+				`2022-03-03 "hello world"`,
+				`A TBD 400 CHF`,
+			),
+			want: lines(
+				`2022-03-03 "hello world"`,
+				`A   TBD        400 CHF`,
+			),
+		},
 	}
 
 	for _, test := range tests {
@@ -58,6 +103,8 @@ func TestPrintFile(t *testing.T) {
 			training := parse(t, test.training)
 			target := parse(t, test.target)
 			model := NewModel("TBD")
+			model.Normalize = test.normalize
+			model.MinConfidence = test.minConfidence
 			for _, d := range training.Directives {
 				if t, ok := d.Directive.(syntax.Transaction); ok {
 					model.Update(&t)
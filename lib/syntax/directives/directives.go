@@ -29,6 +29,22 @@ func (d Date) Parse() (time.Time, error) {
 	return date, nil
 }
 
+// Time is a time of day in the format HH:MM.
+type Time struct{ Range }
+
+// Parse returns the time as an offset from the start of the day.
+func (t Time) Parse() (time.Duration, error) {
+	tm, err := time.Parse("15:04", t.Extract())
+	if err != nil {
+		return 0, Error{
+			Message: "parsing time",
+			Range:   t.Range,
+			Wrapped: err,
+		}
+	}
+	return tm.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)), nil
+}
+
 type Decimal struct{ Range }
 
 func (d Decimal) Parse() (decimal.Decimal, error) {
@@ -48,16 +64,38 @@ type QuotedString struct {
 	Content Range
 }
 
+type Tag struct{ Range }
+
+// Name returns the name of the tag, without the leading '#'.
+func (t Tag) Name() string {
+	return strings.TrimPrefix(t.Extract(), "#")
+}
+
+// State is the clearing state of a booking, either `!` (pending)
+// or `*` (cleared).
+type State struct{ Range }
+
 type Booking struct {
 	Range
+	State         State
 	Credit, Debit Account
 	Quantity      Decimal
 	Commodity     Commodity
+	Tags          []Tag
+
+	// Virtual is set if the accounts are in parentheses.
+	Virtual bool
+
+	// Comment is the comment at the end of the line, if any.
+	Comment Range
 }
 
 type Performance struct {
 	Range
 	Targets []Commodity
+
+	// Comment is the comment at the end of the line, if any.
+	Comment Range
 }
 
 type Interval struct{ Range }
@@ -72,11 +110,24 @@ type File struct {
 	Directives []Directive
 }
 
+// Boundary is the boundary of the periods on which accrual transactions
+// are booked, either `start` or `end`.
+type Boundary struct{ Range }
+
+// Remainder is the period which absorbs the rounding remainder of an
+// accrual, either `first` or `last`.
+type Remainder struct{ Range }
+
 type Accrual struct {
 	Range
 	Interval   Interval
 	Start, End Date
 	Account    Account
+	Boundary   Boundary
+	Remainder  Remainder
+
+	// Comment is the comment at the end of the line, if any.
+	Comment Range
 }
 
 type Addons struct {
@@ -88,21 +139,30 @@ type Addons struct {
 type Transaction struct {
 	Range
 	Date        Date
+	Effective   Date
+	Time        Time
 	Description QuotedString
+	Tags        []Tag
 	Bookings    []Booking
 	Addons      Addons
+
+	// Comment is the comment at the end of the first line, if any.
+	Comment Range
 }
 
 type Open struct {
 	Range
-	Date    Date
-	Account Account
+	Date        Date
+	Account     Account
+	Commodities []Commodity
+	Note        QuotedString
 }
 
 type Close struct {
 	Range
 	Date    Date
 	Account Account
+	Note    QuotedString
 }
 
 type Assertion struct {
@@ -111,11 +171,21 @@ type Assertion struct {
 	Balances []Balance
 }
 
+type Opening struct {
+	Range
+	Date     Date
+	Balances []Balance
+}
+
 type Balance struct {
 	Range
 	Account   Account
 	Quantity  Decimal
 	Commodity Commodity
+
+	// Comment is the comment at the end of the line, if any. Only the
+	// balances of a block are on a line of their own.
+	Comment Range
 }
 
 type Price struct {
@@ -130,6 +200,42 @@ type Include struct {
 	IncludePath QuotedString
 }
 
+// Template is a named transaction with parameters. In the bookings,
+// `$name` stands for the parameter name, in place of an account, a
+// quantity or a commodity.
+type Template struct {
+	Range
+	Name        Range
+	Params      []Range
+	Description QuotedString
+	Tags        []Tag
+	Bookings    []Booking
+
+	// Comment is the comment at the end of the first line, if any.
+	Comment Range
+}
+
+// TagBlock starts a block in which the given tags are applied to all
+// transactions, up to the matching EndTagBlock. Blocks can be nested.
+type TagBlock struct {
+	Range
+	Tags []Tag
+}
+
+// EndTagBlock ends the innermost TagBlock.
+type EndTagBlock struct {
+	Range
+}
+
+// Apply instantiates a template on a date, with one argument for each
+// parameter of the template.
+type Apply struct {
+	Range
+	Date Date
+	Name Range
+	Args []Range
+}
+
 type Range struct {
 	Start, End int
 	Path, Text string
@@ -184,6 +290,16 @@ func (r Range) Location() Location {
 	return loc
 }
 
+// Position returns the path and the location of the start of the range,
+// or <generated> for ranges without a source text.
+func (r Range) Position() string {
+	if r.Text == "" {
+		return "<generated>"
+	}
+	r.End = r.Start
+	return fmt.Sprintf("%s:%s", r.Path, r.Location())
+}
+
 func (r Range) Context(previous int) []string {
 	start := r.Start
 	end := r.End
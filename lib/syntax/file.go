@@ -0,0 +1,64 @@
+package syntax
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/natefinch/atomic"
+)
+
+// gzipMagic are the first bytes of a gzip-compressed file.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadFile reads the given journal file. A gzip-compressed file is
+// decompressed, whatever its name.
+func ReadFile(file string) ([]byte, error) {
+	text, err := os.ReadFile(file)
+	if err != nil || !bytes.HasPrefix(text, gzipMagic) {
+		return text, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if text, err = io.ReadAll(r); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return text, nil
+}
+
+// WriteFile atomically replaces the given journal file with the content
+// of r. The content is gzip-compressed if the file is compressed already
+// or, for a new file, if its name ends in .gz, so that rewriting a
+// compressed file keeps it compressed.
+func WriteFile(file string, r io.Reader) error {
+	if !compressed(file) {
+		return atomic.WriteFile(file, r)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return atomic.WriteFile(file, &buf)
+}
+
+// compressed returns whether the file is gzip-compressed or, if it cannot
+// be read, whether its name ends in .gz.
+func compressed(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return strings.HasSuffix(file, ".gz")
+	}
+	defer f.Close()
+	magic := make([]byte, len(gzipMagic))
+	_, err = io.ReadFull(f, magic)
+	return err == nil && bytes.Equal(magic, gzipMagic)
+}
@@ -0,0 +1,91 @@
+package syntax
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGzip(t *testing.T, file, text string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(text)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseCompressed(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.knut")
+	if err := os.WriteFile(main, []byte("include \"2019.knut.gz\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeGzip(t, filepath.Join(dir, "2019.knut.gz"), "2019-01-01 open Assets:Bank\n")
+
+	files, err := new(Resolver).ParseFiles(main)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || len(files[1].Directives) != 1 {
+		t.Fatalf("ParseFiles() = %v, want the directive of the compressed file", files)
+	}
+
+	writeGzip(t, filepath.Join(dir, "2019.knut.gz"), "2019-01-01 foo\n")
+
+	_, err = new(Resolver).ParseFiles(main)
+
+	if err == nil || !strings.Contains(err.Error(), "2019.knut.gz") {
+		t.Errorf("ParseFiles() = %v, want an error referencing 2019.knut.gz", err)
+	}
+}
+
+func TestWriteFileKeepsCompression(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		name, existing string
+		compressed     bool
+		want           bool
+	}{
+		{name: "plain.knut", existing: "plain", want: false},
+		{name: "compressed.knut", existing: "compressed", compressed: true, want: true},
+		{name: "new.knut.gz", want: true},
+		{name: "new.knut", want: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(dir, test.name)
+			if test.compressed {
+				writeGzip(t, file, test.existing)
+			} else if test.existing != "" {
+				if err := os.WriteFile(file, []byte(test.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := WriteFile(file, strings.NewReader("content")); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := compressed(file); got != test.want {
+				t.Errorf("WriteFile() compressed: %t, want %t", got, test.want)
+			}
+			text, err := ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(text) != "content" {
+				t.Errorf("ReadFile() = %q, want %q", text, "content")
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package infer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// Model infers accounts for bookings.
+type Model interface {
+	// Update trains the model with the given transaction.
+	Update(t *syntax.Transaction)
+
+	// Infer replaces the accounts to be inferred in the given transaction
+	// and returns the predictions.
+	Infer(t *syntax.Transaction) []Prediction
+}
+
+// Options configure a model.
+type Options struct {
+	// Account is the account to be inferred.
+	Account string
+
+	// Normalize enables the normalization of descriptions.
+	Normalize bool
+
+	// MinConfidence is the minimum confidence of a prediction. Accounts
+	// are left unchanged if the confidence is below.
+	MinConfidence float64
+}
+
+// Candidate is a candidate account with its confidence.
+type Candidate struct {
+	Account    string
+	Confidence float64
+}
+
+// Prediction is an account inferred for a booking. Candidates are ranked
+// by decreasing confidence, the first one is the predicted account.
+type Prediction struct {
+	Booking    *syntax.Booking
+	Account    string
+	Confidence float64
+	Accepted   bool
+	Candidates []Candidate
+}
+
+var models = make(map[string]func(Options) Model)
+
+// Register registers a model constructor under the given name.
+func Register(name string, f func(Options) Model) {
+	models[name] = f
+}
+
+// Names returns the names of the registered models.
+func Names() []string {
+	var res []string
+	for name := range models {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// New creates the model with the given name.
+func New(name string, o Options) (Model, error) {
+	f, ok := models[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %q, want one of %v", name, Names())
+	}
+	return f(o), nil
+}
@@ -13,6 +13,10 @@ type Parser struct {
 	scanner.Scanner
 
 	Callback func(d directives.Directive)
+
+	// inTemplate is set while parsing the bookings of a template, where
+	// quantities and commodities may be parameters.
+	inTemplate bool
 }
 
 // New creates a new parser.
@@ -39,31 +43,82 @@ func (p *Parser) ParseFile() (directives.File, error) {
 	defer p.RangeEnd()
 	var file directives.File
 	for p.Current() != scanner.EOF {
-		switch {
+		if err := p.parseLine(&file); err != nil {
+			return directives.SetRange(&file, p.Range()), p.Annotate(err)
+		}
+	}
+	return directives.SetRange(&file, p.Range()), nil
+}
 
-		case p.Current() == '*' || p.Current() == '#' || p.Current() == '/':
-			if _, err := p.readComment(); err != nil {
-				return directives.SetRange(&file, p.Range()), p.Annotate(err)
+// ParseFileAll parses a file like ParseFile, but recovers from errors:
+// it records the error, skips the broken directive including its
+// indented lines and continues with the next one. It returns the
+// directives which could be parsed along with all errors.
+func (p *Parser) ParseFileAll() (directives.File, []error) {
+	p.RangeStart(fmt.Sprintf("parsing file `%s`", p.Path))
+	defer p.RangeEnd()
+	var (
+		file directives.File
+		errs []error
+	)
+	for p.Current() != scanner.EOF {
+		n := len(file.Directives)
+		if err := p.parseLine(&file); err != nil {
+			file.Directives = file.Directives[:n]
+			errs = append(errs, p.Annotate(err))
+			if err := p.skipDirective(); err != nil {
+				errs = append(errs, p.Annotate(err))
+				break
 			}
+		}
+	}
+	return directives.SetRange(&file, p.Range()), errs
+}
 
-		case isAlphanumeric(p.Current()) || p.Current() == '@':
-			dir, err := p.parseDirective()
-			file.Directives = append(file.Directives, dir)
-			if err != nil {
-				return directives.SetRange(&file, p.Range()), p.Annotate(err)
-			}
-			if p.Callback != nil {
-				p.Callback(dir)
-			}
+// parseLine parses a comment or a directive, along with the rest of the
+// line.
+func (p *Parser) parseLine(file *directives.File) error {
+	switch {
+
+	case p.Current() == '*' || p.Current() == '#' || p.Current() == '/':
+		if _, err := p.readComment(); err != nil {
+			return err
+		}
+
+	case isAlphanumeric(p.Current()) || p.Current() == '@':
+		dir, err := p.parseDirective()
+		file.Directives = append(file.Directives, dir)
+		if err != nil {
+			return err
+		}
+		if p.Callback != nil {
+			p.Callback(dir)
+		}
+	}
+	if p.Current() == scanner.EOF {
+		return nil
+	}
+	_, err := p.readRestOfWhitespaceLine()
+	return err
+}
+
+// skipDirective advances to the start of the next line which is not
+// indented.
+func (p *Parser) skipDirective() error {
+	for {
+		if _, err := p.ReadWhile(func(r rune) bool { return !isNewlineOrEOF(r) }); err != nil {
+			return err
 		}
 		if p.Current() == scanner.EOF {
-			break
+			return nil
 		}
-		if _, err := p.readRestOfWhitespaceLine(); err != nil {
-			return directives.SetRange(&file, p.Range()), p.Annotate(err)
+		if err := p.Advance(); err != nil {
+			return err
+		}
+		if c := p.Current(); c != ' ' && c != '\t' {
+			return nil
 		}
 	}
-	return directives.SetRange(&file, p.Range()), nil
 }
 
 func (p *Parser) parseDirective() (directives.Directive, error) {
@@ -83,20 +138,57 @@ func (p *Parser) parseDirective() (directives.Directive, error) {
 		if dir.Directive, err = p.parseInclude(); err != nil {
 			return directives.SetRange(&dir, p.Range()), p.Annotate(err)
 		}
+	} else if p.Current() == 't' || p.Current() == 'e' {
+		r, err := p.ReadAlternative([]string{"template", "tag", "end"})
+		if err != nil {
+			return directives.SetRange(&dir, p.Range()), p.Annotate(err)
+		}
+		switch r.Extract() {
+		case "template":
+			dir.Directive, err = p.parseTemplate()
+		case "tag":
+			dir.Directive, err = p.parseTagBlock()
+		case "end":
+			dir.Directive, err = p.parseEndTagBlock()
+		}
+		if err != nil {
+			return directives.SetRange(&dir, p.Range()), p.Annotate(err)
+		}
 	} else {
 		date, err := p.parseDate()
 		if err != nil {
 			return directives.SetRange(&dir, p.Range()), p.Annotate(err)
 		}
+		var effective directives.Date
+		if p.Current() == '=' {
+			if effective, err = p.parseEffectiveDate(); err != nil {
+				return directives.SetRange(&dir, p.Range()), p.Annotate(err)
+			}
+		}
 		if _, err := p.readWhitespace1(); err != nil {
 			return directives.SetRange(&dir, p.Range()), p.Annotate(err)
 		}
-		if p.Current() == '"' {
-			if dir.Directive, err = p.parseTransaction(date, addons); err != nil {
+		var tm directives.Time
+		if unicode.IsDigit(p.Current()) {
+			if tm, err = p.parseTime(); err != nil {
+				return directives.SetRange(&dir, p.Range()), p.Annotate(err)
+			}
+			if _, err := p.readWhitespace1(); err != nil {
+				return directives.SetRange(&dir, p.Range()), p.Annotate(err)
+			}
+		}
+		if p.Current() == '"' || !tm.Empty() {
+			if dir.Directive, err = p.parseTransaction(date, effective, tm, addons); err != nil {
 				return directives.SetRange(&dir, p.Range()), p.Annotate(err)
 			}
 		} else {
-			r, err := p.ReadAlternative([]string{"open", "close", "balance", "price"})
+			if !effective.Empty() {
+				return directives.SetRange(&dir, p.Range()), p.Annotate(directives.Error{
+					Message: "effective dates are only allowed on transactions",
+					Range:   effective.Range,
+				})
+			}
+			r, err := p.ReadAlternative([]string{"opening", "open", "close", "balance", "price", "apply"})
 			if err != nil {
 				return directives.SetRange(&dir, p.Range()), p.Annotate(err)
 			}
@@ -104,6 +196,10 @@ func (p *Parser) parseDirective() (directives.Directive, error) {
 				return directives.SetRange(&dir, p.Range()), p.Annotate(err)
 			}
 			switch r.Extract() {
+			case "opening":
+				if dir.Directive, err = p.parseOpening(date); err != nil {
+					return directives.SetRange(&dir, p.Range()), p.Annotate(err)
+				}
 			case "open":
 				if dir.Directive, err = p.parseOpen(date); err != nil {
 					return directives.SetRange(&dir, p.Range()), p.Annotate(err)
@@ -120,10 +216,20 @@ func (p *Parser) parseDirective() (directives.Directive, error) {
 				if dir.Directive, err = p.parsePrice(date); err != nil {
 					return directives.SetRange(&dir, p.Range()), p.Annotate(err)
 				}
+			case "apply":
+				if dir.Directive, err = p.parseApply(date); err != nil {
+					return directives.SetRange(&dir, p.Range()), p.Annotate(err)
+				}
 			}
 		}
 	}
-	return directives.SetRange(&dir, p.Range()), nil
+	// Some directives read the whitespace after their last element. It
+	// belongs to the rest of the line, as does a trailing comment.
+	rng := p.Range()
+	for rng.End > rng.Start && isWhitespace(rune(rng.Text[rng.End-1])) {
+		rng.End--
+	}
+	return directives.SetRange(&dir, rng), nil
 }
 
 func (p *Parser) parseInclude() (directives.Include, error) {
@@ -145,6 +251,134 @@ func (p *Parser) parseInclude() (directives.Include, error) {
 	return directives.SetRange(&include, p.Range()), nil
 }
 
+// parseTemplate parses a template, which is written like a transaction,
+// but with a name and parameters instead of the date:
+//
+//	template split-bill amount commodity account "Split bill"
+//	Assets:Bank $account $amount $commodity
+func (p *Parser) parseTemplate() (directives.Template, error) {
+	p.RangeContinue("parsing `template` directive")
+	defer p.RangeEnd()
+	var (
+		tpl directives.Template
+		err error
+	)
+	if _, err := p.readWhitespace1(); err != nil {
+		return directives.SetRange(&tpl, p.Range()), p.Annotate(err)
+	}
+	if tpl.Name, err = p.parseTemplateName(); err != nil {
+		return directives.SetRange(&tpl, p.Range()), p.Annotate(err)
+	}
+	for {
+		if _, err := p.readWhitespace1(); err != nil {
+			return directives.SetRange(&tpl, p.Range()), p.Annotate(err)
+		}
+		if p.Current() == '"' {
+			break
+		}
+		param, err := p.ReadWhile1("a letter or `\"`", unicode.IsLetter)
+		tpl.Params = append(tpl.Params, param)
+		if err != nil {
+			return directives.SetRange(&tpl, p.Range()), p.Annotate(err)
+		}
+	}
+	if tpl.Description, err = p.parseQuotedString(); err != nil {
+		return directives.SetRange(&tpl, p.Range()), p.Annotate(err)
+	}
+	if tpl.Tags, err = p.parseTags(); err != nil {
+		return directives.SetRange(&tpl, p.Range()), p.Annotate(err)
+	}
+	if _, tpl.Comment, err = p.readRestOfLine(); err != nil {
+		return directives.SetRange(&tpl, p.Range()), p.Annotate(err)
+	}
+	p.inTemplate = true
+	defer func() { p.inTemplate = false }()
+	tpl.Bookings, err = p.parseBookings()
+	return directives.SetRange(&tpl, p.Range()), err
+}
+
+// parseTagBlock parses the tags of a `tag` directive, which starts a
+// block of tagged transactions.
+func (p *Parser) parseTagBlock() (directives.TagBlock, error) {
+	p.RangeContinue("parsing `tag` directive")
+	defer p.RangeEnd()
+	var block directives.TagBlock
+	if _, err := p.ReadWhile1("whitespace", isWhitespace); err != nil {
+		return directives.SetRange(&block, p.Range()), p.Annotate(err)
+	}
+	tag, err := p.parseTag()
+	block.Tags = append(block.Tags, tag)
+	if err != nil {
+		return directives.SetRange(&block, p.Range()), p.Annotate(err)
+	}
+	rng := p.Range()
+	tags, err := p.parseTags()
+	block.Tags = append(block.Tags, tags...)
+	if err != nil {
+		return directives.SetRange(&block, p.Range()), p.Annotate(err)
+	}
+	rng.End = block.Tags[len(block.Tags)-1].End
+	return directives.SetRange(&block, rng), nil
+}
+
+// parseEndTagBlock parses an `end tag` directive.
+func (p *Parser) parseEndTagBlock() (directives.EndTagBlock, error) {
+	p.RangeContinue("parsing `end tag` directive")
+	defer p.RangeEnd()
+	var end directives.EndTagBlock
+	if _, err := p.ReadWhile1("whitespace", isWhitespace); err != nil {
+		return directives.SetRange(&end, p.Range()), p.Annotate(err)
+	}
+	if _, err := p.ReadString("tag"); err != nil {
+		return directives.SetRange(&end, p.Range()), p.Annotate(err)
+	}
+	return directives.SetRange(&end, p.Range()), nil
+}
+
+func (p *Parser) parseTemplateName() (directives.Range, error) {
+	p.RangeStart("parsing template name")
+	defer p.RangeEnd()
+	_, err := p.ReadWhile1("a letter, a digit, `-` or `_`", func(r rune) bool {
+		return isAlphanumeric(r) || r == '-' || r == '_'
+	})
+	if err != nil {
+		return p.Range(), p.Annotate(err)
+	}
+	return p.Range(), nil
+}
+
+// parseApply parses the name of a template and its arguments, which are
+// separated by whitespace.
+func (p *Parser) parseApply(date directives.Date) (directives.Apply, error) {
+	p.RangeContinue("parsing `apply` directive")
+	defer p.RangeEnd()
+	var (
+		apply = directives.Apply{Date: date}
+		err   error
+	)
+	if apply.Name, err = p.parseTemplateName(); err != nil {
+		return directives.SetRange(&apply, p.Range()), p.Annotate(err)
+	}
+	rng := p.Range()
+	for isWhitespace(p.Current()) {
+		if _, err := p.ReadWhile(isWhitespace); err != nil {
+			return directives.SetRange(&apply, p.Range()), p.Annotate(err)
+		}
+		if isNewlineOrEOF(p.Current()) || p.Current() == '/' {
+			break
+		}
+		arg, err := p.ReadWhile1("an argument", func(r rune) bool {
+			return !isWhitespaceOrNewline(r) && r != scanner.EOF
+		})
+		apply.Args = append(apply.Args, arg)
+		if err != nil {
+			return directives.SetRange(&apply, p.Range()), p.Annotate(err)
+		}
+		rng.End = arg.End
+	}
+	return directives.SetRange(&apply, rng), nil
+}
+
 func (p *Parser) parseOpen(date directives.Date) (directives.Open, error) {
 	p.RangeContinue("parsing `open` directive")
 	defer p.RangeEnd()
@@ -153,9 +387,31 @@ func (p *Parser) parseOpen(date directives.Date) (directives.Open, error) {
 		err  error
 	)
 	if open.Account, err = p.parseAccount(); err != nil {
-		err = p.Annotate(err)
+		return directives.SetRange(&open, p.Range()), p.Annotate(err)
+	}
+	rng := p.Range()
+	for isWhitespace(p.Current()) {
+		if _, err := p.ReadWhile(isWhitespace); err != nil {
+			return directives.SetRange(&open, p.Range()), p.Annotate(err)
+		}
+		if p.Current() == '"' {
+			if open.Note, err = p.parseQuotedString(); err != nil {
+				return directives.SetRange(&open, p.Range()), p.Annotate(err)
+			}
+			rng.End = open.Note.End
+			break
+		}
+		if !isAlphanumeric(p.Current()) {
+			break
+		}
+		commodity, err := p.parseCommodity()
+		open.Commodities = append(open.Commodities, commodity)
+		if err != nil {
+			return directives.SetRange(&open, p.Range()), p.Annotate(err)
+		}
+		rng.End = commodity.End
 	}
-	return directives.SetRange(&open, p.Range()), err
+	return directives.SetRange(&open, rng), nil
 }
 
 func (p *Parser) parseClose(date directives.Date) (directives.Close, error) {
@@ -166,9 +422,16 @@ func (p *Parser) parseClose(date directives.Date) (directives.Close, error) {
 		err   error
 	)
 	if close.Account, err = p.parseAccount(); err != nil {
-		err = p.Annotate(err)
+		return directives.SetRange(&close, p.Range()), p.Annotate(err)
+	}
+	rng := p.Range()
+	if close.Note, err = p.parseNote(); err != nil {
+		return directives.SetRange(&close, p.Range()), p.Annotate(err)
 	}
-	return directives.SetRange(&close, p.Range()), err
+	if !close.Note.Empty() {
+		rng.End = close.Note.End
+	}
+	return directives.SetRange(&close, rng), nil
 }
 
 func (p *Parser) parseAssertion(date directives.Date) (directives.Assertion, error) {
@@ -178,18 +441,38 @@ func (p *Parser) parseAssertion(date directives.Date) (directives.Assertion, err
 		assertion = directives.Assertion{Date: date}
 		err       error
 	)
+	assertion.Balances, err = p.parseBalances()
+	return directives.SetRange(&assertion, p.Range()), err
+}
+
+func (p *Parser) parseOpening(date directives.Date) (directives.Opening, error) {
+	p.RangeContinue("parsing `opening` directive")
+	defer p.RangeEnd()
+	var (
+		opening = directives.Opening{Date: date}
+		err     error
+	)
+	opening.Balances, err = p.parseBalances()
+	return directives.SetRange(&opening, p.Range()), err
+}
+
+// parseBalances parses either a single balance on the current line, or
+// a block of balances on the following lines.
+func (p *Parser) parseBalances() ([]directives.Balance, error) {
+	var balances []directives.Balance
 	if isNewline(p.Current()) {
 		if _, err := p.readRestOfWhitespaceLine(); err != nil {
-			return directives.SetRange(&assertion, p.Range()), p.Annotate(err)
+			return balances, p.Annotate(err)
 		}
 		for {
 			bal, err := p.parseBalance()
-			assertion.Balances = append(assertion.Balances, bal)
 			if err != nil {
-				return directives.SetRange(&assertion, p.Range()), p.Annotate(err)
+				return append(balances, bal), p.Annotate(err)
 			}
-			if _, err := p.readRestOfWhitespaceLine(); err != nil {
-				return directives.SetRange(&assertion, p.Range()), p.Annotate(err)
+			_, bal.Comment, err = p.readRestOfLine()
+			balances = append(balances, bal)
+			if err != nil {
+				return balances, p.Annotate(err)
 			}
 			if isWhitespaceOrNewline(p.Current()) || p.Current() == scanner.EOF {
 				break
@@ -197,12 +480,12 @@ func (p *Parser) parseAssertion(date directives.Date) (directives.Assertion, err
 		}
 	} else {
 		bal, err := p.parseBalance()
-		assertion.Balances = append(assertion.Balances, bal)
+		balances = append(balances, bal)
 		if err != nil {
-			return directives.SetRange(&assertion, p.Range()), p.Annotate(err)
+			return balances, p.Annotate(err)
 		}
 	}
-	return directives.SetRange(&assertion, p.Range()), err
+	return balances, nil
 }
 
 func (p *Parser) parseBalance() (directives.Balance, error) {
@@ -262,6 +545,10 @@ func (p *Parser) parseCommodity() (directives.Commodity, error) {
 	)
 	p.RangeStart("parsing commodity")
 	defer p.RangeEnd()
+	if p.inTemplate && p.Current() == '$' {
+		err = p.readParameter()
+		return directives.SetRange(&commodity, p.Range()), err
+	}
 	_, err = p.ReadWhile1("a letter or a digit", isAlphanumeric)
 	if err != nil {
 		err = p.Annotate(err)
@@ -272,6 +559,10 @@ func (p *Parser) parseCommodity() (directives.Commodity, error) {
 func (p *Parser) parseDecimal() (directives.Decimal, error) {
 	p.RangeStart("parsing decimal")
 	defer p.RangeEnd()
+	if p.inTemplate && p.Current() == '$' {
+		err := p.readParameter()
+		return directives.Decimal{Range: p.Range()}, err
+	}
 	if p.Current() == '-' {
 		if _, err := p.ReadCharacter('-'); err != nil {
 			return directives.Decimal{Range: p.Range()}, p.Annotate(err)
@@ -292,6 +583,18 @@ func (p *Parser) parseDecimal() (directives.Decimal, error) {
 	return directives.Decimal{Range: p.Range()}, nil
 }
 
+// readParameter reads a reference to a template parameter, such as
+// `$amount`.
+func (p *Parser) readParameter() error {
+	if _, err := p.ReadCharacter('$'); err != nil {
+		return p.Annotate(err)
+	}
+	if _, err := p.ReadWhile1("a letter", unicode.IsLetter); err != nil {
+		return p.Annotate(err)
+	}
+	return nil
+}
+
 func (p *Parser) parseAccount() (directives.Account, error) {
 	p.RangeStart("parsing account")
 	defer p.RangeEnd()
@@ -329,13 +632,22 @@ func (p *Parser) parseBooking() (directives.Booking, error) {
 		booking directives.Booking
 		err     error
 	)
-	if booking.Credit, err = p.parseAccount(); err != nil {
+	if p.Current() == '!' || p.Current() == '*' {
+		if booking.State, err = p.parseState(); err != nil {
+			return directives.SetRange(&booking, p.Range()), p.Annotate(err)
+		}
+		if _, err := p.ReadWhile1("whitespace", isWhitespace); err != nil {
+			return directives.SetRange(&booking, p.Range()), p.Annotate(err)
+		}
+	}
+	booking.Virtual = p.Current() == '('
+	if booking.Credit, err = p.parseBookingAccount(booking.Virtual); err != nil {
 		return directives.SetRange(&booking, p.Range()), p.Annotate(err)
 	}
 	if _, err := p.ReadWhile1("whitespace", isWhitespace); err != nil {
 		return directives.SetRange(&booking, p.Range()), p.Annotate(err)
 	}
-	if booking.Debit, err = p.parseAccount(); err != nil {
+	if booking.Debit, err = p.parseBookingAccount(booking.Virtual); err != nil {
 		return directives.SetRange(&booking, p.Range()), p.Annotate(err)
 	}
 	if _, err := p.ReadWhile1("whitespace", isWhitespace); err != nil {
@@ -350,7 +662,91 @@ func (p *Parser) parseBooking() (directives.Booking, error) {
 	if booking.Commodity, err = p.parseCommodity(); err != nil {
 		return directives.SetRange(&booking, p.Range()), p.Annotate(err)
 	}
-	return directives.SetRange(&booking, p.Range()), nil
+	rng := p.Range()
+	if booking.Tags, err = p.parseTags(); err != nil {
+		return directives.SetRange(&booking, p.Range()), p.Annotate(err)
+	}
+	if n := len(booking.Tags); n > 0 {
+		rng.End = booking.Tags[n-1].End
+	}
+	return directives.SetRange(&booking, rng), nil
+}
+
+// parseBookingAccount parses an account of a booking, which is enclosed
+// in parentheses if the booking is virtual.
+func (p *Parser) parseBookingAccount(virtual bool) (directives.Account, error) {
+	if !virtual {
+		return p.parseAccount()
+	}
+	p.RangeStart("parsing virtual account")
+	defer p.RangeEnd()
+	if _, err := p.ReadCharacter('('); err != nil {
+		return directives.Account{Range: p.Range()}, p.Annotate(err)
+	}
+	a, err := p.parseAccount()
+	if err != nil {
+		return a, p.Annotate(err)
+	}
+	if _, err := p.ReadCharacter(')'); err != nil {
+		return a, p.Annotate(err)
+	}
+	return a, nil
+}
+
+func (p *Parser) parseState() (directives.State, error) {
+	p.RangeStart("parsing state")
+	defer p.RangeEnd()
+	if _, err := p.ReadCharacterWith("`!` or `*`", func(r rune) bool { return r == '!' || r == '*' }); err != nil {
+		return directives.State{Range: p.Range()}, p.Annotate(err)
+	}
+	return directives.State{Range: p.Range()}, nil
+}
+
+// parseNote parses an optional quoted note, preceded by whitespace.
+// Trailing whitespace is consumed.
+func (p *Parser) parseNote() (directives.QuotedString, error) {
+	if !isWhitespace(p.Current()) {
+		return directives.QuotedString{}, nil
+	}
+	if _, err := p.ReadWhile(isWhitespace); err != nil {
+		return directives.QuotedString{}, err
+	}
+	if p.Current() != '"' {
+		return directives.QuotedString{}, nil
+	}
+	return p.parseQuotedString()
+}
+
+// parseTags parses a possibly empty list of whitespace-separated tags.
+// Trailing whitespace is consumed.
+func (p *Parser) parseTags() ([]directives.Tag, error) {
+	var tags []directives.Tag
+	for isWhitespace(p.Current()) {
+		if _, err := p.ReadWhile(isWhitespace); err != nil {
+			return tags, err
+		}
+		if p.Current() != '#' {
+			break
+		}
+		tag, err := p.parseTag()
+		tags = append(tags, tag)
+		if err != nil {
+			return tags, err
+		}
+	}
+	return tags, nil
+}
+
+func (p *Parser) parseTag() (directives.Tag, error) {
+	p.RangeStart("parsing tag")
+	defer p.RangeEnd()
+	if _, err := p.ReadCharacter('#'); err != nil {
+		return directives.Tag{Range: p.Range()}, p.Annotate(err)
+	}
+	if _, err := p.ReadWhile1("a letter or a digit", isAlphanumeric); err != nil {
+		return directives.Tag{Range: p.Range()}, p.Annotate(err)
+	}
+	return directives.Tag{Range: p.Range()}, nil
 }
 
 func (p *Parser) parseDate() (directives.Date, error) {
@@ -375,6 +771,24 @@ func (p *Parser) parseDate() (directives.Date, error) {
 	return directives.Date{Range: p.Range()}, nil
 }
 
+func (p *Parser) parseTime() (directives.Time, error) {
+	p.RangeStart("parsing the time")
+	defer p.RangeEnd()
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			if _, err := p.ReadCharacter(':'); err != nil {
+				return directives.Time{Range: p.Range()}, p.Annotate(err)
+			}
+		}
+		for j := 0; j < 2; j++ {
+			if _, err := p.ReadCharacterWith("a digit", unicode.IsDigit); err != nil {
+				return directives.Time{Range: p.Range()}, p.Annotate(err)
+			}
+		}
+	}
+	return directives.Time{Range: p.Range()}, nil
+}
+
 func (p *Parser) parseQuotedString() (directives.QuotedString, error) {
 	p.RangeStart("parsing quoted string")
 	defer p.RangeEnd()
@@ -394,33 +808,60 @@ func (p *Parser) parseQuotedString() (directives.QuotedString, error) {
 	return directives.SetRange(&qs, p.Range()), nil
 }
 
-func (p *Parser) parseTransaction(date directives.Date, addons directives.Addons) (directives.Transaction, error) {
+// parseEffectiveDate parses the effective date of a transaction, which
+// follows the date after an equals sign, as in `2020-01-01=2020-01-15`.
+func (p *Parser) parseEffectiveDate() (directives.Date, error) {
+	p.RangeStart("parsing effective date")
+	defer p.RangeEnd()
+	if _, err := p.ReadCharacter('='); err != nil {
+		return directives.Date{Range: p.Range()}, p.Annotate(err)
+	}
+	d, err := p.parseDate()
+	if err != nil {
+		return d, p.Annotate(err)
+	}
+	return d, nil
+}
+
+func (p *Parser) parseTransaction(date, effective directives.Date, tm directives.Time, addons directives.Addons) (directives.Transaction, error) {
 	p.RangeContinue("parsing transaction")
 	defer p.RangeEnd()
 	var (
-		trx = directives.Transaction{Date: date, Addons: addons}
+		trx = directives.Transaction{Date: date, Effective: effective, Time: tm, Addons: addons}
 		err error
 	)
 	if trx.Description, err = p.parseQuotedString(); err != nil {
 		return directives.SetRange(&trx, p.Range()), p.Annotate(err)
 	}
-	if _, err := p.readRestOfWhitespaceLine(); err != nil {
+	if trx.Tags, err = p.parseTags(); err != nil {
 		return directives.SetRange(&trx, p.Range()), p.Annotate(err)
 	}
+	if _, trx.Comment, err = p.readRestOfLine(); err != nil {
+		return directives.SetRange(&trx, p.Range()), p.Annotate(err)
+	}
+	trx.Bookings, err = p.parseBookings()
+	return directives.SetRange(&trx, p.Range()), err
+}
+
+// parseBookings parses one booking per line, up to the first line which
+// is empty or indented.
+func (p *Parser) parseBookings() ([]directives.Booking, error) {
+	var bookings []directives.Booking
 	for {
 		b, err := p.parseBooking()
-		trx.Bookings = append(trx.Bookings, b)
 		if err != nil {
-			return directives.SetRange(&trx, p.Range()), p.Annotate(err)
+			return append(bookings, b), p.Annotate(err)
 		}
-		if _, err := p.readRestOfWhitespaceLine(); err != nil {
-			return directives.SetRange(&trx, p.Range()), p.Annotate(err)
+		_, b.Comment, err = p.readRestOfLine()
+		bookings = append(bookings, b)
+		if err != nil {
+			return bookings, p.Annotate(err)
 		}
 		if isWhitespaceOrNewline(p.Current()) || p.Current() == scanner.EOF {
 			break
 		}
 	}
-	return directives.SetRange(&trx, p.Range()), nil
+	return bookings, nil
 }
 
 func (p *Parser) parseAddons() (directives.Addons, error) {
@@ -459,9 +900,15 @@ func (p *Parser) parseAddons() (directives.Addons, error) {
 				return directives.SetRange(&addons, p.Range()), p.Annotate(err)
 			}
 		}
-		if _, err := p.readRestOfWhitespaceLine(); err != nil {
+		_, comment, err := p.readRestOfLine()
+		if err != nil {
 			return directives.SetRange(&addons, p.Range()), p.Annotate(directives.Error{})
 		}
+		if r.Extract() == "@performance" {
+			addons.Performance.Comment = comment
+		} else {
+			addons.Accrual.Comment = comment
+		}
 		if p.Current() != '@' {
 			return directives.SetRange(&addons, p.Range()), nil
 		}
@@ -539,7 +986,49 @@ func (p *Parser) parseAccrual() (directives.Accrual, error) {
 	if accrual.Account, err = p.parseAccount(); err != nil {
 		return directives.SetRange(&accrual, p.Range()), p.Annotate(err)
 	}
-	return directives.SetRange(&accrual, p.Range()), nil
+	rng := p.Range()
+	if isWhitespace(p.Current()) {
+		if _, err := p.ReadWhile(isWhitespace); err != nil {
+			return directives.SetRange(&accrual, p.Range()), p.Annotate(err)
+		}
+		if c := p.Current(); c == 's' || c == 'e' {
+			if accrual.Boundary, err = p.parseBoundary(); err != nil {
+				return directives.SetRange(&accrual, p.Range()), p.Annotate(err)
+			}
+			rng.End = accrual.Boundary.End
+			if !isWhitespace(p.Current()) {
+				return directives.SetRange(&accrual, rng), nil
+			}
+			if _, err := p.ReadWhile(isWhitespace); err != nil {
+				return directives.SetRange(&accrual, p.Range()), p.Annotate(err)
+			}
+		}
+		if c := p.Current(); c == 'f' || c == 'l' {
+			if accrual.Remainder, err = p.parseRemainder(); err != nil {
+				return directives.SetRange(&accrual, p.Range()), p.Annotate(err)
+			}
+			rng.End = accrual.Remainder.End
+		}
+	}
+	return directives.SetRange(&accrual, rng), nil
+}
+
+func (p *Parser) parseRemainder() (directives.Remainder, error) {
+	p.RangeStart("parsing remainder")
+	defer p.RangeEnd()
+	if _, err := p.ReadAlternative([]string{"first", "last"}); err != nil {
+		return directives.Remainder{Range: p.Range()}, p.Annotate(err)
+	}
+	return directives.Remainder{Range: p.Range()}, nil
+}
+
+func (p *Parser) parseBoundary() (directives.Boundary, error) {
+	p.RangeStart("parsing boundary")
+	defer p.RangeEnd()
+	if _, err := p.ReadAlternative([]string{"start", "end"}); err != nil {
+		return directives.Boundary{Range: p.Range()}, p.Annotate(err)
+	}
+	return directives.Boundary{Range: p.Range()}, nil
 }
 
 func (p *Parser) parseInterval() (directives.Interval, error) {
@@ -564,18 +1053,33 @@ func (p *Parser) readWhitespace1() (directives.Range, error) {
 }
 
 func (p *Parser) readRestOfWhitespaceLine() (directives.Range, error) {
+	rng, _, err := p.readRestOfLine()
+	return rng, err
+}
+
+// readRestOfLine reads whitespace, an optional `//` comment and the
+// newline. It returns the range which it has read and the range of the
+// comment, which is empty if there is none.
+func (p *Parser) readRestOfLine() (directives.Range, directives.Range, error) {
 	p.RangeStart("reading the rest of the line")
 	defer p.RangeEnd()
+	var comment directives.Range
 	if _, err := p.ReadWhile(isWhitespace); err != nil {
-		return p.Range(), p.Annotate(err)
+		return p.Range(), comment, p.Annotate(err)
+	}
+	if p.Current() == '/' {
+		var err error
+		if comment, err = p.readComment(); err != nil {
+			return p.Range(), comment, p.Annotate(err)
+		}
 	}
 	if p.Current() == scanner.EOF {
-		return p.Range(), nil
+		return p.Range(), comment, nil
 	}
 	if _, err := p.ReadCharacter('\n'); err != nil {
-		return p.Range(), p.Annotate(err)
+		return p.Range(), comment, p.Annotate(err)
 	}
-	return p.Range(), nil
+	return p.Range(), comment, nil
 }
 
 func isAlphanumeric(r rune) bool {
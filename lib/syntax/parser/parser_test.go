@@ -195,6 +195,35 @@ func TestParseFile(t *testing.T) {
 	}.run(t)
 }
 
+func TestParseFileAll(t *testing.T) {
+	text := strings.Join([]string{
+		"2020-01-01 open A",
+		"2020-01-02 foo",
+		"2020-01-03 \"broken\"",
+		"  A B x CHF",
+		"2020-01-04 open B",
+		"",
+	}, "\n")
+	p := New(text, "")
+	if err := p.Advance(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, errs := p.ParseFileAll()
+
+	var got []string
+	for _, d := range file.Directives {
+		got = append(got, d.Extract())
+	}
+	want := []string{"2020-01-01 open A", "2020-01-04 open B"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("p.ParseFileAll() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if len(errs) != 2 {
+		t.Errorf("p.ParseFileAll() returned %d errors, want 2: %v", len(errs), errs)
+	}
+}
+
 func TestParseCommodity(t *testing.T) {
 	parserTest[directives.Commodity]{
 		tests: []testcase[directives.Commodity]{
@@ -329,6 +358,46 @@ func TestParseAccrual(t *testing.T) {
 					}
 				},
 			},
+			{
+				text: " monthly 2023-01-01 2023-12-31 A:B start",
+				want: func(s string) directives.Accrual {
+					return directives.Accrual{
+						Range:    Range{End: 40, Text: s},
+						Interval: directives.Interval{Range: Range{Start: 1, End: 8, Text: s}},
+						Start:    directives.Date{Range: Range{Start: 9, End: 19, Text: s}},
+						End:      directives.Date{Range: Range{Start: 20, End: 30, Text: s}},
+						Account:  directives.Account{Range: Range{Start: 31, End: 34, Text: s}},
+						Boundary: directives.Boundary{Range: Range{Start: 35, End: 40, Text: s}},
+					}
+				},
+			},
+			{
+				text: " monthly 2023-01-01 2023-12-31 A:B start last",
+				want: func(s string) directives.Accrual {
+					return directives.Accrual{
+						Range:     Range{End: 45, Text: s},
+						Interval:  directives.Interval{Range: Range{Start: 1, End: 8, Text: s}},
+						Start:     directives.Date{Range: Range{Start: 9, End: 19, Text: s}},
+						End:       directives.Date{Range: Range{Start: 20, End: 30, Text: s}},
+						Account:   directives.Account{Range: Range{Start: 31, End: 34, Text: s}},
+						Boundary:  directives.Boundary{Range: Range{Start: 35, End: 40, Text: s}},
+						Remainder: directives.Remainder{Range: Range{Start: 41, End: 45, Text: s}},
+					}
+				},
+			},
+			{
+				text: " monthly 2023-01-01 2023-12-31 A:B last",
+				want: func(s string) directives.Accrual {
+					return directives.Accrual{
+						Range:     Range{End: 39, Text: s},
+						Interval:  directives.Interval{Range: Range{Start: 1, End: 8, Text: s}},
+						Start:     directives.Date{Range: Range{Start: 9, End: 19, Text: s}},
+						End:       directives.Date{Range: Range{Start: 20, End: 30, Text: s}},
+						Account:   directives.Account{Range: Range{Start: 31, End: 34, Text: s}},
+						Remainder: directives.Remainder{Range: Range{Start: 35, End: 39, Text: s}},
+					}
+				},
+			},
 			{
 				text: "",
 				want: func(s string) directives.Accrual {
@@ -718,6 +787,39 @@ func TestParseDate(t *testing.T) {
 	}.run(t)
 }
 
+func TestParseTime(t *testing.T) {
+	parserTest[directives.Time]{
+		tests: []testcase[directives.Time]{
+			{
+				text: "14:30",
+				want: func(s string) directives.Time {
+					return directives.Time{Range: Range{End: 5, Text: s}}
+				},
+			},
+			{
+				text: "1430",
+				want: func(s string) directives.Time {
+					return directives.Time{Range: Range{End: 2, Text: s}}
+				},
+				err: func(s string) error {
+					return directives.Error{
+						Range:   directives.Range{End: 2, Text: s},
+						Message: "while parsing the time",
+						Wrapped: directives.Error{
+							Range:   directives.Range{Start: 2, End: 2, Text: s},
+							Message: "unexpected character `3`, want `:`",
+						},
+					}
+				},
+			},
+		},
+		desc: "p.parseTime()",
+		fn: func(p *Parser) (directives.Time, error) {
+			return p.parseTime()
+		},
+	}.run(t)
+}
+
 func TestReadComment(t *testing.T) {
 	parserTest[directives.Range]{
 		tests: []testcase[directives.Range]{
@@ -817,6 +919,19 @@ func TestParseInterval(t *testing.T) {
 func TestParseBooking(t *testing.T) {
 	parserTest[directives.Booking]{
 		tests: []testcase[directives.Booking]{
+			{
+				text: "(A:B) (C:D) 100.0 CHF",
+				want: func(t string) directives.Booking {
+					return directives.Booking{
+						Range:     Range{End: 21, Text: t},
+						Credit:    directives.Account{Range: Range{Start: 1, End: 4, Text: t}},
+						Debit:     directives.Account{Range: Range{Start: 7, End: 10, Text: t}},
+						Quantity:  directives.Decimal{Range: Range{Start: 12, End: 17, Text: t}},
+						Commodity: directives.Commodity{Range: Range{Start: 18, End: 21, Text: t}},
+						Virtual:   true,
+					}
+				},
+			},
 			{
 				text: "A:B C:D 100.0 CHF",
 				want: func(t string) directives.Booking {
@@ -829,6 +944,35 @@ func TestParseBooking(t *testing.T) {
 					}
 				},
 			},
+			{
+				text: "A:B C:D 100.0 CHF #foo #bar ",
+				want: func(t string) directives.Booking {
+					return directives.Booking{
+						Range:     Range{End: 27, Text: t},
+						Credit:    directives.Account{Range: Range{End: 3, Text: t}},
+						Debit:     directives.Account{Range: Range{Start: 4, End: 7, Text: t}},
+						Quantity:  directives.Decimal{Range: Range{Start: 8, End: 13, Text: t}},
+						Commodity: directives.Commodity{Range: Range{Start: 14, End: 17, Text: t}},
+						Tags: []directives.Tag{
+							{Range: Range{Start: 18, End: 22, Text: t}},
+							{Range: Range{Start: 23, End: 27, Text: t}},
+						},
+					}
+				},
+			},
+			{
+				text: "! A:B C:D 100.0 CHF",
+				want: func(t string) directives.Booking {
+					return directives.Booking{
+						Range:     Range{End: 19, Text: t},
+						State:     directives.State{Range: Range{End: 1, Text: t}},
+						Credit:    directives.Account{Range: Range{Start: 2, End: 5, Text: t}},
+						Debit:     directives.Account{Range: Range{Start: 6, End: 9, Text: t}},
+						Quantity:  directives.Decimal{Range: Range{Start: 10, End: 15, Text: t}},
+						Commodity: directives.Commodity{Range: Range{Start: 16, End: 19, Text: t}},
+					}
+				},
+			},
 			{
 				text: "$dividend C:D 100.0 CHF",
 				want: func(t string) directives.Booking {
@@ -1059,6 +1203,30 @@ func TestParseTransaction(t *testing.T) {
 					}
 				},
 			},
+			{
+				text: "\"foo\" #bar\n" + "A B 1 CHF\n", // 11 + 10
+				want: func(t string) directives.Transaction {
+					return directives.Transaction{
+						Range: Range{End: 21, Text: t},
+						Description: directives.QuotedString{
+							Range:   Range{End: 5, Text: t},
+							Content: Range{Start: 1, End: 4, Text: t},
+						},
+						Tags: []directives.Tag{
+							{Range: Range{Start: 6, End: 10, Text: t}},
+						},
+						Bookings: []directives.Booking{
+							{
+								Range:     Range{Start: 11, End: 20, Text: t},
+								Credit:    directives.Account{Range: Range{Start: 11, End: 12, Text: t}},
+								Debit:     directives.Account{Range: Range{Start: 13, End: 14, Text: t}},
+								Quantity:  directives.Decimal{Range: Range{Start: 15, End: 16, Text: t}},
+								Commodity: directives.Commodity{Range: Range{Start: 17, End: 20, Text: t}},
+							},
+						},
+					}
+				},
+			},
 			{
 				text: "\"foo\"\n" + "A B 1 CHF\n" + "B A 1 CHF\n", // 6 + 10 + 10
 				want: func(t string) directives.Transaction {
@@ -1144,7 +1312,7 @@ func TestParseTransaction(t *testing.T) {
 		},
 		desc: "p.parseTransaction()",
 		fn: func(p *Parser) (directives.Transaction, error) {
-			return p.parseTransaction(directives.Date{}, directives.Addons{})
+			return p.parseTransaction(directives.Date{}, directives.Date{}, directives.Time{}, directives.Addons{})
 		},
 	}.run(t)
 }
@@ -1152,6 +1320,32 @@ func TestParseTransaction(t *testing.T) {
 func TestParseDirective(t *testing.T) {
 	parserTest[directives.Directive]{
 		tests: []testcase[directives.Directive]{
+			{
+				text: "2023-04-03=2023-04-15 \"foo\"\n" + "A B 1 CHF\n", // 28 + 10
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 38, Text: s},
+						Directive: directives.Transaction{
+							Range:     Range{End: 38, Text: s},
+							Date:      directives.Date{Range: Range{End: 10, Text: s}},
+							Effective: directives.Date{Range: Range{Start: 11, End: 21, Text: s}},
+							Description: directives.QuotedString{
+								Range:   Range{Start: 22, End: 27, Text: s},
+								Content: Range{Start: 23, End: 26, Text: s},
+							},
+							Bookings: []directives.Booking{
+								{
+									Range:     Range{Start: 28, End: 37, Text: s},
+									Credit:    directives.Account{Range: Range{Start: 28, End: 29, Text: s}},
+									Debit:     directives.Account{Range: Range{Start: 30, End: 31, Text: s}},
+									Quantity:  directives.Decimal{Range: Range{Start: 32, End: 33, Text: s}},
+									Commodity: directives.Commodity{Range: Range{Start: 34, End: 37, Text: s}},
+								},
+							},
+						},
+					}
+				},
+			},
 			{
 				text: "@performance(USD)\n" + "2023-04-03 \"foo\"\n" + "A B 1 CHF\n", // 18 + 17 + 10
 				want: func(s string) directives.Directive {
@@ -1213,6 +1407,33 @@ func TestParseDirective(t *testing.T) {
 					}
 				},
 			},
+			{
+				text: "2023-04-03 14:30 \"foo\"\n" + "A B 1 CHF\n", // 23 + 10
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 33, Text: s},
+
+						Directive: directives.Transaction{
+							Range: Range{End: 33, Text: s},
+							Date:  directives.Date{Range: directives.Range{End: 10, Text: s}},
+							Time:  directives.Time{Range: directives.Range{Start: 11, End: 16, Text: s}},
+							Description: directives.QuotedString{
+								Range:   Range{Start: 17, End: 22, Text: s},
+								Content: Range{Start: 18, End: 21, Text: s},
+							},
+							Bookings: []directives.Booking{
+								{
+									Range:     Range{Start: 23, End: 32, Text: s},
+									Credit:    directives.Account{Range: Range{Start: 23, End: 24, Text: s}},
+									Debit:     directives.Account{Range: Range{Start: 25, End: 26, Text: s}},
+									Quantity:  directives.Decimal{Range: Range{Start: 27, End: 28, Text: s}},
+									Commodity: directives.Commodity{Range: Range{Start: 29, End: 32, Text: s}},
+								},
+							},
+						},
+					}
+				},
+			},
 			{
 				text: " 2023-04-03 \"foo\"\n" + "A B 1 CHF\n", // 17 + 10
 				want: func(s string) directives.Directive {
@@ -1282,6 +1503,44 @@ func TestParseDirective(t *testing.T) {
 					}
 				},
 			},
+			{
+				text: `2023-04-03 open B:A "note"`,
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 26, Text: s},
+						Directive: directives.Open{
+							Range:   Range{End: 26, Text: s},
+							Date:    directives.Date{Range: directives.Range{End: 10, Text: s}},
+							Account: directives.Account{Range: directives.Range{Start: 16, End: 19, Text: s}},
+							Note: directives.QuotedString{
+								Range:   Range{Start: 20, End: 26, Text: s},
+								Content: Range{Start: 21, End: 25, Text: s},
+							},
+						},
+					}
+				},
+			},
+			{
+				text: `2023-04-03 open B:A USD CHF "note"`,
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 34, Text: s},
+						Directive: directives.Open{
+							Range:   Range{End: 34, Text: s},
+							Date:    directives.Date{Range: directives.Range{End: 10, Text: s}},
+							Account: directives.Account{Range: directives.Range{Start: 16, End: 19, Text: s}},
+							Commodities: []directives.Commodity{
+								{Range: Range{Start: 20, End: 23, Text: s}},
+								{Range: Range{Start: 24, End: 27, Text: s}},
+							},
+							Note: directives.QuotedString{
+								Range:   Range{Start: 28, End: 34, Text: s},
+								Content: Range{Start: 29, End: 33, Text: s},
+							},
+						},
+					}
+				},
+			},
 			{
 				text: `include "foo/foo.knut"`,
 				want: func(s string) directives.Directive {
@@ -1356,6 +1615,26 @@ func TestParseDirective(t *testing.T) {
 					}
 				},
 			},
+			{
+				text: "2023-04-03 opening B:A 1 USD",
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 28, Text: s},
+						Directive: directives.Opening{
+							Range: Range{End: 28, Text: s},
+							Date:  directives.Date{Range: directives.Range{End: 10, Text: s}},
+							Balances: []directives.Balance{
+								{
+									Range:     Range{Start: 19, End: 28, Text: s},
+									Account:   directives.Account{Range: directives.Range{Start: 19, End: 22, Text: s}},
+									Quantity:  directives.Decimal{Range: directives.Range{Start: 23, End: 24, Text: s}},
+									Commodity: directives.Commodity{Range: Range{Start: 25, End: 28, Text: s}},
+								},
+							},
+						},
+					}
+				},
+			},
 			{
 				text: "2023-04-03 price CHF 0.83 USD",
 				want: func(s string) directives.Directive {
@@ -1371,6 +1650,77 @@ func TestParseDirective(t *testing.T) {
 					}
 				},
 			},
+			{
+				text: "template split a c \"foo\"\n" + "A B $a $c\n", // 25 + 10
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 35, Text: s},
+						Directive: directives.Template{
+							Range: Range{End: 35, Text: s},
+							Name:  Range{Start: 9, End: 14, Text: s},
+							Params: []Range{
+								{Start: 15, End: 16, Text: s},
+								{Start: 17, End: 18, Text: s},
+							},
+							Description: directives.QuotedString{
+								Range:   Range{Start: 19, End: 24, Text: s},
+								Content: Range{Start: 20, End: 23, Text: s},
+							},
+							Bookings: []directives.Booking{
+								{
+									Range:     Range{Start: 25, End: 34, Text: s},
+									Credit:    directives.Account{Range: Range{Start: 25, End: 26, Text: s}},
+									Debit:     directives.Account{Range: Range{Start: 27, End: 28, Text: s}},
+									Quantity:  directives.Decimal{Range: Range{Start: 29, End: 31, Text: s}},
+									Commodity: directives.Commodity{Range: Range{Start: 32, End: 34, Text: s}},
+								},
+							},
+						},
+					}
+				},
+			},
+			{
+				text: "2023-04-03 apply split 100 USD A // note",
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 32, Text: s},
+						Directive: directives.Apply{
+							Range: Range{End: 32, Text: s},
+							Date:  directives.Date{Range: directives.Range{End: 10, Text: s}},
+							Name:  Range{Start: 17, End: 22, Text: s},
+							Args: []Range{
+								{Start: 23, End: 26, Text: s},
+								{Start: 27, End: 30, Text: s},
+								{Start: 31, End: 32, Text: s},
+							},
+						},
+					}
+				},
+			},
+			{
+				text: "tag #travel  #work // note",
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range: Range{End: 18, Text: s},
+						Directive: directives.TagBlock{
+							Range: Range{End: 18, Text: s},
+							Tags: []directives.Tag{
+								{Range: Range{Start: 4, End: 11, Text: s}},
+								{Range: Range{Start: 13, End: 18, Text: s}},
+							},
+						},
+					}
+				},
+			},
+			{
+				text: "end tag",
+				want: func(s string) directives.Directive {
+					return directives.Directive{
+						Range:     Range{End: 7, Text: s},
+						Directive: directives.EndTagBlock{Range: Range{End: 7, Text: s}},
+					}
+				},
+			},
 		},
 		desc: "p.parseDirective()",
 		fn: func(p *Parser) (directives.Directive, error) {
@@ -1396,6 +1746,12 @@ func TestParseRestOfWhitespaceLine(t *testing.T) {
 					return Range{End: 2, Text: s}
 				},
 			},
+			{
+				text: " // foo\n",
+				want: func(s string) Range {
+					return Range{End: 8, Text: s}
+				},
+			},
 			{
 				text: " foo",
 				want: func(s string) Range {
@@ -57,10 +57,20 @@ func (p *Printer) printDirective(directive directives.Directive) error {
 		return p.printClose(d)
 	case directives.Assertion:
 		return p.printAssertion(d)
+	case directives.Opening:
+		return p.printOpening(d)
 	case directives.Include:
 		return p.printInclude(d)
 	case directives.Price:
 		return p.printPrice(d)
+	case directives.Template:
+		return p.printTemplate(d)
+	case directives.Apply:
+		return p.printApply(d)
+	case directives.TagBlock:
+		return p.printTagBlock(d)
+	case directives.EndTagBlock:
+		return p.printEndTagBlock(d)
 	}
 	return fmt.Errorf("unknown directive: %v", directive)
 }
@@ -76,11 +86,68 @@ func (p *Printer) printTransaction(t directives.Transaction) error {
 		for _, t := range t.Addons.Performance.Targets {
 			s = append(s, t.Extract())
 		}
-		if _, err := fmt.Fprintf(p, "@performance(%s)\n", strings.Join(s, ",")); err != nil {
+		if _, err := fmt.Fprintf(p, "@performance(%s)", strings.Join(s, ",")); err != nil {
+			return err
+		}
+		if err := p.printComment(t.Addons.Performance.Comment); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(p, "\n"); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(p, t.Date.Extract()); err != nil {
+		return err
+	}
+	if !t.Effective.Empty() {
+		if _, err := fmt.Fprintf(p, "=%s", t.Effective.Extract()); err != nil {
+			return err
+		}
+	}
+	if !t.Time.Empty() {
+		if _, err := fmt.Fprintf(p, " %s", t.Time.Extract()); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(p, ` "%s"`, t.Description.Content.Extract()); err != nil {
+		return err
+	}
+	if err := p.printTags(t.Tags); err != nil {
+		return err
+	}
+	if err := p.printComment(t.Comment); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(p, "\n"); err != nil {
+		return err
+	}
+	for _, po := range t.Bookings {
+		if err := p.printPosting(po); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(p, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Printer) printTemplate(t directives.Template) error {
+	if _, err := fmt.Fprintf(p, "template %s", t.Name.Extract()); err != nil {
+		return err
+	}
+	for _, param := range t.Params {
+		if _, err := fmt.Fprintf(p, " %s", param.Extract()); err != nil {
 			return err
 		}
 	}
-	if _, err := fmt.Fprintf(p, `%s "%s"`, t.Date.Extract(), t.Description.Content.Extract()); err != nil {
+	if _, err := fmt.Fprintf(p, ` "%s"`, t.Description.Content.Extract()); err != nil {
+		return err
+	}
+	if err := p.printTags(t.Tags); err != nil {
+		return err
+	}
+	if err := p.printComment(t.Comment); err != nil {
 		return err
 	}
 	if _, err := io.WriteString(p, "\n"); err != nil {
@@ -97,23 +164,112 @@ func (p *Printer) printTransaction(t directives.Transaction) error {
 	return nil
 }
 
+func (p *Printer) printApply(a directives.Apply) error {
+	if _, err := fmt.Fprintf(p, "%s apply %s", a.Date.Extract(), a.Name.Extract()); err != nil {
+		return err
+	}
+	for _, arg := range a.Args {
+		if _, err := fmt.Fprintf(p, " %s", arg.Extract()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Printer) printTagBlock(b directives.TagBlock) error {
+	if _, err := io.WriteString(p, "tag"); err != nil {
+		return err
+	}
+	return p.printTags(b.Tags)
+}
+
+func (p *Printer) printEndTagBlock(directives.EndTagBlock) error {
+	_, err := io.WriteString(p, "end tag")
+	return err
+}
+
 func (p *Printer) printAccrual(a directives.Accrual) error {
-	_, err := fmt.Fprintf(p, "@accrue %s %s %s %s\n", a.Interval.Extract(), a.Start.Extract(), a.End.Extract(), a.Account.Extract())
+	if _, err := fmt.Fprintf(p, "@accrue %s %s %s %s", a.Interval.Extract(), a.Start.Extract(), a.End.Extract(), a.Account.Extract()); err != nil {
+		return err
+	}
+	if !a.Boundary.Empty() {
+		if _, err := fmt.Fprintf(p, " %s", a.Boundary.Extract()); err != nil {
+			return err
+		}
+	}
+	if !a.Remainder.Empty() {
+		if _, err := fmt.Fprintf(p, " %s", a.Remainder.Extract()); err != nil {
+			return err
+		}
+	}
+	if err := p.printComment(a.Comment); err != nil {
+		return err
+	}
+	_, err := io.WriteString(p, "\n")
 	return err
 }
 
 func (p *Printer) printPosting(t directives.Booking) error {
-	_, err := fmt.Fprintf(p, "%-*s %-*s %10s %s", p.padding, t.Credit.Extract(), p.padding, t.Debit.Extract(), t.Quantity.Extract(), t.Commodity.Extract())
+	if !t.State.Empty() {
+		if _, err := fmt.Fprintf(p, "%s ", t.State.Extract()); err != nil {
+			return err
+		}
+	}
+	credit, debit := t.Credit.Extract(), t.Debit.Extract()
+	if t.Virtual {
+		credit, debit = "("+credit+")", "("+debit+")"
+	}
+	if _, err := fmt.Fprintf(p, "%-*s %-*s %10s %s", p.padding, credit, p.padding, debit, t.Quantity.Extract(), t.Commodity.Extract()); err != nil {
+		return err
+	}
+	if err := p.printTags(t.Tags); err != nil {
+		return err
+	}
+	return p.printComment(t.Comment)
+}
+
+// printComment prints the comment at the end of a line, if there is one.
+func (p *Printer) printComment(c directives.Range) error {
+	if c.Empty() {
+		return nil
+	}
+	_, err := fmt.Fprintf(p, " %s", c.Extract())
 	return err
 }
 
+func (p *Printer) printTags(ts []directives.Tag) error {
+	for _, t := range ts {
+		if _, err := fmt.Fprintf(p, " %s", t.Extract()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *Printer) printOpen(o directives.Open) error {
-	_, err := fmt.Fprintf(p, "%s open %s", o.Date.Extract(), o.Account.Extract())
-	return err
+	if _, err := fmt.Fprintf(p, "%s open %s", o.Date.Extract(), o.Account.Extract()); err != nil {
+		return err
+	}
+	for _, c := range o.Commodities {
+		if _, err := fmt.Fprintf(p, " %s", c.Extract()); err != nil {
+			return err
+		}
+	}
+	return p.printNote(o.Note)
 }
 
 func (p *Printer) printClose(c directives.Close) error {
-	_, err := fmt.Fprintf(p, "%s close %s", c.Date.Extract(), c.Account.Extract())
+	if _, err := fmt.Fprintf(p, "%s close %s", c.Date.Extract(), c.Account.Extract()); err != nil {
+		return err
+	}
+	return p.printNote(c.Note)
+}
+
+func (p *Printer) printNote(n directives.QuotedString) error {
+	if n.Empty() {
+		return nil
+	}
+	_, err := fmt.Fprintf(p, ` "%s"`, n.Content.Extract())
 	return err
 }
 
@@ -131,15 +287,32 @@ func (p *Printer) printAssertion(a directives.Assertion) error {
 	if _, err := fmt.Fprintf(p, "%s balance", a.Date.Extract()); err != nil {
 		return err
 	}
-	if len(a.Balances) == 1 {
-		_, err := fmt.Fprintf(p, " %s %s %s", a.Balances[0].Account.Extract(), a.Balances[0].Quantity.Extract(), a.Balances[0].Commodity.Extract())
+	return p.printBalances(a.Balances)
+}
+
+func (p *Printer) printOpening(o directives.Opening) error {
+	if _, err := fmt.Fprintf(p, "%s opening", o.Date.Extract()); err != nil {
+		return err
+	}
+	return p.printBalances(o.Balances)
+}
+
+func (p *Printer) printBalances(bs []directives.Balance) error {
+	if len(bs) == 1 {
+		_, err := fmt.Fprintf(p, " %s %s %s", bs[0].Account.Extract(), bs[0].Quantity.Extract(), bs[0].Commodity.Extract())
 		return err
 	}
 	if _, err := io.WriteString(p, "\n"); err != nil {
 		return err
 	}
-	for _, bal := range a.Balances {
-		if _, err := fmt.Fprintf(p, "%s %s %s\n", bal.Account.Extract(), bal.Quantity.Extract(), bal.Commodity.Extract()); err != nil {
+	for _, bal := range bs {
+		if _, err := fmt.Fprintf(p, "%s %s %s", bal.Account.Extract(), bal.Quantity.Extract(), bal.Commodity.Extract()); err != nil {
+			return err
+		}
+		if err := p.printComment(bal.Comment); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(p, "\n"); err != nil {
 			return err
 		}
 	}
@@ -162,15 +335,22 @@ func (p *Printer) PrintFile(f directives.File) (int, error) {
 // Initialize initializes the padding of this printer.
 func (p *Printer) Initialize(directive []directives.Directive) {
 	for _, d := range directive {
-		t, ok := d.Directive.(directives.Transaction)
-		if !ok {
-			continue
+		var bookings []directives.Booking
+		switch t := d.Directive.(type) {
+		case directives.Transaction:
+			bookings = t.Bookings
+		case directives.Template:
+			bookings = t.Bookings
 		}
-		for _, b := range t.Bookings {
-			if l := utf8.RuneCountInString(b.Credit.Extract()); l > p.padding {
+		for _, b := range bookings {
+			var parens int
+			if b.Virtual {
+				parens = 2
+			}
+			if l := utf8.RuneCountInString(b.Credit.Extract()) + parens; l > p.padding {
 				p.padding = l
 			}
-			if l := utf8.RuneCountInString(b.Debit.Extract()); l > p.padding {
+			if l := utf8.RuneCountInString(b.Debit.Extract()) + parens; l > p.padding {
 				p.padding = l
 			}
 		}
@@ -27,6 +27,32 @@ func TestPrintFile(t *testing.T) {
 				"",
 			),
 		},
+		{
+			desc: "print transaction with tags",
+			text: lines(
+				`2022-03-03    "Hello, world"  #foo   #bar`,
+				`A:B:C       C:B:ASDF   400 CHF   #baz`,
+			),
+			want: lines(
+				`2022-03-03 "Hello, world" #foo #bar`,
+				"A:B:C C:B:ASDF        400 CHF #baz",
+				"",
+			),
+		},
+		{
+			desc: "print transaction with states",
+			text: lines(
+				`2022-03-03    "Hello, world"`,
+				`*   A:B:C       C:B:ASDF   400 CHF`,
+				`!  A:B:C       C:B:ASDF   100 CHF`,
+			),
+			want: lines(
+				`2022-03-03 "Hello, world"`,
+				"* A:B:C C:B:ASDF        400 CHF",
+				"! A:B:C C:B:ASDF        100 CHF",
+				"",
+			),
+		},
 		{
 			desc: "print transactions",
 			text: lines(
@@ -111,6 +137,38 @@ func TestPrintFile(t *testing.T) {
 				`2022-03-03 open XYZ:ABC3`,
 			),
 		},
+		{
+			desc: "print transaction with time",
+			text: lines(
+				`2022-03-03   09:30  "Buy"`,
+				`A:B C:D 1 USD`,
+			),
+			want: lines(
+				`2022-03-03 09:30 "Buy"`,
+				`A:B C:D          1 USD`,
+				``,
+			),
+		},
+		{
+			desc: "print open with note",
+			text: lines(
+				`2022-03-03       open XYZ:ABC    "Bank, account 123"   `,
+				`2022-03-03 close   XYZ:ABC "closed"`,
+			),
+			want: lines(
+				`2022-03-03 open XYZ:ABC "Bank, account 123"`,
+				`2022-03-03 close XYZ:ABC "closed"`,
+			),
+		},
+		{
+			desc: "print open with commodities",
+			text: lines(
+				`2022-03-03       open XYZ:ABC   USD  CHF   "note"`,
+			),
+			want: lines(
+				`2022-03-03 open XYZ:ABC USD CHF "note"`,
+			),
+		},
 		{
 			desc: "print close",
 			text: lines(
@@ -165,6 +223,25 @@ func TestPrintFile(t *testing.T) {
 				`2022-03-03 balance XYZ:ABC:3 -0.3 CHF`,
 			),
 		},
+		{
+			desc: "print opening",
+			text: lines(`2022-03-03  opening    XYZ:ABC 1000 CHF`),
+			want: lines(`2022-03-03 opening XYZ:ABC 1000 CHF`),
+		},
+		{
+			desc: "print multi opening",
+			text: lines(
+				`2022-03-03  opening`,
+				`XYZ:ABC   1000 CHF`,
+				`ABC:XYZ  100        USD`,
+			),
+			want: lines(
+				`2022-03-03 opening`,
+				`XYZ:ABC 1000 CHF`,
+				`ABC:XYZ 100 USD`,
+				``,
+			),
+		},
 		{
 			desc: "print price",
 			text: lines(
@@ -185,6 +262,32 @@ func TestPrintFile(t *testing.T) {
 				`2022-03-03 price USD 0.895 CHF`,
 			),
 		},
+		{
+			desc: "print template and apply",
+			text: lines(
+				`template   split amount  account "Split bill"   #dinner`,
+				`Assets:Bank   $account   $amount  USD`,
+				``,
+				`2022-03-03  apply  split   10.50  Liabilities:Friend`,
+			),
+			want: lines(
+				`template split amount account "Split bill" #dinner`,
+				"Assets:Bank $account    $amount USD",
+				``,
+				`2022-03-03 apply split 10.50 Liabilities:Friend`,
+			),
+		},
+		{
+			desc: "print tag block",
+			text: lines(
+				`tag   #travel   #work`,
+				`end   tag`,
+			),
+			want: lines(
+				`tag #travel #work`,
+				`end tag`,
+			),
+		},
 	}
 
 	for _, test := range tests {
@@ -235,6 +338,52 @@ func TestFormat(t *testing.T) {
 				`2022-03-03 price USD 0.895 CHF`,
 			),
 		},
+		{
+			desc: "keep trailing comments",
+			text: lines(
+				`@accrue monthly 2023-01-01 2023-02-01 Assets:Accrual // c1`,
+				`2023-01-02 "x"   // c2`,
+				`Assets:Bank Expenses:Food 10 CHF // c3`,
+				``,
+				`2023-01-03 balance`,
+				`Assets:Bank -10 CHF // c4`,
+				`Assets:Cash 0 CHF`,
+			),
+			want: lines(
+				`@accrue monthly 2023-01-01 2023-02-01 Assets:Accrual // c1`,
+				`2023-01-02 "x" // c2`,
+				`Assets:Bank   Expenses:Food         10 CHF // c3`,
+				``,
+				`2023-01-03 balance`,
+				`Assets:Bank -10 CHF // c4`,
+				`Assets:Cash 0 CHF`,
+			),
+		},
+		{
+			desc: "keep comments after open and close",
+			text: lines(
+				`2023-01-01 open Assets:A // c1`,
+				`2023-01-01 open Assets:B CHF  // c2`,
+				`2023-01-01 open Assets:C "note" // c3`,
+				`2023-02-01 close Assets:A // c4`,
+				`2023-02-01 close Assets:B "note"	// c5`,
+				`2023-02-01 apply t 1 // c6`,
+				`2023-02-01 price USD 0.9 CHF // c7`,
+				`tag #a // c8`,
+				`end tag`,
+			),
+			want: lines(
+				`2023-01-01 open Assets:A // c1`,
+				`2023-01-01 open Assets:B CHF  // c2`,
+				`2023-01-01 open Assets:C "note" // c3`,
+				`2023-02-01 close Assets:A // c4`,
+				`2023-02-01 close Assets:B "note"	// c5`,
+				`2023-02-01 apply t 1 // c6`,
+				`2023-02-01 price USD 0.9 CHF // c7`,
+				`tag #a // c8`,
+				`end tag`,
+			),
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,119 @@
+package rename
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sboehler/knut/lib/syntax"
+)
+
+// Rule renames an account and all of its sub-accounts.
+type Rule struct {
+	Old, New string
+}
+
+// Apply returns the new name of the account, and false if the rule does
+// not apply to it.
+func (r Rule) Apply(name string) (string, bool) {
+	if name == r.Old {
+		return r.New, true
+	}
+	if rest, ok := strings.CutPrefix(name, r.Old+":"); ok {
+		return r.New + ":" + rest, true
+	}
+	return "", false
+}
+
+// File returns the text of the file with the accounts of all directives
+// renamed according to the rule, along with the number of renamed
+// accounts. The text outside of the renamed accounts is preserved.
+func File(f syntax.File, r Rule) (string, int) {
+	var accounts []syntax.Account
+	templates := make(map[string]syntax.Template)
+	for _, d := range f.Directives {
+		accounts = append(accounts, Accounts(d)...)
+		if t, ok := d.Directive.(syntax.Template); ok {
+			if _, ok := templates[t.Name.Extract()]; !ok {
+				templates[t.Name.Extract()] = t
+			}
+		}
+	}
+	for _, d := range f.Directives {
+		if a, ok := d.Directive.(syntax.Apply); ok {
+			accounts = append(accounts, ApplyAccounts(templates[a.Name.Extract()], a)...)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Start < accounts[j].Start })
+	var (
+		b     strings.Builder
+		pos   int
+		count int
+	)
+	for _, a := range accounts {
+		if a.Macro {
+			continue
+		}
+		name, ok := r.Apply(a.Extract())
+		if !ok {
+			continue
+		}
+		b.WriteString(f.Text[pos:a.Start])
+		b.WriteString(name)
+		pos = a.End
+		count++
+	}
+	b.WriteString(f.Text[pos:])
+	return b.String(), count
+}
+
+// Accounts returns the accounts which the directive refers to.
+func Accounts(d syntax.Directive) []syntax.Account {
+	var res []syntax.Account
+	switch t := d.Directive.(type) {
+	case syntax.Open:
+		res = append(res, t.Account)
+	case syntax.Close:
+		res = append(res, t.Account)
+	case syntax.Assertion:
+		for _, b := range t.Balances {
+			res = append(res, b.Account)
+		}
+	case syntax.Opening:
+		for _, b := range t.Balances {
+			res = append(res, b.Account)
+		}
+	case syntax.Transaction:
+		if !t.Addons.Accrual.Empty() {
+			res = append(res, t.Addons.Accrual.Account)
+		}
+		for _, b := range t.Bookings {
+			res = append(res, b.Credit, b.Debit)
+		}
+	case syntax.Template:
+		for _, b := range t.Bookings {
+			res = append(res, b.Credit, b.Debit)
+		}
+	}
+	return res
+}
+
+// ApplyAccounts returns the arguments of the apply directive which the
+// template substitutes for an account. Templates are only visible in the
+// file which defines them, so the template must come from the same file.
+func ApplyAccounts(t syntax.Template, a syntax.Apply) []syntax.Account {
+	params := make(map[string]bool)
+	for _, b := range t.Bookings {
+		for _, acc := range []syntax.Account{b.Credit, b.Debit} {
+			if acc.Macro {
+				params[acc.Extract()] = true
+			}
+		}
+	}
+	var res []syntax.Account
+	for i, p := range t.Params {
+		if i < len(a.Args) && params["$"+p.Extract()] {
+			res = append(res, syntax.Account{Range: a.Args[i]})
+		}
+	}
+	return res
+}
@@ -0,0 +1,80 @@
+package rename
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sboehler/knut/lib/syntax/parser"
+)
+
+func TestFile(t *testing.T) {
+	text := `2020-01-01 open Assets:Bank
+2020-01-01 open Assets:Bank:Savings
+2020-01-01 open Assets:Banking
+
+// keep comments
+2020-01-02 "Transfer"
+Assets:Bank    Assets:Bank:Savings   100 CHF
+
+2020-01-03 balance Assets:Bank:Savings 100 CHF
+`
+	p := parser.New(text, "")
+	if err := p.Advance(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := p.ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, n := File(f, Rule{Old: "Assets:Bank", New: "Assets:UBS"})
+
+	want := `2020-01-01 open Assets:UBS
+2020-01-01 open Assets:UBS:Savings
+2020-01-01 open Assets:Banking
+
+// keep comments
+2020-01-02 "Transfer"
+Assets:UBS    Assets:UBS:Savings   100 CHF
+
+2020-01-03 balance Assets:UBS:Savings 100 CHF
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("File() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if n != 5 {
+		t.Errorf("File() renamed %d accounts, want 5", n)
+	}
+}
+
+func TestFileTemplates(t *testing.T) {
+	text := `template transfer amount account "Transfer"
+Assets:Bank $account $amount CHF
+
+2020-01-02 apply transfer 100 Assets:Bank:Savings
+2020-01-03 apply transfer 100 Assets:Banking
+`
+	p := parser.New(text, "")
+	if err := p.Advance(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := p.ParseFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, n := File(f, Rule{Old: "Assets:Bank", New: "Assets:UBS"})
+
+	want := `template transfer amount account "Transfer"
+Assets:UBS $account $amount CHF
+
+2020-01-02 apply transfer 100 Assets:UBS:Savings
+2020-01-03 apply transfer 100 Assets:Banking
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("File() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+	if n != 2 {
+		t.Errorf("File() renamed %d accounts, want 2", n)
+	}
+}
@@ -0,0 +1,218 @@
+package split
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/sboehler/knut/lib/syntax"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v2"
+)
+
+// Rule expands bookings of matching transactions into multiple bookings.
+type Rule struct {
+	// Description selects the transactions by their description.
+	Description *regexp.Regexp
+
+	// Account selects the account of a booking which is split.
+	Account *regexp.Regexp
+
+	// Splits are the parts of the split.
+	Splits []Split
+
+	// Remainder is the account which receives the amount which is not
+	// covered by the splits.
+	Remainder string
+}
+
+// Split is a fixed amount or a percentage of the booking.
+type Split struct {
+	Account string
+	Amount  decimal.Decimal
+	Percent decimal.Decimal
+}
+
+type yamlRule struct {
+	Description string      `yaml:"description"`
+	Account     string      `yaml:"account"`
+	Splits      []yamlSplit `yaml:"splits"`
+	Remainder   string      `yaml:"remainder"`
+}
+
+type yamlSplit struct {
+	Account string  `yaml:"account"`
+	Amount  *string `yaml:"amount"`
+	Percent *string `yaml:"percent"`
+}
+
+// LoadRulesFromFile loads rules from a YAML file.
+func LoadRulesFromFile(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadRules(f)
+}
+
+// LoadRules loads rules in YAML format.
+func LoadRules(r io.Reader) ([]Rule, error) {
+	dec := yaml.NewDecoder(r)
+	dec.SetStrict(true)
+	var rs []yamlRule
+	if err := dec.Decode(&rs); err != nil {
+		return nil, err
+	}
+	var res []Rule
+	for _, r := range rs {
+		rule, err := fromYAML(r)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rule)
+	}
+	return res, nil
+}
+
+func fromYAML(r yamlRule) (Rule, error) {
+	var (
+		rule = Rule{Remainder: r.Remainder}
+		err  error
+	)
+	if rule.Description, err = regexp.Compile(r.Description); err != nil {
+		return rule, err
+	}
+	if len(r.Account) == 0 {
+		return rule, fmt.Errorf("rule %q: missing account", r.Description)
+	}
+	if rule.Account, err = regexp.Compile(r.Account); err != nil {
+		return rule, err
+	}
+	for _, s := range r.Splits {
+		split := Split{Account: s.Account}
+		switch {
+		case s.Amount != nil && s.Percent == nil:
+			if split.Amount, err = decimal.NewFromString(*s.Amount); err != nil {
+				return rule, fmt.Errorf("rule %q: %w", r.Description, err)
+			}
+		case s.Percent != nil && s.Amount == nil:
+			if split.Percent, err = decimal.NewFromString(*s.Percent); err != nil {
+				return rule, fmt.Errorf("rule %q: %w", r.Description, err)
+			}
+		default:
+			return rule, fmt.Errorf("rule %q: split to %s needs either an amount or a percentage", r.Description, s.Account)
+		}
+		rule.Splits = append(rule.Splits, split)
+	}
+	return rule, nil
+}
+
+// Splitter applies rules to transactions.
+type Splitter struct {
+	Rules []Rule
+
+	// CatchAll receives the remainder of a split if the rule does not
+	// define a remainder account.
+	CatchAll string
+}
+
+// Apply splits the bookings of the given transaction according to the first
+// matching rule. Every booking is replaced by one booking per split and a
+// booking for the remainder, which keeps the transaction balanced.
+func (s Splitter) Apply(t *syntax.Transaction) error {
+	desc := t.Description.Content.Extract()
+	for _, rule := range s.Rules {
+		if !rule.Description.MatchString(desc) {
+			continue
+		}
+		var bookings []syntax.Booking
+		for _, b := range t.Bookings {
+			bs, err := s.split(rule, b)
+			if err != nil {
+				return err
+			}
+			bookings = append(bookings, bs...)
+		}
+		t.Bookings = bookings
+		return nil
+	}
+	return nil
+}
+
+// ApplyFile splits the bookings of all transactions of the given file.
+func (s Splitter) ApplyFile(f *syntax.File) error {
+	for i := range f.Directives {
+		if t, ok := f.Directives[i].Directive.(syntax.Transaction); ok {
+			if err := s.Apply(&t); err != nil {
+				return err
+			}
+			f.Directives[i].Directive = t
+		}
+	}
+	return nil
+}
+
+func (s Splitter) split(rule Rule, b syntax.Booking) ([]syntax.Booking, error) {
+	var debit bool
+	switch {
+	case rule.Account.MatchString(b.Debit.Extract()):
+		debit = true
+	case rule.Account.MatchString(b.Credit.Extract()):
+		debit = false
+	default:
+		return []syntax.Booking{b}, nil
+	}
+	quantity, err := b.Quantity.Parse()
+	if err != nil {
+		return nil, err
+	}
+	places := -quantity.Exponent()
+	if places < 0 {
+		places = 0
+	}
+	var (
+		res       []syntax.Booking
+		remainder = quantity
+	)
+	create := func(account string, q decimal.Decimal) {
+		nb := b
+		nb.Quantity = syntax.Decimal{Range: text(q.StringFixed(places))}
+		if debit {
+			nb.Debit = syntax.Account{Range: text(account)}
+		} else {
+			nb.Credit = syntax.Account{Range: text(account)}
+		}
+		res = append(res, nb)
+	}
+	for _, split := range rule.Splits {
+		q := split.Amount
+		if quantity.IsNegative() {
+			q = q.Neg()
+		}
+		if !split.Percent.IsZero() {
+			q = quantity.Mul(split.Percent).Div(decimal.NewFromInt(100)).Round(places)
+		}
+		create(split.Account, q)
+		remainder = remainder.Sub(q)
+	}
+	if !remainder.IsZero() {
+		account := rule.Remainder
+		if len(account) == 0 {
+			account = s.CatchAll
+		}
+		if len(account) == 0 {
+			return nil, syntax.Error{
+				Range:   b.Range,
+				Message: fmt.Sprintf("split leaves a remainder of %s, but no remainder account is configured", remainder),
+			}
+		}
+		create(account, remainder)
+	}
+	return res, nil
+}
+
+func text(s string) syntax.Range {
+	return syntax.Range{End: len(s), Text: s}
+}
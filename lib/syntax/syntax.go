@@ -2,16 +2,21 @@ package syntax
 
 import (
 	"context"
+	"fmt"
 	"io"
-	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"text/scanner"
 
+	"github.com/sboehler/knut/lib/common/compare"
 	"github.com/sboehler/knut/lib/common/cpr"
 	"github.com/sboehler/knut/lib/syntax/directives"
 	"github.com/sboehler/knut/lib/syntax/parser"
 	"github.com/sboehler/knut/lib/syntax/printer"
+	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -21,10 +26,16 @@ type Account = directives.Account
 
 type Date = directives.Date
 
+type Time = directives.Time
+
 type Decimal = directives.Decimal
 
 type QuotedString = directives.QuotedString
 
+type Tag = directives.Tag
+
+type State = directives.State
+
 type Booking = directives.Booking
 
 type Performance = directives.Performance
@@ -37,6 +48,10 @@ type File = directives.File
 
 type Accrual = directives.Accrual
 
+type Boundary = directives.Boundary
+
+type Remainder = directives.Remainder
+
 type Addons = directives.Addons
 
 type Transaction = directives.Transaction
@@ -49,10 +64,20 @@ type Assertion = directives.Assertion
 
 type Balance = directives.Balance
 
+type Opening = directives.Opening
+
 type Price = directives.Price
 
 type Include = directives.Include
 
+type Template = directives.Template
+
+type Apply = directives.Apply
+
+type TagBlock = directives.TagBlock
+
+type EndTagBlock = directives.EndTagBlock
+
 type Range = directives.Range
 
 type Location = directives.Location
@@ -66,7 +91,7 @@ type Parser = parser.Parser
 type Scanner = scanner.Scanner
 
 func ParseFile(file string) (directives.File, error) {
-	text, err := os.ReadFile(file)
+	text, err := ReadFile(file)
 	if err != nil {
 		return directives.File{}, err
 	}
@@ -78,10 +103,66 @@ func ParseFile(file string) (directives.File, error) {
 }
 
 func ParseFileRecursively(file string) (<-chan directives.File, func(context.Context) error) {
+	return new(Resolver).ParseFileRecursively(file)
+}
+
+// Resolver resolves include directives while parsing a journal. It
+// detects files which are included more than once, for example directly
+// and transitively, as their directives would otherwise be duplicated.
+// A Resolver must not be reused.
+type Resolver struct {
+	// Dedupe skips files which have already been included.
+	Dedupe bool
+
+	// Warn, if not nil, is called for every file which is included and
+	// parsed more than once. It may be called concurrently.
+	Warn func(Duplicate)
+
+	// Parallelism is the maximum number of files which are parsed
+	// concurrently. Zero means runtime.GOMAXPROCS(0).
+	Parallelism int
+
+	// Sequential asks for the files to be read one after the other, in a
+	// fixed order, on the calling goroutine. See ParseFiles.
+	Sequential bool
+
+	mu       sync.Mutex
+	seen     map[string]bool
+	included map[string]directives.Range
+	sem      chan struct{}
+}
+
+// Workers returns the number of workers for the given parallelism, which
+// is runtime.GOMAXPROCS(0) if it is not positive.
+func Workers(parallelism int) int {
+	if parallelism <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return parallelism
+}
+
+// Duplicate is an include directive of a file which has already been
+// included.
+type Duplicate struct {
+	Path    string
+	Include directives.Include
+}
+
+func (d Duplicate) String() string {
+	rng := d.Include.Range
+	rng.End = rng.Start
+	return fmt.Sprintf("%s: %s file %s has already been included", rng.Path, rng.Location(), d.Path)
+}
+
+// ParseFileRecursively parses the given file and all files which it
+// includes, recursively.
+func (r *Resolver) ParseFileRecursively(file string) (<-chan directives.File, func(context.Context) error) {
+	r.visit(file)
+	r.sem = make(chan struct{}, Workers(r.Parallelism))
 	return cpr.Produce(func(ctx context.Context, ch chan<- directives.File) error {
 		wg, ctx := errgroup.WithContext(ctx)
 		wg.Go(func() error {
-			res, err := parseRec(ctx, wg, ch, file)
+			res, err := r.parseRec(ctx, wg, ch, nil, file)
 			if err != nil {
 				return err
 			}
@@ -91,13 +172,259 @@ func ParseFileRecursively(file string) (<-chan directives.File, func(context.Con
 	})
 }
 
+// ParseFiles parses the given file and the files which it includes, one
+// after the other on the calling goroutine. Every file is followed by the
+// files which it includes, in the order of the include directives.
+func (r *Resolver) ParseFiles(file string) ([]directives.File, error) {
+	r.visit(file)
+	var res []directives.File
+	var parse func([]string, string) error
+	parse = func(parents []string, file string) error {
+		chain := append(slices.Clip(parents), file)
+		text, err := ReadFile(file)
+		if err != nil {
+			return err
+		}
+		p := parser.New(string(text), file)
+		if err := p.Advance(); err != nil {
+			return err
+		}
+		var (
+			includes []string
+			incErr   error
+		)
+		p.Callback = func(d directives.Directive) {
+			if inc, ok := d.Directive.(directives.Include); ok && incErr == nil {
+				file := path.Join(filepath.Dir(file), inc.IncludePath.Content.Extract())
+				ok, err := r.include(chain, file, inc)
+				if err != nil {
+					incErr = err
+				} else if ok {
+					includes = append(includes, file)
+				}
+			}
+		}
+		f, err := p.ParseFile()
+		if err != nil {
+			return err
+		}
+		if incErr != nil {
+			return incErr
+		}
+		res = append(res, f)
+		for _, inc := range includes {
+			if err := parse(chain, inc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return res, parse(nil, file)
+}
+
+// key returns the absolute path of the file, with symbolic links
+// resolved, such that every file has a single key.
+func key(file string) string {
+	key, err := filepath.Abs(file)
+	if err != nil {
+		key = file
+	}
+	if resolved, err := filepath.EvalSymlinks(key); err == nil {
+		key = resolved
+	}
+	return key
+}
+
+// visit marks the file as included. It returns false if the file has
+// already been included before.
+func (r *Resolver) visit(file string) bool {
+	key := key(file)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen == nil {
+		r.seen = make(map[string]bool)
+	}
+	if r.seen[key] {
+		return false
+	}
+	r.seen[key] = true
+	return true
+}
+
+// include reports whether an included file should be parsed. The chain
+// are the files which lead to the include directive, from the outermost
+// file to the file containing the directive. If the included file is one
+// of them, parsing it again would never end, and include returns an
+// error.
+func (r *Resolver) include(chain []string, file string, inc directives.Include) (bool, error) {
+	if r.visit(file) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.included == nil {
+			r.included = make(map[string]directives.Range)
+		}
+		r.included[file] = inc.Range
+		return true, nil
+	}
+	if r.Dedupe {
+		return false, nil
+	}
+	k := key(file)
+	for i, f := range chain {
+		if key(f) == k {
+			rng := inc.Range
+			rng.End = rng.Start
+			return false, directives.Error{
+				Range:   rng,
+				Message: fmt.Sprintf("include cycle: %s", strings.Join(append(slices.Clone(chain[i:]), file), " -> ")),
+			}
+		}
+	}
+	if r.Warn != nil {
+		r.Warn(Duplicate{Path: file, Include: inc})
+	}
+	return true, nil
+}
+
+// Compare orders ranges by their position in the journal, as if every
+// include directive was replaced by the text of the included file. A
+// file which is included more than once is at its first include. Ranges
+// of files which the resolver has not included are ordered by path.
+func (r *Resolver) Compare(r1, r2 directives.Range) compare.Order {
+	path1, pos1 := r.position(r1)
+	path2, pos2 := r.position(r2)
+	if o := compare.Ordered(path1, path2); o != compare.Equal {
+		return o
+	}
+	return slices.Compare(pos1, pos2)
+}
+
+// position returns the path of the outermost file which includes the
+// range, and the offsets of the include directives which lead to it,
+// followed by the offset of the range.
+func (r *Resolver) position(rng directives.Range) (string, []int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pos := []int{rng.Start}
+	for {
+		inc, ok := r.included[rng.Path]
+		if !ok {
+			break
+		}
+		rng = inc
+		pos = append(pos, rng.Start)
+	}
+	slices.Reverse(pos)
+	return rng.Path, pos
+}
+
+// ParseAll parses the given file and all files which it includes,
+// recursively. Unlike ParseFileRecursively, it does not stop at the first
+// error: it skips broken directives and returns the directives which
+// could be parsed along with all errors.
+func ParseAll(file string) ([]directives.File, []error) {
+	return new(Resolver).ParseAll(file)
+}
+
+// ParseAll is like the function ParseAll, but resolves includes like
+// ParseFileRecursively, or like ParseFiles if the resolver is sequential.
+func (r *Resolver) ParseAll(file string) ([]directives.File, []error) {
+	r.visit(file)
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		files []directives.File
+		errs  []error
+	)
+	sem := make(chan struct{}, Workers(r.Parallelism))
+	var parse func([]string, string)
+	parse = func(parents []string, file string) {
+		chain := append(slices.Clip(parents), file)
+		var includes []string
+		f, ferrs := func() (*directives.File, []error) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			text, err := ReadFile(file)
+			if err != nil {
+				return nil, []error{err}
+			}
+			p := parser.New(string(text), file)
+			if err := p.Advance(); err != nil {
+				return nil, []error{err}
+			}
+			var incErrs []error
+			p.Callback = func(d directives.Directive) {
+				if inc, ok := d.Directive.(directives.Include); ok {
+					file := path.Join(filepath.Dir(file), inc.IncludePath.Content.Extract())
+					ok, err := r.include(chain, file, inc)
+					if err != nil {
+						incErrs = append(incErrs, err)
+					} else if ok {
+						includes = append(includes, file)
+					}
+				}
+			}
+			f, errs := p.ParseFileAll()
+			return &f, append(errs, incErrs...)
+		}()
+		mu.Lock()
+		if f != nil {
+			files = append(files, *f)
+		}
+		errs = append(errs, ferrs...)
+		mu.Unlock()
+		for _, inc := range includes {
+			if r.Sequential {
+				parse(chain, inc)
+				continue
+			}
+			wg.Add(1)
+			go func(inc string) {
+				defer wg.Done()
+				parse(chain, inc)
+			}(inc)
+		}
+	}
+	parse(nil, file)
+	wg.Wait()
+	return files, errs
+}
+
+// Files returns the path of the given file and of all files which it
+// includes, recursively. Every file is returned once, even if it is
+// included several times. In case of an error, the paths of the files
+// parsed so far are returned along with the error.
+func Files(ctx context.Context, file string) ([]string, error) {
+	r := &Resolver{Dedupe: true}
+	ch, worker := r.ParseFileRecursively(file)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- worker(ctx)
+	}()
+	var res []string
+	for f := range ch {
+		res = append(res, f.Path)
+	}
+	return res, <-errCh
+}
+
 type Result struct {
 	File directives.File
 	Err  error
 }
 
-func parseRec(ctx context.Context, wg *errgroup.Group, resCh chan<- directives.File, file string) (directives.File, error) {
-	text, err := os.ReadFile(file)
+func (r *Resolver) parseRec(ctx context.Context, wg *errgroup.Group, resCh chan<- directives.File, parents []string, file string) (directives.File, error) {
+	chain := append(slices.Clip(parents), file)
+	// Only the parsing itself is limited: the callback below starts the
+	// goroutines for included files without blocking, such that a file
+	// never waits for its own includes.
+	select {
+	case r.sem <- struct{}{}:
+	case <-ctx.Done():
+		return directives.File{}, ctx.Err()
+	}
+	defer func() { <-r.sem }()
+	text, err := ReadFile(file)
 	if err != nil {
 		return directives.File{}, err
 	}
@@ -108,8 +435,16 @@ func parseRec(ctx context.Context, wg *errgroup.Group, resCh chan<- directives.F
 	p.Callback = func(d directives.Directive) {
 		if inc, ok := d.Directive.(directives.Include); ok {
 			file := path.Join(filepath.Dir(file), inc.IncludePath.Content.Extract())
+			ok, err := r.include(chain, file, inc)
+			if err != nil {
+				wg.Go(func() error { return err })
+				return
+			}
+			if !ok {
+				return
+			}
 			wg.Go(func() error {
-				res, err := parseRec(ctx, wg, resCh, file)
+				res, err := r.parseRec(ctx, wg, resCh, chain, file)
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,123 @@
+package syntax
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolverDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.knut": "include \"a.knut\"\ninclude \"b.knut\"\n",
+		"a.knut":    "include \"b.knut\"\n",
+		"b.knut":    "2020-01-01 open Assets:B\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		dedupe             bool
+		parallelism        int
+		wantFiles, wantDup int
+	}{
+		{dedupe: false, wantFiles: 4, wantDup: 1},
+		{dedupe: true, wantFiles: 3, wantDup: 0},
+		{dedupe: false, parallelism: 1, wantFiles: 4, wantDup: 1},
+	} {
+		var dups int
+		r := &Resolver{Dedupe: test.dedupe, Parallelism: test.parallelism, Warn: func(Duplicate) { dups++ }}
+		ch, worker := r.ParseFileRecursively(filepath.Join(dir, "main.knut"))
+		errCh := make(chan error, 1)
+		go func() { errCh <- worker(context.Background()) }()
+		var n int
+		for range ch {
+			n++
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+		if n != test.wantFiles || dups != test.wantDup {
+			t.Errorf("dedupe=%t, parallelism=%d: got %d files and %d duplicates, want %d and %d", test.dedupe, test.parallelism, n, dups, test.wantFiles, test.wantDup)
+		}
+	}
+}
+
+func TestResolverCycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.knut": "include \"b.knut\"\n",
+		"b.knut": "2020-01-01 open Assets:B\n\ninclude \"a.knut\"\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b := filepath.Join(dir, "a.knut"), filepath.Join(dir, "b.knut")
+	want := fmt.Sprintf("%s: 3:1 include cycle: %s -> %s -> %s", b, a, b, a)
+
+	t.Run("recursive", func(t *testing.T) {
+		ch, worker := new(Resolver).ParseFileRecursively(a)
+		errCh := make(chan error, 1)
+		go func() { errCh <- worker(context.Background()) }()
+		for range ch {
+		}
+		if err := <-errCh; err == nil || err.Error() != want {
+			t.Errorf("ParseFileRecursively() returned error %v, want %q", err, want)
+		}
+	})
+	t.Run("sequential", func(t *testing.T) {
+		_, err := new(Resolver).ParseFiles(a)
+		if err == nil || err.Error() != want {
+			t.Errorf("ParseFiles() returned error %v, want %q", err, want)
+		}
+	})
+	t.Run("all", func(t *testing.T) {
+		_, errs := new(Resolver).ParseAll(a)
+		if len(errs) != 1 || errs[0].Error() != want {
+			t.Errorf("ParseAll() returned errors %v, want %q", errs, want)
+		}
+	})
+	t.Run("dedupe", func(t *testing.T) {
+		files, err := (&Resolver{Dedupe: true}).ParseFiles(a)
+		if err != nil || len(files) != 2 {
+			t.Errorf("ParseFiles() returned %d files and error %v, want 2 files", len(files), err)
+		}
+	})
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.knut": "include \"a.knut\"\ninclude \"b.knut\"\ninclude \"./b.knut\"\n",
+		"a.knut":    "include \"b.knut\"\n",
+		"b.knut":    "2020-01-01 open Assets:B\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Files(context.Background(), filepath.Join(dir, "main.knut"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	var want []string
+	for _, name := range []string{"a.knut", "b.knut", "main.knut"} {
+		want = append(want, filepath.Join(dir, name))
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Files() returned unexpected diff (-want/+got):\n%s\n", diff)
+	}
+}